@@ -17,6 +17,7 @@ import (
 
 	neko "github.com/m1k1o/neko/server"
 	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/utils"
 )
 
 func Execute() error {
@@ -125,14 +126,27 @@ func init() {
 			}
 		}
 
-		// save new logger output
-		log.Logger = log.Output(logWriter)
-
 		// set custom log level
 		if rootConfig.LogLevel != zerolog.NoLevel {
 			zerolog.SetGlobalLevel(rootConfig.LogLevel)
 		}
 
+		// per module log levels, global level must let through the most verbose one
+		if len(rootConfig.LogLevels) > 0 {
+			globalLevel := zerolog.GlobalLevel()
+			logWriter = utils.NewModuleLevelWriter(logWriter, globalLevel, rootConfig.LogLevels)
+
+			for _, level := range rootConfig.LogLevels {
+				if level < globalLevel {
+					globalLevel = level
+				}
+			}
+			zerolog.SetGlobalLevel(globalLevel)
+		}
+
+		// save new logger output
+		log.Logger = log.Output(logWriter)
+
 		// set custom log tiem format
 		if rootConfig.LogTime != "" {
 			zerolog.TimeFieldFormat = rootConfig.LogTime
@@ -154,6 +154,9 @@ func (c *serve) Start(cmd *cobra.Command) {
 	)
 	c.managers.desktop.Start()
 
+	// audio level is measured as often as it is sent to clients by webrtc
+	c.configs.Capture.AudioLevelInterval = c.configs.WebRTC.AudioLevelInterval
+
 	c.managers.capture = capture.New(
 		c.managers.desktop,
 		&c.configs.Capture,
@@ -162,6 +165,7 @@ func (c *serve) Start(cmd *cobra.Command) {
 
 	c.managers.webRTC = webrtc.New(
 		c.managers.desktop,
+		c.managers.session,
 		c.managers.capture,
 		&c.configs.WebRTC,
 	)
@@ -172,6 +176,7 @@ func (c *serve) Start(cmd *cobra.Command) {
 		c.managers.desktop,
 		c.managers.capture,
 		c.managers.webRTC,
+		&c.configs.Session,
 	)
 	c.managers.webSocket.Start()
 
@@ -180,6 +185,7 @@ func (c *serve) Start(cmd *cobra.Command) {
 		c.managers.member,
 		c.managers.desktop,
 		c.managers.capture,
+		c.managers.webRTC,
 	)
 
 	c.managers.plugins = plugins.New(
@@ -195,6 +201,7 @@ func (c *serve) Start(cmd *cobra.Command) {
 		c.managers.session,
 		c.managers.webSocket,
 		c.managers.api,
+		c.managers.webRTC,
 	)
 
 	c.managers.http = http.New(
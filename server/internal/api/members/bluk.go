@@ -45,6 +45,10 @@ func (h *MembersHandler) membersBulkUpdate(w http.ResponseWriter, r *http.Reques
 				Msgf("unable to unmarshal payload for member %s", memberId)
 		}
 
+		if err := body.Profile.ValidateBlockedKeys(); err != nil {
+			return utils.HttpBadRequest(err.Error())
+		}
+
 		if err := h.members.UpdateProfile(memberId, body.Profile); err != nil {
 			return utils.HttpInternalServerError().
 				WithInternalErr(err).
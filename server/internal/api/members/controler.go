@@ -81,6 +81,10 @@ func (h *MembersHandler) membersCreate(w http.ResponseWriter, r *http.Request) e
 		return utils.HttpBadRequest("password cannot be empty")
 	}
 
+	if err := data.Profile.ValidateBlockedKeys(); err != nil {
+		return utils.HttpBadRequest(err.Error())
+	}
+
 	id, err := h.members.Insert(data.Username, data.Password, data.Profile)
 	if err != nil {
 		if errors.Is(err, types.ErrMemberAlreadyExists) {
@@ -111,6 +115,10 @@ func (h *MembersHandler) membersUpdateProfile(w http.ResponseWriter, r *http.Req
 		return err
 	}
 
+	if err := data.ValidateBlockedKeys(); err != nil {
+		return utils.HttpBadRequest(err.Error())
+	}
+
 	if err := h.members.UpdateProfile(member.ID, *data); err != nil {
 		return utils.HttpInternalServerError().WithInternalErr(err)
 	}
@@ -0,0 +1,79 @@
+package room
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/auth"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+// maximum length of banner message, in bytes
+const bannerMaxLength = 1024
+
+type BannerPayload struct {
+	Banner *types.Banner `json:"banner"`
+}
+
+func (h *RoomHandler) bannerGet(w http.ResponseWriter, r *http.Request) error {
+	return utils.HttpSuccess(w, BannerPayload{
+		Banner: h.sessions.Banner(),
+	})
+}
+
+func (h *RoomHandler) bannerSet(w http.ResponseWriter, r *http.Request) error {
+	session, _ := auth.GetSession(r)
+
+	data := &types.Banner{}
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	if data.Message == "" {
+		return utils.HttpBadRequest("message is required")
+	}
+
+	if len(data.Message) > bannerMaxLength {
+		return utils.HttpBadRequest("message is too long")
+	}
+
+	switch data.Level {
+	case "":
+		data.Level = types.BannerLevelInfo
+	case types.BannerLevelInfo, types.BannerLevelWarning, types.BannerLevelError:
+	default:
+		return utils.HttpBadRequest("unknown banner level")
+	}
+
+	banner := &types.Banner{
+		Message:   data.Message,
+		Level:     data.Level,
+		CreatedAt: time.Now(),
+	}
+
+	h.sessions.SetBanner(banner)
+
+	h.sessions.Audit(types.AuditEntry{
+		Actor:   session.ID(),
+		Action:  types.AuditBannerSet,
+		Details: banner.Message,
+	})
+
+	return utils.HttpSuccess(w, BannerPayload{
+		Banner: banner,
+	})
+}
+
+func (h *RoomHandler) bannerClear(w http.ResponseWriter, r *http.Request) error {
+	session, _ := auth.GetSession(r)
+
+	h.sessions.SetBanner(nil)
+
+	h.sessions.Audit(types.AuditEntry{
+		Actor:  session.ID(),
+		Action: types.AuditBannerClear,
+	})
+
+	return utils.HttpSuccess(w)
+}
@@ -5,26 +5,34 @@ import (
 	//"bytes"
 	//"strings"
 
+	"errors"
+	"fmt"
 	"net/http"
 
+	"github.com/m1k1o/neko/server/pkg/auth"
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/utils"
 )
 
 type ClipboardPayload struct {
-	Text string `json:"text,omitempty"`
-	HTML string `json:"html,omitempty"`
+	Text      string `json:"text,omitempty"`
+	HTML      string `json:"html,omitempty"`
+	Truncated bool   `json:"truncated,omitempty"`
 }
 
 func (h *RoomHandler) clipboardGetText(w http.ResponseWriter, r *http.Request) error {
 	data, err := h.desktop.ClipboardGetText()
+	if errors.Is(err, types.ErrClipboardTooLarge) {
+		return utils.HttpUnprocessableEntity(err.Error())
+	}
 	if err != nil {
 		return utils.HttpInternalServerError().WithInternalErr(err)
 	}
 
 	return utils.HttpSuccess(w, ClipboardPayload{
-		Text: data.Text,
-		HTML: data.HTML,
+		Text:      data.Text,
+		HTML:      data.HTML,
+		Truncated: data.Truncated,
 	})
 }
 
@@ -34,20 +42,40 @@ func (h *RoomHandler) clipboardSetText(w http.ResponseWriter, r *http.Request) e
 		return err
 	}
 
-	err := h.desktop.ClipboardSetText(types.ClipboardText{
+	text := types.ClipboardText{
 		Text: data.Text,
 		HTML: data.HTML,
-	})
+	}
+
+	if err := h.desktop.ClipboardLimit(&text); err != nil {
+		return utils.HttpUnprocessableEntity(err.Error())
+	}
+
+	// http client would not learn that its content was cut, so it is rejected instead
+	if text.Truncated {
+		return utils.HttpUnprocessableEntity(types.ErrClipboardTooLarge.Error())
+	}
 
+	err := h.desktop.ClipboardSetText(text)
 	if err != nil {
 		return utils.HttpInternalServerError().WithInternalErr(err)
 	}
 
+	session, _ := auth.GetSession(r)
+	h.sessions.Audit(types.AuditEntry{
+		Actor:   session.ID(),
+		Action:  types.AuditClipboardSet,
+		Details: fmt.Sprintf("%d bytes", len(data.Text)+len(data.HTML)),
+	})
+
 	return utils.HttpSuccess(w)
 }
 
 func (h *RoomHandler) clipboardGetImage(w http.ResponseWriter, r *http.Request) error {
 	bytes, err := h.desktop.ClipboardGetBinary("image/png")
+	if errors.Is(err, types.ErrClipboardTooLarge) {
+		return utils.HttpUnprocessableEntity(err.Error())
+	}
 	if err != nil {
 		return utils.HttpInternalServerError().WithInternalErr(err)
 	}
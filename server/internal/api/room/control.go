@@ -1,13 +1,13 @@
 package room
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/go-chi/chi"
 
 	"github.com/m1k1o/neko/server/pkg/auth"
-	"github.com/m1k1o/neko/server/pkg/types/event"
-	"github.com/m1k1o/neko/server/pkg/types/message"
+	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/utils"
 )
 
@@ -36,37 +36,37 @@ func (h *RoomHandler) controlStatus(w http.ResponseWriter, r *http.Request) erro
 
 func (h *RoomHandler) controlRequest(w http.ResponseWriter, r *http.Request) error {
 	session, _ := auth.GetSession(r)
-	host, hasHost := h.sessions.GetHost()
-	if hasHost {
-		// TODO: Some throttling mechanism to prevent spamming.
-
-		// let host know that someone wants to take control
-		host.Send(
-			event.CONTROL_REQUEST,
-			message.SessionID{
-				ID: session.ID(),
-			})
 
+	host, err := h.sessions.ControlRequest(session)
+	switch {
+	case errors.Is(err, types.ErrSessionAlreadyHosted):
 		return utils.HttpError(http.StatusAccepted, "control request sent")
+	case errors.Is(err, types.ErrSessionNotAllowedToHost):
+		return utils.HttpForbidden(err.Error())
+	case err != nil:
+		return utils.HttpUnprocessableEntity(err.Error())
 	}
 
-	if h.sessions.Settings().LockedControls && !session.Profile().IsAdmin {
-		return utils.HttpForbidden("controls are locked")
+	// control was taken from the previous host
+	if host != nil {
+		h.desktop.ResetKeys()
 	}
 
-	session.SetAsHost()
-
 	return utils.HttpSuccess(w)
 }
 
 func (h *RoomHandler) controlRelease(w http.ResponseWriter, r *http.Request) error {
 	session, _ := auth.GetSession(r)
-	if !session.IsHost() {
+
+	err := h.sessions.ControlRelease(session)
+	switch {
+	case errors.Is(err, types.ErrSessionNotAllowedToHost):
+		return utils.HttpForbidden(err.Error())
+	case err != nil:
 		return utils.HttpUnprocessableEntity("session is not the host")
 	}
 
 	h.desktop.ResetKeys()
-	session.ClearHost()
 
 	return utils.HttpSuccess(w)
 }
@@ -87,22 +87,23 @@ func (h *RoomHandler) controlGive(w http.ResponseWriter, r *http.Request) error
 		return utils.HttpNotFound("target session was not found")
 	}
 
-	if !target.Profile().CanHost {
+	err := h.sessions.ControlGive(session, target)
+	if errors.Is(err, types.ErrSessionNotAllowedToHost) {
 		return utils.HttpBadRequest("target session is not allowed to host")
+	} else if err != nil {
+		return utils.HttpUnprocessableEntity(err.Error())
 	}
 
-	target.SetAsHostBy(session)
-
+	h.desktop.ResetKeys()
 	return utils.HttpSuccess(w)
 }
 
 func (h *RoomHandler) controlReset(w http.ResponseWriter, r *http.Request) error {
 	session, _ := auth.GetSession(r)
-	_, hasHost := h.sessions.GetHost()
 
-	if hasHost {
+	if _, hasHost := h.sessions.GetHost(); hasHost {
 		h.desktop.ResetKeys()
-		session.ClearHost()
+		h.sessions.ControlReset(session)
 	}
 
 	return utils.HttpSuccess(w)
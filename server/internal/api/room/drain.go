@@ -0,0 +1,41 @@
+package room
+
+import (
+	"net/http"
+
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+type DrainStatusPayload struct {
+	Draining bool `json:"draining"`
+	// connected sessions, server can be stopped when it reaches zero
+	Sessions int `json:"sessions"`
+}
+
+func (h *RoomHandler) drainStatusPayload() DrainStatusPayload {
+	sessions := 0
+	for _, session := range h.sessions.List() {
+		if session.State().IsConnected {
+			sessions++
+		}
+	}
+
+	return DrainStatusPayload{
+		Draining: h.sessions.Draining(),
+		Sessions: sessions,
+	}
+}
+
+func (h *RoomHandler) drainStatus(w http.ResponseWriter, r *http.Request) error {
+	return utils.HttpSuccess(w, h.drainStatusPayload())
+}
+
+func (h *RoomHandler) drainStart(w http.ResponseWriter, r *http.Request) error {
+	h.sessions.SetDraining(true)
+	return utils.HttpSuccess(w, h.drainStatusPayload())
+}
+
+func (h *RoomHandler) drainStop(w http.ResponseWriter, r *http.Request) error {
+	h.sessions.SetDraining(false)
+	return utils.HttpSuccess(w, h.drainStatusPayload())
+}
@@ -15,19 +15,25 @@ type RoomHandler struct {
 	sessions types.SessionManager
 	desktop  types.DesktopManager
 	capture  types.CaptureManager
+	webrtc   types.WebRTCManager
 
 	privateModeImage []byte
+	inputLimiter     *utils.RateLimiter
 }
 
 func New(
 	sessions types.SessionManager,
 	desktop types.DesktopManager,
 	capture types.CaptureManager,
+	webrtc types.WebRTCManager,
 ) *RoomHandler {
 	h := &RoomHandler{
 		sessions: sessions,
 		desktop:  desktop,
 		capture:  capture,
+		webrtc:   webrtc,
+
+		inputLimiter: utils.NewRateLimiter(inputRate, inputBurst),
 	}
 
 	// generate fallback image for private mode when needed
@@ -66,10 +72,22 @@ func (h *RoomHandler) Route(r types.Router) {
 		r.Post("/stop", h.broadcastStop)
 	})
 
+	r.With(auth.AdminsOnly).Route("/drain", func(r types.Router) {
+		r.Get("/", h.drainStatus)
+		r.Post("/start", h.drainStart)
+		r.Post("/stop", h.drainStop)
+	})
+
+	r.With(auth.AdminsOnly).Route("/banner", func(r types.Router) {
+		r.Get("/", h.bannerGet)
+		r.Post("/", h.bannerSet)
+		r.Delete("/", h.bannerClear)
+	})
+
 	r.With(auth.CanAccessClipboardOnly).With(auth.HostsOnly).Route("/clipboard", func(r types.Router) {
-		r.Get("/", h.clipboardGetText)
-		r.Post("/", h.clipboardSetText)
-		r.Get("/image.png", h.clipboardGetImage)
+		r.With(auth.CanReadClipboardOnly).Get("/", h.clipboardGetText)
+		r.With(auth.CanWriteClipboardOnly).Post("/", h.clipboardSetText)
+		r.With(auth.CanReadClipboardOnly).Get("/image.png", h.clipboardGetImage)
 
 		// TODO: Refactor. xclip is failing to set propper target type
 		// and this content is sent back to client as text in another
@@ -99,6 +117,8 @@ func (h *RoomHandler) Route(r types.Router) {
 		r.With(auth.AdminsOnly).Post("/reset", h.controlReset)
 	})
 
+	r.With(auth.AdminsOnly).Post("/input", h.inputInject)
+
 	r.With(auth.CanWatchOnly).Route("/screen", func(r types.Router) {
 		r.Get("/", h.screenConfiguration)
 		r.With(auth.AdminsOnly).Post("/", h.screenConfigurationChange)
@@ -0,0 +1,140 @@
+package room
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/m1k1o/neko/server/pkg/auth"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+const (
+	// maximum number of events in a single request
+	inputMaxEvents = 100
+	// sustained rate and burst of injected events
+	inputRate  = 100
+	inputBurst = 200
+)
+
+type InputEventPayload struct {
+	Type string `json:"type"`
+
+	// move
+	X int `json:"x"`
+	Y int `json:"y"`
+
+	// scroll
+	DeltaX     int  `json:"delta_x"`
+	DeltaY     int  `json:"delta_y"`
+	ControlKey bool `json:"control_key"`
+
+	// button
+	Code uint32 `json:"code"`
+
+	// key
+	Keysym uint32 `json:"keysym"`
+}
+
+type InputPayload struct {
+	Events []InputEventPayload `json:"events"`
+}
+
+func (h *RoomHandler) inputInject(w http.ResponseWriter, r *http.Request) error {
+	data := &InputPayload{}
+	if err := utils.HttpJsonRequest(w, r, data); err != nil {
+		return err
+	}
+
+	if len(data.Events) == 0 {
+		return utils.HttpBadRequest("no input events")
+	}
+
+	if len(data.Events) > inputMaxEvents {
+		return utils.HttpBadRequest(fmt.Sprintf("too many input events, maximum is %d", inputMaxEvents))
+	}
+
+	session, _ := auth.GetSession(r)
+
+	// validate all events before any of them is executed
+	for i, event := range data.Events {
+		if err := h.inputValidate(event); err != nil {
+			return utils.HttpBadRequest(fmt.Sprintf("event %d: %s", i, err))
+		}
+	}
+
+	if !h.inputLimiter.AllowN(len(data.Events)) {
+		return utils.HttpError(http.StatusTooManyRequests, "input rate limit exceeded")
+	}
+
+	for i, event := range data.Events {
+		if err := h.inputExecute(session, event); err != nil {
+			return utils.HttpInternalServerError().
+				WithInternalErr(err).
+				Msgf("event %d failed", i)
+		}
+	}
+
+	return utils.HttpSuccess(w)
+}
+
+func (h *RoomHandler) inputValidate(event InputEventPayload) error {
+	switch event.Type {
+	case "move":
+		size := h.desktop.GetScreenSize()
+		if event.X < 0 || event.Y < 0 || event.X >= size.Width || event.Y >= size.Height {
+			return fmt.Errorf("position out of screen")
+		}
+	case "scroll":
+	case "buttonpress", "buttondown", "buttonup":
+		if event.Code == 0 {
+			return fmt.Errorf("missing button code")
+		}
+	case "keypress", "keydown", "keyup":
+		if event.Keysym == 0 {
+			return fmt.Errorf("missing keysym")
+		}
+	default:
+		return fmt.Errorf("unknown event type '%s'", event.Type)
+	}
+
+	return nil
+}
+
+// inputExecute uses the same desktop calls as control events of websocket handler
+func (h *RoomHandler) inputExecute(session types.Session, event InputEventPayload) error {
+	// key filter of the session drops blocked keys
+	if event.Type == "keypress" || event.Type == "keydown" {
+		if session.Profile().KeyBlocked(event.Keysym, h.desktop.KeysDown()) {
+			log.Warn().
+				Str("session_id", session.ID()).
+				Uint32("keysym", event.Keysym).
+				Msg("blocked key")
+			return nil
+		}
+	}
+
+	switch event.Type {
+	case "move":
+		h.desktop.Move(event.X, event.Y)
+		h.webrtc.SetCursorPosition(event.X, event.Y)
+	case "scroll":
+		h.desktop.Scroll(event.DeltaX, event.DeltaY, event.ControlKey)
+	case "buttonpress":
+		return h.desktop.ButtonPress(event.Code)
+	case "buttondown":
+		return h.desktop.ButtonDown(event.Code)
+	case "buttonup":
+		return h.desktop.ButtonUp(event.Code)
+	case "keypress":
+		return h.desktop.KeyPress(event.Keysym)
+	case "keydown":
+		return h.desktop.KeyDown(event.Keysym)
+	case "keyup":
+		return h.desktop.KeyUp(event.Keysym)
+	}
+
+	return nil
+}
@@ -1,23 +1,32 @@
 package room
 
 import (
+	"errors"
 	"net/http"
 
+	"github.com/m1k1o/neko/server/pkg/auth"
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/utils"
 )
 
 func (h *RoomHandler) keyboardMapSet(w http.ResponseWriter, r *http.Request) error {
+	session, _ := auth.GetSession(r)
+
 	keyboardMap := types.KeyboardMap{}
 	if err := utils.HttpJsonRequest(w, r, &keyboardMap); err != nil {
 		return err
 	}
 
 	err := h.desktop.SetKeyboardMap(keyboardMap)
+	if errors.Is(err, types.ErrKeyboardMapUnavailable) {
+		return utils.HttpBadRequest(err.Error())
+	}
 	if err != nil {
 		return utils.HttpInternalServerError().WithInternalErr(err)
 	}
 
+	// keyboard map is applied again, when session becomes the host
+	session.SetKeyboardMap(keyboardMap)
 	return utils.HttpSuccess(w)
 }
 
@@ -1,6 +1,7 @@
 package room
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -13,8 +14,12 @@ import (
 
 func (h *RoomHandler) screenConfiguration(w http.ResponseWriter, r *http.Request) error {
 	screenSize := h.desktop.GetScreenSize()
+	screenDPI := h.desktop.GetScreenDPI()
 
-	return utils.HttpSuccess(w, screenSize)
+	return utils.HttpSuccess(w, struct {
+		types.ScreenSize
+		types.ScreenDPI
+	}{screenSize, screenDPI})
 }
 
 func (h *RoomHandler) screenConfigurationChange(w http.ResponseWriter, r *http.Request) error {
@@ -31,12 +36,22 @@ func (h *RoomHandler) screenConfigurationChange(w http.ResponseWriter, r *http.R
 		Rate:   data.Rate,
 	})
 
-	if err != nil {
+	if errors.Is(err, types.ErrScreenSizeUnsupported) {
+		return utils.HttpUnprocessableEntity(err.Error())
+	} else if err != nil {
 		return utils.HttpUnprocessableEntity("cannot set screen size").WithInternalErr(err)
 	}
 
+	h.sessions.Audit(types.AuditEntry{
+		Actor:   auth.ID(),
+		Action:  types.AuditScreenSet,
+		Details: size.String(),
+	})
+
 	h.sessions.Broadcast(event.SCREEN_UPDATED, message.ScreenSizeUpdate{
 		ID:         auth.ID(),
+		Display:    h.capture.Display(),
+		Region:     h.capture.Region(),
 		ScreenSize: size,
 	})
 
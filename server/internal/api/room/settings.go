@@ -2,6 +2,7 @@ package room
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 
@@ -27,9 +28,16 @@ func (h *RoomHandler) settingsSet(w http.ResponseWriter, r *http.Request) error
 
 	h.sessions.UpdateSettingsFunc(session, func(settings *types.Settings) bool {
 		err = json.Unmarshal(body, settings)
+		if err == nil && !settings.ControlPolicy.Valid() {
+			err = types.ErrControlPolicyUnknown
+		}
 		return err == nil
 	})
 
+	if errors.Is(err, types.ErrControlPolicyUnknown) {
+		return utils.HttpBadRequest(err.Error())
+	}
+
 	if err != nil {
 		return utils.HttpBadRequest("unable to parse provided data").WithInternalErr(err)
 	}
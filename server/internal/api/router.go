@@ -18,6 +18,7 @@ type ApiManagerCtx struct {
 	members  types.MemberManager
 	desktop  types.DesktopManager
 	capture  types.CaptureManager
+	webrtc   types.WebRTCManager
 	routers  map[string]func(types.Router)
 }
 
@@ -26,6 +27,7 @@ func New(
 	members types.MemberManager,
 	desktop types.DesktopManager,
 	capture types.CaptureManager,
+	webrtc types.WebRTCManager,
 ) *ApiManagerCtx {
 
 	return &ApiManagerCtx{
@@ -33,6 +35,7 @@ func New(
 		members:  members,
 		desktop:  desktop,
 		capture:  capture,
+		webrtc:   webrtc,
 		routers:  make(map[string]func(types.Router)),
 	}
 }
@@ -48,15 +51,21 @@ func (api *ApiManagerCtx) Route(r types.Router) {
 		r.Get("/whoami", api.Whoami)
 		r.Post("/profile", api.UpdateProfile)
 		r.Get("/stats", api.Stats)
+		r.Get("/webrtc", api.WebRTC)
 
-		sessionsHandler := sessions.New(api.sessions)
+		r.Post("/whep", api.WHEPCreate)
+		r.Delete("/whep/{peerId}", api.WebRTCPeerDelete)
+		r.Post("/whip", api.WHIPCreate)
+		r.Delete("/whip/{peerId}", api.WebRTCPeerDelete)
+
+		sessionsHandler := sessions.New(api.sessions, api.capture, api.desktop)
 		r.Route("/sessions", sessionsHandler.Route)
 
 		membersHandler := members.New(api.members)
 		r.Route("/members", membersHandler.Route)
 		r.Route("/members_bulk", membersHandler.RouteBulk)
 
-		roomHandler := room.New(api.sessions, api.desktop, api.capture)
+		roomHandler := room.New(api.sessions, api.desktop, api.capture, api.webrtc)
 		r.Route("/room", roomHandler.Route)
 
 		for path, router := range api.routers {
@@ -10,15 +10,17 @@ import (
 )
 
 type SessionLoginPayload struct {
-	Username string `json:"username"`
-	Password string `json:"password"`
+	Username string                `json:"username"`
+	Password string                `json:"password"`
+	Metadata types.SessionMetadata `json:"metadata,omitempty"`
 }
 
 type SessionDataPayload struct {
-	ID      string              `json:"id"`
-	Token   string              `json:"token,omitempty"`
-	Profile types.MemberProfile `json:"profile"`
-	State   types.SessionState  `json:"state"`
+	ID       string                `json:"id"`
+	Token    string                `json:"token,omitempty"`
+	Profile  types.MemberProfile   `json:"profile"`
+	State    types.SessionState    `json:"state"`
+	Metadata types.SessionMetadata `json:"metadata,omitempty"`
 }
 
 func (api *ApiManagerCtx) Login(w http.ResponseWriter, r *http.Request) error {
@@ -27,6 +29,11 @@ func (api *ApiManagerCtx) Login(w http.ResponseWriter, r *http.Request) error {
 		return err
 	}
 
+	// validate metadata before the session is created
+	if err := data.Metadata.Validate(); err != nil {
+		return utils.HttpUnprocessableEntity(err.Error())
+	}
+
 	session, token, err := api.members.Login(data.Username, data.Password)
 	if err != nil {
 		if errors.Is(err, types.ErrSessionAlreadyConnected) {
@@ -35,15 +42,24 @@ func (api *ApiManagerCtx) Login(w http.ResponseWriter, r *http.Request) error {
 			return utils.HttpUnauthorized().WithInternalErr(err)
 		} else if errors.Is(err, types.ErrSessionLoginsLocked) {
 			return utils.HttpForbidden("logins are locked").WithInternalErr(err)
+		} else if errors.Is(err, types.ErrSessionDraining) {
+			return utils.HttpError(http.StatusServiceUnavailable, "server is draining").WithInternalErr(err)
 		} else {
 			return utils.HttpInternalServerError().WithInternalErr(err)
 		}
 	}
 
+	if len(data.Metadata) > 0 {
+		if err := api.sessions.SetMetadata(session.ID(), data.Metadata); err != nil {
+			return utils.HttpInternalServerError().WithInternalErr(err)
+		}
+	}
+
 	sessionData := SessionDataPayload{
-		ID:      session.ID(),
-		Profile: session.Profile(),
-		State:   session.State(),
+		ID:       session.ID(),
+		Profile:  session.Profile(),
+		State:    session.State(),
+		Metadata: session.Metadata(),
 	}
 
 	if api.sessions.CookieEnabled() {
@@ -78,9 +94,10 @@ func (api *ApiManagerCtx) Whoami(w http.ResponseWriter, r *http.Request) error {
 	session, _ := auth.GetSession(r)
 
 	return utils.HttpSuccess(w, SessionDataPayload{
-		ID:      session.ID(),
-		Profile: session.Profile(),
-		State:   session.State(),
+		ID:       session.ID(),
+		Profile:  session.Profile(),
+		State:    session.State(),
+		Metadata: session.Metadata(),
 	})
 }
 
@@ -0,0 +1,103 @@
+package sessions
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+
+	"github.com/m1k1o/neko/server/pkg/auth"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+func (h *SessionsHandler) sessionsConfigExport(w http.ResponseWriter, r *http.Request) error {
+	sessionId := chi.URLParam(r, "sessionId")
+
+	session, ok := h.sessions.Get(sessionId)
+	if !ok {
+		return utils.HttpNotFound("session not found")
+	}
+
+	config := session.ExportConfig()
+
+	size := h.desktop.GetScreenSize()
+	config.ScreenSize = &size
+
+	return utils.HttpSuccess(w, config)
+}
+
+func (h *SessionsHandler) sessionsConfigApply(w http.ResponseWriter, r *http.Request) error {
+	auth, _ := auth.GetSession(r)
+	sessionId := chi.URLParam(r, "sessionId")
+
+	session, ok := h.sessions.Get(sessionId)
+	if !ok {
+		return utils.HttpNotFound("session not found")
+	}
+
+	config := types.SessionConfig{}
+	if err := utils.HttpJsonRequest(w, r, &config); err != nil {
+		return err
+	}
+
+	// validate shared parts before anything is applied
+	if config.KeyboardMap != nil {
+		if err := h.desktop.KeyboardMapSupported(*config.KeyboardMap); err != nil {
+			return utils.HttpBadRequest(err.Error())
+		}
+	}
+
+	if config.ScreenSize != nil && !h.desktop.ScreenSizeSupported(*config.ScreenSize) {
+		return utils.HttpUnprocessableEntity(types.ErrScreenSizeUnsupported.Error())
+	}
+
+	// session scoped parts are validated as a whole, before they are applied
+	if err := session.ApplyConfig(config); err != nil {
+		if errors.Is(err, types.ErrWebRTCConnectionNotFound) {
+			return utils.HttpUnprocessableEntity("webrtc peer not found")
+		} else if errors.Is(err, types.ErrWebRTCStreamNotFound) {
+			return utils.HttpBadRequest("video stream not found")
+		} else if errors.Is(err, types.ErrWebRTCAudioSyncOffset) ||
+			errors.Is(err, types.ErrCaptureVideoScaleOutOfRange) ||
+			errors.Is(err, types.ErrWebRTCEncoderUnknown) {
+			return utils.HttpBadRequest(err.Error())
+		} else if errors.Is(err, types.ErrWebRTCQuotaExceeded) {
+			return utils.HttpUnprocessableEntity(err.Error())
+		}
+
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+
+	// keyboard map of the host is applied immediately
+	if config.KeyboardMap != nil && session.IsHost() {
+		if err := h.desktop.SetKeyboardMap(*config.KeyboardMap); err != nil {
+			return utils.HttpInternalServerError().WithInternalErr(err)
+		}
+	}
+
+	// screen size is shared by all sessions
+	if config.ScreenSize != nil {
+		size, err := h.desktop.SetScreenSize(*config.ScreenSize)
+		if err != nil {
+			return utils.HttpUnprocessableEntity("cannot set screen size").WithInternalErr(err)
+		}
+
+		h.sessions.Audit(types.AuditEntry{
+			Actor:   auth.ID(),
+			Action:  types.AuditScreenSet,
+			Details: size.String(),
+		})
+
+		h.sessions.Broadcast(event.SCREEN_UPDATED, message.ScreenSizeUpdate{
+			ID:         auth.ID(),
+			Display:    h.capture.Display(),
+			Region:     h.capture.Region(),
+			ScreenSize: size,
+		})
+	}
+
+	return utils.HttpSuccess(w)
+}
@@ -3,6 +3,7 @@ package sessions
 import (
 	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/m1k1o/neko/server/pkg/auth"
 	"github.com/m1k1o/neko/server/pkg/types"
@@ -12,18 +13,20 @@ import (
 )
 
 type SessionDataPayload struct {
-	ID      string              `json:"id"`
-	Profile types.MemberProfile `json:"profile"`
-	State   types.SessionState  `json:"state"`
+	ID       string                `json:"id"`
+	Profile  types.MemberProfile   `json:"profile"`
+	State    types.SessionState    `json:"state"`
+	Metadata types.SessionMetadata `json:"metadata,omitempty"`
 }
 
 func (h *SessionsHandler) sessionsList(w http.ResponseWriter, r *http.Request) error {
 	sessions := []SessionDataPayload{}
 	for _, session := range h.sessions.List() {
 		sessions = append(sessions, SessionDataPayload{
-			ID:      session.ID(),
-			Profile: session.Profile(),
-			State:   session.State(),
+			ID:       session.ID(),
+			Profile:  session.Profile(),
+			State:    session.State(),
+			Metadata: session.Metadata(),
 		})
 	}
 
@@ -39,12 +42,144 @@ func (h *SessionsHandler) sessionsRead(w http.ResponseWriter, r *http.Request) e
 	}
 
 	return utils.HttpSuccess(w, SessionDataPayload{
-		ID:      session.ID(),
-		Profile: session.Profile(),
-		State:   session.State(),
+		ID:       session.ID(),
+		Profile:  session.Profile(),
+		State:    session.State(),
+		Metadata: session.Metadata(),
 	})
 }
 
+func (h *SessionsHandler) sessionsMetadataGet(w http.ResponseWriter, r *http.Request) error {
+	sessionId := chi.URLParam(r, "sessionId")
+
+	session, ok := h.sessions.Get(sessionId)
+	if !ok {
+		return utils.HttpNotFound("session not found")
+	}
+
+	return utils.HttpSuccess(w, session.Metadata())
+}
+
+func (h *SessionsHandler) sessionsMetadataSet(w http.ResponseWriter, r *http.Request) error {
+	sessionId := chi.URLParam(r, "sessionId")
+
+	data := types.SessionMetadata{}
+	if err := utils.HttpJsonRequest(w, r, &data); err != nil {
+		return err
+	}
+
+	err := h.sessions.SetMetadata(sessionId, data)
+	if err != nil {
+		if errors.Is(err, types.ErrSessionNotFound) {
+			return utils.HttpBadRequest("session not found")
+		} else if errors.Is(err, types.ErrSessionMetadataTooLarge) {
+			return utils.HttpUnprocessableEntity(err.Error())
+		} else {
+			return utils.HttpInternalServerError().WithInternalErr(err)
+		}
+	}
+
+	return utils.HttpSuccess(w)
+}
+
+func (h *SessionsHandler) sessionsSnapshot(w http.ResponseWriter, r *http.Request) error {
+	sessionId := chi.URLParam(r, "sessionId")
+
+	session, ok := h.sessions.Get(sessionId)
+	if !ok {
+		return utils.HttpNotFound("session not found")
+	}
+
+	quality, err := strconv.Atoi(r.URL.Query().Get("quality"))
+	if err != nil {
+		quality = 90
+	}
+
+	// use video stream that session is currently watching, or the first one
+	videoID := h.capture.Video().IDs()[0]
+	if peer := session.GetWebRTCPeer(); peer != nil {
+		if video := peer.Video(); video.ID != "" {
+			videoID = video.ID
+		}
+	}
+
+	bytes, err := h.capture.Snapshot(videoID, quality)
+	if err != nil {
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+	w.Header().Set("Content-Type", "image/jpeg")
+
+	_, err = w.Write(bytes)
+	return err
+}
+
+func (h *SessionsHandler) sessionsWebRTC(w http.ResponseWriter, r *http.Request) error {
+	sessionId := chi.URLParam(r, "sessionId")
+
+	session, ok := h.sessions.Get(sessionId)
+	if !ok {
+		return utils.HttpNotFound("session not found")
+	}
+
+	peer := session.GetWebRTCPeer()
+	if peer == nil {
+		return utils.HttpNotFound("webrtc peer not found")
+	}
+
+	return utils.HttpSuccess(w, peer.Diagnostics())
+}
+
+func (h *SessionsHandler) sessionsWebRTCEstimator(w http.ResponseWriter, r *http.Request) error {
+	sessionId := chi.URLParam(r, "sessionId")
+
+	session, ok := h.sessions.Get(sessionId)
+	if !ok {
+		return utils.HttpNotFound("session not found")
+	}
+
+	peer := session.GetWebRTCPeer()
+	if peer == nil {
+		return utils.HttpNotFound("webrtc peer not found")
+	}
+
+	return utils.HttpSuccess(w, peer.EstimatorHistory())
+}
+
+func (h *SessionsHandler) sessionsWebRTCStats(w http.ResponseWriter, r *http.Request) error {
+	sessionId := chi.URLParam(r, "sessionId")
+
+	session, ok := h.sessions.Get(sessionId)
+	if !ok {
+		return utils.HttpNotFound("session not found")
+	}
+
+	peer := session.GetWebRTCPeer()
+	if peer == nil {
+		return utils.HttpNotFound("webrtc peer not found")
+	}
+
+	return utils.HttpSuccess(w, peer.Stats())
+}
+
+func (h *SessionsHandler) sessionsAudit(w http.ResponseWriter, r *http.Request) error {
+	sessionId := chi.URLParam(r, "sessionId")
+
+	return utils.HttpSuccess(w, h.sessions.AuditLog(sessionId))
+}
+
+func (h *SessionsHandler) sessionsUsage(w http.ResponseWriter, r *http.Request) error {
+	sessionId := chi.URLParam(r, "sessionId")
+
+	session, ok := h.sessions.Get(sessionId)
+	if !ok {
+		return utils.HttpNotFound("session not found")
+	}
+
+	return utils.HttpSuccess(w, session.Usage())
+}
+
 func (h *SessionsHandler) sessionsDelete(w http.ResponseWriter, r *http.Request) error {
 	session, _ := auth.GetSession(r)
 
@@ -62,10 +197,18 @@ func (h *SessionsHandler) sessionsDelete(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
+	h.sessions.Audit(types.AuditEntry{
+		Actor:  session.ID(),
+		Action: types.AuditSessionDelete,
+		Target: sessionId,
+	})
+
 	return utils.HttpSuccess(w)
 }
 
 func (h *SessionsHandler) sessionsDisconnect(w http.ResponseWriter, r *http.Request) error {
+	session, _ := auth.GetSession(r)
+
 	sessionId := chi.URLParam(r, "sessionId")
 
 	err := h.sessions.Disconnect(sessionId)
@@ -77,5 +220,11 @@ func (h *SessionsHandler) sessionsDisconnect(w http.ResponseWriter, r *http.Requ
 		}
 	}
 
+	h.sessions.Audit(types.AuditEntry{
+		Actor:  session.ID(),
+		Action: types.AuditSessionDisconnect,
+		Target: sessionId,
+	})
+
 	return utils.HttpSuccess(w)
 }
@@ -7,15 +7,21 @@ import (
 
 type SessionsHandler struct {
 	sessions types.SessionManager
+	capture  types.CaptureManager
+	desktop  types.DesktopManager
 }
 
 func New(
 	sessions types.SessionManager,
+	capture types.CaptureManager,
+	desktop types.DesktopManager,
 ) *SessionsHandler {
 	// Init
 
 	return &SessionsHandler{
 		sessions: sessions,
+		capture:  capture,
+		desktop:  desktop,
 	}
 }
 
@@ -26,5 +32,15 @@ func (h *SessionsHandler) Route(r types.Router) {
 		r.Get("/", h.sessionsRead)
 		r.Delete("/", h.sessionsDelete)
 		r.Post("/disconnect", h.sessionsDisconnect)
+		r.Get("/metadata", h.sessionsMetadataGet)
+		r.Post("/metadata", h.sessionsMetadataSet)
+		r.Get("/snapshot", h.sessionsSnapshot)
+		r.Get("/webrtc", h.sessionsWebRTC)
+		r.Get("/webrtc/stats", h.sessionsWebRTCStats)
+		r.Get("/webrtc/estimator", h.sessionsWebRTCEstimator)
+		r.Get("/audit", h.sessionsAudit)
+		r.Get("/usage", h.sessionsUsage)
+		r.Get("/config", h.sessionsConfigExport)
+		r.Post("/config", h.sessionsConfigApply)
 	})
 }
@@ -0,0 +1,151 @@
+package api
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/pkg/auth"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+type WebRTCPayload struct {
+	ICEServers   []types.ICEServer        `json:"ice_servers"`
+	Fingerprints []webrtc.DTLSFingerprint `json:"fingerprints"`
+}
+
+func (api *ApiManagerCtx) WebRTC(w http.ResponseWriter, r *http.Request) error {
+	fingerprints, err := api.webrtc.Fingerprints()
+	if err != nil {
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+
+	session, _ := auth.GetSession(r)
+
+	return utils.HttpSuccess(w, WebRTCPayload{
+		ICEServers:   api.webrtc.ICEServersFor(session, r.RemoteAddr),
+		Fingerprints: fingerprints,
+	})
+}
+
+// maximum size of SDP offer accepted by WHIP/WHEP endpoints
+const sdpMaxSize = 64 * 1024
+
+func (api *ApiManagerCtx) readOffer(r *http.Request) (webrtc.SessionDescription, error) {
+	if mediaType := r.Header.Get("Content-Type"); !strings.HasPrefix(mediaType, "application/sdp") {
+		return webrtc.SessionDescription{}, utils.HttpError(http.StatusUnsupportedMediaType, "expected application/sdp content type")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, sdpMaxSize+1))
+	if err != nil {
+		return webrtc.SessionDescription{}, utils.HttpBadRequest("unable to read offer").WithInternalErr(err)
+	}
+
+	if len(data) > sdpMaxSize {
+		return webrtc.SessionDescription{}, utils.HttpError(http.StatusRequestEntityTooLarge, "offer is too large")
+	}
+
+	return webrtc.SessionDescription{
+		Type: webrtc.SDPTypeOffer,
+		SDP:  string(data),
+	}, nil
+}
+
+// writeAnswer responds with the answer, location identifies the peer as a resource
+func (api *ApiManagerCtx) writeAnswer(w http.ResponseWriter, r *http.Request, answer *webrtc.SessionDescription, peer types.WebRTCPeer) error {
+	w.Header().Set("Content-Type", "application/sdp")
+	w.Header().Set("Location", path.Join(r.URL.Path, peer.ID()))
+	w.WriteHeader(http.StatusCreated)
+	_, err := w.Write([]byte(answer.SDP))
+	return err
+}
+
+// WHEPCreate handles WebRTC-HTTP Egress Protocol, client receives stream.
+func (api *ApiManagerCtx) WHEPCreate(w http.ResponseWriter, r *http.Request) error {
+	session, _ := auth.GetSession(r)
+
+	if !session.Profile().CanWatch {
+		return utils.HttpForbidden("not allowed to watch")
+	}
+
+	offer, err := api.readOffer(r)
+	if err != nil {
+		return err
+	}
+
+	answer, peer, err := api.webrtc.CreatePeerWithOffer(session, offer, types.PeerOptions{})
+	if err != nil {
+		return utils.HttpBadRequest("unable to create peer").WithInternalErr(err)
+	}
+
+	// set webrtc as paused if session has private mode enabled or is not presented to
+	if session.StreamPaused() {
+		peer.SetPaused(true)
+	}
+
+	// use default first video
+	videos := api.capture.Video().IDs()
+	err = peer.SetVideo(types.PeerVideoRequest{
+		Selector: &types.StreamSelector{
+			ID:   videos[0],
+			Type: types.StreamSelectorTypeExact,
+		},
+	})
+	if err != nil {
+		peer.Destroy()
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+
+	// enable audio by default
+	disabled := false
+	err = peer.SetAudio(types.PeerAudioRequest{
+		Disabled: &disabled,
+	})
+	if err != nil {
+		peer.Destroy()
+		return utils.HttpInternalServerError().WithInternalErr(err)
+	}
+
+	return api.writeAnswer(w, r, answer, peer)
+}
+
+// WHIPCreate handles WebRTC-HTTP Ingestion Protocol, client shares its media.
+func (api *ApiManagerCtx) WHIPCreate(w http.ResponseWriter, r *http.Request) error {
+	session, _ := auth.GetSession(r)
+
+	if !session.Profile().CanShareMedia {
+		return utils.HttpForbidden("not allowed to share media")
+	}
+
+	offer, err := api.readOffer(r)
+	if err != nil {
+		return err
+	}
+
+	// incoming tracks are routed to webcam and microphone by the peer
+	answer, peer, err := api.webrtc.CreatePeerWithOffer(session, offer, types.PeerOptions{})
+	if err != nil {
+		return utils.HttpBadRequest("unable to create peer").WithInternalErr(err)
+	}
+
+	return api.writeAnswer(w, r, answer, peer)
+}
+
+// WebRTCPeerDelete tears down peer created by WHIP or WHEP endpoint.
+func (api *ApiManagerCtx) WebRTCPeerDelete(w http.ResponseWriter, r *http.Request) error {
+	session, _ := auth.GetSession(r)
+
+	// peer might have been replaced in the meantime
+	peer := session.GetWebRTCPeer()
+	if peer == nil || peer.ID() != chi.URLParam(r, "peerId") {
+		return utils.HttpNotFound("webrtc peer does not exist")
+	}
+
+	peer.Destroy()
+	return utils.HttpSuccess(w)
+}
@@ -0,0 +1,158 @@
+package capture
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// usage below target by this margin restores framerate, so that it does not oscillate
+const adaptiveFpsHysteresis = 0.15
+
+var adaptiveFpsGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name:      "video_effective_fps",
+	Namespace: "neko",
+	Subsystem: "capture",
+	Help:      "Effective framerate of the video pipeline, lowered under cpu load.",
+}, []string{"video_id"})
+
+// adaptiveFps lowers framerate of all video pipelines while cpu is saturated,
+// so that encoders can keep up, and restores it when the load drops.
+func (manager *CaptureManagerCtx) adaptiveFps(stop <-chan struct{}) {
+	conf := manager.config
+	logger := manager.logger.With().Str("submodule", "adaptive-fps").Logger()
+
+	ticker := time.NewTicker(conf.VideoAdaptiveFpsInterval)
+	defer ticker.Stop()
+
+	lastIdle, lastTotal, err := readCPUTimes()
+	if err != nil {
+		logger.Warn().Err(err).Msg("unable to read cpu usage, adaptive framerate disabled")
+		return
+	}
+
+	// fraction of configured framerate, that is currently used
+	scale := 1.0
+	manager.setFpsScale(scale)
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		idle, total, err := readCPUTimes()
+		if err != nil {
+			logger.Warn().Err(err).Msg("unable to read cpu usage")
+			continue
+		}
+
+		if total <= lastTotal {
+			continue
+		}
+
+		usage := 1 - float64(idle-lastIdle)/float64(total-lastTotal)
+		lastIdle, lastTotal = idle, total
+
+		newScale := scale
+		if usage > conf.VideoAdaptiveFpsTargetCPU {
+			newScale = max(scale*0.8, 0.01)
+		} else if usage < conf.VideoAdaptiveFpsTargetCPU-adaptiveFpsHysteresis {
+			newScale = min(scale*1.25, 1)
+		}
+
+		if newScale == scale {
+			continue
+		}
+
+		logger.Info().
+			Float64("cpu_usage", usage).
+			Float64("scale", newScale).
+			Msg("adjusting video framerate")
+
+		scale = newScale
+		manager.setFpsScale(scale)
+	}
+}
+
+// setFpsScale limits framerate of video pipelines, including scaled ones,
+// to fraction of their configured framerate, but never below configured minimum
+func (manager *CaptureManagerCtx) setFpsScale(scale float64) {
+	for id, stream := range manager.video.streams {
+		sink, ok := stream.(*StreamSinkManagerCtx)
+		if !ok {
+			continue
+		}
+
+		fps, limit := sink.Fps(), manager.fpsLimit(sink, scale)
+		sink.setFpsLimit(limit)
+		if limit > 0 {
+			fps = limit
+		}
+		adaptiveFpsGauge.WithLabelValues(id).Set(fps)
+	}
+
+	manager.videoScaledMu.Lock()
+	defer manager.videoScaledMu.Unlock()
+
+	// scaled pipelines created later get the current scale as well
+	manager.fpsScale = scale
+	for _, video := range manager.videoScaled {
+		video.setFpsLimit(manager.fpsLimit(video, scale))
+	}
+}
+
+// fpsLimit returns framerate limit of the sink for given scale, 0 means no limit
+func (manager *CaptureManagerCtx) fpsLimit(sink *StreamSinkManagerCtx, scale float64) float64 {
+	fps := sink.Fps()
+	if fps <= 0 || scale <= 0 || scale >= 1 {
+		return 0
+	}
+
+	return min(max(fps*scale, manager.config.VideoAdaptiveFpsMin), fps)
+}
+
+// readCPUTimes returns idle and total cpu time of all cpus since boot
+func readCPUTimes() (idle, total uint64, err error) {
+	file, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+
+		for i, field := range fields[1:] {
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return 0, 0, err
+			}
+
+			total += value
+			// idle and iowait
+			if i == 3 || i == 4 {
+				idle += value
+			}
+		}
+
+		return idle, total, nil
+	}
+
+	if err := scanner.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	return 0, 0, errors.New("cpu times not found")
+}
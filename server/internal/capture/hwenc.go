@@ -0,0 +1,33 @@
+package capture
+
+import "sync"
+
+// hwEncoderSessions limits number of concurrently running pipelines,
+// that use hardware encoder, as GPUs support only a few encode sessions.
+type hwEncoderSessions struct {
+	mu      sync.Mutex
+	max     int
+	running int
+}
+
+// acquire reserves encoder session, returns false if all are in use
+func (s *hwEncoderSessions) acquire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.max > 0 && s.running >= s.max {
+		return false
+	}
+
+	s.running++
+	return true
+}
+
+func (s *hwEncoderSessions) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running > 0 {
+		s.running--
+	}
+}
@@ -3,8 +3,12 @@ package capture
 import (
 	"errors"
 	"fmt"
+	"image"
+	"math"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -12,6 +16,7 @@ import (
 	"github.com/m1k1o/neko/server/internal/config"
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/codec"
+	"github.com/m1k1o/neko/server/pkg/utils"
 )
 
 type CaptureManagerCtx struct {
@@ -19,48 +24,110 @@ type CaptureManagerCtx struct {
 	desktop types.DesktopManager
 	config  *config.Capture
 
+	// currently captured display
+	displayMu sync.Mutex
+	display   *atomic.Value
+	// captured region of the display, nil means whole screen
+	region *atomic.Pointer[types.CaptureRegion]
+
 	// sinks
 	broadcast  *BroacastManagerCtx
 	screencast *ScreencastManagerCtx
 	audio      *StreamSinkManagerCtx
 	video      *StreamSelectorManagerCtx
 
+	// audio sinks with applied gain or encoding, created on demand
+	audioGains   map[audioVariant]*StreamSinkManagerCtx
+	audioGainsMu sync.Mutex
+
+	// video sinks scaled down by a factor, created on demand
+	videoScaled   map[string]*StreamSinkManagerCtx
+	videoScaledMu sync.Mutex
+	videoSinkNew  func(videoID string, conf types.VideoConfig) (*StreamSinkManagerCtx, error)
+
 	// sources
 	webcam     *StreamSrcManagerCtx
 	microphone *StreamSrcManagerCtx
+
+	// stops adaptive framerate, if enabled
+	adaptiveFpsStop chan struct{}
+	// current fraction of configured framerate, guarded by videoScaledMu
+	fpsScale float64
+	// stops screen comparison, shared by idle frames and screen listeners
+	screenWatchStop   chan struct{}
+	screenListeners   map[types.ScreenListener]struct{}
+	screenListenersMu sync.Mutex
+	// cursor image changed, resumes paused encoding
+	cursorChanged chan struct{}
 }
 
 func New(desktop types.DesktopManager, config *config.Capture) *CaptureManagerCtx {
 	logger := log.With().Str("module", "capture").Logger()
 
-	videos := map[string]types.StreamSinkManager{}
-	for video_id, cnf := range config.VideoPipelines {
-		pipelineConf := cnf
+	// display can be changed at runtime, pipelines must always use the current one
+	display := &atomic.Value{}
+	display.Store(config.Display)
+	getDisplay := func() string {
+		return display.Load().(string)
+	}
 
-		createPipeline := func() (string, error) {
-			if pipelineConf.GstPipeline != "" {
-				// replace {display} with valid display
-				return strings.Replace(pipelineConf.GstPipeline, "{display}", config.Display, 1), nil
-			}
+	// video pipelines capture only the region, when set
+	region := &atomic.Pointer[types.CaptureRegion]{}
+	getScreen := func() types.ScreenSize {
+		screen := desktop.GetScreenSize()
+		if r := region.Load(); r != nil {
+			screen.Width, screen.Height = r.Width, r.Height
+		}
+		return screen
+	}
+	getSrcRegion := func() string {
+		r := region.Load()
+		if r == nil {
+			return ""
+		}
 
-			screen := desktop.GetScreenSize()
-			pipeline, err := pipelineConf.GetPipeline(screen)
-			if err != nil {
-				return "", err
-			}
+		// end coordinates are inclusive
+		return fmt.Sprintf("startx=%d starty=%d endx=%d endy=%d ", r.X, r.Y, r.X+r.Width-1, r.Y+r.Height-1)
+	}
 
-			return fmt.Sprintf(
-				"ximagesrc display-name=%s show-pointer=%v use-damage=false "+
-					"%s ! appsink name=appsink", config.Display, pipelineConf.ShowPointer, pipeline,
-			), nil
+	// hardware encoder sessions are shared by all video pipelines
+	hwSessions := &hwEncoderSessions{max: config.VideoHwSessions}
+
+	// damage is shared by all video pipelines, because they share the source
+	useDamage := config.VideoDamage
+	if useDamage && !desktop.HasDamage() {
+		logger.Warn().Msg("x server does not report damaged regions, capturing full frames")
+		useDamage = false
+	}
+
+	// pipelines are evaluated once, so that syntax errors are caught early
+	videoSinkNew := func(video_id string, pipelineConf types.VideoConfig) (*StreamSinkManagerCtx, error) {
+		pipelineFn := func(conf types.VideoConfig) func() (string, error) {
+			return func() (string, error) {
+				if conf.GstPipeline != "" {
+					// replace {display} with valid display
+					return strings.Replace(conf.GstPipeline, "{display}", getDisplay(), 1), nil
+				}
+
+				screen := getScreen()
+				pipeline, err := conf.GetPipeline(screen)
+				if err != nil {
+					return "", err
+				}
+
+				return fmt.Sprintf(
+					"ximagesrc display-name=%s show-pointer=%v use-damage=%v %s"+
+						"! valve name=idle drop=false %s ! appsink name=appsink", getDisplay(), conf.ShowPointer, useDamage, getSrcRegion(), pipeline,
+				), nil
+			}
 		}
 
-		// trigger function to catch evaluation errors at startup
+		createPipeline := pipelineFn(pipelineConf)
+
+		// trigger function to catch evaluation errors
 		pipeline, err := createPipeline()
 		if err != nil {
-			logger.Panic().Err(err).
-				Str("video_id", video_id).
-				Msg("failed to create video pipeline")
+			return nil, fmt.Errorf("failed to create video pipeline: %w", err)
 		}
 
 		logger.Info().
@@ -68,14 +135,89 @@ func New(desktop types.DesktopManager, config *config.Capture) *CaptureManagerCt
 			Str("pipeline", pipeline).
 			Msg("syntax check for video stream pipeline passed")
 
-		// append to videos
-		videos[video_id] = streamSinkNew(config.VideoCodec, createPipeline, video_id)
+		// framerate is evaluated against current screen size
+		getFps := func() float64 {
+			fps, err := pipelineConf.GetFps(getScreen())
+			if err != nil {
+				return 0
+			}
+			return fps
+		}
+
+		// output resolution is evaluated against current screen size
+		getSize := func() (int, int) {
+			screen := getScreen()
+			w, h, err := pipelineConf.GetSize(screen)
+			if err != nil || w == 0 || h == 0 {
+				return screen.Width, screen.Height
+			}
+			return w, h
+		}
+
+		// poster is encoded by the same pipeline as the live video, only the source differs
+		var createPoster func() (string, error)
+		if config.VideoPoster != "" && pipelineConf.GstPipeline == "" {
+			createPoster = func() (string, error) {
+				screen := getScreen()
+				pipeline, err := pipelineConf.GetPipeline(screen)
+				if err != nil {
+					return "", err
+				}
+
+				return fmt.Sprintf(
+					"%s ! videoconvert ! videoscale ! video/x-raw,width=%d,height=%d "+
+						"%s ! appsink name=appsink", posterSrc(config.VideoPoster), screen.Width, screen.Height, pipeline,
+				), nil
+			}
+		}
+
+		sink := streamSinkNew(config.VideoCodec, createPipeline, getFps, getSize, createPoster, video_id)
+		sink.flipped = pipelineConf.Flip
+
+		if pipelineConf.HwEncoder {
+			var createFallback func() (string, error)
+			if pipelineConf.Fallback != nil {
+				// fallback must produce the same image as the hardware pipeline
+				fallbackConf := *pipelineConf.Fallback
+				fallbackConf.Flip = pipelineConf.Flip
+				createFallback = pipelineFn(fallbackConf)
+
+				// trigger function to catch evaluation errors
+				pipeline, err := createFallback()
+				if err != nil {
+					return nil, fmt.Errorf("failed to create video fallback pipeline: %w", err)
+				}
+
+				logger.Info().
+					Str("video_id", video_id).
+					Str("pipeline", pipeline).
+					Msg("syntax check for video stream fallback pipeline passed")
+			}
+
+			sink.setHwEncoder(hwSessions, createFallback)
+		}
+
+		return sink, nil
+	}
+
+	videos := map[string]types.StreamSinkManager{}
+	for video_id, pipelineConf := range config.VideoPipelines {
+		sink, err := videoSinkNew(video_id, pipelineConf)
+		if err != nil {
+			logger.Panic().Err(err).
+				Str("video_id", video_id).
+				Msg("failed to create video stream")
+		}
+
+		videos[video_id] = sink
 	}
 
 	return &CaptureManagerCtx{
 		logger:  logger,
 		desktop: desktop,
 		config:  config,
+		display: display,
+		region:  region,
 
 		// sinks
 		broadcast: broadcastNew(func(url string) (string, error) {
@@ -86,7 +228,7 @@ func New(desktop types.DesktopManager, config *config.Capture) *CaptureManagerCt
 					pipeline = strings.Replace(pipeline, "{hostname}", hostname, 1)
 				}
 				// replace {display} with valid display
-				pipeline = strings.Replace(pipeline, "{display}", config.Display, 1)
+				pipeline = strings.Replace(pipeline, "{display}", getDisplay(), 1)
 				// replace {device} with valid device
 				pipeline = strings.Replace(pipeline, "{device}", config.AudioDevice, 1)
 				// replace {url} with valid URL
@@ -106,7 +248,7 @@ func New(desktop types.DesktopManager, config *config.Capture) *CaptureManagerCt
 					"! videoconvert "+
 					"! queue "+
 					"! x264enc threads=4 bitrate=%d key-int-max=15 byte-stream=true tune=zerolatency speed-preset=%s "+
-					"! mux.", url, config.AudioDevice, config.BroadcastAudioBitrate*1000, config.Display, config.BroadcastVideoBitrate, config.BroadcastPreset,
+					"! mux.", url, config.AudioDevice, config.BroadcastAudioBitrate*1000, getDisplay(), config.BroadcastVideoBitrate, config.BroadcastPreset,
 			), nil
 		}, config.BroadcastUrl, config.BroadcastAutostart),
 		screencast: screencastNew(config.ScreencastEnabled, func() string {
@@ -131,17 +273,18 @@ func New(desktop types.DesktopManager, config *config.Capture) *CaptureManagerCt
 				return strings.Replace(config.AudioPipeline, "{device}", config.AudioDevice, 1), nil
 			}
 
-			return fmt.Sprintf(
-				"pulsesrc device=%s "+
-					"! audio/x-raw,channels=2 "+
-					"! audioconvert "+
-					"! queue "+
-					"! %s "+
-					"! appsink name=appsink", config.AudioDevice, config.AudioCodec.Pipeline,
-			), nil
-		}, "audio"),
+			return audioPipeline(config, 0, types.DefaultAudioEncoding), nil
+		}, nil, nil, nil, "audio"),
 		video: streamSelectorNew(config.VideoCodec, videos, config.VideoIDs),
 
+		audioGains: map[audioVariant]*StreamSinkManagerCtx{},
+
+		screenListeners: map[types.ScreenListener]struct{}{},
+		cursorChanged:   make(chan struct{}, 1),
+
+		videoScaled:  map[string]*StreamSinkManagerCtx{},
+		videoSinkNew: videoSinkNew,
+
 		// sources
 		webcam: streamSrcNew(config.WebcamEnabled, map[string]string{
 			codec.VP8().Name: "appsrc format=time is-live=true do-timestamp=true name=appsrc " +
@@ -194,14 +337,35 @@ func New(desktop types.DesktopManager, config *config.Capture) *CaptureManagerCt
 }
 
 func (manager *CaptureManagerCtx) Start() {
+	if manager.config.VideoAdaptiveFpsMin > 0 {
+		manager.adaptiveFpsStop = make(chan struct{})
+		go manager.adaptiveFps(manager.adaptiveFpsStop)
+	}
+
+	if manager.config.VideoIdleDelay > 0 {
+		manager.desktop.OnCursorChanged(func(serial uint64) {
+			select {
+			case manager.cursorChanged <- struct{}{}:
+			default:
+			}
+		})
+	}
+
+	manager.screenWatchStop = make(chan struct{})
+	go manager.screenWatch(manager.screenWatchStop)
+
 	if manager.broadcast.Started() {
 		if err := manager.broadcast.createPipeline(); err != nil {
 			manager.logger.Panic().Err(err).Msg("unable to create broadcast pipeline")
 		}
 	}
 
+	// vacated pipelines are kept for reconnecting sessions
+	manager.audio.setKeepAlive(manager.config.IdleTimeout)
+	manager.video.setKeepAlive(manager.config.IdleTimeout)
+
 	manager.desktop.OnBeforeScreenSizeChange(func() {
-		manager.video.destroyPipelines()
+		manager.destroyVideoPipelines()
 
 		if manager.broadcast.Started() {
 			manager.broadcast.destroyPipeline()
@@ -213,7 +377,13 @@ func (manager *CaptureManagerCtx) Start() {
 	})
 
 	manager.desktop.OnAfterScreenSizeChange(func() {
-		err := manager.video.recreatePipelines()
+		// region that no longer fits the screen is reset
+		if r := manager.region.Load(); r != nil && !r.Fits(manager.desktop.GetScreenSize()) {
+			manager.logger.Warn().Str("region", r.String()).Msg("capture region outside of new screen size, resetting")
+			manager.region.Store(nil)
+		}
+
+		err := manager.recreateVideoPipelines()
 		if err != nil {
 			manager.logger.Panic().Err(err).Msg("unable to recreate video pipelines")
 		}
@@ -237,12 +407,32 @@ func (manager *CaptureManagerCtx) Start() {
 func (manager *CaptureManagerCtx) Shutdown() error {
 	manager.logger.Info().Msgf("shutdown")
 
+	if manager.adaptiveFpsStop != nil {
+		close(manager.adaptiveFpsStop)
+	}
+
+	if manager.screenWatchStop != nil {
+		close(manager.screenWatchStop)
+	}
+
 	manager.broadcast.shutdown()
 	manager.screencast.shutdown()
 
 	manager.audio.shutdown()
 	manager.video.shutdown()
 
+	manager.audioGainsMu.Lock()
+	for _, audio := range manager.audioGains {
+		audio.shutdown()
+	}
+	manager.audioGainsMu.Unlock()
+
+	manager.videoScaledMu.Lock()
+	for _, video := range manager.videoScaled {
+		video.shutdown()
+	}
+	manager.videoScaledMu.Unlock()
+
 	manager.webcam.shutdown()
 	manager.microphone.shutdown()
 
@@ -261,10 +451,223 @@ func (manager *CaptureManagerCtx) Audio() types.StreamSinkManager {
 	return manager.audio
 }
 
+// audioVariant identifies audio sink created on demand
+type audioVariant struct {
+	gain     float64
+	encoding types.AudioEncoding
+}
+
+func (v audioVariant) id() string {
+	id := fmt.Sprintf("audio_%+.1fdB", v.gain)
+	if !v.encoding.InbandFEC {
+		id += "_nofec"
+	}
+	if v.encoding.DTX {
+		id += "_dtx"
+	}
+	return id
+}
+
+// AudioGain returns audio stream with applied gain in dB and opus encoding, streams
+// with the same gain and encoding are shared, zero gain with default encoding
+// returns the default audio stream.
+func (manager *CaptureManagerCtx) AudioGain(gain float64, encoding types.AudioEncoding) (types.StreamSinkManager, error) {
+	gain = types.RoundAudioGain(gain)
+
+	// only opus encoder can be configured
+	if manager.config.AudioCodec.Name != codec.Opus().Name {
+		encoding = types.DefaultAudioEncoding
+	}
+
+	if gain == 0 && encoding == types.DefaultAudioEncoding {
+		return manager.audio, nil
+	}
+
+	if gain < types.AudioGainMin || gain > types.AudioGainMax {
+		return nil, types.ErrCaptureAudioGainOutOfRange
+	}
+
+	// gain and encoding cannot be injected into custom pipeline
+	if manager.config.AudioPipeline != "" {
+		if gain != 0 {
+			return nil, types.ErrCaptureAudioGainUnsupported
+		}
+		return nil, types.ErrCaptureAudioEncUnsupported
+	}
+
+	manager.audioGainsMu.Lock()
+	defer manager.audioGainsMu.Unlock()
+
+	variant := audioVariant{gain, encoding}
+	audio, ok := manager.audioGains[variant]
+	if !ok {
+		audio = streamSinkNew(manager.config.AudioCodec, func() (string, error) {
+			return audioPipeline(manager.config, gain, encoding), nil
+		}, nil, nil, nil, variant.id())
+
+		// sink is released, when the last listener moves to another gain
+		audio.onStop = func() {
+			manager.audioGainsMu.Lock()
+			defer manager.audioGainsMu.Unlock()
+
+			if manager.audioGains[variant] == audio {
+				delete(manager.audioGains, variant)
+				audio.unregisterMetrics()
+			}
+		}
+
+		// sink might have been released, after it was returned to the caller
+		audio.onStart = func() error {
+			manager.audioGainsMu.Lock()
+			defer manager.audioGainsMu.Unlock()
+
+			return trackSink(manager.audioGains, variant, audio)
+		}
+
+		manager.audioGains[variant] = audio
+	}
+
+	return audio, nil
+}
+
 func (manager *CaptureManagerCtx) Video() types.StreamSelectorManager {
 	return manager.video
 }
 
+// VideoScaled returns video stream with resolution scaled down by given factor,
+// streams with the same source and factor are shared, factor 1 returns the source.
+func (manager *CaptureManagerCtx) VideoScaled(videoID string, factor float64) (types.StreamSinkManager, error) {
+	// round to hundredths, so that similar factors share the same pipeline
+	factor = math.Round(factor*100) / 100
+	if factor < 1 || factor > types.VideoScaleMax {
+		return nil, types.ErrCaptureVideoScaleOutOfRange
+	}
+
+	videoID = types.BaseStreamID(videoID)
+	if factor == 1 {
+		stream, ok := manager.video.GetStream(types.StreamSelector{
+			ID:   videoID,
+			Type: types.StreamSelectorTypeExact,
+		})
+		if !ok {
+			return nil, types.ErrWebRTCStreamNotFound
+		}
+		return stream, nil
+	}
+
+	pipelineConf, ok := manager.config.VideoPipelines[videoID]
+	if !ok {
+		return nil, types.ErrWebRTCStreamNotFound
+	}
+
+	// scaling cannot be injected into custom pipeline
+	if pipelineConf.GstPipeline != "" || (pipelineConf.Fallback != nil && pipelineConf.Fallback.GstPipeline != "") {
+		return nil, types.ErrCaptureVideoScaleUnsupported
+	}
+
+	manager.videoScaledMu.Lock()
+	defer manager.videoScaledMu.Unlock()
+
+	id := types.ScaledStreamID(videoID, factor)
+	if video, ok := manager.videoScaled[id]; ok {
+		return video, nil
+	}
+
+	pipelineConf = scaleVideoConfig(pipelineConf, factor)
+	if pipelineConf.Fallback != nil {
+		fallbackConf := scaleVideoConfig(*pipelineConf.Fallback, factor)
+		pipelineConf.Fallback = &fallbackConf
+	}
+
+	video, err := manager.videoSinkNew(id, pipelineConf)
+	if err != nil {
+		return nil, err
+	}
+
+	// adaptive framerate applies to scaled pipelines as well
+	video.setFpsLimit(manager.fpsLimit(video, manager.fpsScale))
+
+	// sink is evicted, when its last listener goes away
+	video.onStop = func() {
+		manager.videoScaledMu.Lock()
+		defer manager.videoScaledMu.Unlock()
+
+		if manager.videoScaled[id] == video {
+			delete(manager.videoScaled, id)
+			video.unregisterMetrics()
+		}
+	}
+
+	// sink might have been evicted, after it was returned to the caller
+	video.onStart = func() error {
+		manager.videoScaledMu.Lock()
+		defer manager.videoScaledMu.Unlock()
+
+		if err := trackSink(manager.videoScaled, id, video); err != nil {
+			return err
+		}
+
+		// scale could have changed, while the sink was released
+		video.setFpsLimit(manager.fpsLimit(video, manager.fpsScale))
+		return nil
+	}
+
+	manager.videoScaled[id] = video
+	return video, nil
+}
+
+// trackSink puts released sink back to the map, so that its pipeline is
+// not running untracked, it fails if the key was taken by another sink.
+func trackSink[K comparable](sinks map[K]*StreamSinkManagerCtx, key K, sink *StreamSinkManagerCtx) error {
+	current, ok := sinks[key]
+	if ok && current == sink {
+		return nil
+	}
+	if ok {
+		return types.ErrCaptureSinkReleased
+	}
+
+	if err := sink.registerMetrics(); err != nil {
+		return err
+	}
+
+	sinks[key] = sink
+	return nil
+}
+
+func (manager *CaptureManagerCtx) destroyVideoPipelines() {
+	manager.video.destroyPipelines()
+
+	manager.videoScaledMu.Lock()
+	defer manager.videoScaledMu.Unlock()
+
+	for _, video := range manager.videoScaled {
+		video.DestroyPipeline()
+	}
+}
+
+func (manager *CaptureManagerCtx) recreateVideoPipelines() error {
+	if err := manager.video.recreatePipelines(); err != nil {
+		return err
+	}
+
+	manager.videoScaledMu.Lock()
+	defer manager.videoScaledMu.Unlock()
+
+	for _, video := range manager.videoScaled {
+		if !video.Started() {
+			continue
+		}
+
+		err := video.CreatePipeline()
+		if err != nil && !errors.Is(err, types.ErrCapturePipelineAlreadyExists) {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (manager *CaptureManagerCtx) Webcam() types.StreamSrcManager {
 	return manager.webcam
 }
@@ -272,3 +675,185 @@ func (manager *CaptureManagerCtx) Webcam() types.StreamSrcManager {
 func (manager *CaptureManagerCtx) Microphone() types.StreamSrcManager {
 	return manager.microphone
 }
+
+func (manager *CaptureManagerCtx) Displays() []string {
+	return manager.config.Displays
+}
+
+func (manager *CaptureManagerCtx) Display() string {
+	return manager.display.Load().(string)
+}
+
+func (manager *CaptureManagerCtx) SetDisplay(display string) error {
+	if in, _ := utils.ArrayIn(display, manager.config.Displays); !in {
+		return types.ErrCaptureDisplayNotFound
+	}
+
+	manager.displayMu.Lock()
+	defer manager.displayMu.Unlock()
+
+	if manager.Display() == display {
+		return nil
+	}
+
+	// only pipelines are recreated, stream listeners (peers) stay attached
+	manager.destroyVideoPipelines()
+
+	if manager.broadcast.Started() {
+		manager.broadcast.destroyPipeline()
+	}
+
+	manager.display.Store(display)
+	manager.logger.Info().Str("display", display).Msg("switching capture display")
+
+	if err := manager.recreateVideoPipelines(); err != nil {
+		return err
+	}
+
+	if manager.broadcast.Started() {
+		err := manager.broadcast.createPipeline()
+		if err != nil && !errors.Is(err, types.ErrCapturePipelineAlreadyExists) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (manager *CaptureManagerCtx) Region() *types.CaptureRegion {
+	return manager.region.Load()
+}
+
+func (manager *CaptureManagerCtx) SetRegion(region *types.CaptureRegion) error {
+	if region != nil && !region.Fits(manager.desktop.GetScreenSize()) {
+		return types.ErrCaptureRegionInvalid
+	}
+
+	// custom pipelines are not cropped, input would be translated anyway
+	if region != nil {
+		for _, conf := range manager.config.VideoPipelines {
+			if conf.GstPipeline != "" {
+				return types.ErrCaptureRegionUnsupported
+			}
+		}
+	}
+
+	manager.displayMu.Lock()
+	defer manager.displayMu.Unlock()
+
+	current := manager.region.Load()
+	if current == region || (current != nil && region != nil && *current == *region) {
+		return nil
+	}
+
+	// only video pipelines are cropped, stream listeners (peers) stay attached
+	manager.destroyVideoPipelines()
+
+	manager.region.Store(region)
+	if region != nil {
+		manager.logger.Info().Str("region", region.String()).Msg("capturing screen region")
+	} else {
+		manager.logger.Info().Msg("capturing whole screen")
+	}
+
+	return manager.recreateVideoPipelines()
+}
+
+// Snapshot grabs current raw frame and encodes it as JPEG, scaled to the
+// resolution of given video stream. Empty video ID means full resolution.
+func (manager *CaptureManagerCtx) Snapshot(videoID string, quality int) ([]byte, error) {
+	img := manager.desktop.GetScreenshotImage()
+
+	// crop to the captured region
+	if r := manager.region.Load(); r != nil && img != nil {
+		img = img.SubImage(image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height)).(*image.RGBA)
+	}
+
+	if videoID != "" {
+		pipelineConf, ok := manager.config.VideoPipelines[videoID]
+		if !ok {
+			return nil, types.ErrWebRTCStreamNotFound
+		}
+
+		screen := manager.desktop.GetScreenSize()
+		if r := manager.region.Load(); r != nil {
+			screen.Width, screen.Height = r.Width, r.Height
+		}
+
+		width, height, err := pipelineConf.GetSize(screen)
+		if err != nil {
+			return nil, err
+		}
+
+		// scale down only, never up
+		bounds := img.Bounds()
+		if width > 0 && height > 0 && width < bounds.Dx() && height < bounds.Dy() {
+			img = utils.ScaleImage(img, width, height)
+		}
+	}
+
+	return utils.CreateJPGImage(img, quality)
+}
+
+// scaleVideoConfig divides output resolution of the pipeline by factor,
+// rounded to even numbers as required by most encoders.
+func scaleVideoConfig(conf types.VideoConfig, factor float64) types.VideoConfig {
+	width, height := conf.Width, conf.Height
+	if width == "" || height == "" {
+		width, height = "width", "height"
+	}
+
+	conf.Width = fmt.Sprintf("round((%s) / %g / 2) * 2", width, factor)
+	conf.Height = fmt.Sprintf("round((%s) / %g / 2) * 2", height, factor)
+	return conf
+}
+
+// audioPipeline returns default audio pipeline, with volume element if gain is set.
+func audioPipeline(config *config.Capture, gain float64, encoding types.AudioEncoding) string {
+	// properties set later override the ones of the codec pipeline
+	encoder := config.AudioCodec.Pipeline
+	if encoding != types.DefaultAudioEncoding {
+		encoder += fmt.Sprintf(" inband-fec=%t dtx=%t", encoding.InbandFEC, encoding.DTX)
+	}
+
+	volume := ""
+	if gain != 0 {
+		volume = fmt.Sprintf("! volume volume=%f ", math.Pow(10, gain/20))
+	}
+
+	// pulseaudio converts to the requested format
+	caps := "audio/x-raw,channels=2"
+	if config.AudioChannels != 0 {
+		caps = fmt.Sprintf("audio/x-raw,channels=%d", config.AudioChannels)
+	}
+	if config.AudioSampleRate != 0 {
+		caps += fmt.Sprintf(",rate=%d", config.AudioSampleRate)
+	}
+
+	// level element posts rms level of the audio, that is sent to clients
+	level := ""
+	if config.AudioLevelInterval > 0 {
+		level = fmt.Sprintf("! level name=level interval=%d post-messages=true ", config.AudioLevelInterval.Nanoseconds())
+	}
+
+	return fmt.Sprintf(
+		"pulsesrc device=%s "+
+			"! %s "+
+			"! audioconvert "+
+			"%s"+
+			"%s"+
+			"! queue "+
+			"! %s "+
+			"! appsink name=appsink", config.AudioDevice, caps, volume, level, encoder,
+	)
+}
+
+// posterSrc returns gstreamer source producing single frame of
+// either solid color (#rrggbb) or an image loaded from file.
+func posterSrc(poster string) string {
+	if color, ok := strings.CutPrefix(poster, "#"); ok && len(color) == 6 {
+		return fmt.Sprintf("videotestsrc num-buffers=1 pattern=solid-color foreground-color=0xff%s", color)
+	}
+
+	return fmt.Sprintf("filesrc location=%q ! decodebin ! imagefreeze num-buffers=1", poster)
+}
@@ -0,0 +1,194 @@
+package capture
+
+import (
+	"hash/fnv"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// how often is the screen compared with the previous one
+const screenCompareInterval = 200 * time.Millisecond
+
+// how often is the cursor checked while encoding is paused, so that pointer
+// activity resumes encoding without waiting for the next screen comparison
+const idleCursorInterval = 20 * time.Millisecond
+
+// AddScreenListener starts comparing the screen for the listener, listeners
+// and idle frames share a single grab and hash of the screen.
+func (manager *CaptureManagerCtx) AddScreenListener(listener types.ScreenListener) {
+	manager.screenListenersMu.Lock()
+	defer manager.screenListenersMu.Unlock()
+
+	manager.screenListeners[listener] = struct{}{}
+}
+
+func (manager *CaptureManagerCtx) RemoveScreenListener(listener types.ScreenListener) {
+	manager.screenListenersMu.Lock()
+	defer manager.screenListenersMu.Unlock()
+
+	delete(manager.screenListeners, listener)
+}
+
+func (manager *CaptureManagerCtx) getScreenListeners() []types.ScreenListener {
+	manager.screenListenersMu.Lock()
+	defer manager.screenListenersMu.Unlock()
+
+	listeners := make([]types.ScreenListener, 0, len(manager.screenListeners))
+	for listener := range manager.screenListeners {
+		listeners = append(listeners, listener)
+	}
+	return listeners
+}
+
+// screenWatch compares the screen with the previous one, while there are screen
+// listeners or video is encoded with idle frames enabled. Video pipelines are paused
+// after the screen and the cursor were static for the idle delay, and resumed
+// as soon as the screen changes or immediately on cursor activity.
+func (manager *CaptureManagerCtx) screenWatch(stop <-chan struct{}) {
+	logger := manager.logger.With().Str("submodule", "screen-watch").Logger()
+
+	ticker := time.NewTicker(screenCompareInterval)
+	defer ticker.Stop()
+
+	cursorTicker := time.NewTicker(idleCursorInterval)
+	defer cursorTicker.Stop()
+
+	var lastHash uint64
+	var changedAt time.Time
+
+	var cursorX, cursorY int
+	var cursorMovedAt time.Time
+
+	idle := false
+	setIdle := func(value bool) {
+		if idle != value {
+			if value {
+				logger.Debug().Msg("screen is static, pausing encoding")
+			} else {
+				logger.Debug().Msg("screen changed, resuming encoding")
+			}
+		}
+
+		idle = value
+		manager.setVideoIdle(value)
+	}
+	defer manager.setVideoIdle(false)
+
+	// cursor position is part of the video, but not of the screenshot
+	cursorMoved := func() bool {
+		x, y := manager.desktop.GetCursorPosition()
+		if x == cursorX && y == cursorY {
+			return false
+		}
+
+		cursorX, cursorY = x, y
+		cursorMovedAt = time.Now()
+		return true
+	}
+
+	for {
+		// cursor is checked more often only while encoding is paused
+		var cursorTick <-chan time.Time
+		if idle {
+			cursorTick = cursorTicker.C
+		}
+
+		select {
+		case <-stop:
+			return
+		case <-manager.cursorChanged:
+			if idle {
+				cursorMovedAt = time.Now()
+				setIdle(false)
+			}
+			continue
+		case <-cursorTick:
+			if cursorMoved() {
+				setIdle(false)
+			}
+			continue
+		case <-ticker.C:
+		}
+
+		idleFrames := manager.config.VideoIdleDelay > 0 && manager.videoStarted()
+		listeners := manager.getScreenListeners()
+
+		// screen is not grabbed when nobody is interested
+		if !idleFrames && len(listeners) == 0 {
+			if idle {
+				setIdle(false)
+			}
+			lastHash = 0
+			continue
+		}
+
+		img := manager.desktop.GetScreenshotImage()
+		if img == nil {
+			continue
+		}
+
+		hash := fnv.New64a()
+		hash.Write(img.Pix)
+		sum := hash.Sum64()
+
+		now := time.Now()
+		if sum != lastHash {
+			lastHash = sum
+			changedAt = now
+		}
+
+		static := now.Sub(changedAt)
+		for _, listener := range listeners {
+			listener.ScreenCompared(img, static)
+		}
+
+		if cursorMoved() {
+			static = 0
+		} else {
+			static = min(static, now.Sub(cursorMovedAt))
+		}
+
+		// applied every time, because new listeners resume paused pipelines
+		if idleFrames {
+			setIdle(static >= manager.config.VideoIdleDelay)
+		} else if idle {
+			setIdle(false)
+		}
+	}
+}
+
+// videoStarted returns true, if any video pipeline has listeners
+func (manager *CaptureManagerCtx) videoStarted() bool {
+	for _, stream := range manager.video.streams {
+		if stream.Started() {
+			return true
+		}
+	}
+
+	manager.videoScaledMu.Lock()
+	defer manager.videoScaledMu.Unlock()
+
+	for _, video := range manager.videoScaled {
+		if video.Started() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (manager *CaptureManagerCtx) setVideoIdle(idle bool) {
+	for _, stream := range manager.video.streams {
+		if sink, ok := stream.(*StreamSinkManagerCtx); ok {
+			sink.setIdle(idle)
+		}
+	}
+
+	manager.videoScaledMu.Lock()
+	defer manager.videoScaledMu.Unlock()
+
+	for _, video := range manager.videoScaled {
+		video.setIdle(idle)
+	}
+}
@@ -3,6 +3,7 @@ package capture
 import (
 	"errors"
 	"sort"
+	"time"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -39,10 +40,9 @@ func (manager *StreamSelectorManagerCtx) shutdown() {
 }
 
 func (manager *StreamSelectorManagerCtx) destroyPipelines() {
+	// pipelines kept alive without listeners must be destroyed as well
 	for _, stream := range manager.streams {
-		if stream.Started() {
-			stream.DestroyPipeline()
-		}
+		stream.DestroyPipeline()
 	}
 }
 
@@ -58,6 +58,15 @@ func (manager *StreamSelectorManagerCtx) recreatePipelines() error {
 	return nil
 }
 
+// setKeepAlive applies only to configured streams, not to those kept for compatibility
+func (manager *StreamSelectorManagerCtx) setKeepAlive(keepAlive time.Duration) {
+	for _, id := range manager.streamIDs {
+		if sink, ok := manager.streams[id].(*StreamSinkManagerCtx); ok {
+			sink.setKeepAlive(keepAlive)
+		}
+	}
+}
+
 func (manager *StreamSelectorManagerCtx) IDs() []string {
 	return manager.streamIDs
 }
@@ -67,8 +76,48 @@ func (manager *StreamSelectorManagerCtx) Codec() codec.RTPCodec {
 }
 
 func (manager *StreamSelectorManagerCtx) GetStream(selector types.StreamSelector) (types.StreamSinkManager, bool) {
-	// select stream by ID
+	stream, ok := manager.getStream(selector)
+	if !ok || selector.MaxResolution == nil {
+		return stream, ok
+	}
+
+	return manager.capResolution(stream, selector)
+}
+
+// capResolution returns the highest stream not above given stream, that fits into max resolution
+func (manager *StreamSelectorManagerCtx) capResolution(stream types.StreamSinkManager, selector types.StreamSelector) (types.StreamSinkManager, bool) {
+	if selector.MaxResolution.Fits(stream.Size()) {
+		return stream, true
+	}
+
+	// higher stream was requested, but it exceeds the cap
+	if selector.Type == types.StreamSelectorTypeHigher {
+		return nil, false
+	}
+
+	// video ids are ordered from the highest stream
+	lower := false
+	for _, streamID := range manager.streamIDs {
+		if streamID == stream.ID() {
+			lower = true
+			continue
+		}
+
+		candidate, ok := manager.streams[streamID]
+		if lower && ok && selector.MaxResolution.Fits(candidate.Size()) {
+			return candidate, true
+		}
+	}
+
+	// no stream fits into the cap
+	return nil, false
+}
+
+func (manager *StreamSelectorManagerCtx) getStream(selector types.StreamSelector) (types.StreamSinkManager, bool) {
+	// select stream by ID, scaled streams are derived from the configured ones
 	if selector.ID != "" {
+		selector.ID = types.BaseStreamID(selector.ID)
+
 		// select lower stream
 		if selector.Type == types.StreamSelectorTypeLower {
 			var lastStream types.StreamSinkManager
@@ -19,6 +19,12 @@ import (
 
 var moveSinkListenerMu = sync.Mutex{}
 
+// how long to wait for poster pipeline to produce a frame
+const posterTimeout = 2 * time.Second
+
+// level is not reported, if it was not updated for this long
+const levelStaleAfter = time.Second
+
 type StreamSinkManagerCtx struct {
 	id string
 
@@ -36,19 +42,49 @@ type StreamSinkManagerCtx struct {
 	pipeline   gst.Pipeline
 	pipelineMu sync.Mutex
 	pipelineFn func() (string, error)
+	fpsFn      func() float64
+	sizeFn     func() (int, int)
+	flipped    bool
+	// framerate limit applied to running pipeline, 0 means configured framerate
+	fpsLimit float64
+	// frames are dropped before the encoder while the screen is static
+	idle bool
+
+	// poster frame is sent to new listeners before first live keyframe
+	posterFn  func() (string, error)
+	poster    *types.Sample
+	posterSrc string
+	posterMu  sync.Mutex
 
 	listeners   map[uintptr]types.SampleListener
 	listenersKf map[uintptr]types.SampleListener // keyframe lobby
 	listenersMu sync.Mutex
 
+	// pipeline is stopped only after it had no listeners for keepAlive,
+	// so that quick reconnects do not recreate it, 0 stops it immediately
+	keepAlive      time.Duration
+	keepAliveTimer *time.Timer
+	// called with mutex locked, after the last listener was removed and pipeline stopped,
+	// releases sink created on demand, must be set before the sink is shared
+	onStop func()
+	// called with mutex locked, before the first listener is added, tracks
+	// the sink again if it was released by onStop after it had been shared
+	onStart func() error
+
+	// hardware encoded pipeline falls back to software, when sessions are exhausted
+	hwSessions *hwEncoderSessions
+	fallbackFn func() (string, error)
+	encoder    types.EncoderBackend
+
 	// metrics
 	currentListeners prometheus.Gauge
 	totalBytes       prometheus.Counter
 	pipelinesCounter prometheus.Counter
 	pipelinesActive  prometheus.Gauge
+	encoderFallbacks prometheus.Counter
 }
 
-func streamSinkNew(codec codec.RTPCodec, pipelineFn func() (string, error), id string) *StreamSinkManagerCtx {
+func streamSinkNew(codec codec.RTPCodec, pipelineFn func() (string, error), fpsFn func() float64, sizeFn func() (int, int), posterFn func() (string, error), id string) *StreamSinkManagerCtx {
 	logger := log.With().
 		Str("module", "capture").
 		Str("submodule", "stream-sink").
@@ -66,6 +102,9 @@ func streamSinkNew(codec codec.RTPCodec, pipelineFn func() (string, error), id s
 		logger:     logger,
 		codec:      codec,
 		pipelineFn: pipelineFn,
+		fpsFn:      fpsFn,
+		sizeFn:     sizeFn,
+		posterFn:   posterFn,
 
 		listeners:   map[uintptr]types.SampleListener{},
 		listenersKf: map[uintptr]types.SampleListener{},
@@ -122,6 +161,56 @@ func streamSinkNew(codec codec.RTPCodec, pipelineFn func() (string, error), id s
 	return manager
 }
 
+// setHwEncoder marks pipeline as hardware encoded, fallback pipeline is
+// optional and is used when no hardware encoder session is available.
+func (manager *StreamSinkManagerCtx) setHwEncoder(sessions *hwEncoderSessions, fallbackFn func() (string, error)) {
+	manager.hwSessions = sessions
+	manager.fallbackFn = fallbackFn
+	manager.encoderFallbacks = promauto.NewCounter(prometheus.CounterOpts{
+		Name:      "encoder_fallback_total",
+		Namespace: "neko",
+		Subsystem: "capture",
+		Help:      "Total number of pipelines that fell back to software encoding.",
+		ConstLabels: map[string]string{
+			"video_id":   manager.id,
+			"codec_name": manager.codec.Name,
+			"codec_type": manager.codec.Type.String(),
+		},
+	})
+}
+
+// registerMetrics registers metrics removed by unregisterMetrics again
+func (manager *StreamSinkManagerCtx) registerMetrics() error {
+	collectors := []prometheus.Collector{
+		manager.currentListeners,
+		manager.totalBytes,
+		manager.pipelinesCounter,
+		manager.pipelinesActive,
+	}
+	if manager.encoderFallbacks != nil {
+		collectors = append(collectors, manager.encoderFallbacks)
+	}
+
+	for _, collector := range collectors {
+		if err := prometheus.Register(collector); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unregisterMetrics allows sink with the same id to be created again
+func (manager *StreamSinkManagerCtx) unregisterMetrics() {
+	prometheus.Unregister(manager.currentListeners)
+	prometheus.Unregister(manager.totalBytes)
+	prometheus.Unregister(manager.pipelinesCounter)
+	prometheus.Unregister(manager.pipelinesActive)
+	if manager.encoderFallbacks != nil {
+		prometheus.Unregister(manager.encoderFallbacks)
+	}
+}
+
 func (manager *StreamSinkManagerCtx) shutdown() {
 	manager.logger.Info().Msgf("shutdown")
 
@@ -134,6 +223,10 @@ func (manager *StreamSinkManagerCtx) shutdown() {
 	}
 	manager.listenersMu.Unlock()
 
+	manager.mu.Lock()
+	manager.cancelKeepAlive()
+	manager.mu.Unlock()
+
 	manager.DestroyPipeline()
 	manager.wg.Wait()
 }
@@ -146,12 +239,52 @@ func (manager *StreamSinkManagerCtx) Bitrate() uint64 {
 	return manager.bitrate
 }
 
+// Fps returns configured framerate of the stream, 0 if unknown
+func (manager *StreamSinkManagerCtx) Fps() float64 {
+	if manager.fpsFn == nil {
+		return 0
+	}
+	return manager.fpsFn()
+}
+
+// Size returns output resolution of the stream, 0 if unknown
+func (manager *StreamSinkManagerCtx) Size() (int, int) {
+	if manager.sizeFn == nil {
+		return 0, 0
+	}
+	return manager.sizeFn()
+}
+
+func (manager *StreamSinkManagerCtx) Flipped() bool {
+	return manager.flipped
+}
+
+func (manager *StreamSinkManagerCtx) HwEncoder() bool {
+	return manager.hwSessions != nil
+}
+
 func (manager *StreamSinkManagerCtx) Codec() codec.RTPCodec {
 	return manager.codec
 }
 
+func (manager *StreamSinkManagerCtx) Encoder() types.EncoderBackend {
+	manager.pipelineMu.Lock()
+	defer manager.pipelineMu.Unlock()
+
+	return manager.encoder
+}
+
 func (manager *StreamSinkManagerCtx) start() error {
+	// pipeline kept after its last listener is reused
+	manager.cancelKeepAlive()
+
 	if len(manager.listeners)+len(manager.listenersKf) == 0 {
+		if manager.onStart != nil {
+			if err := manager.onStart(); err != nil {
+				return err
+			}
+		}
+
 		err := manager.CreatePipeline()
 		if err != nil && !errors.Is(err, types.ErrCapturePipelineAlreadyExists) {
 			return err
@@ -167,13 +300,60 @@ func (manager *StreamSinkManagerCtx) stop() {
 	if len(manager.listeners)+len(manager.listenersKf) == 0 {
 		manager.DestroyPipeline()
 		manager.logger.Info().Msgf("last listener, stopping")
+
+		if manager.onStop != nil {
+			manager.onStop()
+		}
+	}
+}
+
+// stopLater stops the vacated pipeline once it had no listeners for keepAlive,
+// must be called with mutex locked
+func (manager *StreamSinkManagerCtx) stopLater() {
+	if manager.keepAlive == 0 {
+		manager.stop()
+		return
+	}
+
+	if manager.keepAliveTimer != nil || len(manager.listeners)+len(manager.listenersKf) > 0 {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(manager.keepAlive, func() {
+		manager.mu.Lock()
+		defer manager.mu.Unlock()
+
+		// timer was cancelled in the meantime
+		if manager.keepAliveTimer != timer {
+			return
+		}
+
+		manager.keepAliveTimer = nil
+		manager.stop()
+	})
+	manager.keepAliveTimer = timer
+
+	manager.logger.Debug().Dur("keep_alive", manager.keepAlive).Msg("last listener, keeping pipeline")
+}
+
+// cancelKeepAlive must be called with mutex locked
+func (manager *StreamSinkManagerCtx) cancelKeepAlive() {
+	if manager.keepAliveTimer != nil {
+		manager.keepAliveTimer.Stop()
+		manager.keepAliveTimer = nil
 	}
 }
 
-func (manager *StreamSinkManagerCtx) addListener(listener types.SampleListener) {
+func (manager *StreamSinkManagerCtx) addListener(listener types.SampleListener, poster *types.Sample) {
 	ptr := reflect.ValueOf(listener).Pointer()
 	emitKeyframe := false
 
+	// poster must be written before the listener can receive live keyframe
+	if poster != nil {
+		listener.WriteSample(*poster)
+	}
+
 	manager.listenersMu.Lock()
 	if manager.waitForKf {
 		// if this is the first listener, we need to emit a keyframe
@@ -189,12 +369,76 @@ func (manager *StreamSinkManagerCtx) addListener(listener types.SampleListener)
 	manager.logger.Debug().Interface("ptr", ptr).Msgf("adding listener")
 	manager.currentListeners.Set(float64(manager.ListenersCount()))
 
+	// paused pipeline would never produce the keyframe
+	manager.setIdle(false)
+
 	// if we will be waiting for a keyframe, emit one now
 	if manager.pipeline != nil && emitKeyframe {
 		manager.pipeline.EmitVideoKeyframe()
 	}
 }
 
+// posterSample returns poster frame shown to listeners waiting in the keyframe
+// lobby, it must not be called with manager mutex held, as it might run a pipeline.
+func (manager *StreamSinkManagerCtx) posterSample() *types.Sample {
+	if !manager.waitForKf || manager.posterFn == nil {
+		return nil
+	}
+
+	sample, ok := manager.getPoster()
+	if !ok {
+		return nil
+	}
+
+	return &sample
+}
+
+// getPoster returns encoded poster frame, it is created on first use
+// and recreated only when its pipeline changes (e.g. screen size).
+func (manager *StreamSinkManagerCtx) getPoster() (types.Sample, bool) {
+	manager.posterMu.Lock()
+	defer manager.posterMu.Unlock()
+
+	pipelineStr, err := manager.posterFn()
+	if err != nil {
+		manager.logger.Warn().Err(err).Msg("failed to get poster pipeline")
+		return types.Sample{}, false
+	}
+
+	if manager.poster != nil && manager.posterSrc == pipelineStr {
+		return *manager.poster, true
+	}
+
+	manager.logger.Info().
+		Str("src", pipelineStr).
+		Msgf("creating poster pipeline")
+
+	pipeline, err := gst.CreatePipeline(pipelineStr)
+	if err != nil {
+		manager.logger.Warn().Err(err).Msg("failed to create poster pipeline")
+		return types.Sample{}, false
+	}
+	defer pipeline.Destroy()
+
+	pipeline.AttachAppsink("appsink")
+	pipeline.Play()
+
+	select {
+	case sample, ok := <-pipeline.Sample():
+		if !ok {
+			manager.logger.Warn().Msg("poster pipeline ended without a frame")
+			return types.Sample{}, false
+		}
+
+		manager.poster = &sample
+		manager.posterSrc = pipelineStr
+		return sample, true
+	case <-time.After(posterTimeout):
+		manager.logger.Warn().Msg("timeout while waiting for poster frame")
+		return types.Sample{}, false
+	}
+}
+
 func (manager *StreamSinkManagerCtx) removeListener(listener types.SampleListener) {
 	ptr := reflect.ValueOf(listener).Pointer()
 
@@ -208,20 +452,23 @@ func (manager *StreamSinkManagerCtx) removeListener(listener types.SampleListene
 }
 
 func (manager *StreamSinkManagerCtx) AddListener(listener types.SampleListener) error {
-	manager.mu.Lock()
-	defer manager.mu.Unlock()
-
 	if listener == nil {
 		return errors.New("listener cannot be nil")
 	}
 
+	// poster is prepared before locking, so that it does not block other listeners
+	poster := manager.posterSample()
+
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
 	// start if stopped
 	if err := manager.start(); err != nil {
 		return err
 	}
 
 	// add listener
-	manager.addListener(listener)
+	manager.addListener(listener, poster)
 
 	return nil
 }
@@ -237,12 +484,20 @@ func (manager *StreamSinkManagerCtx) RemoveListener(listener types.SampleListene
 	// remove listener
 	manager.removeListener(listener)
 
-	// stop if started
-	manager.stop()
+	// stop if started, possibly after a grace period for reconnecting clients
+	manager.stopLater()
 
 	return nil
 }
 
+// setKeepAlive must be called before the sink is shared
+func (manager *StreamSinkManagerCtx) setKeepAlive(keepAlive time.Duration) {
+	manager.mu.Lock()
+	defer manager.mu.Unlock()
+
+	manager.keepAlive = keepAlive
+}
+
 // moving listeners between streams ensures, that target pipeline is running
 // before listener is added, and stops source pipeline if there are 0 listeners
 func (manager *StreamSinkManagerCtx) MoveListenerTo(listener types.SampleListener, stream types.StreamSinkManager) error {
@@ -280,10 +535,11 @@ func (manager *StreamSinkManagerCtx) MoveListenerTo(listener types.SampleListene
 
 	// swap listeners
 	manager.removeListener(listener)
-	targetStream.addListener(listener)
+	// poster is sent only to new listeners, moved ones already show the screen
+	targetStream.addListener(listener, nil)
 
-	// stop if started
-	manager.stop()
+	// stop if started, possibly after a grace period for reconnecting clients
+	manager.stopLater()
 
 	return nil
 }
@@ -307,23 +563,45 @@ func (manager *StreamSinkManagerCtx) CreatePipeline() error {
 		return types.ErrCapturePipelineAlreadyExists
 	}
 
-	pipelineStr, err := manager.pipelineFn()
+	pipelineStr, encoder, err := manager.pipelineSrc()
 	if err != nil {
 		return err
 	}
 
 	manager.logger.Info().
 		Str("codec", manager.codec.Name).
+		Str("encoder", string(encoder)).
 		Str("src", pipelineStr).
 		Msgf("creating pipeline")
 
 	manager.pipeline, err = gst.CreatePipeline(pipelineStr)
+
+	// hardware encoder can refuse new session even below configured limit
+	if err != nil && encoder == types.EncoderBackendHardware && manager.fallbackFn != nil {
+		manager.hwSessions.release()
+		manager.logger.Warn().Err(err).Msg("failed to create hardware encoded pipeline, falling back to software encoding")
+		manager.encoderFallbacks.Inc()
+
+		encoder = types.EncoderBackendSoftware
+		pipelineStr, err = manager.fallbackFn()
+		if err == nil {
+			manager.pipeline, err = gst.CreatePipeline(pipelineStr)
+		}
+	}
+
 	if err != nil {
+		if encoder == types.EncoderBackendHardware {
+			manager.hwSessions.release()
+		}
 		return err
 	}
 
+	manager.encoder = encoder
+
 	manager.pipeline.AttachAppsink("appsink")
 	manager.pipeline.Play()
+	manager.applyFpsLimit()
+	manager.idle = false
 
 	manager.wg.Add(1)
 	pipeline := manager.pipeline
@@ -349,6 +627,100 @@ func (manager *StreamSinkManagerCtx) CreatePipeline() error {
 	return nil
 }
 
+// setFpsLimit lowers framerate of the pipeline without recreating it, 0 restores configured framerate
+func (manager *StreamSinkManagerCtx) setFpsLimit(limit float64) {
+	manager.pipelineMu.Lock()
+	defer manager.pipelineMu.Unlock()
+
+	if manager.fpsLimit == limit {
+		return
+	}
+
+	manager.fpsLimit = limit
+	manager.applyFpsLimit()
+}
+
+// effectiveFps returns framerate of the pipeline with applied limit
+func (manager *StreamSinkManagerCtx) effectiveFps() float64 {
+	fps := manager.Fps()
+	if manager.fpsLimit > 0 && (fps == 0 || manager.fpsLimit < fps) {
+		return manager.fpsLimit
+	}
+	return fps
+}
+
+// must be called with pipeline mutex locked
+func (manager *StreamSinkManagerCtx) applyFpsLimit() {
+	if manager.pipeline == nil {
+		return
+	}
+
+	// framerate caps are present only in pipelines with configured framerate
+	fps := manager.effectiveFps()
+	if fps <= 0 || !manager.pipeline.SetCapsFramerate("framerate", int(fps*100), 100) {
+		manager.logger.Debug().Float64("fps", fps).Msg("unable to set pipeline framerate")
+	}
+}
+
+// setIdle stops passing frames to the encoder while the screen is static, pipeline
+// is not paused while listeners wait for a keyframe, so that they get one immediately
+func (manager *StreamSinkManagerCtx) setIdle(idle bool) {
+	manager.pipelineMu.Lock()
+	defer manager.pipelineMu.Unlock()
+
+	if idle && manager.waitForKf {
+		manager.listenersMu.Lock()
+		waiting := len(manager.listenersKf) > 0
+		manager.listenersMu.Unlock()
+		if waiting {
+			return
+		}
+	}
+
+	if manager.idle == idle || manager.pipeline == nil {
+		return
+	}
+
+	manager.idle = idle
+
+	drop := 0
+	if idle {
+		drop = 1
+	}
+
+	// only expression-driven video pipelines contain the valve
+	if !manager.pipeline.SetPropInt("idle", "drop", drop) {
+		manager.logger.Debug().Bool("idle", idle).Msg("unable to pause pipeline")
+	}
+}
+
+// pipelineSrc returns pipeline description along with its encoder backend,
+// hardware encoder session is acquired, if the pipeline is hardware encoded.
+func (manager *StreamSinkManagerCtx) pipelineSrc() (string, types.EncoderBackend, error) {
+	if manager.hwSessions == nil {
+		pipelineStr, err := manager.pipelineFn()
+		return pipelineStr, types.EncoderBackendSoftware, err
+	}
+
+	if manager.hwSessions.acquire() {
+		pipelineStr, err := manager.pipelineFn()
+		if err != nil {
+			manager.hwSessions.release()
+		}
+		return pipelineStr, types.EncoderBackendHardware, err
+	}
+
+	if manager.fallbackFn == nil {
+		return "", "", types.ErrCaptureHwEncoderExhausted
+	}
+
+	manager.logger.Warn().Msg("hardware encoder sessions exhausted, falling back to software encoding")
+	manager.encoderFallbacks.Inc()
+
+	pipelineStr, err := manager.fallbackFn()
+	return pipelineStr, types.EncoderBackendSoftware, err
+}
+
 func (manager *StreamSinkManagerCtx) saveSampleBitrate(timestamp time.Time, delta float64) {
 	// get unix timestamp in seconds
 	sec := timestamp.Unix()
@@ -394,6 +766,24 @@ func (manager *StreamSinkManagerCtx) onSample(sample types.Sample) {
 	}
 }
 
+// Level returns audio level measured by level element of the pipeline,
+// level is present only if it is enabled in the pipeline.
+func (manager *StreamSinkManagerCtx) Level() (float64, bool) {
+	manager.pipelineMu.Lock()
+	defer manager.pipelineMu.Unlock()
+
+	if manager.pipeline == nil {
+		return 0, false
+	}
+
+	level, at := manager.pipeline.Level()
+	if time.Since(at) > levelStaleAfter {
+		return 0, false
+	}
+
+	return level, true
+}
+
 func (manager *StreamSinkManagerCtx) DestroyPipeline() {
 	manager.pipelineMu.Lock()
 	defer manager.pipelineMu.Unlock()
@@ -406,6 +796,11 @@ func (manager *StreamSinkManagerCtx) DestroyPipeline() {
 	manager.logger.Info().Msgf("destroying pipeline")
 	manager.pipeline = nil
 
+	if manager.encoder == types.EncoderBackendHardware {
+		manager.hwSessions.release()
+	}
+	manager.encoder = ""
+
 	manager.pipelinesActive.Set(0)
 
 	manager.brBuckets = make(map[int]float64)
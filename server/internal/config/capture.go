@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strings"
+	"time"
 
 	"github.com/pion/webrtc/v3"
 	"github.com/rs/zerolog/log"
@@ -26,15 +27,33 @@ const (
 )
 
 type Capture struct {
-	Display string
+	Display  string
+	Displays []string
 
 	VideoCodec     codec.RTPCodec
 	VideoIDs       []string
 	VideoPipelines map[string]types.VideoConfig
+	VideoPoster    string
+	// maximum concurrently running hardware encoding pipelines, 0 is unlimited
+	VideoHwSessions int
+	// grab only changed screen regions, when X server reports them
+	VideoDamage bool
+	// encoding is paused after the screen was static for this duration, 0 disables it
+	VideoIdleDelay time.Duration
+	// framerate is lowered down to this minimum while cpu usage is above target, 0 disables it
+	VideoAdaptiveFpsMin       float64
+	VideoAdaptiveFpsTargetCPU float64
+	VideoAdaptiveFpsInterval  time.Duration
 
 	AudioDevice   string
 	AudioCodec    codec.RTPCodec
 	AudioPipeline string
+	// capture format, 0 uses codec defaults
+	AudioSampleRate int
+	AudioChannels   int
+	// audio level is measured by the pipeline only when it is sent to clients, 0 disables it,
+	// it is not a capture option, but it is passed from webrtc configuration
+	AudioLevelInterval time.Duration
 
 	BroadcastAudioBitrate int
 	BroadcastVideoBitrate int
@@ -55,9 +74,17 @@ type Capture struct {
 
 	MicrophoneEnabled bool
 	MicrophoneDevice  string
+
+	// how long are pipelines kept running after all sessions disconnected
+	IdleTimeout time.Duration
 }
 
 func (Capture) Init(cmd *cobra.Command) error {
+	cmd.PersistentFlags().Duration("capture.idle_timeout", 0, "keep configured pipelines running for this duration after their last listener leaves, so that quick reconnects do not recreate them; 0 stops pipelines immediately with their last listener")
+	if err := viper.BindPFlag("capture.idle_timeout", cmd.PersistentFlags().Lookup("capture.idle_timeout")); err != nil {
+		return err
+	}
+
 	// audio
 	cmd.PersistentFlags().String("capture.audio.device", "audio_output.monitor", "pulseaudio device to capture")
 	if err := viper.BindPFlag("capture.audio.device", cmd.PersistentFlags().Lookup("capture.audio.device")); err != nil {
@@ -74,12 +101,27 @@ func (Capture) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Int("capture.audio.sample_rate", 0, "audio capture sample rate, 0 uses codec default")
+	if err := viper.BindPFlag("capture.audio.sample_rate", cmd.PersistentFlags().Lookup("capture.audio.sample_rate")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("capture.audio.channels", 0, "audio capture channel count, 0 uses codec default")
+	if err := viper.BindPFlag("capture.audio.channels", cmd.PersistentFlags().Lookup("capture.audio.channels")); err != nil {
+		return err
+	}
+
 	// videos
 	cmd.PersistentFlags().String("capture.video.display", "", "X display to capture")
 	if err := viper.BindPFlag("capture.video.display", cmd.PersistentFlags().Lookup("capture.video.display")); err != nil {
 		return err
 	}
 
+	cmd.PersistentFlags().StringSlice("capture.video.displays", []string{}, "list of X displays or screens that can be selected for capture, input is sent to the selected one; defaults to the capture display")
+	if err := viper.BindPFlag("capture.video.displays", cmd.PersistentFlags().Lookup("capture.video.displays")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().String("capture.video.codec", "vp8", "video codec to be used")
 	if err := viper.BindPFlag("capture.video.codec", cmd.PersistentFlags().Lookup("capture.video.codec")); err != nil {
 		return err
@@ -100,6 +142,41 @@ func (Capture) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().String("capture.video.poster", "", "image file or hex color (e.g. #000000) sent as the first frame before live video starts")
+	if err := viper.BindPFlag("capture.video.poster", cmd.PersistentFlags().Lookup("capture.video.poster")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("capture.video.hw_sessions", 0, "maximum number of concurrently running hardware encoding pipelines, pipelines with a fallback use software encoding beyond this limit; 0 is unlimited")
+	if err := viper.BindPFlag("capture.video.hw_sessions", cmd.PersistentFlags().Lookup("capture.video.hw_sessions")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("capture.video.damage", false, "grab only changed screen regions and let encoders skip unchanged blocks, falls back to full frames when X server does not report damage")
+	if err := viper.BindPFlag("capture.video.damage", cmd.PersistentFlags().Lookup("capture.video.damage")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("capture.video.idle_delay", 0, "pause encoding after the screen and the cursor were static for this duration, and resume as soon as they change; 0 disables it")
+	if err := viper.BindPFlag("capture.video.idle_delay", cmd.PersistentFlags().Lookup("capture.video.idle_delay")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Float64("capture.video.adaptive_fps.min", 0, "minimum framerate, video framerate is lowered down to it while cpu usage is above target; 0 disables adaptive framerate")
+	if err := viper.BindPFlag("capture.video.adaptive_fps.min", cmd.PersistentFlags().Lookup("capture.video.adaptive_fps.min")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Float64("capture.video.adaptive_fps.target_cpu", 0.85, "target cpu usage between 0 and 1, framerate is lowered above it and restored when usage drops well below it")
+	if err := viper.BindPFlag("capture.video.adaptive_fps.target_cpu", cmd.PersistentFlags().Lookup("capture.video.adaptive_fps.target_cpu")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("capture.video.adaptive_fps.interval", 2*time.Second, "how often is cpu usage checked and framerate adjusted")
+	if err := viper.BindPFlag("capture.video.adaptive_fps.interval", cmd.PersistentFlags().Lookup("capture.video.adaptive_fps.interval")); err != nil {
+		return err
+	}
+
 	// broadcast
 	cmd.PersistentFlags().Int("capture.broadcast.audio_bitrate", 128, "broadcast audio bitrate in KB/s")
 	if err := viper.BindPFlag("capture.broadcast.audio_bitrate", cmd.PersistentFlags().Lookup("capture.broadcast.audio_bitrate")); err != nil {
@@ -326,6 +403,12 @@ func (s *Capture) Set() {
 		s.Display = os.Getenv("DISPLAY")
 	}
 
+	// capture display must always be selectable
+	s.Displays = viper.GetStringSlice("capture.video.displays")
+	if in, _ := utils.ArrayIn(s.Display, s.Displays); !in {
+		s.Displays = append([]string{s.Display}, s.Displays...)
+	}
+
 	// video
 	videoCodec := viper.GetString("capture.video.codec")
 	s.VideoCodec, ok = codec.ParseStr(videoCodec)
@@ -400,6 +483,22 @@ func (s *Capture) Set() {
 		log.Warn().Msg("you are setting both single video pipeline and multiple video pipelines, ignoring single video pipeline")
 	}
 
+	s.VideoPoster = viper.GetString("capture.video.poster")
+	s.VideoHwSessions = viper.GetInt("capture.video.hw_sessions")
+	s.VideoDamage = viper.GetBool("capture.video.damage")
+	s.VideoIdleDelay = viper.GetDuration("capture.video.idle_delay")
+	s.VideoAdaptiveFpsMin = viper.GetFloat64("capture.video.adaptive_fps.min")
+	s.VideoAdaptiveFpsTargetCPU = viper.GetFloat64("capture.video.adaptive_fps.target_cpu")
+	s.VideoAdaptiveFpsInterval = viper.GetDuration("capture.video.adaptive_fps.interval")
+	if s.VideoAdaptiveFpsMin > 0 && (s.VideoAdaptiveFpsTargetCPU <= 0 || s.VideoAdaptiveFpsTargetCPU > 1 || s.VideoAdaptiveFpsInterval <= 0) {
+		log.Warn().
+			Float64("target_cpu", s.VideoAdaptiveFpsTargetCPU).
+			Dur("interval", s.VideoAdaptiveFpsInterval).
+			Msg("invalid adaptive framerate configuration, disabling it")
+		s.VideoAdaptiveFpsMin = 0
+	}
+	s.IdleTimeout = viper.GetDuration("capture.idle_timeout")
+
 	// audio
 	s.AudioDevice = viper.GetString("capture.audio.device")
 	s.AudioPipeline = viper.GetString("capture.audio.pipeline")
@@ -411,6 +510,19 @@ func (s *Capture) Set() {
 		s.AudioCodec = codec.Opus()
 	}
 
+	s.AudioSampleRate = viper.GetInt("capture.audio.sample_rate")
+	s.AudioChannels = viper.GetInt("capture.audio.channels")
+
+	// validate format now, instead of failing when peers connect
+	audioFormat, err := s.AudioCodec.AudioFormat(s.AudioSampleRate, s.AudioChannels)
+	if err != nil {
+		log.Panic().Err(err).
+			Int("sample_rate", s.AudioSampleRate).
+			Int("channels", s.AudioChannels).
+			Msg("unsupported audio format")
+	}
+	s.AudioCodec = audioFormat
+
 	// broadcast
 	s.BroadcastAudioBitrate = viper.GetInt("capture.broadcast.audio_bitrate")
 	s.BroadcastVideoBitrate = viper.GetInt("capture.broadcast.video_bitrate")
@@ -447,6 +559,7 @@ func (s *Capture) SetV2() {
 
 	if display := viper.GetString("display"); display != "" {
 		s.Display = display
+		s.Displays = []string{display}
 		log.Warn().Msg("you are using v2 configuration 'NEKO_DISPLAY' which is deprecated, please use 'NEKO_CAPTURE_VIDEO_DISPLAY' and/or 'NEKO_DESKTOP_DISPLAY' instead, also consider using 'DISPLAY' env variable if both should be the same")
 		enableLegacy = true
 	}
@@ -509,13 +622,35 @@ func (s *Capture) SetV2() {
 		if err != nil {
 			log.Warn().Err(err).Msg("unable to create video pipeline, using default")
 		} else {
+			hwEncoder := videoHWEnc == HwEncVAAPI || videoHWEnc == HwEncNVENC
+
+			// software encoded pipeline is used, when hardware sessions are exhausted
+			var mainFallback, legacyFallback *types.VideoConfig
+			if hwEncoder && videoPipeline == "" {
+				fallback, err := NewVideoPipeline(s.VideoCodec, s.Display, "", videoMaxFPS, videoBitrate, HwEncNone)
+				if err != nil {
+					log.Warn().Err(err).Msg("unable to create software fallback video pipeline")
+				} else {
+					mainFallback = &types.VideoConfig{
+						GstPipeline: strings.Replace(fallback, "show-pointer=true", "show-pointer=false", 1),
+					}
+					legacyFallback = &types.VideoConfig{
+						GstPipeline: fallback,
+					}
+				}
+			}
+
 			s.VideoPipelines = map[string]types.VideoConfig{
 				"main": {
 					// Hacky way to disable pointer.
 					GstPipeline: strings.Replace(pipeline, "show-pointer=true", "show-pointer=false", 1),
+					HwEncoder:   hwEncoder,
+					Fallback:    mainFallback,
 				},
 				"legacy": {
 					GstPipeline: pipeline,
+					HwEncoder:   hwEncoder,
+					Fallback:    legacyFallback,
 				},
 			}
 			// we do not add legacy to VideoIDs so that its ignored by bandwidth estimator
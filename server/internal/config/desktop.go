@@ -20,9 +20,15 @@ type Desktop struct {
 	UseInputDriver bool
 	InputSocket    string
 
+	GamepadEnabled bool
+	GamepadDevice  string
+
 	Unminimize        bool
 	UploadDrop        bool
 	FileChooserDialog bool
+
+	ClipboardMaxSize  int
+	ClipboardTruncate bool
 }
 
 func (Desktop) Init(cmd *cobra.Command) error {
@@ -46,6 +52,16 @@ func (Desktop) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Bool("desktop.gamepad.enabled", false, "whether virtual gamepads should be created using uinput")
+	if err := viper.BindPFlag("desktop.gamepad.enabled", cmd.PersistentFlags().Lookup("desktop.gamepad.enabled")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("desktop.gamepad.device", "/dev/uinput", "uinput device path used to create virtual gamepads")
+	if err := viper.BindPFlag("desktop.gamepad.device", cmd.PersistentFlags().Lookup("desktop.gamepad.device")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().Bool("desktop.unminimize", true, "automatically unminimize window when it is minimized")
 	if err := viper.BindPFlag("desktop.unminimize", cmd.PersistentFlags().Lookup("desktop.unminimize")); err != nil {
 		return err
@@ -61,6 +77,16 @@ func (Desktop) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Int("desktop.clipboard.max_size", 1<<20, "maximum size of clipboard content in bytes, 0 for unlimited")
+	if err := viper.BindPFlag("desktop.clipboard.max_size", cmd.PersistentFlags().Lookup("desktop.clipboard.max_size")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("desktop.clipboard.truncate", false, "truncate oversized clipboard text instead of rejecting it")
+	if err := viper.BindPFlag("desktop.clipboard.truncate", cmd.PersistentFlags().Lookup("desktop.clipboard.truncate")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -104,9 +130,13 @@ func (s *Desktop) Set() {
 
 	s.UseInputDriver = viper.GetBool("desktop.input.enabled")
 	s.InputSocket = viper.GetString("desktop.input.socket")
+	s.GamepadEnabled = viper.GetBool("desktop.gamepad.enabled")
+	s.GamepadDevice = viper.GetString("desktop.gamepad.device")
 	s.Unminimize = viper.GetBool("desktop.unminimize")
 	s.UploadDrop = viper.GetBool("desktop.upload_drop")
 	s.FileChooserDialog = viper.GetBool("desktop.file_chooser_dialog")
+	s.ClipboardMaxSize = viper.GetInt("desktop.clipboard.max_size")
+	s.ClipboardTruncate = viper.GetBool("desktop.clipboard.truncate")
 }
 
 func (s *Desktop) SetV2() {
@@ -16,6 +16,7 @@ type Root struct {
 	Legacy bool
 
 	LogLevel   zerolog.Level
+	LogLevels  map[string]zerolog.Level
 	LogTime    string
 	LogJson    bool
 	LogNocolor bool
@@ -47,6 +48,11 @@ func (Root) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().StringToString("log.levels", map[string]string{}, "override log level per module or submodule, e.g. webrtc=debug,capture=warn")
+	if err := viper.BindPFlag("log.levels", cmd.PersistentFlags().Lookup("log.levels")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().String("log.time", "unix", "time format used in logs (unix, unixms, unixmicro)")
 	if err := viper.BindPFlag("log.time", cmd.PersistentFlags().Lookup("log.time")); err != nil {
 		return err
@@ -94,6 +100,16 @@ func (s *Root) Set() {
 		s.LogLevel = level
 	}
 
+	s.LogLevels = map[string]zerolog.Level{}
+	for module, logLevel := range viper.GetStringMapString("log.levels") {
+		level, err := zerolog.ParseLevel(logLevel)
+		if err != nil {
+			log.Warn().Str("module", module).Msgf("unknown log level %s", logLevel)
+			continue
+		}
+		s.LogLevels[module] = level
+	}
+
 	logTime := viper.GetString("log.time")
 	switch logTime {
 	case "unix":
@@ -6,6 +6,8 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	"github.com/m1k1o/neko/server/pkg/types"
 )
 
 type SessionCookie struct {
@@ -18,6 +20,17 @@ type SessionCookie struct {
 	Path       string
 }
 
+type SessionAudit struct {
+	Size int
+	File string
+}
+
+type SessionWebhook struct {
+	URL     string
+	Timeout time.Duration
+	Retries int
+}
+
 type Session struct {
 	File string
 
@@ -26,12 +39,20 @@ type Session struct {
 	LockedControls    bool
 	ControlProtection bool
 	ImplicitHosting   bool
+	ControlPolicy     types.ControlPolicy
 	InactiveCursors   bool
 	MercifulReconnect bool
 	HeartbeatInterval int
+	ResumeGrace       time.Duration
 	APIToken          string
-
-	Cookie SessionCookie
+	// websocket messages larger than this are rejected, 0 is unlimited
+	MaxMessageSize int
+	// sender of oversize message is disconnected, instead of only rejecting it
+	OversizeDisconnect bool
+
+	Cookie  SessionCookie
+	Webhook SessionWebhook
+	Audit   SessionAudit
 }
 
 func (Session) Init(cmd *cobra.Command) error {
@@ -65,6 +86,11 @@ func (Session) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().String("session.control_policy", "", "what happens when control is requested while someone else is the host: locked, steal or queue, derived from implicit hosting if empty")
+	if err := viper.BindPFlag("session.control_policy", cmd.PersistentFlags().Lookup("session.control_policy")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().Bool("session.inactive_cursors", false, "show inactive cursors on the screen")
 	if err := viper.BindPFlag("session.inactive_cursors", cmd.PersistentFlags().Lookup("session.inactive_cursors")); err != nil {
 		return err
@@ -80,6 +106,21 @@ func (Session) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Int("session.max_message_size", 8<<20, "maximum size of a websocket message in bytes, larger messages are rejected without being buffered; 0 for unlimited")
+	if err := viper.BindPFlag("session.max_message_size", cmd.PersistentFlags().Lookup("session.max_message_size")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("session.oversize_disconnect", false, "disconnect clients sending websocket messages larger than maximum size, instead of only rejecting the messages")
+	if err := viper.BindPFlag("session.oversize_disconnect", cmd.PersistentFlags().Lookup("session.oversize_disconnect")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("session.resume_grace", 0, "how long is disconnected webrtc peer kept alive, so that reconnecting client can resume it using a resume token, 0 disables resuming")
+	if err := viper.BindPFlag("session.resume_grace", cmd.PersistentFlags().Lookup("session.resume_grace")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().String("session.api_token", "", "API token for interacting with external services")
 	if err := viper.BindPFlag("session.api_token", cmd.PersistentFlags().Lookup("session.api_token")); err != nil {
 		return err
@@ -121,6 +162,33 @@ func (Session) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	// webhook
+	cmd.PersistentFlags().String("session.webhook.url", "", "URL that receives POST request when a session connects or disconnects")
+	if err := viper.BindPFlag("session.webhook.url", cmd.PersistentFlags().Lookup("session.webhook.url")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("session.webhook.timeout", 5*time.Second, "timeout of a single webhook request")
+	if err := viper.BindPFlag("session.webhook.timeout", cmd.PersistentFlags().Lookup("session.webhook.timeout")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("session.webhook.retries", 3, "how many times a failed webhook request should be retried")
+	if err := viper.BindPFlag("session.webhook.retries", cmd.PersistentFlags().Lookup("session.webhook.retries")); err != nil {
+		return err
+	}
+
+	// audit
+	cmd.PersistentFlags().Int("session.audit.size", 100, "how many audit entries are kept in memory per session, 0 disables audit log")
+	if err := viper.BindPFlag("session.audit.size", cmd.PersistentFlags().Lookup("session.audit.size")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("session.audit.file", "", "if audit entries should be appended to a file as JSON lines")
+	if err := viper.BindPFlag("session.audit.file", cmd.PersistentFlags().Lookup("session.audit.file")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -156,9 +224,17 @@ func (s *Session) Set() {
 	s.LockedControls = viper.GetBool("session.locked_controls")
 	s.ControlProtection = viper.GetBool("session.control_protection")
 	s.ImplicitHosting = viper.GetBool("session.implicit_hosting")
+	s.ControlPolicy = types.ControlPolicy(viper.GetString("session.control_policy"))
+	if !s.ControlPolicy.Valid() {
+		log.Warn().Str("control_policy", string(s.ControlPolicy)).Msg("unknown control policy, deriving it from implicit hosting")
+		s.ControlPolicy = ""
+	}
 	s.InactiveCursors = viper.GetBool("session.inactive_cursors")
 	s.MercifulReconnect = viper.GetBool("session.merciful_reconnect")
 	s.HeartbeatInterval = viper.GetInt("session.heartbeat_interval")
+	s.ResumeGrace = viper.GetDuration("session.resume_grace")
+	s.MaxMessageSize = viper.GetInt("session.max_message_size")
+	s.OversizeDisconnect = viper.GetBool("session.oversize_disconnect")
 	s.APIToken = viper.GetString("session.api_token")
 
 	s.Cookie.Enabled = viper.GetBool("session.cookie.enabled")
@@ -168,6 +244,13 @@ func (s *Session) Set() {
 	s.Cookie.HTTPOnly = viper.GetBool("session.cookie.http_only")
 	s.Cookie.Domain = viper.GetString("session.cookie.domain")
 	s.Cookie.Path = viper.GetString("session.cookie.path")
+
+	s.Webhook.URL = viper.GetString("session.webhook.url")
+	s.Webhook.Timeout = viper.GetDuration("session.webhook.timeout")
+	s.Webhook.Retries = viper.GetInt("session.webhook.retries")
+
+	s.Audit.Size = viper.GetInt("session.audit.size")
+	s.Audit.File = viper.GetString("session.audit.file")
 }
 
 func (s *Session) SetV2() {
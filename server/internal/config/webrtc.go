@@ -2,6 +2,8 @@ package config
 
 import (
 	"encoding/json"
+	"maps"
+	"net"
 	"strconv"
 	"strings"
 	"time"
@@ -11,6 +13,7 @@ import (
 	"github.com/spf13/viper"
 
 	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/codec"
 	"github.com/m1k1o/neko/server/pkg/utils"
 )
 
@@ -24,6 +27,8 @@ type WebRTCEstimator struct {
 	InitialBitrate int
 
 	// how often to read and process bandwidth estimation reports
+	// TODO: estimator stats are not pushed to clients yet, once they are, add
+	// a separate client stats interval, so that they are not sent on every read
 	ReadInterval time.Duration
 	// how long to wait for stable connection (only neutral or upward trend) before upgrading
 	StableDuration time.Duration
@@ -37,6 +42,82 @@ type WebRTCEstimator struct {
 	UpgradeBackoff time.Duration
 	// how bigger the difference between estimated and stream bitrate must be to trigger upgrade/downgrade
 	DiffThreshold float64
+	// send padding to discover available bandwidth before upgrading
+	Probing bool
+	// maximum probing bitrate, as a fraction of current stream bitrate
+	ProbeOverhead float64
+	// global video bitrate budget shared by all peers, 0 means unlimited
+	Budget int
+	// how many recent estimator decisions are kept per peer, 0 disables history
+	History int
+	// stream bitrate is multiplied by factor of its codec before it is compared with the estimate,
+	// factor above 1 requires more headroom, below 1 less
+	CodecFactors map[string]float64
+}
+
+// default bitrate factors of codecs, vp9 and av1 need fewer bits than h264 or vp8
+// for the same quality, so their streams are upgraded with less headroom
+var defaultCodecFactors = map[string]string{
+	"vp9": "0.8",
+	"av1": "0.7",
+}
+
+// CodecFactor returns bitrate factor of the codec, 1 if it is not configured
+func (e WebRTCEstimator) CodecFactor(name string) float64 {
+	if factor, ok := e.CodecFactors[name]; ok {
+		return factor
+	}
+	return 1
+}
+
+// thresholds of connection quality levels, connection must satisfy
+// both round trip time and fraction lost to reach the level
+type WebRTCQuality struct {
+	// how often is quality evaluated, 0 disables quality events
+	Interval time.Duration
+
+	ExcellentRTT  time.Duration
+	ExcellentLoss float64
+	GoodRTT       time.Duration
+	GoodLoss      float64
+	FairRTT       time.Duration
+	FairLoss      float64
+}
+
+type WebRTCStandby struct {
+	// maximum number of standby peers, 0 disables
+	Max int
+	// unused standby peer is replaced after this duration
+	TTL time.Duration
+}
+
+type WebRTCQuotaPeriod string
+
+const (
+	// usage is counted for the whole lifetime of the session
+	WebRTCQuotaPeriodSession WebRTCQuotaPeriod = "session"
+	// usage is reset at the start of every calendar month
+	WebRTCQuotaPeriodMonthly WebRTCQuotaPeriod = "monthly"
+)
+
+type WebRTCQuotaAction string
+
+const (
+	WebRTCQuotaActionDisconnect WebRTCQuotaAction = "disconnect"
+	WebRTCQuotaActionAudioOnly  WebRTCQuotaAction = "audio_only"
+)
+
+type WebRTCQuota struct {
+	// bytes sent and received by a session within a period, 0 disables the quota
+	Bytes  uint64
+	Period WebRTCQuotaPeriod
+	Action WebRTCQuotaAction
+}
+
+// client network mapped to region of ICE servers
+type WebRTCICERegion struct {
+	Network *net.IPNet
+	Region  string
 }
 
 type WebRTC struct {
@@ -44,15 +125,46 @@ type WebRTC struct {
 	ICETrickle         bool
 	ICEServersFrontend []types.ICEServer
 	ICEServersBackend  []types.ICEServer
+	ICERegions         []WebRTCICERegion
 	EphemeralMin       uint16
 	EphemeralMax       uint16
 	TCPMux             int
 	UDPMux             int
+	// network interfaces and local IPs used to gather candidates, all if empty
+	Interfaces []string
+	BindIPs    []net.IP
 
 	NAT1To1IPs     []string
 	IpRetrievalUrl string
 
+	DTLSCertificate string
+	DSCP            int
+	DataEncryption  bool
+	CursorMaxSize   int
+	StatsMetrics    bool
+	// bulk data channel messages yield to urgent ones, instead of strict ordering
+	DataPriority bool
+	// resend last frames when video source is static, 0 disables
+	VideoKeepAlive time.Duration
+	// send lossless frame after screen was static for this duration, 0 disables
+	StillFrameDelay time.Duration
+	// negotiate reduced-size rtcp
+	RTCPReducedSize bool
+	// replace peers periodically to rotate SRTP keys, 0 disables
+	RekeyInterval time.Duration
+	// audio track is not added to peers at all
+	DisableAudio bool
+	// send audio level to clients, 0 disables
+	AudioLevelInterval time.Duration
+	// built-in transforms of local descriptions sent to clients
+	SDPTransforms []string
+	// restart ICE when the selected candidate pair changes or fails
+	ICEAutoRestart bool
+
 	Estimator WebRTCEstimator
+	Quality   WebRTCQuality
+	Standby   WebRTCStandby
+	Quota     WebRTCQuota
 }
 
 func (WebRTC) Init(cmd *cobra.Command) error {
@@ -82,12 +194,17 @@ func (WebRTC) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().StringToString("webrtc.iceservers.regions", map[string]string{}, "map client networks to region of frontend ICE servers, e.g. 10.0.0.0/8=eu; servers of the matching region are advertised first")
+	if err := viper.BindPFlag("webrtc.iceservers.regions", cmd.PersistentFlags().Lookup("webrtc.iceservers.regions")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().String("webrtc.epr", "", "limits the pool of ephemeral ports that ICE UDP connections can allocate from")
 	if err := viper.BindPFlag("webrtc.epr", cmd.PersistentFlags().Lookup("webrtc.epr")); err != nil {
 		return err
 	}
 
-	cmd.PersistentFlags().Int("webrtc.tcpmux", 0, "single TCP mux port for all peers")
+	cmd.PersistentFlags().Int("webrtc.tcpmux", 0, "single TCP mux port for all peers, enables ICE-TCP candidates for clients that cannot use UDP")
 	if err := viper.BindPFlag("webrtc.tcpmux", cmd.PersistentFlags().Lookup("webrtc.tcpmux")); err != nil {
 		return err
 	}
@@ -97,6 +214,16 @@ func (WebRTC) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().StringSlice("webrtc.interfaces", []string{}, "network interfaces used to gather ICE candidates, all interfaces are used if empty")
+	if err := viper.BindPFlag("webrtc.interfaces", cmd.PersistentFlags().Lookup("webrtc.interfaces")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("webrtc.bind_ips", []string{}, "local IP addresses used to gather ICE candidates, all addresses are used if empty")
+	if err := viper.BindPFlag("webrtc.bind_ips", cmd.PersistentFlags().Lookup("webrtc.bind_ips")); err != nil {
+		return err
+	}
+
 	cmd.PersistentFlags().StringSlice("webrtc.nat1to1", []string{}, "sets a list of external IP addresses of 1:1 (D)NAT and a candidate type for which the external IP address is used")
 	if err := viper.BindPFlag("webrtc.nat1to1", cmd.PersistentFlags().Lookup("webrtc.nat1to1")); err != nil {
 		return err
@@ -107,6 +234,76 @@ func (WebRTC) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().String("webrtc.dtls_certificate", "", "path to PEM file with DTLS certificate and private key, if empty a new certificate is generated at startup")
+	if err := viper.BindPFlag("webrtc.dtls_certificate", cmd.PersistentFlags().Lookup("webrtc.dtls_certificate")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.dscp", 0, "DSCP value (0-63) used to mark outgoing UDP media packets, e.g. 46 for EF, 0 disables marking")
+	if err := viper.BindPFlag("webrtc.dscp", cmd.PersistentFlags().Lookup("webrtc.dscp")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("webrtc.data_priority", false, "let input related data channel messages, e.g. pong and cursor position, overtake queued cursor images, still frames and clipboard")
+	if err := viper.BindPFlag("webrtc.data_priority", cmd.PersistentFlags().Lookup("webrtc.data_priority")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("webrtc.data_encryption", false, "encrypt data channel messages with per-peer key exchanged over signaling, in addition to DTLS")
+	if err := viper.BindPFlag("webrtc.data_encryption", cmd.PersistentFlags().Lookup("webrtc.data_encryption")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("webrtc.stats_metrics", false, "periodically export rtp stream stats of every peer as prometheus metrics")
+	if err := viper.BindPFlag("webrtc.stats_metrics", cmd.PersistentFlags().Lookup("webrtc.stats_metrics")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.cursor_max_size", 0, "maximum width or height of cursor image sent to clients, bigger cursors are downscaled, 0 means no limit")
+	if err := viper.BindPFlag("webrtc.cursor_max_size", cmd.PersistentFlags().Lookup("webrtc.cursor_max_size")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("webrtc.rtcp_rsize", true, "negotiate reduced-size RTCP (RFC 5506) for media sections, to lower RTCP overhead")
+	if err := viper.BindPFlag("webrtc.rtcp_rsize", cmd.PersistentFlags().Lookup("webrtc.rtcp_rsize")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringSlice("webrtc.sdp_transforms", []string{}, "built-in transforms applied to SDP sent to clients, in order: strip:<attribute> removes attribute, prefer:<codec> moves codec to the front of media formats")
+	if err := viper.BindPFlag("webrtc.sdp_transforms", cmd.PersistentFlags().Lookup("webrtc.sdp_transforms")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("webrtc.disable_audio", false, "do not negotiate audio with any peer, audio pipeline is never started")
+	if err := viper.BindPFlag("webrtc.disable_audio", cmd.PersistentFlags().Lookup("webrtc.disable_audio")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.audio_level_interval", 0, "send audio level to clients at most this often and add audio level header extension to audio packets; 0 disables")
+	if err := viper.BindPFlag("webrtc.audio_level_interval", cmd.PersistentFlags().Lookup("webrtc.audio_level_interval")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.rekey_interval", 0, "replace peer connections periodically to rotate SRTP keys, causes short video interruption; 0 disables")
+	if err := viper.BindPFlag("webrtc.rekey_interval", cmd.PersistentFlags().Lookup("webrtc.rekey_interval")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Bool("webrtc.ice_auto_restart", false, "restart ICE automatically when the selected candidate pair changes or fails, e.g. when clients switch networks")
+	if err := viper.BindPFlag("webrtc.ice_auto_restart", cmd.PersistentFlags().Lookup("webrtc.ice_auto_restart")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.video_keepalive", 0, "resend last video frames to the peer, when no new frame was produced for this duration, to keep decoders from stalling on static content; 0 disables")
+	if err := viper.BindPFlag("webrtc.video_keepalive", cmd.PersistentFlags().Lookup("webrtc.video_keepalive")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.still_frame_delay", 0, "send a lossless PNG frame over the data channel to peers that requested it, when the screen was static for this duration; 0 disables")
+	if err := viper.BindPFlag("webrtc.still_frame_delay", cmd.PersistentFlags().Lookup("webrtc.still_frame_delay")); err != nil {
+		return err
+	}
+
 	// bandwidth estimator
 
 	cmd.PersistentFlags().Bool("webrtc.estimator.enabled", false, "enables the bandwidth estimator")
@@ -164,6 +361,95 @@ func (WebRTC) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Bool("webrtc.estimator.probing", false, "send padding packets to discover available bandwidth faster before upgrading")
+	if err := viper.BindPFlag("webrtc.estimator.probing", cmd.PersistentFlags().Lookup("webrtc.estimator.probing")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Float64("webrtc.estimator.probe_overhead", 0.25, "maximum probing bitrate as a fraction of current stream bitrate")
+	if err := viper.BindPFlag("webrtc.estimator.probe_overhead", cmd.PersistentFlags().Lookup("webrtc.estimator.probe_overhead")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.estimator.budget", 0, "global video bitrate budget in bps shared by all peers, higher priority sessions get higher streams first, 0 means unlimited")
+	if err := viper.BindPFlag("webrtc.estimator.budget", cmd.PersistentFlags().Lookup("webrtc.estimator.budget")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("webrtc.estimator.history", 50, "how many recent estimator decisions are kept per peer for tuning, 0 disables history")
+	if err := viper.BindPFlag("webrtc.estimator.history", cmd.PersistentFlags().Lookup("webrtc.estimator.history")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().StringToString("webrtc.estimator.codec_factors", defaultCodecFactors, "bitrate factor of each video codec, stream bitrate is multiplied by it before it is compared with the estimate, above 1 requires more headroom; unlisted codecs use their default or 1")
+	if err := viper.BindPFlag("webrtc.estimator.codec_factors", cmd.PersistentFlags().Lookup("webrtc.estimator.codec_factors")); err != nil {
+		return err
+	}
+
+	// connection quality
+
+	// bandwidth quota
+	cmd.PersistentFlags().Uint64("webrtc.quota.bytes", 0, "bytes sent and received by a session within the quota period; 0 disables the quota")
+	if err := viper.BindPFlag("webrtc.quota.bytes", cmd.PersistentFlags().Lookup("webrtc.quota.bytes")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("webrtc.quota.period", string(WebRTCQuotaPeriodSession), "quota period: 'session' counts the whole lifetime of the session, 'monthly' resets usage every calendar month")
+	if err := viper.BindPFlag("webrtc.quota.period", cmd.PersistentFlags().Lookup("webrtc.quota.period")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().String("webrtc.quota.action", string(WebRTCQuotaActionDisconnect), "what happens when the quota is exceeded: 'disconnect' or 'audio_only'")
+	if err := viper.BindPFlag("webrtc.quota.action", cmd.PersistentFlags().Lookup("webrtc.quota.action")); err != nil {
+		return err
+	}
+
+	// warm standby
+	cmd.PersistentFlags().Int("webrtc.standby.max", 0, "maximum number of pre-negotiated standby peers, kept for members with warm_standby profile; 0 disables")
+	if err := viper.BindPFlag("webrtc.standby.max", cmd.PersistentFlags().Lookup("webrtc.standby.max")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.standby.ttl", 5*time.Minute, "unused standby peer is replaced after this duration")
+	if err := viper.BindPFlag("webrtc.standby.ttl", cmd.PersistentFlags().Lookup("webrtc.standby.ttl")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.quality.interval", 5*time.Second, "how often is connection quality evaluated, clients are notified only when it changes; 0 disables")
+	if err := viper.BindPFlag("webrtc.quality.interval", cmd.PersistentFlags().Lookup("webrtc.quality.interval")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.quality.excellent_rtt", 50*time.Millisecond, "maximum round trip time of excellent connection")
+	if err := viper.BindPFlag("webrtc.quality.excellent_rtt", cmd.PersistentFlags().Lookup("webrtc.quality.excellent_rtt")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Float64("webrtc.quality.excellent_loss", 0.01, "maximum fraction of lost packets of excellent connection")
+	if err := viper.BindPFlag("webrtc.quality.excellent_loss", cmd.PersistentFlags().Lookup("webrtc.quality.excellent_loss")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.quality.good_rtt", 150*time.Millisecond, "maximum round trip time of good connection")
+	if err := viper.BindPFlag("webrtc.quality.good_rtt", cmd.PersistentFlags().Lookup("webrtc.quality.good_rtt")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Float64("webrtc.quality.good_loss", 0.03, "maximum fraction of lost packets of good connection")
+	if err := viper.BindPFlag("webrtc.quality.good_loss", cmd.PersistentFlags().Lookup("webrtc.quality.good_loss")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Duration("webrtc.quality.fair_rtt", 300*time.Millisecond, "maximum round trip time of fair connection, anything worse is poor")
+	if err := viper.BindPFlag("webrtc.quality.fair_rtt", cmd.PersistentFlags().Lookup("webrtc.quality.fair_rtt")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Float64("webrtc.quality.fair_loss", 0.08, "maximum fraction of lost packets of fair connection, anything worse is poor")
+	if err := viper.BindPFlag("webrtc.quality.fair_loss", cmd.PersistentFlags().Lookup("webrtc.quality.fair_loss")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -229,6 +515,20 @@ func (s *WebRTC) Set() {
 		log.Warn().Err(err).Msgf("unable to parse backend ICE servers")
 	}
 
+	s.ICERegions = []WebRTCICERegion{}
+	for cidr, region := range viper.GetStringMapString("webrtc.iceservers.regions") {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			log.Warn().Err(err).Str("cidr", cidr).Msg("unable to parse ICE server region network")
+			continue
+		}
+
+		s.ICERegions = append(s.ICERegions, WebRTCICERegion{
+			Network: network,
+			Region:  region,
+		})
+	}
+
 	if s.ICELite && len(s.ICEServersBackend) > 0 {
 		log.Warn().Msgf("ICE Lite is enabled, but backend ICE servers are configured. Backend ICE servers will be ignored.")
 	}
@@ -291,7 +591,32 @@ func (s *WebRTC) Set() {
 			Msgf("no TCP, UDP mux or epr specified, using default epr range")
 	}
 
+	s.Interfaces = viper.GetStringSlice("webrtc.interfaces")
+	for _, name := range s.Interfaces {
+		if _, err := net.InterfaceByName(name); err != nil {
+			log.Panic().Err(err).Str("interface", name).Msgf("unknown network interface")
+		}
+	}
+
+	s.BindIPs = []net.IP{}
+	for _, addr := range viper.GetStringSlice("webrtc.bind_ips") {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			log.Panic().Str("ip", addr).Msgf("invalid bind IP address")
+		}
+		s.BindIPs = append(s.BindIPs, ip)
+	}
+
 	s.NAT1To1IPs = viper.GetStringSlice("webrtc.nat1to1")
+	for _, mapping := range s.NAT1To1IPs {
+		// either external IP, or external/local IP pair
+		for _, addr := range strings.Split(mapping, "/") {
+			if net.ParseIP(addr) == nil {
+				log.Panic().Str("nat1to1", mapping).Msgf("invalid 1:1 NAT IP address")
+			}
+		}
+	}
+
 	s.IpRetrievalUrl = viper.GetString("webrtc.ip_retrieval_url")
 	if s.IpRetrievalUrl != "" && len(s.NAT1To1IPs) == 0 {
 		ip, err := utils.HttpRequestGET(s.IpRetrievalUrl)
@@ -302,6 +627,26 @@ func (s *WebRTC) Set() {
 		}
 	}
 
+	s.DTLSCertificate = viper.GetString("webrtc.dtls_certificate")
+
+	s.DSCP = viper.GetInt("webrtc.dscp")
+	if s.DSCP < 0 || s.DSCP > 63 {
+		log.Panic().Int("dscp", s.DSCP).Msgf("DSCP value must be between 0 and 63")
+	}
+
+	s.DataEncryption = viper.GetBool("webrtc.data_encryption")
+	s.DataPriority = viper.GetBool("webrtc.data_priority")
+	s.CursorMaxSize = viper.GetInt("webrtc.cursor_max_size")
+	s.StatsMetrics = viper.GetBool("webrtc.stats_metrics")
+	s.VideoKeepAlive = viper.GetDuration("webrtc.video_keepalive")
+	s.StillFrameDelay = viper.GetDuration("webrtc.still_frame_delay")
+	s.RTCPReducedSize = viper.GetBool("webrtc.rtcp_rsize")
+	s.RekeyInterval = viper.GetDuration("webrtc.rekey_interval")
+	s.DisableAudio = viper.GetBool("webrtc.disable_audio")
+	s.AudioLevelInterval = viper.GetDuration("webrtc.audio_level_interval")
+	s.SDPTransforms = viper.GetStringSlice("webrtc.sdp_transforms")
+	s.ICEAutoRestart = viper.GetBool("webrtc.ice_auto_restart")
+
 	// bandwidth estimator
 
 	s.Estimator.Enabled = viper.GetBool("webrtc.estimator.enabled")
@@ -315,6 +660,62 @@ func (s *WebRTC) Set() {
 	s.Estimator.DowngradeBackoff = viper.GetDuration("webrtc.estimator.downgrade_backoff")
 	s.Estimator.UpgradeBackoff = viper.GetDuration("webrtc.estimator.upgrade_backoff")
 	s.Estimator.DiffThreshold = viper.GetFloat64("webrtc.estimator.diff_threshold")
+	s.Estimator.Probing = viper.GetBool("webrtc.estimator.probing")
+	s.Estimator.ProbeOverhead = viper.GetFloat64("webrtc.estimator.probe_overhead")
+	s.Estimator.Budget = viper.GetInt("webrtc.estimator.budget")
+	s.Estimator.History = viper.GetInt("webrtc.estimator.history")
+
+	// configured factors override the defaults of listed codecs only
+	codecFactors := maps.Clone(defaultCodecFactors)
+	maps.Copy(codecFactors, viper.GetStringMapString("webrtc.estimator.codec_factors"))
+
+	s.Estimator.CodecFactors = map[string]float64{}
+	for name, value := range codecFactors {
+		rtpCodec, ok := codec.ParseStr(name)
+		if !ok {
+			log.Warn().Str("codec", name).Msg("unknown codec of estimator codec factor")
+			continue
+		}
+
+		factor, err := strconv.ParseFloat(value, 64)
+		if err != nil || factor <= 0 {
+			log.Warn().Str("codec", name).Str("factor", value).Msg("invalid estimator codec factor, it must be a positive number")
+			continue
+		}
+
+		s.Estimator.CodecFactors[rtpCodec.Name] = factor
+	}
+
+	// bandwidth quota
+
+	s.Quota.Bytes = viper.GetUint64("webrtc.quota.bytes")
+
+	s.Quota.Period = WebRTCQuotaPeriod(viper.GetString("webrtc.quota.period"))
+	if s.Quota.Period != WebRTCQuotaPeriodSession && s.Quota.Period != WebRTCQuotaPeriodMonthly {
+		log.Warn().Str("period", string(s.Quota.Period)).Msg("unknown quota period, using session")
+		s.Quota.Period = WebRTCQuotaPeriodSession
+	}
+
+	s.Quota.Action = WebRTCQuotaAction(viper.GetString("webrtc.quota.action"))
+	if s.Quota.Action != WebRTCQuotaActionDisconnect && s.Quota.Action != WebRTCQuotaActionAudioOnly {
+		log.Warn().Str("action", string(s.Quota.Action)).Msg("unknown quota action, using disconnect")
+		s.Quota.Action = WebRTCQuotaActionDisconnect
+	}
+
+	// warm standby
+
+	s.Standby.Max = viper.GetInt("webrtc.standby.max")
+	s.Standby.TTL = viper.GetDuration("webrtc.standby.ttl")
+
+	// connection quality
+
+	s.Quality.Interval = viper.GetDuration("webrtc.quality.interval")
+	s.Quality.ExcellentRTT = viper.GetDuration("webrtc.quality.excellent_rtt")
+	s.Quality.ExcellentLoss = viper.GetFloat64("webrtc.quality.excellent_loss")
+	s.Quality.GoodRTT = viper.GetDuration("webrtc.quality.good_rtt")
+	s.Quality.GoodLoss = viper.GetFloat64("webrtc.quality.good_loss")
+	s.Quality.FairRTT = viper.GetDuration("webrtc.quality.fair_rtt")
+	s.Quality.FairLoss = viper.GetFloat64("webrtc.quality.fair_loss")
 }
 
 func (s *WebRTC) SetV2() {
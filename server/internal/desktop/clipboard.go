@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os/exec"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/xevent"
+	"github.com/m1k1o/neko/server/pkg/xorg"
 )
 
 const (
@@ -15,18 +17,93 @@ const (
 	ClipboardTextHtmlTarget  = "text/html"
 )
 
+// limitedBuffer keeps at most limit bytes, rest of the data is discarded
+// so that oversized clipboard content does not have to be held in memory.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.limit > 0 && b.Len()+n > b.limit {
+		b.overflow = true
+		p = p[:b.limit-b.Len()]
+	}
+
+	b.Buffer.Write(p)
+	return n, nil
+}
+
+// truncateText cuts text to at most size bytes, without splitting a rune.
+func truncateText(text string, size int) string {
+	if len(text) <= size {
+		return text
+	}
+
+	for size > 0 && !utf8.RuneStart(text[size]) {
+		size--
+	}
+
+	return text[:size]
+}
+
+// ClipboardLimit applies configured size limit to clipboard text. Oversized
+// content is either truncated or rejected with ErrClipboardTooLarge.
+func (manager *DesktopManagerCtx) ClipboardLimit(data *types.ClipboardText) error {
+	limit := manager.config.ClipboardMaxSize
+	if limit <= 0 || (len(data.Text) <= limit && len(data.HTML) <= limit) {
+		return nil
+	}
+
+	if !manager.config.ClipboardTruncate {
+		return types.ErrClipboardTooLarge
+	}
+
+	// Rich text cannot be cut without breaking its markup.
+	if len(data.HTML) > limit {
+		data.HTML = ""
+	}
+
+	data.Text = truncateText(data.Text, limit)
+	data.Truncated = true
+	return nil
+}
+
 func (manager *DesktopManagerCtx) ClipboardGetText() (*types.ClipboardText, error) {
-	text, err := manager.ClipboardGetBinary(ClipboardTextPlainTarget)
+	limit := manager.config.ClipboardMaxSize
+
+	text, overflow, err := manager.clipboardGet(ClipboardTextPlainTarget, limit)
 	if err != nil {
 		return nil, err
 	}
 
+	if overflow && !manager.config.ClipboardTruncate {
+		return nil, types.ErrClipboardTooLarge
+	}
+
 	// Rich text must not always be available, can fail silently.
-	html, _ := manager.ClipboardGetBinary(ClipboardTextHtmlTarget)
+	html, htmlOverflow, _ := manager.clipboardGet(ClipboardTextHtmlTarget, limit)
+	if htmlOverflow {
+		html = nil
+	}
+
+	value := string(text)
+	if overflow {
+		// Limited buffer could have cut the last rune in half.
+		for i := 0; i < utf8.UTFMax-1; i++ {
+			if r, size := utf8.DecodeLastRuneInString(value); r != utf8.RuneError || size != 1 {
+				break
+			}
+			value = value[:len(value)-1]
+		}
+	}
 
 	return &types.ClipboardText{
-		Text: string(text),
-		HTML: string(html),
+		Text:      value,
+		HTML:      string(html),
+		Truncated: overflow || htmlOverflow,
 	}, nil
 }
 
@@ -42,20 +119,46 @@ func (manager *DesktopManagerCtx) ClipboardSetText(data types.ClipboardText) err
 	return manager.ClipboardSetBinary(ClipboardTextPlainTarget, []byte(data.Text))
 }
 
+// ClipboardClear empties all selections, instead of overwriting them. Clearing
+// is not reported as clipboard update, because there is no content to sync.
+func (manager *DesktopManagerCtx) ClipboardClear() error {
+	// previous clipboard command would keep serving its content
+	manager.replaceClipboardCommand(nil)
+
+	xorg.ClearSelections()
+	return nil
+}
+
 func (manager *DesktopManagerCtx) ClipboardGetBinary(mime string) ([]byte, error) {
+	data, overflow, err := manager.clipboardGet(mime, manager.config.ClipboardMaxSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// Binary data cannot be truncated.
+	if overflow {
+		return nil, types.ErrClipboardTooLarge
+	}
+
+	return data, nil
+}
+
+// clipboardGet reads clipboard content up to the size limit, reports whether it was exceeded.
+func (manager *DesktopManagerCtx) clipboardGet(mime string, limit int) ([]byte, bool, error) {
 	cmd := exec.Command("xclip", "-selection", "clipboard", "-out", "-target", mime)
 
-	var stdout, stderr bytes.Buffer
+	var stderr bytes.Buffer
+	stdout := limitedBuffer{limit: limit}
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
 	err := cmd.Run()
 	if err != nil {
 		msg := strings.TrimSpace(stderr.String())
-		return nil, fmt.Errorf("%s", msg)
+		return nil, false, fmt.Errorf("%s", msg)
 	}
 
-	return stdout.Bytes(), nil
+	return stdout.Bytes(), stdout.overflow, nil
 }
 
 func (manager *DesktopManagerCtx) replaceClipboardCommand(newCmd *exec.Cmd) {
@@ -76,6 +179,10 @@ func (manager *DesktopManagerCtx) replaceClipboardCommand(newCmd *exec.Cmd) {
 }
 
 func (manager *DesktopManagerCtx) ClipboardSetBinary(mime string, data []byte) error {
+	if limit := manager.config.ClipboardMaxSize; limit > 0 && len(data) > limit {
+		return types.ErrClipboardTooLarge
+	}
+
 	cmd := exec.Command("xclip", "-selection", "clipboard", "-in", "-target", mime)
 
 	var stderr bytes.Buffer
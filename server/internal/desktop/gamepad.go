@@ -0,0 +1,71 @@
+package desktop
+
+import (
+	"fmt"
+
+	"github.com/m1k1o/neko/server/pkg/uinput"
+)
+
+func (manager *DesktopManagerCtx) HasGamepadSupport() bool {
+	return manager.config.GamepadEnabled
+}
+
+func (manager *DesktopManagerCtx) GamepadConnect(index uint8) error {
+	if !manager.HasGamepadSupport() {
+		return fmt.Errorf("gamepad support is disabled")
+	}
+
+	manager.gamepadsMu.Lock()
+	defer manager.gamepadsMu.Unlock()
+
+	if _, ok := manager.gamepads[index]; ok {
+		return fmt.Errorf("gamepad %d is already connected", index)
+	}
+
+	gamepad, err := uinput.NewGamepad(manager.config.GamepadDevice, fmt.Sprintf("Neko Virtual Gamepad %d", index))
+	if err != nil {
+		return err
+	}
+
+	manager.gamepads[index] = gamepad
+	manager.logger.Info().Uint8("index", index).Msg("gamepad connected")
+	return nil
+}
+
+func (manager *DesktopManagerCtx) GamepadUpdate(index uint8, axes []int16, buttons []uint8) error {
+	manager.gamepadsMu.Lock()
+	gamepad, ok := manager.gamepads[index]
+	manager.gamepadsMu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("gamepad %d is not connected", index)
+	}
+
+	return gamepad.Update(axes, buttons)
+}
+
+func (manager *DesktopManagerCtx) GamepadDisconnect(index uint8) error {
+	manager.gamepadsMu.Lock()
+	defer manager.gamepadsMu.Unlock()
+
+	gamepad, ok := manager.gamepads[index]
+	if !ok {
+		return fmt.Errorf("gamepad %d is not connected", index)
+	}
+
+	delete(manager.gamepads, index)
+	manager.logger.Info().Uint8("index", index).Msg("gamepad disconnected")
+	return gamepad.Close()
+}
+
+func (manager *DesktopManagerCtx) GamepadDisconnectAll() {
+	manager.gamepadsMu.Lock()
+	defer manager.gamepadsMu.Unlock()
+
+	for index, gamepad := range manager.gamepads {
+		if err := gamepad.Close(); err != nil {
+			manager.logger.Warn().Err(err).Uint8("index", index).Msg("unable to disconnect gamepad")
+		}
+		delete(manager.gamepads, index)
+	}
+}
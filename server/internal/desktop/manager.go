@@ -12,6 +12,7 @@ import (
 
 	"github.com/m1k1o/neko/server/internal/config"
 	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/uinput"
 	"github.com/m1k1o/neko/server/pkg/xevent"
 	"github.com/m1k1o/neko/server/pkg/xinput"
 	"github.com/m1k1o/neko/server/pkg/xorg"
@@ -25,9 +26,18 @@ type DesktopManagerCtx struct {
 	shutdown   chan struct{}
 	emmiter    events.EventEmmiter
 	config     *config.Desktop
+	display    string           // display receiving input
 	screenSize types.ScreenSize // cached screen size
+	screenDPI  atomic.Int32     // last known screen dpi
 	input      xinput.Driver
 
+	gamepadsMu sync.Mutex
+	gamepads   map[uint8]*uinput.Gamepad
+
+	// available keyboard layouts, loaded on first use
+	xkbOnce    sync.Once
+	xkbLayouts xkbLayouts
+
 	// Clipboard process holding the most recent clipboard data.
 	// It must remain running to allow pasting clipboard data.
 	// The last command is kept running until it is replaced or shutdown.
@@ -47,8 +57,10 @@ func New(config *config.Desktop) *DesktopManagerCtx {
 		shutdown:   make(chan struct{}),
 		emmiter:    events.New(),
 		config:     config,
+		display:    config.Display,
 		screenSize: config.ScreenSize,
 		input:      input,
+		gamepads:   make(map[uint8]*uinput.Gamepad),
 	}
 }
 
@@ -75,6 +87,8 @@ func (manager *DesktopManagerCtx) Start() {
 			Msgf("setting initial screen size")
 	}
 
+	manager.screenDPI.Store(int32(xorg.GetScreenDPI()))
+
 	err = manager.input.Connect()
 	if err != nil {
 		// TODO: fail silently to dummy driver?
@@ -100,6 +114,9 @@ func (manager *DesktopManagerCtx) Start() {
 			Msg("X event error occured")
 	})
 
+	// dpi is checked only when the screen changes, not polled
+	manager.OnScreenChanged(manager.checkScreenDPI)
+
 	manager.wg.Add(1)
 
 	go func() {
@@ -134,12 +151,35 @@ func (manager *DesktopManagerCtx) OnAfterScreenSizeChange(listener func()) {
 	})
 }
 
+func (manager *DesktopManagerCtx) OnScreenDPIChange(listener func(dpi types.ScreenDPI)) {
+	manager.emmiter.On("screen_dpi_change", func(payload ...any) {
+		listener(payload[0].(types.ScreenDPI))
+	})
+}
+
+// checkScreenDPI emits an event if the dpi differs from the last known one
+func (manager *DesktopManagerCtx) checkScreenDPI() {
+	dpi := xorg.GetScreenDPI()
+	if manager.screenDPI.Swap(int32(dpi)) == int32(dpi) {
+		return
+	}
+
+	screenDPI := types.NewScreenDPI(dpi)
+	manager.logger.Info().
+		Int("dpi", screenDPI.DPI).
+		Float64("scale", screenDPI.Scale).
+		Msg("screen dpi changed")
+
+	manager.emmiter.Emit("screen_dpi_change", screenDPI)
+}
+
 func (manager *DesktopManagerCtx) Shutdown() error {
 	manager.logger.Info().Msgf("shutdown")
 
 	close(manager.shutdown)
 
 	manager.replaceClipboardCommand(nil)
+	manager.GamepadDisconnectAll()
 	manager.wg.Wait()
 
 	xorg.DisplayClose()
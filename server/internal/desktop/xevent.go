@@ -16,6 +16,13 @@ func (manager *DesktopManagerCtx) OnClipboardUpdated(listener func()) {
 	})
 }
 
+// OnScreenChanged is called when screen configuration or resources, e.g. Xft.dpi, change
+func (manager *DesktopManagerCtx) OnScreenChanged(listener func()) {
+	xevent.Emmiter.On("screen-changed", func(payload ...any) {
+		listener()
+	})
+}
+
 func (manager *DesktopManagerCtx) OnFileChooserDialogOpened(listener func()) {
 	xevent.Emmiter.On("file-chooser-dialog-opened", func(payload ...any) {
 		listener()
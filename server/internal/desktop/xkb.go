@@ -0,0 +1,112 @@
+package desktop
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// xkbRulesList lists layouts and variants known to the X keyboard extension.
+const xkbRulesList = "/usr/share/X11/xkb/rules/evdev.lst"
+
+// xkbLayouts maps available layouts to their variants.
+type xkbLayouts map[string]map[string]struct{}
+
+func loadXkbLayouts(path string) (xkbLayouts, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	layouts := xkbLayouts{}
+
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "!") {
+			section = strings.TrimSpace(line[1:])
+			continue
+		}
+
+		fields := strings.Fields(line)
+		switch section {
+		case "layout":
+			if _, ok := layouts[fields[0]]; !ok {
+				layouts[fields[0]] = map[string]struct{}{}
+			}
+		case "variant":
+			// variant line is in format: <variant> <layout>: <description>
+			if len(fields) < 2 || !strings.HasSuffix(fields[1], ":") {
+				continue
+			}
+
+			layout := strings.TrimSuffix(fields[1], ":")
+			if _, ok := layouts[layout]; !ok {
+				layouts[layout] = map[string]struct{}{}
+			}
+			layouts[layout][fields[0]] = struct{}{}
+		}
+	}
+
+	return layouts, scanner.Err()
+}
+
+// validate checks that every layout, and its variant if set, is available.
+// Multiple layouts and variants are separated by comma.
+func (l xkbLayouts) validate(kbd types.KeyboardMap) error {
+	layouts := strings.Split(kbd.Layout, ",")
+
+	var variants []string
+	if kbd.Variant != "" {
+		variants = strings.Split(kbd.Variant, ",")
+	}
+
+	if len(variants) > len(layouts) {
+		return types.ErrKeyboardMapUnavailable
+	}
+
+	for i, layout := range layouts {
+		known, ok := l[layout]
+		if !ok {
+			return types.ErrKeyboardMapUnavailable
+		}
+
+		if i >= len(variants) || variants[i] == "" {
+			continue
+		}
+
+		if _, ok := known[variants[i]]; !ok {
+			return types.ErrKeyboardMapUnavailable
+		}
+	}
+
+	return nil
+}
+
+// xkbValidate validates keyboard map against available layouts, when
+// the list of layouts cannot be loaded, keyboard map is not validated.
+func (manager *DesktopManagerCtx) xkbValidate(kbd types.KeyboardMap) error {
+	manager.xkbOnce.Do(func() {
+		layouts, err := loadXkbLayouts(xkbRulesList)
+		if err != nil {
+			manager.logger.Warn().Err(err).Msg("unable to load keyboard layouts, keyboard maps will not be validated")
+			return
+		}
+
+		manager.xkbLayouts = layouts
+	})
+
+	if manager.xkbLayouts == nil {
+		return nil
+	}
+
+	return manager.xkbLayouts.validate(kbd)
+}
@@ -4,6 +4,7 @@ import (
 	"image"
 	"os/exec"
 	"regexp"
+	"slices"
 	"time"
 
 	"github.com/m1k1o/neko/server/pkg/types"
@@ -14,6 +15,10 @@ func (manager *DesktopManagerCtx) Move(x, y int) {
 	xorg.Move(x, y)
 }
 
+func (manager *DesktopManagerCtx) MoveRelative(dx, dy int) {
+	xorg.MoveRelative(dx, dy)
+}
+
 func (manager *DesktopManagerCtx) GetCursorPosition() (int, int) {
 	return xorg.GetCursorPosition()
 }
@@ -38,6 +43,10 @@ func (manager *DesktopManagerCtx) KeyUp(code uint32) error {
 	return xorg.KeyUp(code)
 }
 
+func (manager *DesktopManagerCtx) KeysDown() []uint32 {
+	return xorg.KeysDown()
+}
+
 func (manager *DesktopManagerCtx) ButtonPress(code uint32) error {
 	xorg.ResetKeys()
 	defer xorg.ResetKeys()
@@ -66,6 +75,22 @@ func (manager *DesktopManagerCtx) ResetKeys() {
 	xorg.ResetKeys()
 }
 
+// ResetModifiers releases all pressed keys and forces all non-locking modifiers up.
+func (manager *DesktopManagerCtx) ResetModifiers() {
+	xorg.ResetKeys()
+
+	for _, mod := range []xorg.KbdMod{
+		xorg.KbdModShift,
+		xorg.KbdModControl,
+		xorg.KbdModAlt,
+		xorg.KbdModMeta,
+		xorg.KbdModSuper,
+		xorg.KbdModAltGr,
+	} {
+		xorg.SetKeyboardModifier(mod, false)
+	}
+}
+
 func (manager *DesktopManagerCtx) ScreenConfigurations() []types.ScreenSize {
 	var configs []types.ScreenSize
 	for _, size := range xorg.ScreenConfigurations {
@@ -85,13 +110,65 @@ func (manager *DesktopManagerCtx) ScreenConfigurations() []types.ScreenSize {
 	return configs
 }
 
+// SetDisplay reopens the connection used for input on another display,
+// screen configurations and size are read from the new display.
+func (manager *DesktopManagerCtx) SetDisplay(display string) error {
+	mu.Lock()
+	defer func() {
+		mu.Unlock()
+
+		// dpi of the new display can differ
+		manager.checkScreenDPI()
+	}()
+
+	if manager.display == display {
+		return nil
+	}
+
+	// keys pressed on the previous display must not stay pressed
+	xorg.ResetKeys()
+
+	// input stays on the previous display, if the new one cannot be opened
+	if err := xorg.DisplaySwitch(display); err != nil {
+		return err
+	}
+
+	manager.display = display
+	xorg.GetScreenConfigurations()
+	manager.screenSize = xorg.GetScreenSize()
+
+	manager.logger.Info().
+		Str("display", display).
+		Str("screen_size", manager.screenSize.String()).
+		Msg("input display changed")
+
+	return nil
+}
+
+// ScreenSizeSupported checks, if screen size is one of the screen configurations
+// returned to clients, rate is optional and defaults to 60, as xorg does.
+func (manager *DesktopManagerCtx) ScreenSizeSupported(screenSize types.ScreenSize) bool {
+	if screenSize.Rate == 0 {
+		screenSize.Rate = 60
+	}
+
+	return slices.Contains(manager.ScreenConfigurations(), screenSize)
+}
+
 func (manager *DesktopManagerCtx) SetScreenSize(screenSize types.ScreenSize) (types.ScreenSize, error) {
+	if !manager.ScreenSizeSupported(screenSize) {
+		return screenSize, types.ErrScreenSizeUnsupported
+	}
+
 	mu.Lock()
 	manager.emmiter.Emit("before_screen_size_change")
 
 	defer func() {
 		manager.emmiter.Emit("after_screen_size_change")
 		mu.Unlock()
+
+		// physical size of the new configuration can differ
+		manager.checkScreenDPI()
 	}()
 
 	screenSize, err := xorg.ChangeScreenSize(screenSize)
@@ -107,7 +184,23 @@ func (manager *DesktopManagerCtx) GetScreenSize() types.ScreenSize {
 	return xorg.GetScreenSize()
 }
 
+func (manager *DesktopManagerCtx) GetScreenDPI() types.ScreenDPI {
+	return types.NewScreenDPI(xorg.GetScreenDPI())
+}
+
+func (manager *DesktopManagerCtx) HasDamage() bool {
+	return xorg.HasDamage()
+}
+
+func (manager *DesktopManagerCtx) KeyboardMapSupported(kbd types.KeyboardMap) error {
+	return manager.xkbValidate(kbd)
+}
+
 func (manager *DesktopManagerCtx) SetKeyboardMap(kbd types.KeyboardMap) error {
+	if err := manager.xkbValidate(kbd); err != nil {
+		return err
+	}
+
 	// TOOD: Use native API.
 	cmd := exec.Command("setxkbmap", "-layout", kbd.Layout, "-variant", kbd.Variant)
 	_, err := cmd.Output()
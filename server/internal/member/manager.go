@@ -145,6 +145,11 @@ func (manager *MemberManagerCtx) Login(username string, password string) (types.
 		return nil, "", types.ErrSessionLoginsLocked
 	}
 
+	// do not replace existing session, when new one cannot be created
+	if manager.sessions.Draining() {
+		return nil, "", types.ErrSessionDraining
+	}
+
 	session, ok := manager.sessions.Get(id)
 	if ok {
 		if session.State().IsConnected {
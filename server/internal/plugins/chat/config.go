@@ -7,6 +7,11 @@ import (
 
 type Config struct {
 	Enabled bool
+	// how many messages are kept for late joiners
+	History int
+	// messages per second and burst allowed for a single session
+	RateLimit float64
+	RateBurst int
 }
 
 func (Config) Init(cmd *cobra.Command) error {
@@ -15,9 +20,27 @@ func (Config) Init(cmd *cobra.Command) error {
 		return err
 	}
 
+	cmd.PersistentFlags().Int("chat.history", 50, "how many recent messages are sent to newly connected sessions, 0 disables history")
+	if err := viper.BindPFlag("chat.history", cmd.PersistentFlags().Lookup("chat.history")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Float64("chat.rate_limit", 1, "how many messages per second a session can send, 0 disables rate limiting")
+	if err := viper.BindPFlag("chat.rate_limit", cmd.PersistentFlags().Lookup("chat.rate_limit")); err != nil {
+		return err
+	}
+
+	cmd.PersistentFlags().Int("chat.rate_burst", 5, "how many messages a session can send at once before being rate limited")
+	if err := viper.BindPFlag("chat.rate_burst", cmd.PersistentFlags().Lookup("chat.rate_burst")); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func (s *Config) Set() {
 	s.Enabled = viper.GetBool("chat.enabled")
+	s.History = viper.GetInt("chat.history")
+	s.RateLimit = viper.GetFloat64("chat.rate_limit")
+	s.RateBurst = viper.GetInt("chat.rate_burst")
 }
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -25,6 +26,8 @@ func NewManager(
 		logger:   logger,
 		config:   config,
 		sessions: sessions,
+		history:  []Message{},
+		limiters: map[string]*utils.RateLimiter{},
 	}
 }
 
@@ -32,6 +35,12 @@ type Manager struct {
 	logger   zerolog.Logger
 	config   *Config
 	sessions types.SessionManager
+
+	history   []Message
+	historyMu sync.Mutex
+
+	limiters   map[string]*utils.RateLimiter
+	limitersMu sync.Mutex
 }
 
 type Settings struct {
@@ -65,8 +74,56 @@ func (m *Manager) settingsForSession(session types.Session) (Settings, error) {
 	}, nil
 }
 
+// allowed returns false, if session sends messages too fast
+func (m *Manager) allowed(session types.Session) bool {
+	if m.config.RateLimit <= 0 {
+		return true
+	}
+
+	m.limitersMu.Lock()
+	limiter, ok := m.limiters[session.ID()]
+	if !ok {
+		limiter = utils.NewRateLimiter(m.config.RateLimit, m.config.RateBurst)
+		m.limiters[session.ID()] = limiter
+	}
+	m.limitersMu.Unlock()
+
+	return limiter.AllowN(1)
+}
+
+func (m *Manager) addHistory(message Message) {
+	if m.config.History <= 0 {
+		return
+	}
+
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+
+	m.history = append(m.history, message)
+	if over := len(m.history) - m.config.History; over > 0 {
+		m.history = append([]Message{}, m.history[over:]...)
+	}
+}
+
+func (m *Manager) getHistory() []Message {
+	m.historyMu.Lock()
+	defer m.historyMu.Unlock()
+
+	return append([]Message{}, m.history...)
+}
+
 func (m *Manager) sendMessage(session types.Session, content Content) {
-	now := time.Now()
+	message := Message{
+		ID:      session.ID(),
+		Created: time.Now(),
+		Content: content,
+	}
+
+	if metadata := session.Metadata(); len(metadata) > 0 {
+		message.Metadata = &metadata
+	}
+
+	m.addHistory(message)
 
 	// get all sessions that have chat enabled
 	var sessions []types.Session
@@ -80,20 +137,29 @@ func (m *Manager) sendMessage(session types.Session, content Content) {
 
 	// send content to all sessions
 	for _, s := range sessions {
-		s.Send(CHAT_MESSAGE, Message{
-			ID:      session.ID(),
-			Created: now,
-			Content: content,
-		})
+		s.Send(CHAT_MESSAGE, message)
 	}
 }
 
 func (m *Manager) Start() error {
 	// send init message once a user connects
 	m.sessions.OnConnected(func(session types.Session) {
-		session.Send(CHAT_INIT, Init{
+		init := Init{
 			Enabled: m.config.Enabled,
-		})
+		}
+
+		// late joiners get recent messages
+		if settings, err := m.settingsForSession(session); err == nil && settings.CanReceive {
+			init.History = m.getHistory()
+		}
+
+		session.Send(CHAT_INIT, init)
+	})
+
+	m.sessions.OnDeleted(func(session types.Session) {
+		m.limitersMu.Lock()
+		delete(m.limiters, session.ID())
+		m.limitersMu.Unlock()
 	})
 
 	return nil
@@ -129,6 +195,12 @@ func (m *Manager) WebSocketHandler(session types.Session, msg types.WebSocketMes
 			return true
 		}
 
+		if !m.allowed(session) {
+			m.logger.Warn().Str("session_id", session.ID()).Msg("chat rate limit exceeded, message dropped")
+			// we processed the message, return true
+			return true
+		}
+
 		m.sendMessage(session, content)
 		return true
 	}
@@ -152,6 +224,10 @@ func (m *Manager) sendMessageHandler(w http.ResponseWriter, r *http.Request) err
 		return utils.HttpForbidden("not allowed to send chat messages")
 	}
 
+	if !m.allowed(session) {
+		return utils.HttpError(http.StatusTooManyRequests, "chat rate limit exceeded")
+	}
+
 	content := Content{}
 	if err := utils.HttpJsonRequest(w, r, &content); err != nil {
 		return err
@@ -1,6 +1,10 @@
 package chat
 
-import "time"
+import (
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
 
 const PluginName = "chat"
 
@@ -11,6 +15,8 @@ const (
 
 type Init struct {
 	Enabled bool `json:"enabled"`
+	// recent messages, if session can receive them
+	History []Message `json:"history,omitempty"`
 }
 
 type Content struct {
@@ -18,7 +24,9 @@ type Content struct {
 }
 
 type Message struct {
-	ID      string    `json:"id"`
-	Created time.Time `json:"created"`
-	Content Content   `json:"content"`
+	ID string `json:"id"`
+	// sender metadata, omitted when session has none
+	Metadata *types.SessionMetadata `json:"metadata,omitempty"`
+	Created  time.Time              `json:"created"`
+	Content  Content                `json:"content"`
 }
@@ -117,11 +117,13 @@ func (manager *ManagerCtx) Start(
 	sessionManager types.SessionManager,
 	webSocketManager types.WebSocketManager,
 	apiManager types.ApiManager,
+	webRTCManager types.WebRTCManager,
 ) {
 	err := manager.plugins.start(types.PluginManagers{
 		SessionManager:        sessionManager,
 		WebSocketManager:      webSocketManager,
 		ApiManager:            apiManager,
+		WebRTCManager:         webRTCManager,
 		LoadServiceFromPlugin: manager.LookupService,
 	})
 
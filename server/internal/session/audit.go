@@ -0,0 +1,153 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+func (manager *SessionManagerCtx) Audit(entry types.AuditEntry) {
+	if manager.config.Audit.Size <= 0 {
+		return
+	}
+
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	manager.auditMu.Lock()
+	manager.auditAppend(entry.Actor, entry)
+	if entry.Target != "" && entry.Target != entry.Actor {
+		manager.auditAppend(entry.Target, entry)
+	}
+	manager.auditMu.Unlock()
+
+	manager.logger.Info().
+		Str("actor", entry.Actor).
+		Str("action", entry.Action).
+		Str("target", entry.Target).
+		Str("details", entry.Details).
+		Msg("audit")
+
+	if manager.config.Audit.File != "" {
+		manager.auditPersist(entry)
+	}
+}
+
+func (manager *SessionManagerCtx) AuditLog(id string) []types.AuditEntry {
+	manager.auditMu.Lock()
+	defer manager.auditMu.Unlock()
+
+	return append([]types.AuditEntry{}, manager.audit[id]...)
+}
+
+// must be called with audit mutex locked
+func (manager *SessionManagerCtx) auditAppend(id string, entry types.AuditEntry) {
+	entries := append(manager.audit[id], entry)
+
+	// keep only the newest entries
+	if over := len(entries) - manager.config.Audit.Size; over > 0 {
+		entries = append([]types.AuditEntry{}, entries[over:]...)
+	}
+
+	manager.audit[id] = entries
+}
+
+func (manager *SessionManagerCtx) auditDelete(id string) {
+	manager.auditMu.Lock()
+	defer manager.auditMu.Unlock()
+
+	delete(manager.audit, id)
+}
+
+func (manager *SessionManagerCtx) auditPersist(entry types.AuditEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		manager.logger.Err(err).Msg("failed to marshal audit entry")
+		return
+	}
+
+	manager.auditMu.Lock()
+	defer manager.auditMu.Unlock()
+
+	file, err := os.OpenFile(manager.config.Audit.File, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		manager.logger.Err(err).Msg("failed to open audit file")
+		return
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		manager.logger.Err(err).Msg("failed to write audit file")
+	}
+}
+
+func (manager *SessionManagerCtx) auditHost(session types.Session, prevHostId, hostId string) {
+	if session == nil || prevHostId == hostId {
+		return
+	}
+
+	entry := types.AuditEntry{
+		Actor: session.ID(),
+	}
+
+	switch {
+	case hostId == "" && prevHostId == session.ID():
+		entry.Action = types.AuditControlRelease
+	case hostId == "":
+		entry.Action = types.AuditControlRevoke
+		entry.Target = prevHostId
+	case hostId == session.ID():
+		entry.Action = types.AuditControlGrab
+		entry.Target = prevHostId
+	default:
+		entry.Action = types.AuditControlGive
+		entry.Target = hostId
+	}
+
+	manager.Audit(entry)
+}
+
+func (manager *SessionManagerCtx) auditSettings(session types.Session, new, old types.Settings) {
+	if session == nil {
+		return
+	}
+
+	changes := []struct {
+		name     string
+		old, new bool
+	}{
+		{"private_mode", old.PrivateMode, new.PrivateMode},
+		{"locked_logins", old.LockedLogins, new.LockedLogins},
+		{"locked_controls", old.LockedControls, new.LockedControls},
+		{"control_protection", old.ControlProtection, new.ControlProtection},
+		{"implicit_hosting", old.ImplicitHosting, new.ImplicitHosting},
+		{"inactive_cursors", old.InactiveCursors, new.InactiveCursors},
+		{"merciful_reconnect", old.MercifulReconnect, new.MercifulReconnect},
+	}
+
+	details := []string{}
+	for _, change := range changes {
+		if change.old != change.new {
+			details = append(details, fmt.Sprintf("%s=%t", change.name, change.new))
+		}
+	}
+
+	if old.ControlPolicy != new.ControlPolicy {
+		details = append(details, fmt.Sprintf("control_policy=%s", new.ControlPolicy))
+	}
+
+	if len(details) == 0 {
+		return
+	}
+
+	manager.Audit(types.AuditEntry{
+		Actor:   session.ID(),
+		Action:  types.AuditSettingsUpdate,
+		Details: strings.Join(details, ","),
+	})
+}
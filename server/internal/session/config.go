@@ -0,0 +1,135 @@
+package session
+
+import (
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+func (session *SessionCtx) SetKeyboardMap(kbd types.KeyboardMap) {
+	session.keyboardMapMu.Lock()
+	defer session.keyboardMapMu.Unlock()
+
+	session.keyboardMap = &kbd
+}
+
+func (session *SessionCtx) KeyboardMap() *types.KeyboardMap {
+	session.keyboardMapMu.Lock()
+	defer session.keyboardMapMu.Unlock()
+
+	if session.keyboardMap == nil {
+		return nil
+	}
+
+	kbd := *session.keyboardMap
+	return &kbd
+}
+
+// ExportConfig returns current setup of the session, video and audio
+// are only exported when the session has a webrtc peer.
+func (session *SessionCtx) ExportConfig() types.SessionConfig {
+	config := types.SessionConfig{
+		KeyboardMap: session.KeyboardMap(),
+	}
+
+	peer := session.GetWebRTCPeer()
+	if peer == nil {
+		return config
+	}
+
+	video := peer.Video()
+	config.Video = &types.PeerVideoRequest{
+		Disabled:   &video.Disabled,
+		Auto:       &video.Auto,
+		MaxFPS:     &video.MaxFPS,
+		FollowHost: &video.FollowHost,
+		// empty backend removes the forced one
+		ForceEncoder: &video.ForceEncoder,
+		// zero size removes the limit, when it is not set
+		Viewport:      &types.PeerViewport{},
+		MaxResolution: &types.StreamResolution{},
+	}
+	if video.Viewport != nil {
+		config.Video.Viewport = video.Viewport
+	}
+	if video.MaxResolution != nil {
+		config.Video.MaxResolution = video.MaxResolution
+	}
+
+	// scale of 1 removes the downscale, when it is not set
+	scale := video.ScaleResolutionDownBy
+	if scale < 1 {
+		scale = 1
+	}
+	config.Video.ScaleResolutionDownBy = &scale
+
+	// manual ceiling is set by selecting it
+	if video.Ceiling != "" {
+		manualCeiling := true
+		config.Video.ManualCeiling = &manualCeiling
+		config.Video.Selector = &types.StreamSelector{
+			ID:   video.Ceiling,
+			Type: types.StreamSelectorTypeExact,
+		}
+		config.Video.Auto = nil
+	} else if video.ID != "" {
+		config.Video.Selector = &types.StreamSelector{
+			ID:   video.ID,
+			Type: types.StreamSelectorTypeExact,
+		}
+	}
+
+	audio := peer.Audio()
+	config.Audio = &types.PeerAudioRequest{
+		Disabled:   &audio.Disabled,
+		Gain:       &audio.Gain,
+		SyncOffset: &audio.SyncOffset,
+	}
+
+	pointerLocked := peer.PointerLocked()
+	config.PointerLocked = &pointerLocked
+
+	return config
+}
+
+// ApplyConfig applies session scoped parts of the configuration, screen size
+// is shared by all sessions and must be applied by the caller. The whole
+// configuration is validated first, so that it is not applied only partially.
+func (session *SessionCtx) ApplyConfig(config types.SessionConfig) error {
+	peer := session.GetWebRTCPeer()
+	if peer == nil && (config.Video != nil || config.Audio != nil || config.PointerLocked != nil) {
+		return types.ErrWebRTCConnectionNotFound
+	}
+
+	if config.Video != nil {
+		if err := peer.ValidateVideo(*config.Video); err != nil {
+			return err
+		}
+	}
+
+	if config.Audio != nil {
+		if err := peer.ValidateAudio(*config.Audio); err != nil {
+			return err
+		}
+	}
+
+	if config.KeyboardMap != nil {
+		session.SetKeyboardMap(*config.KeyboardMap)
+	}
+
+	if config.Video != nil {
+		if err := peer.SetVideo(*config.Video); err != nil {
+			return err
+		}
+	}
+
+	if config.Audio != nil {
+		if err := peer.SetAudio(*config.Audio); err != nil {
+			return err
+		}
+	}
+
+	if config.PointerLocked != nil {
+		peer.SetPointerLocked(*config.PointerLocked)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package session
+
+import (
+	"slices"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// ---
+// control
+// ---
+
+func (manager *SessionManagerCtx) ControlRequest(session types.Session) (types.Session, error) {
+	if !session.Profile().CanHost || session.PrivateModeEnabled() {
+		return nil, types.ErrSessionNotAllowedToHost
+	}
+
+	if session.IsHost() {
+		return nil, types.ErrSessionAlreadyTheHost
+	}
+
+	if manager.Settings().LockedControls && !session.Profile().IsAdmin {
+		return nil, types.ErrSessionNotAllowedToHost
+	}
+
+	// if there is no host, set session as host
+	host, hasHost := manager.GetHost()
+	if !hasHost {
+		session.SetAsHost()
+		return nil, nil
+	}
+
+	switch manager.Settings().GetControlPolicy() {
+	case types.ControlPolicySteal:
+		// take control without asking
+		session.SetAsHost()
+		return host, nil
+	case types.ControlPolicyQueue:
+		// host is notified only once, when session joins the queue
+		if !manager.controlEnqueue(session) {
+			return nil, types.ErrSessionAlreadyHosted
+		}
+	}
+
+	// TODO: Some throttling mechanism to prevent spamming.
+
+	// let host know that someone wants to take control
+	host.Send(
+		event.CONTROL_REQUEST,
+		message.SessionID{
+			ID: session.ID(),
+		})
+
+	return nil, types.ErrSessionAlreadyHosted
+}
+
+func (manager *SessionManagerCtx) ControlRelease(session types.Session) error {
+	if !session.Profile().CanHost || session.PrivateModeEnabled() {
+		return types.ErrSessionNotAllowedToHost
+	}
+
+	if !session.IsHost() {
+		return types.ErrSessionNotTheHost
+	}
+
+	manager.ControlReset(session)
+	return nil
+}
+
+func (manager *SessionManagerCtx) ControlReset(session types.Session) {
+	if _, hasHost := manager.GetHost(); !hasHost {
+		return
+	}
+
+	// with queue policy, control is handed to the next waiting session
+	if next, ok := manager.controlDequeue(); ok {
+		next.SetAsHostBy(session)
+		return
+	}
+
+	session.ClearHost()
+}
+
+func (manager *SessionManagerCtx) ControlGive(session types.Session, target types.Session) error {
+	if !target.Profile().CanHost || target.PrivateModeEnabled() {
+		return types.ErrSessionNotAllowedToHost
+	}
+
+	if manager.Settings().LockedControls && !target.Profile().IsAdmin {
+		return types.ErrSessionNotAllowedToHost
+	}
+
+	// target does not wait for control anymore
+	manager.controlQueueRemove(target)
+
+	target.SetAsHostBy(session)
+	return nil
+}
+
+// controlEnqueue adds session to the control queue, returns false if it is already waiting
+func (manager *SessionManagerCtx) controlEnqueue(session types.Session) bool {
+	manager.controlQueueMu.Lock()
+	defer manager.controlQueueMu.Unlock()
+
+	if slices.Contains(manager.controlQueue, session.ID()) {
+		return false
+	}
+
+	manager.controlQueue = append(manager.controlQueue, session.ID())
+	return true
+}
+
+// controlDequeue returns next waiting session that is still able to host
+func (manager *SessionManagerCtx) controlDequeue() (types.Session, bool) {
+	manager.controlQueueMu.Lock()
+	defer manager.controlQueueMu.Unlock()
+
+	// queue is not used anymore, if policy was changed in the meantime
+	if manager.Settings().GetControlPolicy() != types.ControlPolicyQueue {
+		manager.controlQueue = nil
+		return nil, false
+	}
+
+	for len(manager.controlQueue) > 0 {
+		id := manager.controlQueue[0]
+		manager.controlQueue = manager.controlQueue[1:]
+
+		next, ok := manager.Get(id)
+		if !ok || !next.State().IsConnected || next.IsHost() {
+			continue
+		}
+
+		if !next.Profile().CanHost || next.PrivateModeEnabled() {
+			continue
+		}
+
+		if manager.Settings().LockedControls && !next.Profile().IsAdmin {
+			continue
+		}
+
+		return next, true
+	}
+
+	return nil, false
+}
+
+// controlQueueRemove removes session from the control queue
+func (manager *SessionManagerCtx) controlQueueRemove(session types.Session) {
+	manager.controlQueueMu.Lock()
+	defer manager.controlQueueMu.Unlock()
+
+	manager.controlQueue = slices.DeleteFunc(manager.controlQueue, func(id string) bool {
+		return id == session.ID()
+	})
+}
@@ -2,6 +2,7 @@ package session
 
 import (
 	"errors"
+	"maps"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -25,6 +26,7 @@ func New(config *config.Session) *SessionManagerCtx {
 			LockedControls:    config.LockedControls || config.ControlProtection,
 			ControlProtection: config.ControlProtection,
 			ImplicitHosting:   config.ImplicitHosting,
+			ControlPolicy:     config.ControlPolicy,
 			InactiveCursors:   config.InactiveCursors,
 			MercifulReconnect: config.MercifulReconnect,
 			HeartbeatInterval: config.HeartbeatInterval,
@@ -34,6 +36,10 @@ func New(config *config.Session) *SessionManagerCtx {
 		cursors:  make(map[types.Session][]types.Cursor),
 		emmiter:  events.New(),
 
+		resumeTokens: make(map[string]string),
+		audit:        make(map[string][]types.AuditEntry),
+		webhooks:     make(map[string][]webhookPayload),
+
 		serverStartedAt: time.Now(),
 	}
 
@@ -59,6 +65,16 @@ func New(config *config.Session) *SessionManagerCtx {
 	// try to load sessions from file
 	manager.load()
 
+	// notify external service about sessions joining and leaving
+	if config.Webhook.URL != "" {
+		manager.OnConnected(func(session types.Session) {
+			manager.webhook("connected", session)
+		})
+		manager.OnDisconnected(func(session types.Session) {
+			manager.webhook("disconnected", session)
+		})
+	}
+
 	return manager
 }
 
@@ -75,6 +91,16 @@ type SessionManagerCtx struct {
 
 	hostId atomic.Value
 
+	resumeTokens   map[string]string
+	resumeTokensMu sync.Mutex
+
+	audit   map[string][]types.AuditEntry
+	auditMu sync.Mutex
+
+	// pending webhook payloads by session, delivered in order by one worker per session
+	webhooks   map[string][]webhookPayload
+	webhooksMu sync.Mutex
+
 	cursors   map[types.Session][]types.Cursor
 	cursorsMu sync.Mutex
 
@@ -86,6 +112,15 @@ type SessionManagerCtx struct {
 	lastAdminLeftAt atomic.Value
 	totalUsers      atomic.Int32
 	lastUserLeftAt  atomic.Value
+
+	// sessions waiting for control with queue policy, in order of requests
+	controlQueue   []string
+	controlQueueMu sync.Mutex
+
+	draining atomic.Bool
+	banner   atomic.Pointer[types.Banner]
+
+	presentation atomic.Pointer[types.Presentation]
 }
 
 func (manager *SessionManagerCtx) Create(id string, profile types.MemberProfile) (types.Session, string, error) {
@@ -94,6 +129,10 @@ func (manager *SessionManagerCtx) Create(id string, profile types.MemberProfile)
 		return nil, "", err
 	}
 
+	if manager.draining.Load() {
+		return nil, "", types.ErrSessionDraining
+	}
+
 	manager.sessionsMu.Lock()
 	if _, ok := manager.sessions[id]; ok {
 		manager.sessionsMu.Unlock()
@@ -143,6 +182,28 @@ func (manager *SessionManagerCtx) Update(id string, profile types.MemberProfile)
 	return nil
 }
 
+func (manager *SessionManagerCtx) SetMetadata(id string, metadata types.SessionMetadata) error {
+	if err := metadata.Validate(); err != nil {
+		return err
+	}
+
+	manager.sessionsMu.Lock()
+
+	session, ok := manager.sessions[id]
+	if !ok {
+		manager.sessionsMu.Unlock()
+		return types.ErrSessionNotFound
+	}
+
+	old := session.setMetadata(metadata)
+	manager.sessionsMu.Unlock()
+
+	manager.emmiter.Emit("metadata_changed", session, maps.Clone(metadata), old)
+	manager.save()
+
+	return nil
+}
+
 func (manager *SessionManagerCtx) Delete(id string) error {
 	manager.sessionsMu.Lock()
 	session, ok := manager.sessions[id]
@@ -155,13 +216,15 @@ func (manager *SessionManagerCtx) Delete(id string) error {
 	delete(manager.sessions, id)
 	manager.sessionsMu.Unlock()
 
+	manager.resumeTokenDelete(session)
+	manager.auditDelete(id)
+
 	if session.State().IsConnected {
 		session.DestroyWebSocketPeer("session deleted")
 	}
 
-	if session.State().IsWatching {
-		session.GetWebRTCPeer().Destroy()
-	}
+	session.destroyWebRTCPeer()
+	manager.controlQueueRemove(session)
 
 	manager.emmiter.Emit("deleted", session)
 	manager.save()
@@ -182,9 +245,7 @@ func (manager *SessionManagerCtx) Disconnect(id string) error {
 		session.DestroyWebSocketPeer("session disconnected")
 	}
 
-	if session.State().IsWatching {
-		session.GetWebRTCPeer().Destroy()
-	}
+	session.destroyWebRTCPeer()
 
 	return nil
 }
@@ -247,7 +308,9 @@ func (manager *SessionManagerCtx) setHost(session, host types.Session) {
 		hostId = host.ID()
 	}
 
-	manager.hostId.Store(hostId)
+	prevHostId, _ := manager.hostId.Swap(hostId).(string)
+	manager.auditHost(session, prevHostId, hostId)
+
 	manager.emmiter.Emit("host_changed", session, host)
 }
 
@@ -378,6 +441,12 @@ func (manager *SessionManagerCtx) OnProfileChanged(listener func(session types.S
 	})
 }
 
+func (manager *SessionManagerCtx) OnMetadataChanged(listener func(session types.Session, new, old types.SessionMetadata)) {
+	manager.emmiter.On("metadata_changed", func(payload ...any) {
+		listener(payload[0].(*SessionCtx), payload[1].(types.SessionMetadata), payload[2].(types.SessionMetadata))
+	})
+}
+
 func (manager *SessionManagerCtx) OnStateChanged(listener func(session types.Session)) {
 	manager.emmiter.On("state_changed", func(payload ...any) {
 		listener(payload[0].(*SessionCtx))
@@ -400,6 +469,24 @@ func (manager *SessionManagerCtx) OnSettingsChanged(listener func(session types.
 	})
 }
 
+func (manager *SessionManagerCtx) OnDrainingChanged(listener func(draining bool)) {
+	manager.emmiter.On("draining_changed", func(payload ...any) {
+		listener(payload[0].(bool))
+	})
+}
+
+func (manager *SessionManagerCtx) OnBannerChanged(listener func(banner *types.Banner)) {
+	manager.emmiter.On("banner_changed", func(payload ...any) {
+		listener(payload[0].(*types.Banner))
+	})
+}
+
+func (manager *SessionManagerCtx) OnPresentationChanged(listener func(presentation *types.Presentation)) {
+	manager.emmiter.On("presentation_changed", func(payload ...any) {
+		listener(payload[0].(*types.Presentation))
+	})
+}
+
 // ---
 // settings
 // ---
@@ -411,6 +498,7 @@ func (manager *SessionManagerCtx) UpdateSettingsFunc(session types.Session, f fu
 		old := manager.settings
 		manager.settings = new
 		manager.settingsMu.Unlock()
+		manager.auditSettings(session, new, old)
 		manager.updateSettings(session, new, old)
 		return
 	}
@@ -431,7 +519,7 @@ func (manager *SessionManagerCtx) updateSettings(session types.Session, new, old
 
 			// its webrtc connection will be paused or unpaused
 			if webrtcPeer := s.GetWebRTCPeer(); webrtcPeer != nil {
-				webrtcPeer.SetPaused(enabled)
+				webrtcPeer.SetPaused(s.StreamPaused())
 			}
 		}
 	}
@@ -479,6 +567,74 @@ func (manager *SessionManagerCtx) CookieEnabled() bool {
 	return manager.config.Cookie.Enabled
 }
 
+// ---
+// draining
+// ---
+
+func (manager *SessionManagerCtx) SetDraining(draining bool) {
+	if manager.draining.Swap(draining) == draining {
+		return
+	}
+
+	manager.logger.Info().Bool("draining", draining).Msg("draining changed")
+	manager.emmiter.Emit("draining_changed", draining)
+}
+
+func (manager *SessionManagerCtx) Draining() bool {
+	return manager.draining.Load()
+}
+
+// ---
+// banner
+// ---
+
+func (manager *SessionManagerCtx) SetBanner(banner *types.Banner) {
+	if manager.banner.Swap(banner) == nil && banner == nil {
+		return
+	}
+
+	if banner != nil {
+		manager.logger.Info().Str("message", banner.Message).Str("level", string(banner.Level)).Msg("banner set")
+	} else {
+		manager.logger.Info().Msg("banner cleared")
+	}
+
+	manager.emmiter.Emit("banner_changed", banner)
+}
+
+func (manager *SessionManagerCtx) Banner() *types.Banner {
+	return manager.banner.Load()
+}
+
+// ---
+// presentation
+// ---
+
+func (manager *SessionManagerCtx) SetPresentation(presentation *types.Presentation) {
+	if manager.presentation.Swap(presentation) == nil && presentation == nil {
+		return
+	}
+
+	if presentation != nil {
+		manager.logger.Info().Str("presenter", presentation.Presenter).Strs("targets", presentation.Targets).Msg("presentation started")
+	} else {
+		manager.logger.Info().Msg("presentation stopped")
+	}
+
+	// sessions outside of presentation are paused, the others are resumed
+	for _, s := range manager.List() {
+		if webrtcPeer := s.GetWebRTCPeer(); webrtcPeer != nil {
+			webrtcPeer.SetPaused(s.StreamPaused())
+		}
+	}
+
+	manager.emmiter.Emit("presentation_changed", presentation)
+}
+
+func (manager *SessionManagerCtx) Presentation() *types.Presentation {
+	return manager.presentation.Load()
+}
+
 // ---
 // stats
 // ---
@@ -507,6 +663,7 @@ func (manager *SessionManagerCtx) Stats() types.Stats {
 		ServerStartedAt: manager.serverStartedAt,
 		TotalUsers:      int(manager.totalUsers.Load()),
 		LastUserLeftAt:  lastUserLeftAt,
+		Draining:        manager.draining.Load(),
 		TotalAdmins:     int(manager.totalAdmins.Load()),
 		LastAdminLeftAt: lastAdminLeftAt,
 	}
@@ -0,0 +1,46 @@
+package session
+
+import (
+	"crypto/subtle"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+// ResumeTokenCreate issues new resume token for the session, replacing the previous one.
+func (manager *SessionManagerCtx) ResumeTokenCreate(session types.Session) (string, error) {
+	if manager.config.ResumeGrace <= 0 {
+		return "", types.ErrSessionResumeDisabled
+	}
+
+	token, err := utils.NewUID(32)
+	if err != nil {
+		return "", err
+	}
+
+	manager.resumeTokensMu.Lock()
+	manager.resumeTokens[session.ID()] = token
+	manager.resumeTokensMu.Unlock()
+
+	return token, nil
+}
+
+// ResumeTokenVerify checks if the token belongs to the session and its webrtc peer still exists.
+func (manager *SessionManagerCtx) ResumeTokenVerify(session types.Session, token string) bool {
+	manager.resumeTokensMu.Lock()
+	expected, ok := manager.resumeTokens[session.ID()]
+	manager.resumeTokensMu.Unlock()
+
+	if !ok || session.GetWebRTCPeer() == nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1
+}
+
+// resumeTokenDelete revokes resume token, when its webrtc peer is gone.
+func (manager *SessionManagerCtx) resumeTokenDelete(session types.Session) {
+	manager.resumeTokensMu.Lock()
+	delete(manager.resumeTokens, session.ID())
+	manager.resumeTokensMu.Unlock()
+}
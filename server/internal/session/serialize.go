@@ -17,9 +17,10 @@ func (manager *SessionManagerCtx) save() {
 	sessions := make([]types.SessionProfile, 0, len(manager.sessions))
 	for _, session := range manager.sessions {
 		sessions = append(sessions, types.SessionProfile{
-			Id:      session.id,
-			Token:   session.token,
-			Profile: session.profile,
+			Id:       session.id,
+			Token:    session.token,
+			Profile:  session.profile,
+			Metadata: session.Metadata(),
 		})
 	}
 
@@ -81,11 +82,12 @@ func (manager *SessionManagerCtx) load() {
 	for _, session := range sessions {
 		manager.tokens[session.Token] = session.Id
 		manager.sessions[session.Id] = &SessionCtx{
-			id:      session.Id,
-			token:   session.Token,
-			manager: manager,
-			logger:  manager.logger.With().Str("session_id", session.Id).Logger(),
-			profile: session.Profile,
+			id:       session.Id,
+			token:    session.Token,
+			manager:  manager,
+			logger:   manager.logger.With().Str("session_id", session.Id).Logger(),
+			profile:  session.Profile,
+			metadata: session.Metadata,
 		}
 	}
 	manager.sessionsMu.Unlock()
@@ -1,7 +1,9 @@
 package session
 
 import (
+	"maps"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -12,7 +14,7 @@ import (
 
 // client is expected to reconnect within 5 second
 // if some unexpected websocket disconnect happens
-const WS_DELAYED_DURATION = 5 * time.Second
+var WS_DELAYED_DURATION = 5 * time.Second
 
 type SessionCtx struct {
 	id      string
@@ -22,15 +24,31 @@ type SessionCtx struct {
 	profile types.MemberProfile
 	state   types.SessionState
 
+	metadata   types.SessionMetadata
+	metadataMu sync.Mutex
+
 	websocketPeer types.WebSocketPeer
 	websocketMu   sync.Mutex
+	// negotiated websocket protocol version
+	protocolVersion int
 
 	// websocket delayed set connected events
 	wsDelayedMu    sync.Mutex
 	wsDelayedTimer *time.Timer
+	// webrtc peer teardown after websocket is gone
+	teardownTimer *time.Timer
 
 	webrtcPeer types.WebRTCPeer
 	webrtcMu   sync.Mutex
+
+	keyboardMap   *types.KeyboardMap
+	keyboardMapMu sync.Mutex
+
+	// client window lost focus, zero value is focused
+	blurred atomic.Bool
+
+	usage   types.SessionUsage
+	usageMu sync.Mutex
 }
 
 func (session *SessionCtx) ID() string {
@@ -50,9 +68,7 @@ func (session *SessionCtx) profileChanged() {
 		// TODO: Needed for legacy implementation. Websocket must die before webrtc and deliver signal close message
 		// otherwise webrtc destroy would trigger websocket reconnect. In case of kick event, webrtc destroy is called
 		// before websocket destroy that delivers the information about the kick.
-		time.AfterFunc(time.Second, func() {
-			session.GetWebRTCPeer().Destroy()
-		})
+		time.AfterFunc(time.Second, session.destroyWebRTCPeer)
 	}
 
 	if (!session.profile.CanConnect || !session.profile.CanLogin) && session.state.IsConnected {
@@ -61,7 +77,7 @@ func (session *SessionCtx) profileChanged() {
 
 	// update webrtc paused state
 	if webrtcPeer := session.GetWebRTCPeer(); webrtcPeer != nil {
-		webrtcPeer.SetPaused(session.PrivateModeEnabled())
+		webrtcPeer.SetPaused(session.StreamPaused())
 	}
 }
 
@@ -69,6 +85,24 @@ func (session *SessionCtx) State() types.SessionState {
 	return session.state
 }
 
+// Metadata returns a copy, so that it can be used without holding the lock
+func (session *SessionCtx) Metadata() types.SessionMetadata {
+	session.metadataMu.Lock()
+	defer session.metadataMu.Unlock()
+
+	return maps.Clone(session.metadata)
+}
+
+// setMetadata stores a copy of the metadata and returns the previous one
+func (session *SessionCtx) setMetadata(metadata types.SessionMetadata) types.SessionMetadata {
+	session.metadataMu.Lock()
+	defer session.metadataMu.Unlock()
+
+	old := session.metadata
+	session.metadata = maps.Clone(metadata)
+	return old
+}
+
 func (session *SessionCtx) IsHost() bool {
 	return session.manager.isHost(session)
 }
@@ -95,12 +129,86 @@ func (session *SessionCtx) PrivateModeEnabled() bool {
 	return session.manager.Settings().PrivateMode && !session.profile.IsAdmin
 }
 
+func (session *SessionCtx) StreamPaused() bool {
+	if session.profile.IsAdmin {
+		return false
+	}
+
+	if session.manager.Settings().PrivateMode {
+		return true
+	}
+
+	presentation := session.manager.Presentation()
+	return presentation != nil && !presentation.Includes(session.id)
+}
+
+func (session *SessionCtx) ResumeGrace() time.Duration {
+	return session.manager.config.ResumeGrace
+}
+
 func (session *SessionCtx) SetCursor(cursor types.Cursor) {
 	if session.manager.Settings().InactiveCursors && session.profile.SendsInactiveCursor {
 		session.manager.SetCursor(cursor, session)
 	}
 }
 
+// ---
+// usage
+// ---
+
+func (session *SessionCtx) AddUsage(sent, received uint64) types.SessionUsage {
+	session.usageMu.Lock()
+	defer session.usageMu.Unlock()
+
+	if session.usage.Since.IsZero() {
+		session.usage.Since = time.Now()
+	}
+
+	session.usage.BytesSent += sent
+	session.usage.BytesReceived += received
+	return session.usage
+}
+
+// ResetUsage starts a new accounting period
+func (session *SessionCtx) ResetUsage() {
+	session.usageMu.Lock()
+	defer session.usageMu.Unlock()
+
+	session.usage = types.SessionUsage{
+		Since: time.Now(),
+	}
+}
+
+func (session *SessionCtx) SetQuotaExceeded(exceeded bool) {
+	session.usageMu.Lock()
+	defer session.usageMu.Unlock()
+
+	session.usage.QuotaExceeded = exceeded
+}
+
+func (session *SessionCtx) Usage() types.SessionUsage {
+	session.usageMu.Lock()
+	defer session.usageMu.Unlock()
+
+	return session.usage
+}
+
+// ---
+// focus
+// ---
+
+func (session *SessionCtx) SetFocused(focused bool) {
+	if session.blurred.Swap(!focused) == !focused {
+		return
+	}
+
+	session.logger.Debug().Bool("focused", focused).Msg("set focused")
+}
+
+func (session *SessionCtx) Focused() bool {
+	return !session.blurred.Load()
+}
+
 // ---
 // websocket
 // ---
@@ -120,6 +228,17 @@ func (session *SessionCtx) ConnectWebSocketPeer(websocketPeer types.WebSocketPee
 
 	session.logger.Info().Msg("set websocket connected")
 
+	// client is back, keep its webrtc peer
+	session.wsDelayedMu.Lock()
+	if session.teardownTimer != nil {
+		session.teardownTimer.Stop()
+		session.teardownTimer = nil
+	}
+	session.wsDelayedMu.Unlock()
+
+	// new client starts focused
+	session.blurred.Store(false)
+
 	// update state
 	now := time.Now()
 	session.state.IsConnected = true
@@ -203,6 +322,7 @@ func (session *SessionCtx) DisconnectWebSocketPeer(websocketPeer types.WebSocket
 		}
 	}
 
+	session.manager.controlQueueRemove(session)
 	session.manager.emmiter.Emit("disconnected", session)
 
 	session.websocketMu.Lock()
@@ -210,6 +330,31 @@ func (session *SessionCtx) DisconnectWebSocketPeer(websocketPeer types.WebSocket
 		session.websocketPeer = nil
 	}
 	session.websocketMu.Unlock()
+
+	session.teardownWebRTCPeer()
+}
+
+// Teardown WebRTC peer destroys the peer, when websocket connection is gone. If resuming is
+// enabled, the peer is kept for the grace period so that the client can reconnect and resume.
+func (session *SessionCtx) teardownWebRTCPeer() {
+	grace := session.ResumeGrace()
+	if grace <= 0 {
+		session.destroyWebRTCPeer()
+		return
+	}
+
+	timer := time.AfterFunc(grace, func() {
+		if !session.State().IsConnected {
+			session.destroyWebRTCPeer()
+		}
+	})
+
+	session.wsDelayedMu.Lock()
+	if session.teardownTimer != nil {
+		session.teardownTimer.Stop()
+	}
+	session.teardownTimer = timer
+	session.wsDelayedMu.Unlock()
 }
 
 // Destroy WebSocket peer disconnects the peer and destroys it. It ensures that the peer is
@@ -230,6 +375,36 @@ func (session *SessionCtx) DestroyWebSocketPeer(reason string) {
 	peer.Destroy(reason)
 }
 
+func (session *SessionCtx) RemoteAddr() string {
+	session.websocketMu.Lock()
+	peer := session.websocketPeer
+	session.websocketMu.Unlock()
+
+	if peer == nil {
+		return ""
+	}
+
+	return peer.RemoteAddr()
+}
+
+func (session *SessionCtx) SetProtocolVersion(version int) {
+	session.websocketMu.Lock()
+	defer session.websocketMu.Unlock()
+
+	session.protocolVersion = version
+}
+
+func (session *SessionCtx) ProtocolVersion() int {
+	session.websocketMu.Lock()
+	defer session.websocketMu.Unlock()
+
+	if session.protocolVersion == 0 {
+		return types.WebSocketProtocolV1
+	}
+
+	return session.protocolVersion
+}
+
 // Send event to websocket peer.
 func (session *SessionCtx) Send(event string, payload any) {
 	session.websocketMu.Lock()
@@ -256,6 +431,28 @@ func (session *SessionCtx) SetWebRTCPeer(webrtcPeer types.WebRTCPeer) {
 	}
 }
 
+// Destroy WebRTC peer detaches the current peer from the session and destroys it. Because the
+// peer is detached under lock, it is destroyed exactly once even if called concurrently.
+func (session *SessionCtx) destroyWebRTCPeer() {
+	session.webrtcMu.Lock()
+	webrtcPeer := session.webrtcPeer
+	session.webrtcPeer = nil
+	session.webrtcMu.Unlock()
+
+	if webrtcPeer == nil {
+		return
+	}
+
+	session.manager.resumeTokenDelete(session)
+
+	if session.state.IsWatching {
+		session.setWatching(false)
+	}
+
+	webrtcPeer.Destroy()
+	session.Send(event.SIGNAL_CLOSE, nil)
+}
+
 // Set if current webrtc peer is connected or not. Since there might be lefover calls from
 // webrtc peer, that are not used anymore, we need to check if the webrtc peer is still the
 // same as the one we are setting the connected state for.
@@ -271,21 +468,7 @@ func (session *SessionCtx) SetWebRTCConnected(webrtcPeer types.WebRTCPeer, conne
 		return
 	}
 
-	session.logger.Info().
-		Bool("connected", connected).
-		Msg("set webrtc connected")
-
-	// update state
-	session.state.IsWatching = connected
-	if now := time.Now(); connected {
-		session.state.WatchingSince = &now
-		session.state.NotWatchingSince = nil
-	} else {
-		session.state.WatchingSince = nil
-		session.state.NotWatchingSince = &now
-	}
-
-	session.manager.emmiter.Emit("state_changed", session)
+	session.setWatching(connected)
 
 	if connected {
 		return
@@ -299,10 +482,29 @@ func (session *SessionCtx) SetWebRTCConnected(webrtcPeer types.WebRTCPeer, conne
 	session.webrtcMu.Unlock()
 
 	if isCurrentPeer {
+		session.manager.resumeTokenDelete(session)
 		session.Send(event.SIGNAL_CLOSE, nil)
 	}
 }
 
+func (session *SessionCtx) setWatching(connected bool) {
+	session.logger.Info().
+		Bool("connected", connected).
+		Msg("set webrtc connected")
+
+	// update state
+	session.state.IsWatching = connected
+	if now := time.Now(); connected {
+		session.state.WatchingSince = &now
+		session.state.NotWatchingSince = nil
+	} else {
+		session.state.WatchingSince = nil
+		session.state.NotWatchingSince = &now
+	}
+
+	session.manager.emmiter.Emit("state_changed", session)
+}
+
 // Get current WebRTC peer. Nil if not connected.
 func (session *SessionCtx) GetWebRTCPeer() types.WebRTCPeer {
 	session.webrtcMu.Lock()
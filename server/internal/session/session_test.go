@@ -0,0 +1,178 @@
+package session
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// livePeers counts webrtc peers, that were created but not destroyed yet
+type livePeers struct {
+	live      atomic.Int32
+	destroyed atomic.Int32
+}
+
+func (l *livePeers) newPeer() *testWebRTCPeer {
+	l.live.Add(1)
+	return &testWebRTCPeer{peers: l}
+}
+
+type testWebRTCPeer struct {
+	types.WebRTCPeer
+
+	peers *livePeers
+}
+
+func (peer *testWebRTCPeer) Destroy() {
+	peer.peers.live.Add(-1)
+	peer.peers.destroyed.Add(1)
+}
+
+type testWebSocketPeer struct {
+	types.WebSocketPeer
+}
+
+func (peer *testWebSocketPeer) Send(event string, payload any) {}
+
+func (peer *testWebSocketPeer) Destroy(reason string) {}
+
+func newTestSession(t *testing.T, conf config.Session) *SessionCtx {
+	t.Helper()
+
+	manager := New(&conf)
+
+	session, _, err := manager.Create("test", types.MemberProfile{
+		CanLogin:   true,
+		CanConnect: true,
+		CanWatch:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return session.(*SessionCtx)
+}
+
+// setDelayedDuration shortens delayed websocket disconnect for the duration of the test
+func setDelayedDuration(t *testing.T, d time.Duration) {
+	t.Helper()
+
+	prev := WS_DELAYED_DURATION
+	WS_DELAYED_DURATION = d
+	t.Cleanup(func() {
+		WS_DELAYED_DURATION = prev
+	})
+}
+
+// waitFor polls condition until it is met, instead of sleeping for a fixed time
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSessionCtx_WebRTCPeerTeardown(t *testing.T) {
+	setDelayedDuration(t, time.Millisecond)
+
+	tests := []struct {
+		name  string
+		conf  config.Session
+		close func(session *SessionCtx, ws types.WebSocketPeer)
+	}{
+		{
+			name: "normal websocket close",
+			close: func(session *SessionCtx, ws types.WebSocketPeer) {
+				session.DisconnectWebSocketPeer(ws, false)
+			},
+		}, {
+			name: "abnormal websocket close",
+			close: func(session *SessionCtx, ws types.WebSocketPeer) {
+				session.DisconnectWebSocketPeer(ws, true)
+			},
+		}, {
+			name: "websocket close with resume grace",
+			conf: config.Session{ResumeGrace: time.Millisecond},
+			close: func(session *SessionCtx, ws types.WebSocketPeer) {
+				session.DisconnectWebSocketPeer(ws, false)
+			},
+		}, {
+			name: "session deleted",
+			close: func(session *SessionCtx, ws types.WebSocketPeer) {
+				_ = session.manager.Delete(session.ID())
+			},
+		}, {
+			name: "concurrent teardown",
+			close: func(session *SessionCtx, ws types.WebSocketPeer) {
+				var wg sync.WaitGroup
+				for i := 0; i < 10; i++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						session.destroyWebRTCPeer()
+					}()
+				}
+				wg.Wait()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := newTestSession(t, tt.conf)
+			peers := &livePeers{}
+
+			ws := &testWebSocketPeer{}
+			session.ConnectWebSocketPeer(ws)
+			session.SetWebRTCPeer(peers.newPeer())
+
+			tt.close(session, ws)
+			waitFor(t, func() bool {
+				return peers.destroyed.Load() > 0 && session.GetWebRTCPeer() == nil
+			})
+
+			if live := peers.live.Load(); live != 0 {
+				t.Errorf("live peers = %d, want 0", live)
+			}
+			if destroyed := peers.destroyed.Load(); destroyed != 1 {
+				t.Errorf("Destroy() called %d times, want 1", destroyed)
+			}
+			if peer := session.GetWebRTCPeer(); peer != nil {
+				t.Errorf("GetWebRTCPeer() = %v, want nil", peer)
+			}
+		})
+	}
+}
+
+func TestSessionCtx_WebRTCPeerResumed(t *testing.T) {
+	// grace period never elapses during the test, reconnect must cancel it
+	session := newTestSession(t, config.Session{ResumeGrace: time.Minute})
+	peers := &livePeers{}
+
+	ws := &testWebSocketPeer{}
+	session.ConnectWebSocketPeer(ws)
+	session.SetWebRTCPeer(peers.newPeer())
+
+	// client reconnects within resume grace period
+	session.DisconnectWebSocketPeer(ws, false)
+	session.ConnectWebSocketPeer(&testWebSocketPeer{})
+
+	session.wsDelayedMu.Lock()
+	teardownTimer := session.teardownTimer
+	session.wsDelayedMu.Unlock()
+
+	if teardownTimer != nil {
+		t.Error("teardown timer was not cancelled on reconnect")
+	}
+	if live := peers.live.Load(); live != 1 {
+		t.Errorf("live peers = %d, want 1", live)
+	}
+}
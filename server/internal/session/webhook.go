@@ -0,0 +1,118 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// delay before first retry, doubled after every attempt
+const webhookRetryDelay = time.Second
+
+type webhookPayload struct {
+	Event     string                `json:"event"`
+	SessionId string                `json:"session_id"`
+	Name      string                `json:"name"`
+	Metadata  types.SessionMetadata `json:"metadata,omitempty"`
+	Timestamp time.Time             `json:"timestamp"`
+}
+
+// webhook queues the event without blocking, events of a session are delivered
+// in order, so that a retried request is never overtaken by a later event.
+func (manager *SessionManagerCtx) webhook(event string, session types.Session) {
+	payload := webhookPayload{
+		Event:     event,
+		SessionId: session.ID(),
+		Name:      session.Profile().Name,
+		Metadata:  session.Metadata(),
+		Timestamp: time.Now(),
+	}
+
+	id := session.ID()
+
+	manager.webhooksMu.Lock()
+	pending, running := manager.webhooks[id]
+	manager.webhooks[id] = append(pending, payload)
+	manager.webhooksMu.Unlock()
+
+	if !running {
+		go manager.webhookWorker(id)
+	}
+}
+
+// webhookWorker delivers pending payloads of the session, until there are none
+func (manager *SessionManagerCtx) webhookWorker(id string) {
+	for {
+		manager.webhooksMu.Lock()
+		pending := manager.webhooks[id]
+		if len(pending) == 0 {
+			delete(manager.webhooks, id)
+			manager.webhooksMu.Unlock()
+			return
+		}
+		payload := pending[0]
+		manager.webhooks[id] = pending[1:]
+		manager.webhooksMu.Unlock()
+
+		manager.webhookDeliver(payload)
+	}
+}
+
+func (manager *SessionManagerCtx) webhookDeliver(payload webhookPayload) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		manager.logger.Err(err).Msg("unable to marshal webhook payload")
+		return
+	}
+
+	logger := manager.logger.With().
+		Str("event", payload.Event).
+		Str("session_id", payload.SessionId).
+		Logger()
+
+	delay := webhookRetryDelay
+	for attempt := 0; attempt <= manager.config.Webhook.Retries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(delay)
+			delay *= 2
+		}
+
+		err = manager.webhookRequest(data)
+		if err == nil {
+			logger.Debug().Int("attempt", attempt).Msg("webhook delivered")
+			return
+		}
+
+		logger.Warn().Err(err).Int("attempt", attempt).Msg("webhook request failed")
+	}
+
+	logger.Error().Err(err).Msg("webhook delivery failed, giving up")
+}
+
+func (manager *SessionManagerCtx) webhookRequest(data []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), manager.config.Webhook.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, manager.config.Webhook.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return nil
+}
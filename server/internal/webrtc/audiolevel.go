@@ -0,0 +1,132 @@
+package webrtc
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtp"
+	"github.com/pion/sdp/v3"
+
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// audio louder than this level in dB is considered as sound activity
+const audioActivityThreshold = -50.0
+
+// audioLevelInterceptor adds ssrc-audio-level header extension (RFC 6464)
+// to outgoing audio packets, if it was negotiated with the client.
+type audioLevelInterceptor struct {
+	interceptor.NoOp
+
+	mu    sync.Mutex
+	level func() (float64, bool)
+}
+
+// NewInterceptor implements interceptor.Factory, a new instance is created for every peer connection.
+func (i *audioLevelInterceptor) NewInterceptor(_ string) (interceptor.Interceptor, error) {
+	return i, nil
+}
+
+// setSource sets level getter, it is known only after the peer was created
+func (i *audioLevelInterceptor) setSource(level func() (float64, bool)) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.level = level
+}
+
+func (i *audioLevelInterceptor) get() (float64, bool) {
+	i.mu.Lock()
+	level := i.level
+	i.mu.Unlock()
+
+	if level == nil {
+		return 0, false
+	}
+
+	return level()
+}
+
+func (i *audioLevelInterceptor) BindLocalStream(info *interceptor.StreamInfo, writer interceptor.RTPWriter) interceptor.RTPWriter {
+	var id uint8
+	for _, ext := range info.RTPHeaderExtensions {
+		if ext.URI == sdp.AudioLevelURI {
+			id = uint8(ext.ID)
+		}
+	}
+
+	// not negotiated or not an audio stream
+	if id == 0 {
+		return writer
+	}
+
+	return interceptor.RTPWriterFunc(func(header *rtp.Header, payload []byte, attributes interceptor.Attributes) (int, error) {
+		if level, ok := i.get(); ok {
+			// level is expressed in -dBov, from 0 (loudest) to 127 (silence)
+			ext, err := rtp.AudioLevelExtension{
+				Level: uint8(min(max(-math.Round(level), 0), 127)),
+				Voice: level > audioActivityThreshold,
+			}.Marshal()
+			if err == nil {
+				_ = header.SetExtension(id, ext)
+			}
+		}
+
+		return writer.Write(header, payload, attributes)
+	})
+}
+
+// audioLevel returns level of audio stream sent to the peer
+func (peer *WebRTCPeerCtx) audioLevel() (float64, bool) {
+	if peer.audioTrack == nil {
+		return 0, false
+	}
+
+	stream, ok := peer.audioTrack.Stream()
+	if !ok {
+		return 0, false
+	}
+
+	return stream.Level()
+}
+
+// audioLevelReporter sends audio level to the client, at most once per interval
+// and only when it changed, while the peer receives audio
+func (peer *WebRTCPeerCtx) audioLevelReporter(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var last *message.AudioLevel
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		peer.mu.Lock()
+		muted := peer.audioDisabled || peer.paused
+		peer.mu.Unlock()
+
+		level, ok := peer.audioLevel()
+		if muted || !ok {
+			continue
+		}
+
+		data := message.AudioLevel{
+			Level:  math.Round(level),
+			Active: level > audioActivityThreshold,
+		}
+
+		if last != nil && *last == data {
+			continue
+		}
+		last = &data
+
+		peer.session.Send(event.AUDIO_LEVEL, data)
+	}
+}
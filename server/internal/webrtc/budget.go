@@ -0,0 +1,56 @@
+package webrtc
+
+import (
+	"sync"
+)
+
+// bandwidthBudget is a global video bitrate budget shared by all peers,
+// bandwidth is allocated to sessions with higher priority first.
+type bandwidthBudget struct {
+	total int
+
+	mu    sync.Mutex
+	peers map[*WebRTCPeerCtx]struct{}
+}
+
+func newBandwidthBudget(total int) *bandwidthBudget {
+	return &bandwidthBudget{
+		total: total,
+		peers: map[*WebRTCPeerCtx]struct{}{},
+	}
+}
+
+func (b *bandwidthBudget) add(peer *WebRTCPeerCtx) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.peers[peer] = struct{}{}
+}
+
+func (b *bandwidthBudget) remove(peer *WebRTCPeerCtx) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.peers, peer)
+}
+
+// allowance returns bitrate available to the peer, that is the budget without bitrate
+// used by other peers with the same or higher priority. Peers with lower priority
+// are not taken into account, because they must yield when the budget is exceeded.
+func (b *bandwidthBudget) allowance(peer *WebRTCPeerCtx) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	priority := peer.session.Profile().Priority
+
+	allowance := b.total
+	for p := range b.peers {
+		if p == peer || p.session.Profile().Priority < priority {
+			continue
+		}
+
+		allowance -= p.videoBitrate()
+	}
+
+	return allowance
+}
@@ -0,0 +1,80 @@
+package webrtc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync/atomic"
+
+	"github.com/m1k1o/neko/server/internal/webrtc/payload"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// max size of clipboard text in a single data channel message
+const clipboardChunkSize = 60000
+
+// SendClipboard sends clipboard text over the data channel, if the client requested
+// it and the channel has a send queue, where clipboard yields to input related
+// messages. It returns false, when clipboard must be sent over websocket instead.
+func (peer *WebRTCPeerCtx) SendClipboard(data types.ClipboardText) (bool, error) {
+	if !peer.clipboardChannel {
+		return false, nil
+	}
+
+	// without send queue, clipboard would delay input in the ordered channel
+	_, queue := peer.getDataChannel()
+	if queue == nil {
+		return false, nil
+	}
+
+	id := atomic.AddUint32(&peer.clipboardSerial, 1)
+	text := []byte(data.Text)
+
+	// empty clipboard is sent as a single empty chunk
+	messages := [][]byte{}
+	for offset := 0; ; offset += clipboardChunkSize {
+		chunk := text[offset:min(offset+clipboardChunkSize, len(text))]
+
+		header := payload.Header{
+			Event:  payload.OP_CLIPBOARD,
+			Length: uint16(16 + len(chunk)),
+		}
+
+		clipboard := payload.Clipboard{
+			ID:        id,
+			Offset:    uint32(offset),
+			Total:     uint32(len(text)),
+			Truncated: data.Truncated,
+		}
+
+		buffer := &bytes.Buffer{}
+
+		if err := binary.Write(buffer, binary.BigEndian, header); err != nil {
+			return false, err
+		}
+
+		if err := binary.Write(buffer, binary.BigEndian, clipboard); err != nil {
+			return false, err
+		}
+
+		if err := binary.Write(buffer, binary.BigEndian, chunk); err != nil {
+			return false, err
+		}
+
+		message, err := peer.sealData(buffer.Bytes())
+		if err != nil {
+			return false, err
+		}
+		messages = append(messages, message)
+
+		if offset+len(chunk) >= len(text) {
+			break
+		}
+	}
+
+	// chunks are queued all at once, so that client never receives partial clipboard
+	if err := queue.SendAll(payload.OP_CLIPBOARD, messages); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
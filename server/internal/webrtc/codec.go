@@ -0,0 +1,198 @@
+package webrtc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/codec"
+)
+
+// audioEncodingOptions returns opus encoder settings requested by peer.
+func audioEncodingOptions(options types.PeerOptions) types.AudioEncoding {
+	encoding := types.DefaultAudioEncoding
+	if options.AudioFEC != nil {
+		encoding.InbandFEC = *options.AudioFEC
+	}
+	if options.AudioDTX != nil {
+		encoding.DTX = *options.AudioDTX
+	}
+	return encoding
+}
+
+// applyAudioEncoding sets opus fmtp parameters matching the encoder settings.
+func applyAudioEncoding(audioCodec codec.RTPCodec, encoding types.AudioEncoding) codec.RTPCodec {
+	if audioCodec.Name != codec.Opus().Name {
+		return audioCodec
+	}
+
+	audioCodec.Capability.SDPFmtpLine = setFmtpParam(audioCodec.Capability.SDPFmtpLine, "useinbandfec", encoding.InbandFEC)
+	audioCodec.Capability.SDPFmtpLine = setFmtpParam(audioCodec.Capability.SDPFmtpLine, "usedtx", encoding.DTX)
+	return audioCodec
+}
+
+// setFmtpParam sets boolean parameter in fmtp line, replacing existing value.
+func setFmtpParam(line, key string, enabled bool) string {
+	value := "0"
+	if enabled {
+		value = "1"
+	}
+
+	params := []string{}
+	for _, param := range strings.Split(line, ";") {
+		param = strings.TrimSpace(param)
+		if param == "" || strings.HasPrefix(param, key+"=") {
+			continue
+		}
+		params = append(params, param)
+	}
+
+	return strings.Join(append(params, key+"="+value), ";")
+}
+
+// codecTrack is a local track with a single codec, e.g. TrackLocalStaticSample.
+type codecTrack interface {
+	Codec() webrtc.RTPCodecCapability
+}
+
+// codecMismatch is a sending transceiver, whose codec is missing in the remote description.
+type codecMismatch struct {
+	Kind   string
+	Mid    string
+	Codec  string
+	Remote []string
+}
+
+// findCodecMismatches inspects transceivers that send a track, media would
+// never flow if the remote description rejected or omitted their codec.
+func findCodecMismatches(transceivers []*webrtc.RTPTransceiver, remote *webrtc.SessionDescription) ([]codecMismatch, error) {
+	parsed, err := remote.Unmarshal()
+	if err != nil {
+		return nil, err
+	}
+
+	sections := map[string]*sdp.MediaDescription{}
+	for _, media := range parsed.MediaDescriptions {
+		if mid, ok := media.Attribute(sdp.AttrKeyMID); ok {
+			sections[mid] = media
+		}
+	}
+
+	mismatches := []codecMismatch{}
+	for _, tr := range transceivers {
+		sender := tr.Sender()
+		if sender == nil || sender.Track() == nil {
+			continue
+		}
+
+		track, ok := sender.Track().(codecTrack)
+		if !ok {
+			continue
+		}
+
+		// section not negotiated yet
+		media, ok := sections[tr.Mid()]
+		if !ok {
+			continue
+		}
+
+		// mime type is e.g. video/VP8
+		_, name, _ := strings.Cut(track.Codec().MimeType, "/")
+
+		// rejected section has zero port and carries no codecs
+		remoteCodecs := []string{}
+		if media.MediaName.Port.Value != 0 {
+			for _, attr := range media.Attributes {
+				if attr.Key != "rtpmap" {
+					continue
+				}
+				_, encoding, _ := strings.Cut(attr.Value, " ")
+				codec, _, _ := strings.Cut(encoding, "/")
+				remoteCodecs = append(remoteCodecs, codec)
+			}
+		}
+
+		found := false
+		for _, codec := range remoteCodecs {
+			if strings.EqualFold(codec, name) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			mismatches = append(mismatches, codecMismatch{
+				Kind:   tr.Kind().String(),
+				Mid:    tr.Mid(),
+				Codec:  name,
+				Remote: remoteCodecs,
+			})
+		}
+	}
+
+	return mismatches, nil
+}
+
+func (m codecMismatch) String() string {
+	return fmt.Sprintf("%s (mid %s) offered %s, answered %v", m.Kind, m.Mid, m.Codec, m.Remote)
+}
+
+// negotiatedCodecs reads codec and header extensions agreed for sending
+// transceivers by track kind, codec is the one that the track is bound to.
+func negotiatedCodecs(transceivers []*webrtc.RTPTransceiver) map[string]types.PeerNegotiatedCodec {
+	codecs := map[string]types.PeerNegotiatedCodec{}
+	for _, tr := range transceivers {
+		sender := tr.Sender()
+		if sender == nil || sender.Track() == nil {
+			continue
+		}
+
+		track, ok := sender.Track().(codecTrack)
+		if !ok {
+			continue
+		}
+
+		params := sender.GetParameters()
+
+		// payload type is known once the track was bound, mime type is used until then
+		var payloadType webrtc.PayloadType
+		if len(params.Encodings) > 0 {
+			payloadType = params.Encodings[0].PayloadType
+		}
+
+		var negotiated *webrtc.RTPCodecParameters
+		for i, c := range params.Codecs {
+			if (payloadType != 0 && c.PayloadType == payloadType) ||
+				(payloadType == 0 && strings.EqualFold(c.MimeType, track.Codec().MimeType)) {
+				negotiated = &params.Codecs[i]
+				break
+			}
+		}
+		if negotiated == nil {
+			continue
+		}
+
+		extensions := make([]types.PeerHeaderExtension, len(params.HeaderExtensions))
+		for i, ext := range params.HeaderExtensions {
+			extensions[i] = types.PeerHeaderExtension{
+				ID:  ext.ID,
+				URI: ext.URI,
+			}
+		}
+
+		codecs[tr.Kind().String()] = types.PeerNegotiatedCodec{
+			Mid:              tr.Mid(),
+			MimeType:         negotiated.MimeType,
+			ClockRate:        negotiated.ClockRate,
+			Channels:         negotiated.Channels,
+			SDPFmtpLine:      negotiated.SDPFmtpLine,
+			PayloadType:      uint8(negotiated.PayloadType),
+			HeaderExtensions: extensions,
+		}
+	}
+
+	return codecs
+}
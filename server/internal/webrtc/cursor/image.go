@@ -1,6 +1,8 @@
 package cursor
 
 import (
+	"encoding/binary"
+	"hash/fnv"
 	"reflect"
 	"sync"
 
@@ -11,13 +13,14 @@ import (
 )
 
 type ImageListener interface {
-	SendCursorImage(cur *types.CursorImage, img []byte) error
+	// hash identifies the image together with its hotspot
+	SendCursorImage(cur *types.CursorImage, img []byte, hash uint64) error
 }
 
 type Image interface {
 	Start()
 	Shutdown()
-	GetCurrent() (cur *types.CursorImage, img []byte, err error)
+	GetCurrent() (cur *types.CursorImage, img []byte, hash uint64, err error)
 	AddListener(listener ImageListener)
 	RemoveListener(listener ImageListener)
 }
@@ -25,6 +28,7 @@ type Image interface {
 type imageEntry struct {
 	*types.CursorImage
 	ImagePNG []byte
+	Hash     uint64
 }
 
 type image struct {
@@ -37,16 +41,20 @@ type image struct {
 	cache     map[uint64]*imageEntry
 	cacheMu   sync.RWMutex
 	current   *imageEntry
+	currentMu sync.Mutex
 	maxSerial uint64
+	// cursor images bigger than this are downscaled, zero means no limit
+	maxSize int
 }
 
-func NewImage(logger zerolog.Logger, desktop types.DesktopManager) *image {
+func NewImage(logger zerolog.Logger, desktop types.DesktopManager, maxSize int) *image {
 	return &image{
 		logger:    logger.With().Str("submodule", "cursor-image").Logger(),
 		desktop:   desktop,
 		listeners: map[uintptr]ImageListener{},
 		cache:     map[uint64]*imageEntry{},
 		maxSerial: 300, // TODO: Cleanup?
+		maxSize:   maxSize,
 	}
 }
 
@@ -58,11 +66,19 @@ func (manager *image) Start() {
 			return
 		}
 
+		// skip sending identical consecutive cursors, only position changed
+		manager.currentMu.Lock()
+		if manager.current != nil && manager.current.Hash == entry.Hash {
+			manager.currentMu.Unlock()
+			manager.logger.Debug().Uint64("serial", serial).Msg("cursor image unchanged")
+			return
+		}
 		manager.current = entry
+		manager.currentMu.Unlock()
 
 		manager.listenersMu.RLock()
 		for _, l := range manager.listeners {
-			if err := l.SendCursorImage(entry.CursorImage, entry.ImagePNG); err != nil {
+			if err := l.SendCursorImage(entry.CursorImage, entry.ImagePNG, entry.Hash); err != nil {
 				manager.logger.Err(err).Msg("failed to set cursor image")
 			}
 		}
@@ -118,18 +134,21 @@ func (manager *image) getCached(serial uint64) (*imageEntry, error) {
 	return entry, nil
 }
 
-func (manager *image) GetCurrent() (cur *types.CursorImage, img []byte, err error) {
+func (manager *image) GetCurrent() (cur *types.CursorImage, img []byte, hash uint64, err error) {
+	manager.currentMu.Lock()
+	defer manager.currentMu.Unlock()
+
 	if manager.current != nil {
-		return manager.current.CursorImage, manager.current.ImagePNG, nil
+		return manager.current.CursorImage, manager.current.ImagePNG, manager.current.Hash, nil
 	}
 
 	entry, err := manager.fetchEntry()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, 0, err
 	}
 
 	manager.current = entry
-	return entry.CursorImage, entry.ImagePNG, nil
+	return entry.CursorImage, entry.ImagePNG, entry.Hash, nil
 }
 
 func (manager *image) AddListener(listener ImageListener) {
@@ -155,14 +174,33 @@ func (manager *image) RemoveListener(listener ImageListener) {
 func (manager *image) fetchEntry() (*imageEntry, error) {
 	cur := manager.desktop.GetCursorImage()
 
+	// downscale big cursors, keeping aspect ratio and hotspot position
+	if manager.maxSize > 0 && (int(cur.Width) > manager.maxSize || int(cur.Height) > manager.maxSize) {
+		scale := float64(manager.maxSize) / float64(max(cur.Width, cur.Height))
+		width := max(int(float64(cur.Width)*scale), 1)
+		height := max(int(float64(cur.Height)*scale), 1)
+
+		cur.Image = utils.ScaleImage(cur.Image, width, height)
+		cur.Xhot = uint16(float64(cur.Xhot) * scale)
+		cur.Yhot = uint16(float64(cur.Yhot) * scale)
+		cur.Width = uint16(width)
+		cur.Height = uint16(height)
+	}
+
 	img, err := utils.CreatePNGImage(cur.Image)
 	if err != nil {
 		return nil, err
 	}
 	cur.Image = nil // free memory
 
+	// same image with different hotspot is a different cursor
+	hash := fnv.New64a()
+	hash.Write(img)
+	binary.Write(hash, binary.BigEndian, [2]uint16{cur.Xhot, cur.Yhot})
+
 	return &imageEntry{
 		CursorImage: cur,
 		ImagePNG:    img,
+		Hash:        hash.Sum64(),
 	}, nil
 }
@@ -0,0 +1,143 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// closed data channel is recreated at most this many times in a row,
+// counter is reset once a recreated channel opens
+const dataChannelReconnectAttempts = 3
+
+// delay before recreating data channel, multiplied by attempt number
+const dataChannelReconnectDelay = time.Second
+
+// setupDataChannel registers handlers of the reliable data channel,
+// queue is the send queue bound to this channel, if enabled.
+func (manager *WebRTCManagerCtx) setupDataChannel(logger zerolog.Logger, peer *WebRTCPeerCtx, session types.Session, dataChannel *webrtc.DataChannel, queue *sendQueue) {
+	dataChannel.OnOpen(func() {
+		peer.mu.Lock()
+		peer.dataReconnects = 0
+		peer.mu.Unlock()
+
+		manager.curImage.AddListener(peer)
+		manager.curPosition.AddListener(peer)
+
+		// send initial cursor image
+		cur, img, hash, err := manager.curImage.GetCurrent()
+		if err == nil {
+			err := peer.SendCursorImage(cur, img, hash)
+			if err != nil {
+				logger.Err(err).Msg("failed to set cursor image")
+			}
+		} else {
+			logger.Err(err).Msg("failed to get cursor image")
+		}
+
+		// send initial cursor position
+		x, y := manager.desktop.GetCursorPosition()
+		err = peer.SendCursorPosition(x, y)
+		if err != nil {
+			logger.Err(err).Msg("failed to set cursor position")
+		}
+
+		if manager.still != nil && peer.losslessStills {
+			manager.still.AddListener(peer)
+		}
+	})
+
+	dataChannel.OnClose(func() {
+		if queue != nil {
+			queue.Clear()
+		}
+		manager.curImage.RemoveListener(peer)
+		manager.curPosition.RemoveListener(peer)
+		if manager.still != nil {
+			manager.still.RemoveListener(peer)
+		}
+
+		manager.reconnectDataChannel(logger, peer, session, dataChannel)
+	})
+
+	// channel in a bad state is closed, so that it gets recreated
+	dataChannel.OnError(func(err error) {
+		logger.Warn().Err(err).Msg("data channel error, closing it")
+		if err := dataChannel.Close(); err != nil {
+			logger.Err(err).Msg("failed to close data channel")
+		}
+	})
+
+	dataChannel.OnMessage(func(message webrtc.DataChannelMessage) {
+		if err := manager.handle(logger, message.Data, peer, session); err != nil {
+			logger.Err(err).Msg("data handle failed")
+		}
+	})
+}
+
+// reconnectDataChannel replaces closed data channel with a new one over the
+// existing connection, media tracks are not affected. Client is notified, so
+// that it adopts the channel announced by ondatachannel instead of the old one.
+func (manager *WebRTCManagerCtx) reconnectDataChannel(logger zerolog.Logger, peer *WebRTCPeerCtx, session types.Session, closed *webrtc.DataChannel) {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	// legacy clients open their own data channel
+	if viper.GetBool("legacy") {
+		return
+	}
+
+	// channel was already replaced, or the whole peer is going away
+	if current, _ := peer.getDataChannel(); current != closed || peer.destroyed ||
+		peer.connection.ConnectionState() != webrtc.PeerConnectionStateConnected {
+		return
+	}
+
+	if peer.dataReconnects >= dataChannelReconnectAttempts {
+		logger.Warn().Int("attempts", peer.dataReconnects).Msg("data channel could not be recreated, giving up")
+		return
+	}
+	peer.dataReconnects++
+
+	delay := dataChannelReconnectDelay * time.Duration(peer.dataReconnects)
+	logger.Info().Int("attempt", peer.dataReconnects).Dur("delay", delay).Msg("data channel closed, recreating it")
+
+	time.AfterFunc(delay, func() {
+		peer.mu.Lock()
+
+		current, currentQueue := peer.getDataChannel()
+		if current != closed || peer.destroyed {
+			peer.mu.Unlock()
+			return
+		}
+
+		dataChannel, err := peer.connection.CreateDataChannel(closed.Label(), nil)
+		if err != nil {
+			peer.mu.Unlock()
+			logger.Err(err).Msg("failed to recreate data channel")
+			return
+		}
+
+		var queue *sendQueue
+		if currentQueue != nil {
+			queue = newSendQueue(logger, dataChannel)
+		}
+
+		peer.setDataChannel(dataChannel, queue)
+		// cursor image is sent again, once the new channel opens
+		peer.cursorImageHash = 0
+		peer.mu.Unlock()
+
+		manager.setupDataChannel(logger, peer, session, dataChannel, queue)
+
+		session.Send(event.SIGNAL_DATA_CHANNEL, message.SignalDataChannel{
+			Label: dataChannel.Label(),
+		})
+	})
+}
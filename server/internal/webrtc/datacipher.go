@@ -0,0 +1,62 @@
+package webrtc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+)
+
+// size of symmetric key used for data channel encryption (AES-256)
+const dataCipherKeySize = 32
+
+// dataCipher encrypts whole data channel messages (header and body) using AES-GCM,
+// every encrypted message is prefixed with its random nonce.
+type dataCipher struct {
+	key  []byte
+	aead cipher.AEAD
+}
+
+func newDataCipher() (*dataCipher, error) {
+	key := make([]byte, dataCipherKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dataCipher{
+		key:  key,
+		aead: aead,
+	}, nil
+}
+
+func (c *dataCipher) Key() []byte {
+	return c.key
+}
+
+func (c *dataCipher) Seal(data []byte) ([]byte, error) {
+	nonce := make([]byte, c.aead.NonceSize(), c.aead.NonceSize()+len(data)+c.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return c.aead.Seal(nonce, nonce, data, nil), nil
+}
+
+func (c *dataCipher) Open(data []byte) ([]byte, error) {
+	nonceSize := c.aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, fmt.Errorf("encrypted message is too short")
+	}
+
+	return c.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+}
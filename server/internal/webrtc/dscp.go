@@ -0,0 +1,105 @@
+package webrtc
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"github.com/pion/transport/v2"
+	"github.com/pion/transport/v2/stdnet"
+	"github.com/rs/zerolog"
+)
+
+// dscpNet is a network that marks all UDP sockets with given DSCP value,
+// so that routers on managed networks can prioritize media packets.
+type dscpNet struct {
+	*stdnet.Net
+
+	logger zerolog.Logger
+	dscp   int
+}
+
+func newDSCPNet(logger zerolog.Logger, dscp int) (*dscpNet, error) {
+	n, err := stdnet.NewNet()
+	if err != nil {
+		return nil, err
+	}
+
+	return &dscpNet{
+		Net:    n,
+		logger: logger,
+		dscp:   dscp,
+	}, nil
+}
+
+func (n *dscpNet) ListenUDP(network string, locAddr *net.UDPAddr) (transport.UDPConn, error) {
+	conn, err := n.Net.ListenUDP(network, locAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	// local address is optional, bound address is used instead
+	var ip net.IP
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		ip = addr.IP
+	}
+
+	n.mark(conn, ip)
+	return conn, nil
+}
+
+func (n *dscpNet) ListenPacket(network string, address string) (net.PacketConn, error) {
+	conn, err := n.Net.ListenPacket(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	var ip net.IP
+	if addr, ok := conn.LocalAddr().(*net.UDPAddr); ok {
+		ip = addr.IP
+	}
+
+	n.mark(conn, ip)
+	return conn, nil
+}
+
+func (n *dscpNet) mark(conn any, ip net.IP) {
+	if err := n.setDSCP(conn, ip); err != nil {
+		n.logger.Warn().Err(err).
+			Int("dscp", n.dscp).
+			Str("addr", ip.String()).
+			Msg("unable to set DSCP on socket")
+	}
+}
+
+func (n *dscpNet) setDSCP(conn any, ip net.IP) error {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return fmt.Errorf("connection does not expose raw socket")
+	}
+
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	// DSCP occupies upper six bits of the TOS / traffic class byte
+	tos := n.dscp << 2
+
+	var sockErr error
+	err = raw.Control(func(fd uintptr) {
+		if ip.To4() != nil {
+			sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS, tos)
+			return
+		}
+
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, tos)
+		// dual-stack sockets carry IPv4 traffic as well, failure is not fatal here
+		_ = syscall.SetsockoptInt(int(fd), syscall.IPPROTO_IP, syscall.IP_TOS, tos)
+	})
+	if err != nil {
+		return err
+	}
+
+	return sockErr
+}
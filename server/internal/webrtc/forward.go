@@ -0,0 +1,251 @@
+package webrtc
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"sync"
+
+	"github.com/pion/rtp"
+	"github.com/pion/rtp/codecs"
+	"github.com/pion/srtp/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/codec"
+)
+
+// forwarded packets must fit into a single udp datagram without fragmentation
+const rtpForwardMTU = 1200
+
+// rtpForwarder packetizes encoded samples of a stream and sends them to
+// an external endpoint over plain RTP, or SRTP if a key is configured.
+type rtpForwarder struct {
+	logger     zerolog.Logger
+	track      *Track
+	conn       net.Conn
+	packetizer rtp.Packetizer
+	clockRate  float64
+	srtp       *srtp.Context
+
+	// stream follows video track of the peer, it is nil once closed
+	stream   types.StreamSinkManager
+	status   types.RTPForwardStatus
+	streamMu sync.Mutex
+
+	// samples are written from the pipeline goroutine, closing waits for them
+	mu     sync.Mutex
+	closed bool
+}
+
+func rtpPayloader(c codec.RTPCodec) (rtp.Payloader, bool) {
+	switch c.Name {
+	case "vp8":
+		return &codecs.VP8Payloader{EnablePictureID: true}, true
+	case "vp9":
+		return &codecs.VP9Payloader{}, true
+	case "h264":
+		return &codecs.H264Payloader{}, true
+	case "av1":
+		return &codecs.AV1Payloader{}, true
+	default:
+		return nil, false
+	}
+}
+
+func newRTPForwarder(logger zerolog.Logger, stream types.StreamSinkManager, config types.RTPForward) (*rtpForwarder, error) {
+	c := stream.Codec()
+	payloader, ok := rtpPayloader(c)
+	if !ok {
+		return nil, fmt.Errorf("%w: codec %s cannot be forwarded", types.ErrWebRTCForwardInvalid, c.Name)
+	}
+
+	payloadType := config.PayloadType
+	if payloadType == 0 {
+		payloadType = uint8(c.PayloadType)
+	}
+
+	var srtpCtx *srtp.Context
+	if config.SRTPKey != "" {
+		// master key followed by master salt, as in SDES crypto attribute
+		key, err := base64.StdEncoding.DecodeString(config.SRTPKey)
+		if err != nil || len(key) != 30 {
+			return nil, fmt.Errorf("%w: srtp key must be 30 bytes encoded in base64", types.ErrWebRTCForwardInvalid)
+		}
+
+		srtpCtx, err = srtp.CreateContext(key[:16], key[16:], srtp.ProtectionProfileAes128CmHmacSha1_80)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	conn, err := net.Dial("udp", config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", types.ErrWebRTCForwardInvalid, err)
+	}
+
+	ssrc := config.SSRC
+	if ssrc == 0 {
+		ssrc = rand.Uint32()
+	}
+
+	clockRate := c.Capability.ClockRate
+	return &rtpForwarder{
+		logger:     logger.With().Str("submodule", "rtp-forward").Str("address", config.Address).Logger(),
+		stream:     stream,
+		conn:       conn,
+		packetizer: rtp.NewPacketizer(rtpForwardMTU, payloadType, ssrc, payloader, rtp.NewRandomSequencer(), clockRate),
+		clockRate:  float64(clockRate),
+		srtp:       srtpCtx,
+		status: types.RTPForwardStatus{
+			IsActive:    true,
+			StreamID:    stream.ID(),
+			Address:     config.Address,
+			SSRC:        ssrc,
+			PayloadType: payloadType,
+			SRTP:        srtpCtx != nil,
+		},
+	}, nil
+}
+
+func (f *rtpForwarder) WriteSample(sample types.Sample) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return
+	}
+
+	packets := f.packetizer.Packetize(sample.Data, uint32(sample.Duration.Seconds()*f.clockRate))
+	for _, packet := range packets {
+		raw, err := packet.Marshal()
+		if err == nil && f.srtp != nil {
+			raw, err = f.srtp.EncryptRTP(nil, raw, &packet.Header)
+		}
+		if err == nil {
+			_, err = f.conn.Write(raw)
+		}
+		if err != nil {
+			f.logger.Debug().Err(err).Msg("failed to forward packet")
+			return
+		}
+	}
+}
+
+// setStream moves forwarder to the stream, that the peer currently receives
+func (f *rtpForwarder) setStream(stream types.StreamSinkManager) {
+	f.streamMu.Lock()
+	defer f.streamMu.Unlock()
+
+	// forwarder was closed or stream did not change
+	if f.stream == nil || f.stream == stream {
+		return
+	}
+
+	if err := f.stream.MoveListenerTo(f, stream); err != nil {
+		f.logger.Warn().Err(err).Str("stream_id", stream.ID()).Msg("failed to move forward listener")
+		return
+	}
+
+	f.stream = stream
+	f.status.StreamID = stream.ID()
+	f.logger.Info().Str("stream_id", stream.ID()).Msg("rtp forward switched stream")
+}
+
+func (f *rtpForwarder) Status() types.RTPForwardStatus {
+	f.streamMu.Lock()
+	defer f.streamMu.Unlock()
+
+	return f.status
+}
+
+func (f *rtpForwarder) Close() {
+	f.streamMu.Lock()
+	if f.stream != nil {
+		if err := f.stream.RemoveListener(f); err != nil {
+			f.logger.Warn().Err(err).Msg("failed to remove forward listener")
+		}
+		f.stream = nil
+	}
+	f.streamMu.Unlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.closed = true
+	f.conn.Close()
+}
+
+// StartForward forwards video stream currently received by the session and follows
+// it when the peer switches streams, already running forward is replaced.
+func (manager *WebRTCManagerCtx) StartForward(session types.Session, config types.RTPForward) error {
+	peer, ok := session.GetWebRTCPeer().(*WebRTCPeerCtx)
+	if !ok || peer == nil {
+		return types.ErrWebRTCConnectionNotFound
+	}
+
+	stream, ok := peer.videoTrack.Stream()
+	if !ok {
+		return types.ErrWebRTCStreamNotFound
+	}
+
+	forwarder, err := newRTPForwarder(manager.logger, stream, config)
+	if err != nil {
+		return err
+	}
+	forwarder.status.SessionID = session.ID()
+	forwarder.track = peer.videoTrack
+
+	if err := stream.AddListener(forwarder); err != nil {
+		forwarder.conn.Close()
+		return err
+	}
+
+	manager.forwardMu.Lock()
+	previous := manager.forward
+	manager.forward = forwarder
+	manager.forwardMu.Unlock()
+
+	if previous != nil {
+		// new forwarder replaces follower of the same track below
+		if previous.track != forwarder.track {
+			previous.track.FollowStream(nil)
+		}
+		previous.Close()
+	}
+
+	// stream switched in the meantime is picked up immediately
+	peer.videoTrack.FollowStream(forwarder.setStream)
+
+	forwarder.logger.Info().
+		Str("session_id", session.ID()).
+		Str("stream_id", stream.ID()).
+		Uint32("ssrc", forwarder.status.SSRC).
+		Msg("rtp forward started")
+	return nil
+}
+
+func (manager *WebRTCManagerCtx) StopForward() {
+	manager.forwardMu.Lock()
+	forwarder := manager.forward
+	manager.forward = nil
+	manager.forwardMu.Unlock()
+
+	if forwarder != nil {
+		forwarder.track.FollowStream(nil)
+		forwarder.Close()
+		forwarder.logger.Info().Msg("rtp forward stopped")
+	}
+}
+
+func (manager *WebRTCManagerCtx) ForwardStatus() types.RTPForwardStatus {
+	manager.forwardMu.Lock()
+	defer manager.forwardMu.Unlock()
+
+	if manager.forward == nil {
+		return types.RTPForwardStatus{}
+	}
+
+	return manager.forward.Status()
+}
@@ -0,0 +1,92 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+// playout is considered frozen, when the client did not receive any new packet
+// for this long, while the server kept sending them
+const freezeThreshold = 2 * time.Second
+
+// freezeDetector compares progress of highest sequence number received by the
+// client, from receiver reports, with packets sent by the server in the meantime.
+type freezeDetector struct {
+	clockRate   float64
+	packetsSent func() (uint64, bool)
+
+	mu           sync.Mutex
+	started      bool
+	lastSeq      uint32
+	lastSent     uint64
+	stalledSince time.Time
+	frozen       bool
+	count        uint32
+	jitter       time.Duration
+}
+
+func newFreezeDetector(clockRate uint32, packetsSent func() (uint64, bool)) *freezeDetector {
+	return &freezeDetector{
+		clockRate:   float64(clockRate),
+		packetsSent: packetsSent,
+	}
+}
+
+// Update processes reception report of the stream, it returns whether a new freeze
+// started and for how long is the playout frozen, 0 if it is not.
+func (d *freezeDetector) Update(report rtcp.ReceptionReport, now time.Time) (started bool, frozenFor time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// jitter is expressed in rtp timestamp units
+	if d.clockRate > 0 {
+		d.jitter = time.Duration(float64(report.Jitter) / d.clockRate * float64(time.Second))
+	}
+
+	sent, ok := d.packetsSent()
+	if !ok {
+		return false, 0
+	}
+
+	received := report.LastSequenceNumber != d.lastSeq
+	sending := sent > d.lastSent
+	first := !d.started
+
+	d.started = true
+	d.lastSeq = report.LastSequenceNumber
+	d.lastSent = sent
+
+	// nothing was sent, e.g. paused video, is not a freeze
+	if first || received || !sending {
+		d.stalledSince = time.Time{}
+		d.frozen = false
+		return false, 0
+	}
+
+	if d.stalledSince.IsZero() {
+		d.stalledSince = now
+	}
+
+	frozenFor = now.Sub(d.stalledSince)
+	if frozenFor < freezeThreshold {
+		return false, 0
+	}
+
+	if !d.frozen {
+		d.frozen = true
+		d.count++
+		started = true
+	}
+
+	return started, frozenFor
+}
+
+// Stats returns count of freezes so far and the latest jitter
+func (d *freezeDetector) Stats() (count uint32, jitter time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.count, d.jitter
+}
@@ -0,0 +1,149 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+func TestFreezeDetector_Update(t *testing.T) {
+	type step struct {
+		at     time.Duration
+		seq    uint32
+		sent   uint64
+		noStat bool
+
+		wantStarted   bool
+		wantFrozenFor time.Duration
+	}
+
+	tests := []struct {
+		name      string
+		steps     []step
+		wantCount uint32
+	}{
+		{
+			name: "first report is not a freeze",
+			steps: []step{
+				{at: 0, seq: 1, sent: 10},
+			},
+		}, {
+			name: "client keeps receiving",
+			steps: []step{
+				{at: 0, seq: 1, sent: 10},
+				{at: 3 * time.Second, seq: 2, sent: 20},
+				{at: 6 * time.Second, seq: 3, sent: 30},
+			},
+		}, {
+			name: "nothing sent is not a freeze",
+			steps: []step{
+				{at: 0, seq: 1, sent: 10},
+				{at: 3 * time.Second, seq: 1, sent: 10},
+				{at: 6 * time.Second, seq: 1, sent: 10},
+			},
+		}, {
+			name: "stats unavailable",
+			steps: []step{
+				{at: 0, seq: 1, noStat: true},
+				{at: 3 * time.Second, seq: 1, noStat: true},
+			},
+		}, {
+			name: "stall below threshold",
+			steps: []step{
+				{at: 0, seq: 1, sent: 10},
+				{at: time.Second, seq: 1, sent: 20},
+				{at: 2500 * time.Millisecond, seq: 1, sent: 30},
+			},
+		}, {
+			name: "stall over threshold starts freeze once",
+			steps: []step{
+				{at: 0, seq: 1, sent: 10},
+				{at: time.Second, seq: 1, sent: 20},
+				{at: 3 * time.Second, seq: 1, sent: 30, wantStarted: true, wantFrozenFor: 2 * time.Second},
+				{at: 4 * time.Second, seq: 1, sent: 40, wantFrozenFor: 3 * time.Second},
+			},
+			wantCount: 1,
+		}, {
+			name: "recovery ends freeze and next stall starts new one",
+			steps: []step{
+				{at: 0, seq: 1, sent: 10},
+				{at: time.Second, seq: 1, sent: 20},
+				{at: 3 * time.Second, seq: 1, sent: 30, wantStarted: true, wantFrozenFor: 2 * time.Second},
+				{at: 4 * time.Second, seq: 2, sent: 40},
+				{at: 5 * time.Second, seq: 2, sent: 50},
+				{at: 7 * time.Second, seq: 2, sent: 60, wantStarted: true, wantFrozenFor: 2 * time.Second},
+			},
+			wantCount: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var current step
+			d := newFreezeDetector(90000, func() (uint64, bool) {
+				return current.sent, !current.noStat
+			})
+
+			start := time.Now()
+			for i, s := range tt.steps {
+				current = s
+
+				started, frozenFor := d.Update(rtcp.ReceptionReport{LastSequenceNumber: s.seq}, start.Add(s.at))
+				if started != s.wantStarted {
+					t.Errorf("step %d: started = %v, want %v", i, started, s.wantStarted)
+				}
+				if frozenFor != s.wantFrozenFor {
+					t.Errorf("step %d: frozenFor = %v, want %v", i, frozenFor, s.wantFrozenFor)
+				}
+			}
+
+			if count, _ := d.Stats(); count != tt.wantCount {
+				t.Errorf("count = %d, want %d", count, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestFreezeDetector_Jitter(t *testing.T) {
+	tests := []struct {
+		name      string
+		clockRate uint32
+		jitter    uint32
+		want      time.Duration
+	}{
+		{
+			name:      "video clock rate",
+			clockRate: 90000,
+			jitter:    900,
+			want:      10 * time.Millisecond,
+		}, {
+			name:      "audio clock rate",
+			clockRate: 48000,
+			jitter:    1200,
+			want:      25 * time.Millisecond,
+		}, {
+			name:      "no jitter",
+			clockRate: 90000,
+			jitter:    0,
+			want:      0,
+		}, {
+			name:      "unknown clock rate",
+			clockRate: 0,
+			jitter:    900,
+			want:      0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFreezeDetector(tt.clockRate, func() (uint64, bool) {
+				return 0, false
+			})
+
+			d.Update(rtcp.ReceptionReport{Jitter: tt.jitter}, time.Now())
+
+			if _, jitter := d.Stats(); jitter != tt.want {
+				t.Errorf("jitter = %v, want %v", jitter, tt.want)
+			}
+		})
+	}
+}
@@ -3,19 +3,64 @@ package webrtc
 import (
 	"bytes"
 	"encoding/binary"
+	"fmt"
+	"io"
 	"math"
 	"time"
 
 	"github.com/m1k1o/neko/server/internal/webrtc/payload"
 	"github.com/m1k1o/neko/server/pkg/types"
 
-	"github.com/pion/webrtc/v3"
 	"github.com/rs/zerolog"
 )
 
+// maximum time a single batch can take to replay
+const batchMaxDuration = 5 * time.Second
+
+// maximum batches of a peer waiting for replay, further batches are rejected
+const batchMaxPending = 4
+
 func (manager *WebRTCManagerCtx) handle(
 	logger zerolog.Logger, data []byte,
-	dataChannel *webrtc.DataChannel,
+	peer *WebRTCPeerCtx,
+	session types.Session,
+) error {
+	data, err := peer.receiveData(data)
+	if err != nil {
+		return err
+	}
+
+	return manager.handleData(logger, data, peer, session)
+}
+
+// handleLossy accepts only events on unreliable input channel, that can be
+// lost or reordered without consequences, e.g. keys could get stuck otherwise
+func (manager *WebRTCManagerCtx) handleLossy(
+	logger zerolog.Logger, data []byte,
+	peer *WebRTCPeerCtx,
+	session types.Session,
+) error {
+	data, err := peer.receiveData(data)
+	if err != nil {
+		return err
+	}
+
+	if len(data) == 0 {
+		return io.ErrUnexpectedEOF
+	}
+
+	switch data[0] {
+	case payload.OP_MOVE, payload.OP_SCROLL:
+	default:
+		return fmt.Errorf("event %d is not allowed on input channel", data[0])
+	}
+
+	return manager.handleData(logger, data, peer, session)
+}
+
+func (manager *WebRTCManagerCtx) handleData(
+	logger zerolog.Logger, data []byte,
+	peer *WebRTCPeerCtx,
 	session types.Session,
 ) error {
 	isHost := session.IsHost()
@@ -42,8 +87,13 @@ func (manager *WebRTCManagerCtx) handle(
 			return err
 		}
 
-		x, y := int(payload.X), int(payload.Y)
+		x, y := peer.ToScreen(int(payload.X), int(payload.Y))
 		if isHost {
+			// input is muted while client window is not focused
+			if !session.Focused() {
+				return nil
+			}
+
 			// handle active cursor movement
 			manager.desktop.Move(x, y)
 			manager.curPosition.Set(x, y)
@@ -88,15 +138,207 @@ func (manager *WebRTCManagerCtx) handle(
 			return err
 		}
 
-		return dataChannel.Send(buffer.Bytes())
+		return peer.sendData(buffer.Bytes())
+	}
+
+	// client requests pointer lock, switching to relative mode
+	if header.Event == payload.OP_POINTER_LOCK {
+		lock := &payload.PointerLock{}
+		if err := binary.Read(buffer, binary.BigEndian, lock); err != nil {
+			return err
+		}
+
+		peer.SetPointerLocked(lock.Locked)
+		logger.Debug().Bool("locked", lock.Locked).Msg("pointer lock")
+		return nil
+	}
+
+	// continue only if session is host with focused window
+	if !isHost || !session.Focused() {
+		return nil
+	}
+
+	if header.Event == payload.OP_BATCH {
+		return manager.handleBatch(logger, peer, session, buffer)
 	}
 
-	// continue only if session is host
-	if !isHost {
+	if header.Event == payload.OP_MOVE_RELATIVE {
+		payload := &payload.MoveRelative{}
+		if err := binary.Read(buffer, binary.BigEndian, payload); err != nil {
+			return err
+		}
+
+		// relative movement is accepted only while pointer is locked
+		if !peer.PointerLocked() {
+			return nil
+		}
+
+		dx := int(payload.DX)
+		if peer.flipped() {
+			dx = -dx
+		}
+
+		manager.desktop.MoveRelative(dx, int(payload.DY))
+
+		// propagate resulting absolute position to other peers
+		x, y := manager.desktop.GetCursorPosition()
+		manager.curPosition.Set(x, y)
 		return nil
 	}
 
+	return manager.handleInput(logger, peer, header, buffer)
+}
+
+// batched input event along with its payload
+type batchEvent struct {
+	delay  time.Duration
+	header *payload.Header
+	data   []byte
+}
+
+// parse batched input events, they are replayed in background
+// respecting their relative timing, so that other messages are not blocked
+func (manager *WebRTCManagerCtx) handleBatch(logger zerolog.Logger, peer *WebRTCPeerCtx, session types.Session, buffer *bytes.Buffer) error {
+	batch := &payload.Batch{}
+	if err := binary.Read(buffer, binary.BigEndian, batch); err != nil {
+		return err
+	}
+
+	events := make([]batchEvent, 0, batch.Count)
+
+	var total time.Duration
+	for i := 0; i < int(batch.Count); i++ {
+		event := &payload.BatchEvent{}
+		if err := binary.Read(buffer, binary.BigEndian, event); err != nil {
+			return err
+		}
+
+		// only keyboard and mouse events can be batched
+		switch event.Event {
+		case payload.OP_MOVE, payload.OP_SCROLL,
+			payload.OP_KEY_DOWN, payload.OP_KEY_UP,
+			payload.OP_BTN_DOWN, payload.OP_BTN_UP:
+		default:
+			return fmt.Errorf("unsupported batch event %d", event.Event)
+		}
+
+		delay := time.Duration(event.Delay) * time.Millisecond
+		if total+delay > batchMaxDuration {
+			return fmt.Errorf("batch exceeds maximum duration of %v", batchMaxDuration)
+		}
+		total += delay
+
+		data := buffer.Next(int(event.Length))
+		if len(data) < int(event.Length) {
+			return io.ErrUnexpectedEOF
+		}
+
+		events = append(events, batchEvent{
+			delay: delay,
+			header: &payload.Header{
+				Event:  event.Event,
+				Length: event.Length,
+			},
+			data: data,
+		})
+	}
+
+	// slow replay must not pile up goroutines
+	if peer.batchPending.Add(1) > batchMaxPending {
+		peer.batchPending.Add(-1)
+		return fmt.Errorf("too many pending batches, maximum is %d", batchMaxPending)
+	}
+
+	go manager.replayBatch(logger, peer, session, events)
+	return nil
+}
+
+// replay batched input events, control is checked before each event and when
+// the batch is aborted, keys and buttons pressed by the batch are released
+func (manager *WebRTCManagerCtx) replayBatch(logger zerolog.Logger, peer *WebRTCPeerCtx, session types.Session, events []batchEvent) {
+	// batches of a peer are replayed one after another
+	peer.batchMu.Lock()
+	defer peer.batchMu.Unlock()
+	defer peer.batchPending.Add(-1)
+
+	keys := map[uint32]struct{}{}
+	buttons := map[uint32]struct{}{}
+
+	release := func() {
+		for key := range keys {
+			if err := manager.desktop.KeyUp(key); err != nil {
+				logger.Warn().Err(err).Uint32("key", key).Msg("key up failed")
+			}
+		}
+		for button := range buttons {
+			if err := manager.desktop.ButtonUp(button); err != nil {
+				logger.Warn().Err(err).Uint32("key", button).Msg("button up failed")
+			}
+		}
+	}
+
+	for i, event := range events {
+		if event.delay > 0 {
+			time.Sleep(event.delay)
+		}
+
+		// control could have been lost while waiting
+		if !session.IsHost() || !session.Focused() || peer.isDestroyed() {
+			logger.Debug().Int("replayed", i).Msg("batch aborted, control lost")
+			release()
+			return
+		}
+
+		if err := manager.handleInput(logger, peer, event.header, bytes.NewBuffer(event.data)); err != nil {
+			logger.Warn().Err(err).Int("replayed", i).Msg("batch aborted")
+			release()
+			return
+		}
+
+		// remember what is held down, key and button payloads start with the code
+		if len(event.data) < 4 {
+			continue
+		}
+		code := binary.BigEndian.Uint32(event.data)
+
+		switch event.header.Event {
+		case payload.OP_KEY_DOWN:
+			keys[code] = struct{}{}
+		case payload.OP_KEY_UP:
+			delete(keys, code)
+		case payload.OP_BTN_DOWN:
+			buttons[code] = struct{}{}
+		case payload.OP_BTN_UP:
+			delete(buttons, code)
+		}
+	}
+
+	logger.Trace().
+		Int("count", len(events)).
+		Msg("batch")
+}
+
+// handle input events that are allowed only for host
+func (manager *WebRTCManagerCtx) handleInput(logger zerolog.Logger, peer *WebRTCPeerCtx, header *payload.Header, buffer *bytes.Buffer) error {
+	// gamepad events are ignored, when gamepad support is disabled
+	switch header.Event {
+	case payload.OP_GAMEPAD_CONNECT, payload.OP_GAMEPAD, payload.OP_GAMEPAD_DISCONNECT:
+		if !manager.desktop.HasGamepadSupport() {
+			logger.Trace().Uint8("event", header.Event).Msg("gamepad support is disabled, ignoring event")
+			return nil
+		}
+	}
+
 	switch header.Event {
+	case payload.OP_MOVE:
+		payload := &payload.Move{}
+		if err := binary.Read(buffer, binary.BigEndian, payload); err != nil {
+			return err
+		}
+
+		x, y := peer.ToScreen(int(payload.X), int(payload.Y))
+		manager.desktop.Move(x, y)
+		manager.curPosition.Set(x, y)
 	case payload.OP_SCROLL:
 		// TODO: remove this once the client is fixed
 		if header.Length == 4 {
@@ -129,6 +371,11 @@ func (manager *WebRTCManagerCtx) handle(
 			return err
 		}
 
+		if peer.session.Profile().KeyBlocked(payload.Key, manager.desktop.KeysDown()) {
+			logger.Warn().Uint32("key", payload.Key).Msg("blocked key")
+			return nil
+		}
+
 		if err := manager.desktop.KeyDown(payload.Key); err != nil {
 			logger.Warn().Err(err).Uint32("key", payload.Key).Msg("key down failed")
 		} else {
@@ -173,7 +420,8 @@ func (manager *WebRTCManagerCtx) handle(
 			return err
 		}
 
-		if err := manager.desktop.TouchBegin(payload.TouchId, int(payload.X), int(payload.Y), payload.Pressure); err != nil {
+		x, y := peer.ToScreen(int(payload.X), int(payload.Y))
+		if err := manager.desktop.TouchBegin(payload.TouchId, x, y, payload.Pressure); err != nil {
 			logger.Warn().Err(err).Uint32("touchId", payload.TouchId).Msg("touch begin failed")
 		} else {
 			logger.Trace().Uint32("touchId", payload.TouchId).Msg("touch begin")
@@ -184,7 +432,8 @@ func (manager *WebRTCManagerCtx) handle(
 			return err
 		}
 
-		if err := manager.desktop.TouchUpdate(payload.TouchId, int(payload.X), int(payload.Y), payload.Pressure); err != nil {
+		x, y := peer.ToScreen(int(payload.X), int(payload.Y))
+		if err := manager.desktop.TouchUpdate(payload.TouchId, x, y, payload.Pressure); err != nil {
 			logger.Warn().Err(err).Uint32("touchId", payload.TouchId).Msg("touch update failed")
 		} else {
 			logger.Trace().Uint32("touchId", payload.TouchId).Msg("touch update")
@@ -195,11 +444,55 @@ func (manager *WebRTCManagerCtx) handle(
 			return err
 		}
 
-		if err := manager.desktop.TouchEnd(payload.TouchId, int(payload.X), int(payload.Y), payload.Pressure); err != nil {
+		x, y := peer.ToScreen(int(payload.X), int(payload.Y))
+		if err := manager.desktop.TouchEnd(payload.TouchId, x, y, payload.Pressure); err != nil {
 			logger.Warn().Err(err).Uint32("touchId", payload.TouchId).Msg("touch end failed")
 		} else {
 			logger.Trace().Uint32("touchId", payload.TouchId).Msg("touch end")
 		}
+	case payload.OP_GAMEPAD_CONNECT:
+		payload := &payload.GamepadConnect{}
+		if err := binary.Read(buffer, binary.BigEndian, payload); err != nil {
+			return err
+		}
+
+		if err := manager.desktop.GamepadConnect(payload.Index); err != nil {
+			logger.Warn().Err(err).Uint8("index", payload.Index).Msg("gamepad connect failed")
+		} else {
+			logger.Debug().Uint8("index", payload.Index).Msg("gamepad connect")
+		}
+	case payload.OP_GAMEPAD:
+		payload := &payload.Gamepad{}
+		if err := binary.Read(buffer, binary.BigEndian, payload); err != nil {
+			return err
+		}
+
+		axes := make([]int16, payload.Axes)
+		if err := binary.Read(buffer, binary.BigEndian, axes); err != nil {
+			return err
+		}
+
+		buttons := make([]uint8, payload.Buttons)
+		if err := binary.Read(buffer, binary.BigEndian, buttons); err != nil {
+			return err
+		}
+
+		if err := manager.desktop.GamepadUpdate(payload.Index, axes, buttons); err != nil {
+			logger.Warn().Err(err).Uint8("index", payload.Index).Msg("gamepad update failed")
+		} else {
+			logger.Trace().Uint8("index", payload.Index).Msg("gamepad update")
+		}
+	case payload.OP_GAMEPAD_DISCONNECT:
+		payload := &payload.GamepadConnect{}
+		if err := binary.Read(buffer, binary.BigEndian, payload); err != nil {
+			return err
+		}
+
+		if err := manager.desktop.GamepadDisconnect(payload.Index); err != nil {
+			logger.Warn().Err(err).Uint8("index", payload.Index).Msg("gamepad disconnect failed")
+		} else {
+			logger.Debug().Uint8("index", payload.Index).Msg("gamepad disconnect")
+		}
 	}
 
 	return nil
@@ -0,0 +1,114 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+const (
+	// minimum time between automatic ICE restarts
+	iceRestartBackoff = 5 * time.Second
+	// disconnected peer is kept alive for this long, while ICE is being restarted
+	iceRestartTimeout = 15 * time.Second
+)
+
+// candidatePairKey identifies network path of the selected candidate pair
+func candidatePairKey(pair *webrtc.ICECandidatePair) string {
+	if pair == nil || pair.Local == nil || pair.Remote == nil {
+		return ""
+	}
+
+	return pair.Local.Protocol.String() + " " +
+		pair.Local.Address + " " + pair.Remote.Address
+}
+
+// onSelectedCandidatePairChange restarts ICE when the connection moved to another
+// network path, e.g. client switched from WiFi to cellular, so that both sides
+// gather candidates for the new network instead of relying on a stale pair.
+func (peer *WebRTCPeerCtx) onSelectedCandidatePairChange(pair *webrtc.ICECandidatePair) {
+	key := candidatePairKey(pair)
+
+	peer.mu.Lock()
+	previous := peer.iceSelectedPair
+	peer.iceSelectedPair = key
+	peer.mu.Unlock()
+
+	// first selected pair or the same network path
+	if previous == "" || previous == key {
+		return
+	}
+
+	peer.logger.Info().
+		Str("previous", previous).
+		Str("selected", key).
+		Msg("selected candidate pair changed")
+
+	go peer.autoRestartICE("candidate pair changed")
+}
+
+// onICEConnectionStateChange restarts ICE as soon as the selected pair stops
+// working, instead of waiting for the client to notice.
+func (peer *WebRTCPeerCtx) onICEConnectionStateChange(state webrtc.ICEConnectionState) {
+	if state != webrtc.ICEConnectionStateDisconnected && state != webrtc.ICEConnectionStateFailed {
+		return
+	}
+
+	go peer.autoRestartICE("ice " + state.String())
+}
+
+// autoRestartICE runs outside of pion callbacks, because gathering
+// candidates for the new offer can wait on the ICE agent
+func (peer *WebRTCPeerCtx) autoRestartICE(reason string) {
+	if err := peer.restartICE(reason); err != nil {
+		peer.logger.Err(err).Msg("automatic ice restart failed")
+	}
+}
+
+// restartICE sends offer with new ICE credentials to the client, media tracks
+// and data channels are kept. Restarts are rate limited by iceRestartBackoff.
+func (peer *WebRTCPeerCtx) restartICE(reason string) error {
+	peer.negotiationMu.Lock()
+	defer peer.negotiationMu.Unlock()
+
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if !peer.iceAutoRestart || peer.destroyed {
+		return nil
+	}
+
+	if time.Since(peer.iceRestartLast) < iceRestartBackoff {
+		peer.logger.Debug().Str("reason", reason).Msg("ice restart already in progress, skipping")
+		return nil
+	}
+
+	// client must answer pending offer first
+	if peer.connection.SignalingState() != webrtc.SignalingStateStable {
+		peer.logger.Warn().Str("reason", reason).Msg("connection isn't stable, skipping ice restart")
+		return nil
+	}
+
+	peer.iceRestartLast = time.Now()
+
+	description, err := peer.setLocalDescription(func() (webrtc.SessionDescription, error) {
+		return peer.connection.CreateOffer(&webrtc.OfferOptions{
+			ICERestart: true,
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	peer.logger.Info().Str("reason", reason).Msg("sending ice restart offer")
+	peer.session.Send(
+		event.SIGNAL_RESTART,
+		message.SignalDescription{
+			SDP: description.SDP,
+		})
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package webrtc
+
+import (
+	"net"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// regionSelector advertises ICE servers of the region that client network
+// belongs to, servers without region are always advertised.
+type regionSelector struct {
+	regions []config.WebRTCICERegion
+}
+
+func (s *regionSelector) SelectICEServers(_ types.Session, remoteAddr string, servers []types.ICEServer) []types.ICEServer {
+	region := s.regionOf(remoteAddr)
+	if region == "" {
+		return servers
+	}
+
+	found := false
+	selected := []types.ICEServer{}
+	for _, server := range servers {
+		if server.Region == region {
+			selected = append(selected, server)
+			found = true
+		}
+	}
+
+	// no server in client region, fall back to full list
+	if !found {
+		return servers
+	}
+
+	for _, server := range servers {
+		if server.Region == "" {
+			selected = append(selected, server)
+		}
+	}
+
+	return selected
+}
+
+func (s *regionSelector) regionOf(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return ""
+	}
+
+	// most specific network wins
+	region, prefix := "", -1
+	for _, r := range s.regions {
+		if !r.Network.Contains(ip) {
+			continue
+		}
+
+		if ones, _ := r.Network.Mask.Size(); ones > prefix {
+			region, prefix = r.Region, ones
+		}
+	}
+
+	return region
+}
@@ -44,8 +44,8 @@ func (manager *WebRTCManagerCtx) handleLegacy(
 	logger zerolog.Logger, data []byte,
 	session types.Session,
 ) error {
-	// continue only if session is host
-	if !session.LegacyIsHost() {
+	// continue only if session is host with focused window
+	if !session.LegacyIsHost() || !session.Focused() {
 		return nil
 	}
 
@@ -70,7 +70,12 @@ func (manager *WebRTCManagerCtx) handleLegacy(
 			return err
 		}
 
-		manager.desktop.Move(int(payload.X), int(payload.Y))
+		x, y := int(payload.X), int(payload.Y)
+		if peer, ok := session.GetWebRTCPeer().(*WebRTCPeerCtx); ok {
+			x, y = peer.ToScreen(x, y)
+		}
+
+		manager.desktop.Move(x, y)
 	case OP_SCROLL:
 		payload := &PayloadScroll{}
 		if err := binary.Read(buffer, binary.LittleEndian, payload); err != nil {
@@ -99,6 +104,11 @@ func (manager *WebRTCManagerCtx) handleLegacy(
 
 			logger.Trace().Msgf("button down %d", payload.Key)
 		} else {
+			if session.Profile().KeyBlocked(uint32(payload.Key), manager.desktop.KeysDown()) {
+				logger.Warn().Msgf("blocked key %d", payload.Key)
+				return nil
+			}
+
 			err := manager.desktop.KeyDown(uint32(payload.Key))
 			if err != nil {
 				logger.Warn().Err(err).Msg("key down failed")
@@ -1,8 +1,13 @@
 package webrtc
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"fmt"
 	"net"
+	"os"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -13,6 +18,7 @@ import (
 	"github.com/pion/interceptor/pkg/cc"
 	"github.com/pion/interceptor/pkg/gcc"
 	"github.com/pion/rtcp"
+	"github.com/pion/sdp/v3"
 	"github.com/pion/webrtc/v3"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -48,7 +54,7 @@ const (
 	rtcpPLIInterval = 3 * time.Second
 )
 
-func New(desktop types.DesktopManager, capture types.CaptureManager, config *config.WebRTC) *WebRTCManagerCtx {
+func New(desktop types.DesktopManager, sessions types.SessionManager, capture types.CaptureManager, config *config.WebRTC) *WebRTCManagerCtx {
 	logger := log.With().Str("module", "webrtc").Logger()
 
 	configuration := webrtc.Configuration{
@@ -75,18 +81,61 @@ func New(desktop types.DesktopManager, capture types.CaptureManager, config *con
 		configuration.ICEServers = ICEServers
 	}
 
-	return &WebRTCManagerCtx{
+	var budget *bandwidthBudget
+	if config.Estimator.Budget > 0 {
+		budget = newBandwidthBudget(config.Estimator.Budget)
+	}
+
+	var iceSelector types.ICEServerSelector
+	if len(config.ICERegions) > 0 {
+		iceSelector = &regionSelector{regions: config.ICERegions}
+	}
+
+	sdpTransforms := []types.SDPTransform{}
+	for _, spec := range config.SDPTransforms {
+		transform, err := newSDPTransform(spec)
+		if err != nil {
+			logger.Warn().Err(err).Msg("skipping sdp transform")
+			continue
+		}
+		sdpTransforms = append(sdpTransforms, transform)
+	}
+
+	var still *stillFrame
+	if config.StillFrameDelay > 0 {
+		still = newStillFrame(logger, capture, config.StillFrameDelay)
+	}
+
+	var standby *standbyPool
+	if config.Standby.Max > 0 {
+		standby = newStandbyPool(config.Standby.Max, config.Standby.TTL)
+	}
+
+	manager := &WebRTCManagerCtx{
 		logger:  logger,
 		config:  config,
 		metrics: newMetricsManager(),
+		budget:  budget,
 
 		webrtcConfiguration: configuration,
+		iceSelector:         iceSelector,
+		sdpTransforms:       sdpTransforms,
+		dataHandlers:        map[string]types.DataChannelHandler{},
 
 		desktop:     desktop,
+		sessions:    sessions,
 		capture:     capture,
-		curImage:    cursor.NewImage(logger, desktop),
+		curImage:    cursor.NewImage(logger, desktop, config.CursorMaxSize),
 		curPosition: cursor.NewPosition(logger),
+		still:       still,
+		standby:     standby,
+	}
+
+	if standby != nil {
+		standby.create = manager.createStandbyPeer
 	}
+
+	return manager
 }
 
 type WebRTCManagerCtx struct {
@@ -94,29 +143,73 @@ type WebRTCManagerCtx struct {
 	config  *config.WebRTC
 	metrics *metricsManager
 	peerId  int32
+	budget  *bandwidthBudget
 
 	desktop     types.DesktopManager
+	sessions    types.SessionManager
 	capture     types.CaptureManager
 	curImage    cursor.Image
 	curPosition cursor.Position
+	// nil when lossless still frames are disabled
+	still *stillFrame
+	// nil when warm standby peers are disabled
+	standby *standbyPool
 
 	webrtcConfiguration webrtc.Configuration
 
+	iceSelector   types.ICEServerSelector
+	iceSelectorMu sync.Mutex
+
+	sdpTransforms   []types.SDPTransform
+	sdpTransformsMu sync.Mutex
+
+	dataHandlers   map[string]types.DataChannelHandler
+	dataHandlersMu sync.RWMutex
+
+	rekeyHandler   types.PeerRekeyHandler
+	rekeyHandlerMu sync.Mutex
+
 	tcpMux ice.TCPMux
 	udpMux ice.UDPMux
+	net    *dscpNet
 
 	camStop, micStop *func()
+
+	// video of a session forwarded to an external endpoint
+	forward   *rtpForwarder
+	forwardMu sync.Mutex
 }
 
 func (manager *WebRTCManagerCtx) Start() {
 	manager.curImage.Start()
 
+	// standby peer is prepared only for connected sessions
+	if manager.standby != nil {
+		manager.sessions.OnDisconnected(manager.discardSessionStandby)
+		manager.sessions.OnDeleted(manager.discardSessionStandby)
+	}
+
+	// use the same DTLS certificate for all peers, so that it can be pinned
+	certificate, err := manager.loadCertificate()
+	if err != nil {
+		manager.logger.Fatal().Err(err).Msg("unable to setup DTLS certificate")
+	}
+	manager.webrtcConfiguration.Certificates = []webrtc.Certificate{*certificate}
+
 	logger := pionlog.New(manager.logger)
 
-	// add TCP Mux listener
+	// mark outgoing UDP packets with DSCP value
+	if manager.config.DSCP > 0 {
+		manager.net, err = newDSCPNet(manager.logger, manager.config.DSCP)
+		if err != nil {
+			manager.logger.Fatal().Err(err).Msg("unable to setup DSCP network")
+		}
+	}
+
+	// add TCP Mux listener, on all interfaces for both IPv4 and IPv6
+	// so that TCP candidates of both network types are reachable
 	if manager.config.TCPMux > 0 {
 		tcpListener, err := net.ListenTCP("tcp", &net.TCPAddr{
-			IP:   net.IP{0, 0, 0, 0},
 			Port: manager.config.TCPMux,
 		})
 
@@ -134,10 +227,23 @@ func (manager *WebRTCManagerCtx) Start() {
 
 	// add UDP Mux listener
 	if manager.config.UDPMux > 0 {
-		var err error
-		manager.udpMux, err = ice.NewMultiUDPMuxFromPort(manager.config.UDPMux,
+		opts := []ice.UDPMuxFromPortOption{
 			ice.UDPMuxFromPortWithLogger(logger.NewLogger("ice-udp")),
-		)
+		}
+
+		if manager.net != nil {
+			opts = append(opts, ice.UDPMuxFromPortWithNet(manager.net))
+		}
+
+		if filter := manager.interfaceFilter(); filter != nil {
+			opts = append(opts, ice.UDPMuxFromPortWithInterfaceFilter(filter))
+		}
+
+		if filter := manager.ipFilter(); filter != nil {
+			opts = append(opts, ice.UDPMuxFromPortWithIPFilter(filter))
+		}
+
+		manager.udpMux, err = ice.NewMultiUDPMuxFromPort(manager.config.UDPMux, opts...)
 
 		if err != nil {
 			manager.logger.Fatal().Err(err).Msg("unable to setup ice UDP mux")
@@ -153,14 +259,68 @@ func (manager *WebRTCManagerCtx) Start() {
 		Str("epr", fmt.Sprintf("%d-%d", manager.config.EphemeralMin, manager.config.EphemeralMax)).
 		Int("tcpmux", manager.config.TCPMux).
 		Int("udpmux", manager.config.UDPMux).
+		Strs("interfaces", manager.config.Interfaces).
+		Interface("bind_ips", manager.config.BindIPs).
+		Int("dscp", manager.config.DSCP).
 		Msg("webrtc starting")
 }
 
+// interfaceFilter allows only configured network interfaces, nil if not limited
+func (manager *WebRTCManagerCtx) interfaceFilter() func(string) bool {
+	if len(manager.config.Interfaces) == 0 {
+		return nil
+	}
+
+	return func(name string) bool {
+		in, _ := utils.ArrayIn(name, manager.config.Interfaces)
+		return in
+	}
+}
+
+// ipFilter allows only configured local IP addresses, nil if not limited
+func (manager *WebRTCManagerCtx) ipFilter() func(net.IP) bool {
+	if len(manager.config.BindIPs) == 0 {
+		return nil
+	}
+
+	return func(ip net.IP) bool {
+		for _, bindIP := range manager.config.BindIPs {
+			if bindIP.Equal(ip) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func (manager *WebRTCManagerCtx) loadCertificate() (*webrtc.Certificate, error) {
+	if manager.config.DTLSCertificate != "" {
+		pem, err := os.ReadFile(manager.config.DTLSCertificate)
+		if err != nil {
+			return nil, err
+		}
+
+		return webrtc.CertificateFromPEM(string(pem))
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return webrtc.GenerateCertificate(key)
+}
+
 func (manager *WebRTCManagerCtx) Shutdown() error {
 	manager.logger.Info().Msg("shutdown")
 
 	manager.curImage.Shutdown()
 	manager.curPosition.Shutdown()
+	if manager.still != nil {
+		manager.still.Shutdown()
+	}
+	manager.discardStandby()
+	manager.StopForward()
 
 	return nil
 }
@@ -169,10 +329,119 @@ func (manager *WebRTCManagerCtx) ICEServers() []types.ICEServer {
 	return manager.config.ICEServersFrontend
 }
 
-func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs []codec.RTPCodec) (*webrtc.PeerConnection, cc.BandwidthEstimator, error) {
+func (manager *WebRTCManagerCtx) ICEServersFor(session types.Session, remoteAddr string) []types.ICEServer {
+	manager.iceSelectorMu.Lock()
+	selector := manager.iceSelector
+	manager.iceSelectorMu.Unlock()
+
+	if selector == nil {
+		return manager.ICEServers()
+	}
+
+	// selector must not modify configured servers
+	servers := append([]types.ICEServer{}, manager.config.ICEServersFrontend...)
+	return selector.SelectICEServers(session, remoteAddr, servers)
+}
+
+// SetICEServerSelector replaces default selector, e.g. by a plugin using geolocation.
+func (manager *WebRTCManagerCtx) SetICEServerSelector(selector types.ICEServerSelector) {
+	manager.iceSelectorMu.Lock()
+	defer manager.iceSelectorMu.Unlock()
+
+	manager.iceSelector = selector
+}
+
+func (manager *WebRTCManagerCtx) AddSDPTransform(transform types.SDPTransform) {
+	manager.sdpTransformsMu.Lock()
+	defer manager.sdpTransformsMu.Unlock()
+
+	manager.sdpTransforms = append(manager.sdpTransforms, transform)
+}
+
+func (manager *WebRTCManagerCtx) getSDPTransforms() []types.SDPTransform {
+	manager.sdpTransformsMu.Lock()
+	defer manager.sdpTransformsMu.Unlock()
+
+	return slices.Clone(manager.sdpTransforms)
+}
+
+func (manager *WebRTCManagerCtx) AddDataChannelHandler(label string, handler types.DataChannelHandler) {
+	manager.dataHandlersMu.Lock()
+	defer manager.dataHandlersMu.Unlock()
+
+	manager.dataHandlers[label] = handler
+}
+
+func (manager *WebRTCManagerCtx) SetRekeyHandler(handler types.PeerRekeyHandler) {
+	manager.rekeyHandlerMu.Lock()
+	defer manager.rekeyHandlerMu.Unlock()
+
+	manager.rekeyHandler = handler
+}
+
+// rekeyAfter replaces the peer after rekey interval. DTLS cannot be renegotiated
+// within an existing connection, new SRTP keys require a new peer connection.
+// Only websocket peers are rekeyed, WHIP/WHEP peers have no channel to receive
+// the new offer and are excluded by not being scheduled here.
+func (manager *WebRTCManagerCtx) rekeyAfter(session types.Session, peer *WebRTCPeerCtx, options types.PeerOptions) {
+	manager.rekeyHandlerMu.Lock()
+	handler := manager.rekeyHandler
+	manager.rekeyHandlerMu.Unlock()
+
+	if handler == nil || manager.config.RekeyInterval <= 0 {
+		return
+	}
+
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if peer.destroyed {
+		return
+	}
+
+	peer.rekeyTimer = time.AfterFunc(manager.config.RekeyInterval, func() {
+		// peer was already replaced or destroyed
+		if session.GetWebRTCPeer() != peer {
+			return
+		}
+
+		peer.logger.Info().Msg("rekeying peer")
+
+		if err := handler(session, peer, options); err != nil {
+			peer.logger.Err(err).Msg("rekeying peer failed")
+			return
+		}
+
+		// only rekeys that were applied are counted
+		manager.metrics.getBySession(session).NewRekey()
+	})
+}
+
+func (manager *WebRTCManagerCtx) dataHandler(label string) (types.DataChannelHandler, bool) {
+	manager.dataHandlersMu.RLock()
+	defer manager.dataHandlersMu.RUnlock()
+
+	handler, ok := manager.dataHandlers[label]
+	return handler, ok
+}
+
+func (manager *WebRTCManagerCtx) Fingerprints() ([]webrtc.DTLSFingerprint, error) {
+	if len(manager.webrtcConfiguration.Certificates) == 0 {
+		return nil, fmt.Errorf("DTLS certificate is not set up")
+	}
+
+	return manager.webrtcConfiguration.Certificates[0].GetFingerprints()
+}
+
+func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs []codec.RTPCodec, nack bool, iceCredentials *types.ICECredentials, senderReports *senderReportInterceptor, rtpStats *rtpStatsGetter, audioLevel *audioLevelInterceptor) (*webrtc.PeerConnection, cc.BandwidthEstimator, error) {
 	// create media engine
 	engine := &webrtc.MediaEngine{}
 	for _, codec := range codecs {
+		// retransmissions are not sent, when nack is disabled
+		if !nack {
+			codec = codec.WithoutNACK()
+		}
+
 		if err := codec.Register(engine); err != nil {
 			return nil, nil, err
 		}
@@ -191,6 +460,18 @@ func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs
 	// otherwise iOS renegotiation fails with: Failed to set SSL role for the transport.
 	settings.SetAnsweringDTLSRole(webrtc.DTLSRoleServer)
 
+	if manager.net != nil {
+		settings.SetNet(manager.net)
+	}
+
+	// limit interfaces and addresses used for candidates gathering
+	if filter := manager.interfaceFilter(); filter != nil {
+		settings.SetInterfaceFilter(filter)
+	}
+	if filter := manager.ipFilter(); filter != nil {
+		settings.SetIPFilter(filter)
+	}
+
 	var networkType []webrtc.NetworkType
 
 	// udp candidates
@@ -223,6 +504,26 @@ func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs
 	// create interceptor registry
 	registry := &interceptor.Registry{}
 
+	// must be added before default interceptors, so that it sees sender reports they write
+	registry.Add(senderReports)
+
+	// collects rtp stream stats, that are not provided by pion GetStats
+	statsInterceptor, err := rtpStats.newInterceptor()
+	if err != nil {
+		return nil, nil, err
+	}
+	registry.Add(statsInterceptor)
+
+	// audio level header extension, only for audio
+	if audioLevel != nil {
+		if err := engine.RegisterHeaderExtension(webrtc.RTPHeaderExtensionCapability{
+			URI: sdp.AudioLevelURI,
+		}, webrtc.RTPCodecTypeAudio); err != nil {
+			return nil, nil, err
+		}
+		registry.Add(audioLevel)
+	}
+
 	// create bandwidth estimator
 	estimatorChan := make(chan cc.BandwidthEstimator, 1)
 	if manager.config.Estimator.Enabled {
@@ -249,7 +550,18 @@ func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs
 		estimatorChan <- nil
 	}
 
-	if err := webrtc.RegisterDefaultInterceptors(engine, registry); err != nil {
+	// default interceptors, but nack can be disabled when retransmissions hurt latency
+	if nack {
+		if err := webrtc.ConfigureNack(engine, registry); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := webrtc.ConfigureRTCPReports(registry); err != nil {
+		return nil, nil, err
+	}
+
+	if err := webrtc.ConfigureTWCCSender(engine, registry); err != nil {
 		return nil, nil, err
 	}
 
@@ -262,16 +574,87 @@ func (manager *WebRTCManagerCtx) newPeerConnection(logger zerolog.Logger, codecs
 
 	// create new peer connection
 	configuration := manager.webrtcConfiguration
+
+	// TURN servers use credentials of this peer, configured servers must not be modified
+	if iceCredentials != nil && len(configuration.ICEServers) > 0 {
+		servers := make([]webrtc.ICEServer, 0, len(configuration.ICEServers))
+		for _, server := range configuration.ICEServers {
+			if (types.ICEServer{URLs: server.URLs}).IsTURN() {
+				server.Username = iceCredentials.Username
+				server.Credential = iceCredentials.Credential
+			}
+			servers = append(servers, server)
+		}
+		configuration.ICEServers = servers
+	}
 	connection, err := api.NewPeerConnection(configuration)
 	return connection, <-estimatorChan, err
 }
 
-func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.SessionDescription, types.WebRTCPeer, error) {
+func (manager *WebRTCManagerCtx) CreatePeer(session types.Session, options types.PeerOptions) (*webrtc.SessionDescription, types.WebRTCPeer, error) {
+	// reconnecting client adopts prepared peer, if there is one,
+	// quota was checked when it was created for the disconnected session
+	if offer, peer, ok := manager.adoptStandby(session, options); ok {
+		return offer, peer, nil
+	}
+
+	return manager.createPeer(session, options, peerKindWebSocket, func(peer *WebRTCPeerCtx) (*webrtc.SessionDescription, error) {
+		offer, err := peer.CreateOffer(false)
+		if err == nil {
+			manager.rekeyAfter(session, peer, options)
+		}
+		return offer, err
+	})
+}
+
+// CreatePeerWithOffer creates a peer from a remote offer and returns local answer,
+// answer contains all ICE candidates, because remote peer might not support trickle.
+func (manager *WebRTCManagerCtx) CreatePeerWithOffer(session types.Session, offer webrtc.SessionDescription, options types.PeerOptions) (*webrtc.SessionDescription, types.WebRTCPeer, error) {
+	return manager.createPeer(session, options, peerKindHTTP, func(peer *WebRTCPeerCtx) (*webrtc.SessionDescription, error) {
+		if err := peer.SetRemoteDescription(offer); err != nil {
+			return nil, err
+		}
+
+		return peer.CreateAnswer()
+	})
+}
+
+// how the peer is signaled
+type peerKind int
+
+const (
+	// signaled over websocket
+	peerKindWebSocket peerKind = iota
+	// prepared in advance, until a reconnecting websocket client adopts it
+	peerKindStandby
+	// created from remote offer, signaled over HTTP (WHIP/WHEP)
+	peerKindHTTP
+)
+
+func (manager *WebRTCManagerCtx) createPeer(
+	session types.Session, options types.PeerOptions, kind peerKind,
+	negotiate func(peer *WebRTCPeerCtx) (*webrtc.SessionDescription, error),
+) (*webrtc.SessionDescription, types.WebRTCPeer, error) {
+	if err := manager.checkQuota(session); err != nil {
+		return nil, nil, err
+	}
+
+	if options.ICECredentials != nil {
+		if err := options.ICECredentials.Validate(); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	id := atomic.AddInt32(&manager.peerId, 1)
 
 	// get metrics for session
 	metrics := manager.metrics.getBySession(session)
-	metrics.NewConnection()
+
+	// candidates of standby and http peers are gathered into the local description
+	iceTrickle := kind == peerKindWebSocket && manager.config.ICETrickle
+
+	// restart offer is sent over websocket, http peers cannot receive it
+	iceAutoRestart := kind != peerKindHTTP && manager.config.ICEAutoRestart
 
 	// add session id to logger context
 	logger := manager.logger.With().Str("session_id", session.ID()).Int32("peer_id", id).Logger()
@@ -281,18 +664,43 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 	audio := manager.capture.Audio()
 	audioCodec := audio.Codec()
 
+	// requested opus encoding needs its own encoder, fmtp is set to match it
+	audioEncoding := audioEncodingOptions(options)
+	if audioEncoding != types.DefaultAudioEncoding {
+		encoded, err := manager.capture.AudioGain(0, audioEncoding)
+		if err == nil {
+			audio = encoded
+			audioCodec = applyAudioEncoding(audioCodec, audioEncoding)
+		} else {
+			logger.Warn().Err(err).Msg("unable to apply requested audio encoding, using default")
+			audioEncoding = types.DefaultAudioEncoding
+		}
+	}
+
 	// all videos must have the same codec
 	video := manager.capture.Video()
 	videoCodec := video.Codec()
 
+	// nack is enabled by default
+	nack := options.NACK == nil || *options.NACK
+
+	senderReports := newSenderReportInterceptor(logger)
+	rtpStats := &rtpStatsGetter{}
+
+	// audio level is sent only if enabled and audio is available
+	var audioLevel *audioLevelInterceptor
+	if manager.config.AudioLevelInterval > 0 && !manager.config.DisableAudio {
+		audioLevel = &audioLevelInterceptor{}
+	}
+
 	connection, estimator, err := manager.newPeerConnection(
-		logger, []codec.RTPCodec{audioCodec, videoCodec})
+		logger, []codec.RTPCodec{audioCodec, videoCodec}, nack, options.ICECredentials, senderReports, rtpStats, audioLevel)
 	if err != nil {
 		return nil, nil, err
 	}
 
 	// asynchronously send local ICE Candidates
-	if manager.config.ICETrickle {
+	if iceTrickle {
 		connection.OnICECandidate(func(candidate *webrtc.ICECandidate) {
 			if candidate == nil {
 				logger.Debug().Msg("all local ice candidates sent")
@@ -307,24 +715,30 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 		})
 	}
 
-	// audio track
-	audioTrack, err := NewTrack(logger, audioCodec, connection)
-	if err != nil {
-		return nil, nil, err
-	}
+	// audio track, omitted when audio is disabled on the server
+	var audioTrack *Track
+	if !manager.config.DisableAudio {
+		audioTrack, err = NewTrack(logger, audioCodec, connection,
+			WithDropCounters(metrics.audioFramesDroppedBackpressure, nil))
+		if err != nil {
+			return nil, nil, err
+		}
 
-	// we disable audio by default manually
-	audioTrack.SetPaused(true)
+		// we disable audio by default manually
+		audioTrack.SetPaused(true)
 
-	// set stream for audio track
-	_, err = audioTrack.SetStream(audio)
-	if err != nil {
-		return nil, nil, err
+		// set stream for audio track
+		_, err = audioTrack.SetStream(audio)
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	// video track
 	videoRtcp := make(chan []rtcp.Packet, 1)
-	videoTrack, err := NewTrack(logger, videoCodec, connection, WithRtcpChan(videoRtcp))
+	videoTrack, err := NewTrack(logger, videoCodec, connection, WithRtcpChan(videoRtcp),
+		WithDropCounters(metrics.videoFramesDroppedBackpressure, metrics.videoFramesDroppedKeyframeWait),
+		WithKeepAlive(manager.config.VideoKeepAlive))
 	if err != nil {
 		return nil, nil, err
 	}
@@ -333,6 +747,15 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 	// stream for video track will be set later
 	//
 
+	// freezes of video playout are detected from receiver reports
+	freezes := newFreezeDetector(videoCodec.Capability.ClockRate, func() (uint64, bool) {
+		s, ok := rtpStats.Get(videoTrack.SSRC())
+		if !ok {
+			return 0, false
+		}
+		return s.OutboundRTPStreamStats.PacketsSent, true
+	})
+
 	// data channel
 
 	dataChannel, err := connection.CreateDataChannel("data", nil)
@@ -340,7 +763,38 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 		return nil, nil, err
 	}
 
+	// lost input events are superseded by next ones, so they are not retransmitted
+	// and do not block following messages
+	var inputChannel *webrtc.DataChannel
+	if options.UnreliableInput {
+		ordered, maxRetransmits := false, uint16(0)
+		inputChannel, err = connection.CreateDataChannel("input", &webrtc.DataChannelInit{
+			Ordered:        &ordered,
+			MaxRetransmits: &maxRetransmits,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// legacy clients replace data channel with their own
+	var dataQueue *sendQueue
+	if manager.config.DataPriority && !viper.GetBool("legacy") {
+		dataQueue = newSendQueue(logger, dataChannel)
+	}
+
+	// data channel encryption is not supported by legacy clients, and the key
+	// is sent over websocket, which http peers cannot receive
+	var dataCipher *dataCipher
+	if manager.config.DataEncryption && !viper.GetBool("legacy") && kind != peerKindHTTP {
+		dataCipher, err = newDataCipher()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
 	peer := &WebRTCPeerCtx{
+		id:         id,
 		logger:     logger,
 		session:    session,
 		metrics:    metrics,
@@ -358,18 +812,48 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 				DownwardTrendThreshold: -0.5,
 				CollapseValues:         true,
 			}),
+		budget: manager.budget,
 		// stream selectors
-		video: video,
-		audio: audio,
+		video:   video,
+		audio:   audio,
+		capture: manager.capture,
+		desktop: manager.desktop,
 		// tracks & channels
-		audioTrack:  audioTrack,
-		videoTrack:  videoTrack,
-		dataChannel: dataChannel,
-		rtcpChannel: videoRtcp,
+		audioTrack:     audioTrack,
+		videoTrack:     videoTrack,
+		dataChannel:    dataChannel,
+		dataQueue:      dataQueue,
+		inputChannel:   inputChannel,
+		remoteChannels: map[string]*webrtc.DataChannel{},
+		dataCipher:     dataCipher,
+		rtcpChannel:    videoRtcp,
+		freezes:        freezes,
+		// rtcp
+		senderReports: senderReports,
+		rtpStats:      rtpStats,
 		// config
-		iceTrickle:      manager.config.ICETrickle,
-		estimatorConfig: manager.config.Estimator,
-		audioDisabled:   true, // we disable audio by default manually
+		iceTrickle:       iceTrickle,
+		iceAutoRestart:   iceAutoRestart,
+		nack:             nack,
+		rtcpRsize:        manager.config.RTCPReducedSize,
+		sdpTransforms:    manager.getSDPTransforms(),
+		estimatorConfig:  manager.config.Estimator,
+		quota:            manager.config.Quota,
+		audioDisabled:    true, // we disable audio by default manually
+		audioEncoding:    audioEncoding,
+		cursorMotion:     options.CursorMotion,
+		losslessStills:   options.LosslessStills,
+		clipboardChannel: options.ClipboardChannel,
+		cursorEpoch:      time.Now(),
+		kind:             kind,
+	}
+	peer.standby.Store(kind == peerKindStandby)
+
+	// audio level of the stream sent to this peer
+	audioLevelStop := make(chan struct{})
+	if audioLevel != nil {
+		audioLevel.setSource(peer.audioLevel)
+		go peer.audioLevelReporter(manager.config.AudioLevelInterval, audioLevelStop)
 	}
 
 	connection.OnTrack(func(track *webrtc.TrackRemote, receiver *webrtc.RTPReceiver) {
@@ -486,18 +970,67 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 			})
 
 			// handle legacy data channel
-			peer.dataChannel = dc
+			peer.setDataChannel(dc, nil)
+			return
 		}
+
+		label := dc.Label()
+
+		// client may open its own input channel with custom reliability,
+		// it accepts the same events as the one created by the server
+		if label == "input" {
+			dc.OnMessage(func(message webrtc.DataChannelMessage) {
+				if err := manager.handleLossy(logger, message.Data, peer, session); err != nil {
+					logger.Err(err).Msg("input handle failed")
+				}
+			})
+
+			peer.addRemoteChannel(dc)
+			return
+		}
+
+		handler, ok := manager.dataHandler(label)
+		if !ok {
+			logger.Warn().Str("label", label).Msg("no handler for data channel, closing")
+			if err := dc.Close(); err != nil {
+				logger.Err(err).Msg("failed to close data channel")
+			}
+			return
+		}
+
+		dc.OnMessage(func(message webrtc.DataChannelMessage) {
+			if err := handler(session, message.Data); err != nil {
+				logger.Err(err).Str("label", label).Msg("data channel handler failed")
+			}
+		})
+
+		peer.addRemoteChannel(dc)
 	})
 
+	connection.OnICEConnectionStateChange(peer.onICEConnectionStateChange)
+	connection.SCTP().Transport().ICETransport().OnSelectedCandidatePairChange(peer.onSelectedCandidatePairChange)
+
 	var once sync.Once
 	connection.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
 		switch state {
 		case webrtc.PeerConnectionStateConnected:
+			logger.Info().Str("audio_fmtp", peer.audioFmtp()).Msg("negotiated audio codec parameters")
+			peer.cancelDestroy()
 			session.SetWebRTCConnected(peer, true)
+			manager.standbyPeerConnected(session, peer, options)
 		case webrtc.PeerConnectionStateDisconnected,
 			webrtc.PeerConnectionStateFailed:
-			peer.Destroy()
+			// keep peer alive, if client is able to resume it
+			grace := session.ResumeGrace()
+			// or while automatic ice restart is recovering the connection
+			if grace == 0 && peer.iceAutoRestart {
+				grace = iceRestartTimeout
+			}
+			if grace > 0 {
+				peer.destroyAfter(grace)
+			} else {
+				peer.Destroy()
+			}
 		case webrtc.PeerConnectionStateClosed:
 			// ensure we only run this once
 			once.Do(func() {
@@ -505,88 +1038,106 @@ func (manager *WebRTCManagerCtx) CreatePeer(session types.Session) (*webrtc.Sess
 				//
 				// TODO: Shutdown peer?
 				//
-				audioTrack.Shutdown()
+				if manager.budget != nil {
+					manager.budget.remove(peer)
+				}
+				if audioTrack != nil {
+					audioTrack.Shutdown()
+				}
 				videoTrack.Shutdown()
 				close(videoRtcp)
+				close(audioLevelStop)
 			})
 		}
 
-		metrics.SetState(state)
-	})
-
-	dataChannel.OnOpen(func() {
-		manager.curImage.AddListener(peer)
-		manager.curPosition.AddListener(peer)
-
-		// send initial cursor image
-		cur, img, err := manager.curImage.GetCurrent()
-		if err == nil {
-			err := peer.SendCursorImage(cur, img)
-			if err != nil {
-				logger.Err(err).Msg("failed to set cursor image")
-			}
-		} else {
-			logger.Err(err).Msg("failed to get cursor image")
-		}
-
-		// send initial cursor position
-		x, y := manager.desktop.GetCursorPosition()
-		err = peer.SendCursorPosition(x, y)
-		if err != nil {
-			logger.Err(err).Msg("failed to set cursor position")
+		// standby peer must not report over the current peer of the session
+		if !peer.standby.Load() {
+			metrics.SetState(state)
 		}
 	})
 
-	dataChannel.OnClose(func() {
-		manager.curImage.RemoveListener(peer)
-		manager.curPosition.RemoveListener(peer)
-	})
+	manager.setupDataChannel(logger, peer, session, dataChannel, dataQueue)
 
-	dataChannel.OnMessage(func(message webrtc.DataChannelMessage) {
-		if err := manager.handle(logger, message.Data, dataChannel, session); err != nil {
-			logger.Err(err).Msg("data handle failed")
-		}
-	})
-
-	session.SetWebRTCPeer(peer)
+	if inputChannel != nil {
+		inputChannel.OnMessage(func(message webrtc.DataChannelMessage) {
+			if err := manager.handleLossy(logger, message.Data, peer, session); err != nil {
+				logger.Err(err).Msg("input handle failed")
+			}
+		})
+	}
 
-	offer, err := peer.CreateOffer(false)
+	description, err := negotiate(peer)
 	if err != nil {
+		peer.Destroy()
 		return nil, nil, err
 	}
 
-	// on negotiation needed handler must be registered after creating initial
-	// offer, otherwise it can fire and intercept sucessful negotiation
+	// standby peer is attached when adopted
+	if kind != peerKindStandby {
+		manager.attachPeer(session, peer)
+	}
+
+	// on negotiation needed handler must be registered after initial
+	// negotiation, otherwise it can fire and intercept sucessful negotiation
 
 	connection.OnNegotiationNeeded(func() {
 		logger.Warn().Msg("negotiation is needed")
 
-		if connection.SignalingState() != webrtc.SignalingStateStable {
-			logger.Warn().Msg("connection isn't stable yet; postponing...")
-			return
+		if err := peer.Renegotiate(); err != nil {
+			logger.Err(err).Msg("renegotiation failed")
 		}
+	})
 
-		offer, err := peer.CreateOffer(false)
-		if err != nil {
-			logger.Err(err).Msg("sdp offer failed")
-			return
-		}
+	connection.OnSignalingStateChange(peer.onSignalingStateChange)
 
-		session.Send(
-			event.SIGNAL_OFFER,
-			message.SignalDescription{
-				SDP: offer.SDP,
-			})
-	})
+	// standby peer starts metrics collectors when adopted
+	if kind != peerKindStandby {
+		manager.startCollectors(peer)
+	}
+
+	// in passive mode, estimator reader only collects metrics, otherwise
+	// it is started and stopped together with video auto in SetVideo
+	if manager.config.Estimator.Passive {
+		peer.mu.Lock()
+		peer.startEstimatorReader()
+		peer.mu.Unlock()
+	}
+
+	return description, peer, nil
+}
+
+// startCollectors starts metrics collectors, that report to the metrics of the session
+func (manager *WebRTCManagerCtx) startCollectors(peer *WebRTCPeerCtx) {
+	peer.standby.Store(false)
+
+	peer.metrics.NewConnection()
+	go peer.metrics.rtcpReceiver(peer.rtcpChannel, peer.freezes)
+	go peer.metrics.connectionStats(peer.connection)
+	go peer.usageReporter()
+	if manager.config.StatsMetrics {
+		go peer.metrics.peerStats(peer)
+	}
+	if manager.config.Quality.Interval > 0 {
+		go peer.qualityReporter(manager.config.Quality)
+	}
+}
 
-	// start metrics collectors
-	go metrics.rtcpReceiver(videoRtcp)
-	go metrics.connectionStats(connection)
+// checkQuota rejects new peers of sessions, that are disconnected over quota
+func (manager *WebRTCManagerCtx) checkQuota(session types.Session) error {
+	quota := manager.config.Quota
+	if quota.Action == config.WebRTCQuotaActionDisconnect && quotaExceeded(session, quota) {
+		return types.ErrWebRTCQuotaExceeded
+	}
+	return nil
+}
 
-	// start estimator reader
-	go peer.estimatorReader()
+// attachPeer makes the peer current peer of the session
+func (manager *WebRTCManagerCtx) attachPeer(session types.Session, peer *WebRTCPeerCtx) {
+	if manager.budget != nil {
+		manager.budget.add(peer)
+	}
 
-	return offer, peer, nil
+	session.SetWebRTCPeer(peer)
 }
 
 func (manager *WebRTCManagerCtx) SetCursorPosition(x, y int) {
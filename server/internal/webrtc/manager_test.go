@@ -0,0 +1,110 @@
+package webrtc
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pion/ice/v2"
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+)
+
+func TestWebRTCManagerCtx_ICETCP(t *testing.T) {
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tcpMux := ice.NewTCPMuxDefault(ice.TCPMuxParams{
+		Listener:       listener,
+		ReadBufferSize: tcpReadChanBufferSize,
+	})
+	t.Cleanup(func() {
+		_ = tcpMux.Close()
+	})
+
+	// only tcp mux is configured, as when udp is blocked
+	manager := &WebRTCManagerCtx{
+		logger: zerolog.Nop(),
+		config: &config.WebRTC{},
+		tcpMux: tcpMux,
+	}
+
+	logger := zerolog.Nop()
+	local, _, err := manager.newPeerConnection(logger, nil, true, nil, newSenderReportInterceptor(logger), &rtpStatsGetter{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// client is able to use only active tcp candidates
+	settings := webrtc.SettingEngine{}
+	settings.SetNetworkTypes([]webrtc.NetworkType{webrtc.NetworkTypeTCP4})
+	remote, err := webrtc.NewAPI(webrtc.WithSettingEngine(settings)).NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = local.Close()
+		_ = remote.Close()
+	})
+
+	connected := make(chan struct{})
+	local.OnConnectionStateChange(func(state webrtc.PeerConnectionState) {
+		if state == webrtc.PeerConnectionStateConnected {
+			close(connected)
+		}
+	})
+
+	if _, err := local.CreateDataChannel("data", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// candidates are exchanged as part of the descriptions
+	offer, err := local.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gathered := webrtc.GatheringCompletePromise(local)
+	if err := local.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+	<-gathered
+
+	if err := remote.SetRemoteDescription(*local.LocalDescription()); err != nil {
+		t.Fatal(err)
+	}
+
+	answer, err := remote.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gathered = webrtc.GatheringCompletePromise(remote)
+	if err := remote.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+	<-gathered
+
+	if err := local.SetRemoteDescription(*remote.LocalDescription()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-connected:
+	case <-time.After(10 * time.Second):
+		t.Fatal("peer did not connect over ice tcp")
+	}
+
+	pair, err := local.SCTP().Transport().ICETransport().GetSelectedCandidatePair()
+	if err != nil || pair == nil {
+		t.Fatalf("no selected candidate pair: %v", err)
+	}
+	if pair.Local.Protocol != webrtc.ICEProtocolTCP {
+		t.Errorf("selected candidate protocol = %v, want %v", pair.Local.Protocol, webrtc.ICEProtocolTCP)
+	}
+}
@@ -70,6 +70,18 @@ func (m *metricsManager) getBySession(session types.Session) *metrics {
 				"session_id": sessionId,
 			},
 		}),
+		rekeyCount: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "rekey_count",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Count of peer replacements to rotate SRTP keys of a session.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
+
+		rtpStreams:   map[string]*rtpStreamMetrics{},
+		rtpStreamsMu: &sync.Mutex{},
 
 		iceCandidates:   map[string]struct{}{},
 		iceCandidatesMu: &sync.Mutex{},
@@ -137,6 +149,41 @@ func (m *metricsManager) getBySession(session types.Session) *metrics {
 			},
 		}),
 
+		estimatorSmoothedBitrate: promauto.NewGauge(prometheus.GaugeOpts{
+			Name:      "estimator_smoothed_bitrate",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Exponentially weighted moving average of the estimated target bitrate.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
+		estimatorBitrateSlope: promauto.NewGauge(prometheus.GaugeOpts{
+			Name:      "estimator_bitrate_slope",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Slope of the estimated target bitrate over the trend window, in bits per second per sample.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
+		estimatorStalls: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "estimator_stalls_total",
+			Namespace: "neko",
+			Help:      "Count of times the estimate stalled below the current stream bitrate.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
+		estimatorStalledSeconds: promauto.NewGauge(prometheus.GaugeOpts{
+			Name:      "estimator_stalled_seconds",
+			Namespace: "neko",
+			Help:      "Duration of the current estimator stall, 0 if not stalled.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
+
 		receiverReportDelay: promauto.NewGauge(prometheus.GaugeOpts{
 			Name:      "receiver_report_delay",
 			Namespace: "neko",
@@ -155,6 +202,33 @@ func (m *metricsManager) getBySession(session types.Session) *metrics {
 				"session_id": sessionId,
 			},
 		}),
+		receiverReportJitterSeconds: promauto.NewGauge(prometheus.GaugeOpts{
+			Name:      "receiver_report_jitter_seconds",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Video interarrival jitter from RTCP receiver reports, in seconds.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
+		videoFreezes: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "video_freezes_total",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Count of video freezes, when client received no packets for a while although they were sent.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
+		videoFrozenSeconds: promauto.NewGauge(prometheus.GaugeOpts{
+			Name:      "video_frozen_seconds",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Duration of the current video freeze, 0 if not frozen.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
 		receiverReportTotalLost: promauto.NewGauge(prometheus.GaugeOpts{
 			Name:      "receiver_report_total_lost",
 			Namespace: "neko",
@@ -216,6 +290,49 @@ func (m *metricsManager) getBySession(session types.Session) *metrics {
 				"transport":  "sctp",
 			},
 		}),
+
+		videoFramesDroppedBackpressure: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "frames_dropped",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Encoded frames dropped before being sent to a session.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+				"kind":       "video",
+				"reason":     "backpressure",
+			},
+		}),
+		videoFramesDroppedKeyframeWait: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "frames_dropped",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Encoded frames dropped before being sent to a session.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+				"kind":       "video",
+				"reason":     "keyframe_wait",
+			},
+		}),
+		audioFramesDroppedBackpressure: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "frames_dropped",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Encoded frames dropped before being sent to a session.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+				"kind":       "audio",
+				"reason":     "backpressure",
+			},
+		}),
+		cursorFramesDropped: promauto.NewCounter(prometheus.CounterOpts{
+			Name:      "cursor_frames_dropped",
+			Namespace: "neko",
+			Subsystem: "webrtc",
+			Help:      "Cursor position updates dropped because data channel was congested.",
+			ConstLabels: map[string]string{
+				"session_id": sessionId,
+			},
+		}),
 	}
 
 	m.sessions[sessionId] = met
@@ -228,6 +345,7 @@ type metrics struct {
 	connectionState      prometheus.Gauge
 	connectionStateCount prometheus.Counter
 	connectionCount      prometheus.Counter
+	rekeyCount           prometheus.Counter
 
 	iceCandidates         map[string]struct{}
 	iceCandidatesMu       *sync.Mutex
@@ -240,12 +358,22 @@ type metrics struct {
 	videoIds   map[string]prometheus.Gauge
 	videoIdsMu *sync.Mutex
 
+	rtpStreams   map[string]*rtpStreamMetrics
+	rtpStreamsMu *sync.Mutex
+
 	receiverEstimatedMaximumBitrate prometheus.Gauge
 	receiverEstimatedTargetBitrate  prometheus.Gauge
-
-	receiverReportDelay     prometheus.Gauge
-	receiverReportJitter    prometheus.Gauge
-	receiverReportTotalLost prometheus.Gauge
+	estimatorSmoothedBitrate        prometheus.Gauge
+	estimatorBitrateSlope           prometheus.Gauge
+	estimatorStalls                 prometheus.Counter
+	estimatorStalledSeconds         prometheus.Gauge
+
+	receiverReportDelay         prometheus.Gauge
+	receiverReportJitter        prometheus.Gauge
+	receiverReportJitterSeconds prometheus.Gauge
+	receiverReportTotalLost     prometheus.Gauge
+	videoFreezes                prometheus.Counter
+	videoFrozenSeconds          prometheus.Gauge
 
 	transportLayerNacks prometheus.Counter
 
@@ -253,6 +381,18 @@ type metrics struct {
 	iceBytesReceived  prometheus.Gauge
 	sctpBytesSent     prometheus.Gauge
 	sctpBytesReceived prometheus.Gauge
+
+	videoFramesDroppedBackpressure prometheus.Counter
+	videoFramesDroppedKeyframeWait prometheus.Counter
+	audioFramesDroppedBackpressure prometheus.Counter
+	cursorFramesDropped            prometheus.Counter
+}
+
+type rtpStreamMetrics struct {
+	packetsSent   prometheus.Gauge
+	packetsLost   prometheus.Gauge
+	jitter        prometheus.Gauge
+	roundTripTime prometheus.Gauge
 }
 
 func (met *metrics) reset() {
@@ -266,15 +406,22 @@ func (met *metrics) reset() {
 	met.iceCandidatesUsedTcp.Set(float64(0))
 
 	met.receiverEstimatedMaximumBitrate.Set(0)
+	met.estimatorStalledSeconds.Set(0)
 
 	met.receiverReportDelay.Set(0)
 	met.receiverReportJitter.Set(0)
+	met.receiverReportJitterSeconds.Set(0)
+	met.videoFrozenSeconds.Set(0)
 }
 
 func (met *metrics) NewConnection() {
 	met.connectionCount.Add(1)
 }
 
+func (met *metrics) NewRekey() {
+	met.rekeyCount.Add(1)
+}
+
 func (met *metrics) NewICECandidate(candidate webrtc.ICECandidateStats) {
 	met.iceCandidatesMu.Lock()
 	defer met.iceCandidatesMu.Unlock()
@@ -353,6 +500,56 @@ func (met *metrics) SetVideoID(videoId string) {
 	}
 }
 
+func (met *metrics) SetRTPStreamStats(kind string, data types.PeerRTPStreamStats) {
+	met.rtpStreamsMu.Lock()
+	defer met.rtpStreamsMu.Unlock()
+
+	stream, ok := met.rtpStreams[kind]
+	if !ok {
+		labels := map[string]string{
+			"session_id": met.sessionId,
+			"kind":       kind,
+		}
+
+		stream = &rtpStreamMetrics{
+			packetsSent: promauto.NewGauge(prometheus.GaugeOpts{
+				Name:        "rtp_packets_sent",
+				Namespace:   "neko",
+				Subsystem:   "webrtc",
+				Help:        "RTP packets sent to a session.",
+				ConstLabels: labels,
+			}),
+			packetsLost: promauto.NewGauge(prometheus.GaugeOpts{
+				Name:        "rtp_packets_lost",
+				Namespace:   "neko",
+				Subsystem:   "webrtc",
+				Help:        "RTP packets lost, as reported by a session.",
+				ConstLabels: labels,
+			}),
+			jitter: promauto.NewGauge(prometheus.GaugeOpts{
+				Name:        "rtp_jitter",
+				Namespace:   "neko",
+				Subsystem:   "webrtc",
+				Help:        "RTP interarrival jitter, as reported by a session.",
+				ConstLabels: labels,
+			}),
+			roundTripTime: promauto.NewGauge(prometheus.GaugeOpts{
+				Name:        "rtp_round_trip_time_seconds",
+				Namespace:   "neko",
+				Subsystem:   "webrtc",
+				Help:        "Round trip time calculated from receiver reports of a session.",
+				ConstLabels: labels,
+			}),
+		}
+		met.rtpStreams[kind] = stream
+	}
+
+	stream.packetsSent.Set(float64(data.PacketsSent))
+	stream.packetsLost.Set(float64(data.PacketsLost))
+	stream.jitter.Set(data.Jitter)
+	stream.roundTripTime.Set(data.RoundTripTime)
+}
+
 func (met *metrics) SetReceiverEstimatedMaximumBitrate(bitrate float32) {
 	met.receiverEstimatedMaximumBitrate.Set(float64(bitrate))
 }
@@ -361,12 +558,39 @@ func (met *metrics) SetReceiverEstimatedTargetBitrate(bitrate float64) {
 	met.receiverEstimatedTargetBitrate.Set(bitrate)
 }
 
+func (met *metrics) SetEstimatorTrend(smoothed, slope float64) {
+	met.estimatorSmoothedBitrate.Set(smoothed)
+	met.estimatorBitrateSlope.Set(slope)
+}
+
+// SetEstimatorStalled counts new stalls and tracks duration of the current one
+func (met *metrics) SetEstimatorStalled(stalled, started bool, duration time.Duration) {
+	if started {
+		met.estimatorStalls.Inc()
+	}
+
+	if !stalled {
+		duration = 0
+	}
+	met.estimatorStalledSeconds.Set(duration.Seconds())
+}
+
 func (met *metrics) SetReceiverReport(report rtcp.ReceptionReport) {
 	met.receiverReportDelay.Set(float64(report.Delay))
 	met.receiverReportJitter.Set(float64(report.Jitter))
 	met.receiverReportTotalLost.Set(float64(report.TotalLost))
 }
 
+// SetVideoFreeze counts new freezes and tracks duration of the current one
+func (met *metrics) SetVideoFreeze(started bool, frozenFor, jitter time.Duration) {
+	if started {
+		met.videoFreezes.Inc()
+	}
+
+	met.videoFrozenSeconds.Set(frozenFor.Seconds())
+	met.receiverReportJitterSeconds.Set(jitter.Seconds())
+}
+
 func (met *metrics) SetIceTransportStats(data webrtc.TransportStats) {
 	met.iceBytesSent.Set(float64(data.BytesSent))
 	met.iceBytesReceived.Set(float64(data.BytesReceived))
@@ -381,7 +605,7 @@ func (met *metrics) SetSctpTransportStats(data webrtc.TransportStats) {
 // collectors
 //
 
-func (met *metrics) rtcpReceiver(rtcpCh chan []rtcp.Packet) {
+func (met *metrics) rtcpReceiver(rtcpCh chan []rtcp.Packet, freezes *freezeDetector) {
 	for {
 		packets, ok := <-rtcpCh
 		if !ok {
@@ -397,7 +621,12 @@ func (met *metrics) rtcpReceiver(rtcpCh chan []rtcp.Packet) {
 				l := len(rtcpPacket.Reports)
 				if l > 0 {
 					// use only last report
-					met.SetReceiverReport(rtcpPacket.Reports[l-1])
+					report := rtcpPacket.Reports[l-1]
+					met.SetReceiverReport(report)
+
+					started, frozenFor := freezes.Update(report, time.Now())
+					_, jitter := freezes.Stats()
+					met.SetVideoFreeze(started, frozenFor, jitter)
 				}
 			case *rtcp.TransportLayerNack:
 				for _, pair := range rtcpPacket.Nacks {
@@ -457,3 +686,18 @@ func (met *metrics) connectionStats(connection *webrtc.PeerConnection) {
 		met.SetICECandidatesUsed(iceCandidatesUsed)
 	}
 }
+
+func (met *metrics) peerStats(peer *WebRTCPeerCtx) {
+	ticker := time.NewTicker(connectionStatsInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if peer.connection.ConnectionState() == webrtc.PeerConnectionStateClosed {
+			break
+		}
+
+		for kind, data := range peer.Stats().RTPStreams {
+			met.SetRTPStreamStats(kind, data)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package webrtc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+const (
+	// how many times is description created or ICE gathering awaited, before negotiation fails
+	negotiationAttempts = 3
+	// delay before the first retry, doubled with every following one
+	negotiationBackoff = 100 * time.Millisecond
+	// how long to wait for ICE gathering in non-trickle mode, in each attempt
+	iceGatherTimeout = 5 * time.Second
+)
+
+var (
+	errICEGatherTimeout  = errors.New("ice gathering timed out")
+	errCreateDescription = errors.New("unable to create description")
+)
+
+// setLocalDescription creates local description and sets it, transient failures are
+// retried with backoff. In non-trickle mode, it waits for ICE gathering to complete,
+// so that all candidates are part of the description.
+//
+// It must be called with both mu and negotiationMu locked, mu is released
+// while waiting, so that the peer is not blocked for the whole negotiation.
+func (peer *WebRTCPeerCtx) setLocalDescription(create func() (webrtc.SessionDescription, error)) (*webrtc.SessionDescription, error) {
+	var gatherComplete <-chan struct{}
+	var transformed *webrtc.SessionDescription
+	isSet := false
+
+	// creating description is retried only while signaling state stays the same,
+	// otherwise the description would not be valid anymore
+	signalingState := peer.connection.SignalingState()
+
+	attempt := func() error {
+		// description was set, but gathering timed out, it is only awaited again
+		if !isSet {
+			description, err := create()
+			if err != nil {
+				return fmt.Errorf("%w: %w", errCreateDescription, err)
+			}
+
+			// transforms are applied before the description is set, but pion accepts
+			// only the description it created, so transformed one is sent instead
+			if len(peer.sdpTransforms) > 0 {
+				description := peer.transformSDP(description)
+				transformed = &description
+			}
+
+			if !peer.iceTrickle {
+				// Create channel that is blocked until ICE Gathering is complete
+				gatherComplete = webrtc.GatheringCompletePromise(peer.connection)
+			}
+
+			if err := peer.connection.SetLocalDescription(description); err != nil {
+				return err
+			}
+
+			isSet = true
+		}
+
+		if gatherComplete != nil {
+			peer.mu.Unlock()
+			defer peer.mu.Lock()
+
+			select {
+			case <-gatherComplete:
+			case <-time.After(iceGatherTimeout):
+				return errICEGatherTimeout
+			}
+		}
+
+		return nil
+	}
+
+	backoff := negotiationBackoff
+	for i := 1; ; i++ {
+		err := attempt()
+		if err == nil {
+			break
+		}
+
+		// connection is gone, there is nothing to retry
+		if peer.destroyed || peer.connection.ConnectionState() == webrtc.PeerConnectionStateClosed {
+			return nil, err
+		}
+
+		// other errors, e.g. invalid signaling state, would fail again
+		retry := errors.Is(err, errICEGatherTimeout) ||
+			(errors.Is(err, errCreateDescription) && peer.connection.SignalingState() == signalingState)
+		if !retry || i >= negotiationAttempts {
+			peer.logger.Err(err).Int("attempts", i).Msg("failed to create local description")
+			return nil, fmt.Errorf("%w: %w", types.ErrWebRTCNegotiationFailed, err)
+		}
+
+		peer.logger.Warn().Err(err).Int("attempt", i).Dur("backoff", backoff).Msg("failed to create local description, retrying")
+
+		peer.mu.Unlock()
+		time.Sleep(backoff)
+		peer.mu.Lock()
+
+		backoff *= 2
+	}
+
+	local := *peer.connection.LocalDescription()
+
+	// candidates gathered meanwhile are part of the description set on the connection
+	if transformed != nil {
+		var err error
+		local, err = withLocalCandidates(*transformed, local)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// pion rejects modified local description, attribute is removed
+	// only from the description that is sent to the client
+	if !peer.rtcpRsize {
+		var err error
+		local, err = withoutRtcpRsize(local)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &local, nil
+}
@@ -14,6 +14,15 @@ const (
 	OP_TOUCH_BEGIN  = 0x08
 	OP_TOUCH_UPDATE = 0x09
 	OP_TOUCH_END    = 0x0a
+	// gamepad events
+	OP_GAMEPAD_CONNECT    = 0x0b
+	OP_GAMEPAD            = 0x0c
+	OP_GAMEPAD_DISCONNECT = 0x0d
+	// batch of input events
+	OP_BATCH = 0x0e
+	// relative mouse movement while pointer is locked
+	OP_POINTER_LOCK  = 0x0f
+	OP_MOVE_RELATIVE = 0x10
 )
 
 type Move struct {
@@ -21,6 +30,15 @@ type Move struct {
 	Y uint16
 }
 
+type PointerLock struct {
+	Locked bool
+}
+
+type MoveRelative struct {
+	DX int16
+	DY int16
+}
+
 // TODO: remove this once the client is fixed
 type Scroll_Old struct {
 	X int16
@@ -53,3 +71,25 @@ type Touch struct {
 	Y        int32
 	Pressure uint8
 }
+
+type GamepadConnect struct {
+	Index uint8
+}
+
+// followed by Axes x int16 and Buttons x uint8
+type Gamepad struct {
+	Index   uint8
+	Axes    uint8
+	Buttons uint8
+}
+
+// followed by Count x (BatchEvent + event payload)
+type Batch struct {
+	Count uint16
+}
+
+type BatchEvent struct {
+	Delay  uint16 // milliseconds since previous event
+	Event  uint8
+	Length uint16
+}
@@ -3,9 +3,13 @@ package payload
 import "math"
 
 const (
-	OP_CURSOR_POSITION = 0x01
-	OP_CURSOR_IMAGE    = 0x02
-	OP_PONG            = 0x03
+	OP_CURSOR_POSITION     = 0x01
+	OP_CURSOR_IMAGE        = 0x02
+	OP_PONG                = 0x03
+	OP_CURSOR_POSITION_EXT = 0x04
+	OP_STILL_FRAME         = 0x05
+	OP_STILL_CLEAR         = 0x06
+	OP_CLIPBOARD           = 0x07
 )
 
 type CursorPosition struct {
@@ -13,6 +17,17 @@ type CursorPosition struct {
 	Y uint16
 }
 
+// CursorPositionExt allows client to interpolate between position updates
+type CursorPositionExt struct {
+	CursorPosition
+
+	// monotonic time in milliseconds since the peer was created
+	Timestamp uint32
+	// movement since previous position sent to the peer
+	DX int16
+	DY int16
+}
+
 type CursorImage struct {
 	Width  uint16
 	Height uint16
@@ -20,6 +35,27 @@ type CursorImage struct {
 	Yhot   uint16
 }
 
+// StillFrame is a chunk of lossless PNG frame, client assembles chunks with
+// the same ID and shows the frame over the video until it receives a clear.
+type StillFrame struct {
+	ID     uint32
+	Offset uint32
+	Total  uint32
+}
+
+type StillClear struct {
+	ID uint32
+}
+
+// Clipboard is a chunk of UTF-8 clipboard text, client assembles
+// chunks with the same ID, newer ID replaces incomplete older one.
+type Clipboard struct {
+	ID        uint32
+	Offset    uint32
+	Total     uint32
+	Truncated bool
+}
+
 type Pong struct {
 	Ping
 
@@ -3,7 +3,13 @@ package webrtc
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/pion/interceptor/pkg/cc"
@@ -15,33 +21,110 @@ import (
 	"github.com/m1k1o/neko/server/internal/webrtc/payload"
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
 	"github.com/m1k1o/neko/server/pkg/utils"
 )
 
+// data channel buffered amount above which cursor position updates are dropped
+const cursorMaxBufferedAmount = 64 * 1024
+
 type WebRTCPeerCtx struct {
-	mu         sync.Mutex
-	logger     zerolog.Logger
-	session    types.Session
-	metrics    *metrics
-	connection *webrtc.PeerConnection
+	mu sync.Mutex
+	// serializes negotiations, mu is released while negotiation waits
+	negotiationMu sync.Mutex
+	id            int32
+	logger        zerolog.Logger
+	session       types.Session
+	metrics       *metrics
+	connection    *webrtc.PeerConnection
 	// bandwidth estimator
 	estimator     cc.BandwidthEstimator
 	estimateTrend *utils.TrendDetector
+	// last trend direction, shared with quality reporter
+	estimateDirection atomic.Int32
+	estimatorStop     chan struct{}
+	budget            *bandwidthBudget
+	// recent estimator decisions, for tuning
+	estimatorHistory   []types.EstimatorDecision
+	estimatorHistoryMu sync.Mutex
 	// stream selectors
-	video types.StreamSelectorManager
-	audio types.StreamSinkManager
+	video   types.StreamSelectorManager
+	audio   types.StreamSinkManager
+	capture types.CaptureManager
+	desktop types.DesktopManager
 	// tracks & channels
 	audioTrack  *Track
 	videoTrack  *Track
 	dataChannel *webrtc.DataChannel
-	rtcpChannel chan []rtcp.Packet
+	// bulk messages yield to urgent ones on data channel, nil if not enabled
+	dataQueue *sendQueue
+	// data channel and its queue are replaced together, when the channel is recreated
+	dataMu sync.RWMutex
+	// closed data channel was recreated this many times in a row
+	dataReconnects int
+	// video playout freezes, as seen in receiver reports
+	freezes *freezeDetector
+	// unreliable channel for input and cursor position, nil if not enabled
+	inputChannel *webrtc.DataChannel
+	// channels opened by client, by label
+	remoteChannels   map[string]*webrtc.DataChannel
+	remoteChannelsMu sync.Mutex
+	dataCipher       *dataCipher
+	rtcpChannel      chan []rtcp.Packet
+	// rtcp
+	senderReports *senderReportInterceptor
+	rtpStats      *rtpStatsGetter
+	// how the peer is signaled
+	kind peerKind
+	// prepared standby peer, that was not adopted yet
+	standby atomic.Bool
 	// config
 	iceTrickle      bool
+	nack            bool
+	rtcpRsize       bool
+	sdpTransforms   []types.SDPTransform
 	estimatorConfig config.WebRTCEstimator
+	quota           config.WebRTCQuota
 	paused          bool
-	videoAuto       bool
-	videoDisabled   bool
-	audioDisabled   bool
+	// renegotiation requested while signaling was not stable
+	negotiationPending bool
+	videoAuto          bool
+	videoMaxFps        float64
+	videoCeiling       string
+	videoMaxResolution *types.StreamResolution
+	videoViewport      *types.PeerViewport
+	videoFollowHost    bool
+	videoScale         float64
+	videoForceEncoder  types.EncoderBackend
+	videoDisabled      bool
+	audioDisabled      bool
+	audioGain          float64
+	audioEncoding      types.AudioEncoding
+	audioSyncOffset    int
+	pointerLocked      bool
+	// batched input of this peer is replayed one batch at a time
+	batchMu      sync.Mutex
+	batchPending atomic.Int32
+	// extended cursor position frames, for client side interpolation
+	cursorMotion bool
+	cursorEpoch  time.Time
+	cursorLast   *payload.CursorPosition
+	// hash of the last cursor image sent to this peer
+	cursorImageHash uint64
+	// lossless still frames were requested by the client
+	losslessStills bool
+	// clipboard is sent over the data channel, as requested by the client
+	clipboardChannel bool
+	clipboardSerial  uint32
+	// disconnected peer is destroyed after resume grace period
+	destroyTimer *time.Timer
+	destroyed    bool
+	// peer is replaced after rekey interval
+	rekeyTimer *time.Timer
+	// automatic ice restart on network change
+	iceAutoRestart  bool
+	iceSelectedPair string
+	iceRestartLast  time.Time
 }
 
 //
@@ -49,55 +132,141 @@ type WebRTCPeerCtx struct {
 //
 
 func (peer *WebRTCPeerCtx) CreateOffer(ICERestart bool) (*webrtc.SessionDescription, error) {
+	peer.negotiationMu.Lock()
+	defer peer.negotiationMu.Unlock()
+
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
 
-	offer, err := peer.connection.CreateOffer(&webrtc.OfferOptions{
-		ICERestart: ICERestart,
+	return peer.setLocalDescription(func() (webrtc.SessionDescription, error) {
+		return peer.connection.CreateOffer(&webrtc.OfferOptions{
+			ICERestart: ICERestart,
+		})
 	})
-	if err != nil {
-		return nil, err
-	}
-
-	return peer.setLocalDescription(offer)
 }
 
 func (peer *WebRTCPeerCtx) CreateAnswer() (*webrtc.SessionDescription, error) {
+	peer.negotiationMu.Lock()
+	defer peer.negotiationMu.Unlock()
+
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	return peer.setLocalDescription(func() (webrtc.SessionDescription, error) {
+		return peer.connection.CreateAnswer(nil)
+	})
+}
+
+// Renegotiate creates a new offer and sends it to the client, the answer
+// is then handled by SetRemoteDescription. If signaling is not stable,
+// renegotiation is postponed until it becomes stable again.
+func (peer *WebRTCPeerCtx) Renegotiate() error {
+	peer.negotiationMu.Lock()
+	defer peer.negotiationMu.Unlock()
+
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
 
-	answer, err := peer.connection.CreateAnswer(nil)
+	if peer.connection.SignalingState() != webrtc.SignalingStateStable {
+		peer.logger.Warn().Msg("connection isn't stable yet; postponing renegotiation...")
+		peer.negotiationPending = true
+		return nil
+	}
+
+	peer.negotiationPending = false
+
+	description, err := peer.setLocalDescription(func() (webrtc.SessionDescription, error) {
+		return peer.connection.CreateOffer(nil)
+	})
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return peer.setLocalDescription(answer)
-}
+	peer.logger.Info().Msg("sending renegotiation offer")
+	peer.session.Send(
+		event.SIGNAL_OFFER,
+		message.SignalDescription{
+			SDP: description.SDP,
+		})
 
-func (peer *WebRTCPeerCtx) setLocalDescription(description webrtc.SessionDescription) (*webrtc.SessionDescription, error) {
-	if !peer.iceTrickle {
-		// Create channel that is blocked until ICE Gathering is complete
-		gatherComplete := webrtc.GatheringCompletePromise(peer.connection)
+	return nil
+}
 
-		if err := peer.connection.SetLocalDescription(description); err != nil {
-			return nil, err
-		}
+func (peer *WebRTCPeerCtx) onSignalingStateChange(state webrtc.SignalingState) {
+	peer.mu.Lock()
+	pending := peer.negotiationPending
+	peer.mu.Unlock()
 
-		<-gatherComplete
-	} else {
-		if err := peer.connection.SetLocalDescription(description); err != nil {
-			return nil, err
+	// run postponed renegotiation once we are stable again
+	if state == webrtc.SignalingStateStable && pending {
+		if err := peer.Renegotiate(); err != nil {
+			peer.logger.Err(err).Msg("postponed renegotiation failed")
 		}
 	}
-
-	return peer.connection.LocalDescription(), nil
 }
 
+// SetRemoteDescription applies client description, if it fits current signaling
+// state. Stale answers are ignored and offers colliding with our pending offer
+// are rejected, because pion does not support rolling back local description.
 func (peer *WebRTCPeerCtx) SetRemoteDescription(desc webrtc.SessionDescription) error {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
 
-	return peer.connection.SetRemoteDescription(desc)
+	state := peer.connection.SignalingState()
+
+	switch desc.Type {
+	case webrtc.SDPTypeAnswer, webrtc.SDPTypePranswer:
+		// answer is only expected while our offer is pending, e.g. duplicate answer
+		if state != webrtc.SignalingStateHaveLocalOffer && state != webrtc.SignalingStateHaveRemotePranswer {
+			peer.logger.Warn().
+				Str("type", desc.Type.String()).
+				Str("signaling_state", state.String()).
+				Msg("ignoring stale answer, no local offer is pending")
+			return nil
+		}
+	case webrtc.SDPTypeOffer:
+		// offer collision, client must answer our offer first
+		if state == webrtc.SignalingStateHaveLocalOffer {
+			peer.logger.Warn().
+				Str("signaling_state", state.String()).
+				Msg("offer collision, rejecting remote offer while local offer is pending")
+			return types.ErrWebRTCOfferCollision
+		}
+	}
+
+	err := peer.connection.SetRemoteDescription(desc)
+
+	// answer is applied even when senders cannot be started, pion then
+	// returns only a generic error, so that negotiated codecs are inspected
+	isAnswer := desc.Type == webrtc.SDPTypeAnswer || desc.Type == webrtc.SDPTypePranswer
+	if isAnswer && (err == nil || errors.Is(err, webrtc.ErrUnsupportedCodec)) {
+		if mismatchErr := peer.checkCodecs(&desc); mismatchErr != nil {
+			return mismatchErr
+		}
+	}
+
+	return err
+}
+
+// checkCodecs reports sending transceivers without an agreed codec
+func (peer *WebRTCPeerCtx) checkCodecs(desc *webrtc.SessionDescription) error {
+	mismatches, err := findCodecMismatches(peer.connection.GetTransceivers(), desc)
+	if err != nil || len(mismatches) == 0 {
+		return nil
+	}
+
+	details := make([]string, len(mismatches))
+	for i, m := range mismatches {
+		peer.logger.Error().
+			Str("kind", m.Kind).
+			Str("mid", m.Mid).
+			Str("codec", m.Codec).
+			Strs("remote_codecs", m.Remote).
+			Msg("no common codec negotiated, media will not flow")
+		details[i] = m.String()
+	}
+
+	return fmt.Errorf("%w: %s", types.ErrWebRTCCodecMismatch, strings.Join(details, "; "))
 }
 
 func (peer *WebRTCPeerCtx) SetCandidate(candidate webrtc.ICECandidateInit) error {
@@ -112,6 +281,22 @@ func (peer *WebRTCPeerCtx) Destroy() {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
 
+	// peer can be destroyed from multiple places, only the first call is effective
+	if peer.destroyed {
+		return
+	}
+	peer.destroyed = true
+
+	if peer.destroyTimer != nil {
+		peer.destroyTimer.Stop()
+		peer.destroyTimer = nil
+	}
+
+	if peer.rekeyTimer != nil {
+		peer.rekeyTimer.Stop()
+		peer.rekeyTimer = nil
+	}
+
 	var err error
 
 	// if peer connection is not closed, close it
@@ -122,7 +307,76 @@ func (peer *WebRTCPeerCtx) Destroy() {
 	peer.logger.Err(err).Msg("peer connection destroyed")
 }
 
-func (peer *WebRTCPeerCtx) estimatorReader() {
+func (peer *WebRTCPeerCtx) isDestroyed() bool {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	return peer.destroyed
+}
+
+// destroyAfter keeps disconnected peer alive for the given time, so that it can be resumed
+func (peer *WebRTCPeerCtx) destroyAfter(delay time.Duration) {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if peer.destroyTimer != nil {
+		return
+	}
+
+	peer.logger.Info().Dur("grace", delay).Msg("peer disconnected, waiting for resume")
+	peer.destroyTimer = time.AfterFunc(delay, peer.Destroy)
+}
+
+// cancelDestroy is called when disconnected peer was resumed
+func (peer *WebRTCPeerCtx) cancelDestroy() {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	if peer.destroyTimer == nil {
+		return
+	}
+
+	peer.destroyTimer.Stop()
+	peer.destroyTimer = nil
+	peer.logger.Info().Msg("peer resumed")
+}
+
+// current video bitrate sent to the peer, 0 if video is not sent
+func (peer *WebRTCPeerCtx) videoBitrate() int {
+	if peer.videoTrack.Paused() {
+		return 0
+	}
+
+	stream, ok := peer.videoTrack.Stream()
+	if !ok {
+		return 0
+	}
+
+	return int(stream.Bitrate())
+}
+
+// must be called with peer mutex locked
+func (peer *WebRTCPeerCtx) startEstimatorReader() {
+	// if estimator is disabled or already running, do nothing
+	if peer.estimator == nil || peer.estimatorStop != nil {
+		return
+	}
+
+	peer.estimatorStop = make(chan struct{})
+	go peer.estimatorReader(peer.estimatorStop)
+}
+
+// must be called with peer mutex locked
+func (peer *WebRTCPeerCtx) stopEstimatorReader() {
+	if peer.estimatorStop == nil {
+		return
+	}
+
+	close(peer.estimatorStop)
+	peer.estimatorStop = nil
+}
+
+func (peer *WebRTCPeerCtx) estimatorReader(stop <-chan struct{}) {
 	conf := peer.estimatorConfig
 
 	// if estimator is not in debug mode, use a nop logger
@@ -148,27 +402,50 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 	// since when are we neutral but cannot accomodate current bitrate
 	// we migt be stalled or estimator just reached zer (very bad connection)
 	stalledSince := time.Time{}
+	wasStalled := false
+	defer peer.metrics.SetEstimatorStalled(false, false, 0)
 	// when was the last upgrade/downgrade
 	lastUpgradeTime := time.Time{}
 	lastDowngradeTime := time.Time{}
 
-	for range ticker.C {
+	// padding is sent only while waiting for upgrade, stopped when reader exits
+	probe := newProber(peer.videoTrack)
+	defer probe.Stop()
+
+	debugLogger.Debug().Msg("estimator reader started")
+	defer debugLogger.Debug().Msg("estimator reader stopped")
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
 		targetBitrate := peer.estimator.GetTargetBitrate()
 		peer.metrics.SetReceiverEstimatedTargetBitrate(float64(targetBitrate))
 
 		// if peer connection is closed, stop reading
 		if peer.connection.ConnectionState() == webrtc.PeerConnectionStateClosed {
-			break
+			return
 		}
 
+		// settings of the peer are changed by signaling, they are read under the lock
+		peer.mu.Lock()
+		videoAuto, videoDisabled, paused, videoCeiling := peer.videoAuto, peer.videoDisabled, peer.paused, peer.videoCeiling
+		peer.mu.Unlock()
+
 		// if estimation or video is disabled, do nothing
-		if !peer.videoAuto || peer.videoDisabled || peer.paused || conf.Passive {
+		if !videoAuto || videoDisabled || paused || conf.Passive {
+			probe.Stop()
 			continue
 		}
 
 		// get trend direction to decide if we should upgrade or downgrade
 		peer.estimateTrend.AddValue(int64(targetBitrate))
+		peer.metrics.SetEstimatorTrend(peer.estimateTrend.GetSmoothedValue(), peer.estimateTrend.GetSlope())
 		direction := peer.estimateTrend.GetDirection()
+		peer.estimateDirection.Store(int32(direction))
 
 		// get current stream bitrate
 		stream, ok := peer.videoTrack.Stream()
@@ -184,8 +461,63 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 			continue
 		}
 
+		// codecs need different headroom above their stream bitrate
+		codecFactor := conf.CodecFactor(stream.Codec().Name)
+
 		// check whats the difference between target and stream bitrate
-		diff := float64(targetBitrate) / float64(streamBitrate)
+		diff := float64(targetBitrate) / (float64(streamBitrate) * codecFactor)
+
+		// record decision with the current state of the estimate
+		decide := func(action, reason string) {
+			peer.recordEstimatorDecision(types.EstimatorDecision{
+				Timestamp:     time.Now(),
+				Action:        action,
+				Reason:        reason,
+				StreamID:      streamId,
+				TargetBitrate: targetBitrate,
+				StreamBitrate: streamBitrate,
+				Diff:          diff,
+				Direction:     direction.String(),
+			})
+		}
+
+		// under global budget, bandwidth is allocated to higher priority sessions first
+		if peer.budget != nil {
+			allowance := peer.budget.allowance(peer)
+
+			// yield bandwidth to sessions with higher priority
+			if int(streamBitrate) > allowance {
+				if time.Since(lastDowngradeTime) >= conf.DowngradeBackoff {
+					err := peer.SetVideo(types.PeerVideoRequest{
+						Selector: &types.StreamSelector{
+							ID:   streamId,
+							Type: types.StreamSelectorTypeLower,
+						},
+						Reason: types.VideoChangeReasonEstimatorDowngrade,
+					})
+					if err != nil && err != types.ErrWebRTCStreamNotFound {
+						peer.logger.Warn().Err(err).Msg("failed to downgrade video stream")
+					}
+					lastDowngradeTime = time.Now()
+					decide(types.EstimatorActionDowngrade, types.EstimatorReasonBudget)
+
+					debugLogger.Info().
+						Int("allowance", allowance).
+						Msg("downgraded video stream, bandwidth budget exceeded")
+				} else {
+					decide(types.EstimatorActionHold, types.EstimatorReasonDowngradeBackoff)
+				}
+
+				probe.Stop()
+				continue
+			}
+
+			// do not upgrade beyond what is left from the budget
+			if targetBitrate > allowance {
+				targetBitrate = allowance
+				diff = float64(targetBitrate) / (float64(streamBitrate) * codecFactor)
+			}
+		}
 
 		debugLogger.Info().
 			Float64("diff", diff).
@@ -202,6 +534,8 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 
 		// if we are neutral and stalled for too long, we might be congesting
 		stalled := direction == utils.TrendDirectionNeutral && time.Since(stalledSince) > conf.StalledDuration
+		peer.metrics.SetEstimatorStalled(stalled, stalled && !wasStalled, time.Since(stalledSince))
+		wasStalled = stalled
 		if stalled {
 			debugLogger.Warn().
 				Time("stalled_since", stalledSince).
@@ -210,11 +544,23 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 
 		// if we have an downward trend or are stalled, we might be congesting
 		if direction == utils.TrendDirectionDownward || stalled {
+			reason := types.EstimatorReasonDownwardTrend
+			if stalled {
+				reason = types.EstimatorReasonStalled
+			}
+
 			// we reset the stable time because we are congesting
 			stableSince = time.Now()
 
+			// do not add more traffic to congested connection
+			if probe.Running() {
+				probe.Stop()
+				debugLogger.Info().Msg("stopped probing, connection is congesting")
+			}
+
 			// if we downgraded recently, we wait for some more time
 			if time.Since(lastDowngradeTime) < conf.DowngradeBackoff {
+				decide(types.EstimatorActionHold, types.EstimatorReasonDowngradeBackoff)
 				debugLogger.Debug().
 					Time("last_downgrade", lastDowngradeTime).
 					Msgf("downgraded recently, waiting for at least %v", conf.DowngradeBackoff)
@@ -223,6 +569,7 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 
 			// if we are not unstable but we fluctuate we should wait for some more time
 			if time.Since(unstableSince) < conf.UnstableDuration {
+				decide(types.EstimatorActionHold, types.EstimatorReasonUnstable)
 				debugLogger.Debug().
 					Time("unstable_since", unstableSince).
 					Msgf("we are not unstable long enough, waiting for at least %v", conf.UnstableDuration)
@@ -231,6 +578,7 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 
 			// if we still have a big difference between target and stream bitrate, we wait for some more time
 			if conf.DiffThreshold >= 0 && diff > 1+conf.DiffThreshold {
+				decide(types.EstimatorActionHold, types.EstimatorReasonDiffThreshold)
 				debugLogger.Debug().
 					Float64("diff", diff).
 					Float64("threshold", conf.DiffThreshold).
@@ -244,6 +592,7 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 					ID:   streamId,
 					Type: types.StreamSelectorTypeLower,
 				},
+				Reason: types.VideoChangeReasonEstimatorDowngrade,
 			})
 			if err != nil && err != types.ErrWebRTCStreamNotFound {
 				peer.logger.Warn().Err(err).Msg("failed to downgrade video stream")
@@ -251,8 +600,10 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 			lastDowngradeTime = time.Now()
 
 			if err == types.ErrWebRTCStreamNotFound {
+				decide(types.EstimatorActionHold, types.EstimatorReasonLowestStream)
 				debugLogger.Info().Msg("looks like we are already on the lowest stream")
 			} else {
+				decide(types.EstimatorActionDowngrade, reason)
 				debugLogger.Info().Msg("downgraded video stream")
 			}
 			continue
@@ -265,8 +616,42 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 		// if we are on the highest stream, we don't need to do anything
 		// but if there is a higher stream, we should try to upgrade and see if it works
 
+		// manually picked stream is the highest one we may upgrade to
+		if streamId == videoCeiling {
+			if probe.Running() {
+				probe.Stop()
+			}
+
+			decide(types.EstimatorActionHold, types.EstimatorReasonCeiling)
+			debugLogger.Debug().Msg("reached manual ceiling, not upgrading")
+			continue
+		}
+
+		// probe for available bandwidth if there is a higher stream we cannot accomodate yet,
+		// so that estimator does not need to wait for real traffic to grow
+		if conf.Probing {
+			peer.mu.Lock()
+			_, hasHigher := peer.getStream(types.StreamSelector{
+				ID:   streamId,
+				Type: types.StreamSelectorTypeHigher,
+			})
+			peer.mu.Unlock()
+
+			if hasHigher && diff < 1+conf.DiffThreshold {
+				probeBitrate := int(float64(streamBitrate) * conf.ProbeOverhead)
+				if !probe.Running() {
+					debugLogger.Info().Int("probe_bitrate", probeBitrate).Msg("started probing")
+				}
+				probe.Start(probeBitrate)
+			} else if probe.Running() {
+				probe.Stop()
+				debugLogger.Info().Msg("stopped probing")
+			}
+		}
+
 		// if we upgraded recently, we wait for some more time
 		if time.Since(lastUpgradeTime) < conf.UpgradeBackoff {
+			decide(types.EstimatorActionHold, types.EstimatorReasonUpgradeBackoff)
 			debugLogger.Debug().
 				Time("last_upgrade", lastUpgradeTime).
 				Msgf("upgraded recently, waiting for at least %v", conf.UpgradeBackoff)
@@ -276,6 +661,7 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 		// if we are not stable for long enough, we wait for some more time
 		// because bandwidth estimation might fluctuate
 		if time.Since(stableSince) < conf.StableDuration {
+			decide(types.EstimatorActionHold, types.EstimatorReasonStable)
 			debugLogger.Debug().
 				Time("stable_since", stableSince).
 				Msgf("we are not stable long enough, waiting for at least %v", conf.StableDuration)
@@ -284,6 +670,7 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 
 		// upgrade only if estimated bitrate passed the threshold
 		if conf.DiffThreshold >= 0 && diff < 1+conf.DiffThreshold {
+			decide(types.EstimatorActionHold, types.EstimatorReasonDiffThreshold)
 			debugLogger.Debug().
 				Float64("diff", diff).
 				Float64("threshold", conf.DiffThreshold).
@@ -297,26 +684,69 @@ func (peer *WebRTCPeerCtx) estimatorReader() {
 				ID:   streamId,
 				Type: types.StreamSelectorTypeHigher,
 			},
+			Reason: types.VideoChangeReasonEstimatorUpgrade,
 		})
 		if err != nil && err != types.ErrWebRTCStreamNotFound {
 			peer.logger.Warn().Err(err).Msg("failed to upgrade video stream")
 		}
 		lastUpgradeTime = time.Now()
 
+		// new stream brings its own traffic, probing is restarted if needed
+		probe.Stop()
+
 		if err == types.ErrWebRTCStreamNotFound {
+			decide(types.EstimatorActionHold, types.EstimatorReasonHighestStream)
 			debugLogger.Info().Msg("looks like we are already on the highest stream")
 		} else {
+			decide(types.EstimatorActionUpgrade, types.EstimatorReasonStableEstimate)
 			debugLogger.Info().Msg("upgraded video stream")
 		}
 	}
 }
 
+// recordEstimatorDecision appends decision to the history, holds are recorded
+// only when their reason changes, so that history is not flooded on every tick
+func (peer *WebRTCPeerCtx) recordEstimatorDecision(decision types.EstimatorDecision) {
+	size := peer.estimatorConfig.History
+	if size <= 0 {
+		return
+	}
+
+	peer.estimatorHistoryMu.Lock()
+	defer peer.estimatorHistoryMu.Unlock()
+
+	if n := len(peer.estimatorHistory); n > 0 && decision.Action == types.EstimatorActionHold {
+		last := peer.estimatorHistory[n-1]
+		if last.Action == decision.Action && last.Reason == decision.Reason {
+			return
+		}
+	}
+
+	history := append(peer.estimatorHistory, decision)
+
+	// keep only the newest decisions
+	if over := len(history) - size; over > 0 {
+		history = append([]types.EstimatorDecision{}, history[over:]...)
+	}
+
+	peer.estimatorHistory = history
+}
+
+func (peer *WebRTCPeerCtx) EstimatorHistory() []types.EstimatorDecision {
+	peer.estimatorHistoryMu.Lock()
+	defer peer.estimatorHistoryMu.Unlock()
+
+	return append([]types.EstimatorDecision{}, peer.estimatorHistory...)
+}
+
 func (peer *WebRTCPeerCtx) SetPaused(isPaused bool) error {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
 
 	peer.videoTrack.SetPaused(isPaused || peer.videoDisabled)
-	peer.audioTrack.SetPaused(isPaused || peer.audioDisabled)
+	if peer.audioTrack != nil {
+		peer.audioTrack.SetPaused(isPaused || peer.audioDisabled)
+	}
 
 	peer.logger.Info().Bool("is_paused", isPaused).Msg("set paused")
 	peer.paused = isPaused
@@ -335,10 +765,52 @@ func (peer *WebRTCPeerCtx) Paused() bool {
 // video
 //
 
+func (peer *WebRTCPeerCtx) ValidateVideo(r types.PeerVideoRequest) error {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	return peer.validateVideo(r)
+}
+
+// validateVideo checks request before anything is applied, must be called with mutex locked
+func (peer *WebRTCPeerCtx) validateVideo(r types.PeerVideoRequest) error {
+	// video stays disabled until the quota period is over
+	if r.Disabled != nil && !*r.Disabled && peer.quota.Action == config.WebRTCQuotaActionAudioOnly && quotaExceeded(peer.session, peer.quota) {
+		return types.ErrWebRTCQuotaExceeded
+	}
+
+	if r.ScaleResolutionDownBy != nil {
+		if scale := *r.ScaleResolutionDownBy; scale < 1 || scale > types.VideoScaleMax {
+			return types.ErrCaptureVideoScaleOutOfRange
+		}
+	}
+
+	if r.ForceEncoder != nil {
+		switch *r.ForceEncoder {
+		case "", types.EncoderBackendSoftware, types.EncoderBackendHardware:
+		default:
+			return types.ErrWebRTCEncoderUnknown
+		}
+	}
+
+	if r.Selector != nil {
+		if _, ok := peer.getStream(*r.Selector); !ok {
+			return types.ErrWebRTCStreamNotFound
+		}
+	}
+
+	return nil
+}
+
 func (peer *WebRTCPeerCtx) SetVideo(r types.PeerVideoRequest) error {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
 
+	// validate before anything is applied
+	if err := peer.validateVideo(r); err != nil {
+		return err
+	}
+
 	modified := false
 
 	// video disabled
@@ -355,16 +827,191 @@ func (peer *WebRTCPeerCtx) SetVideo(r types.PeerVideoRequest) error {
 		}
 	}
 
+	// video max fps
+	if r.MaxFPS != nil {
+		maxFps := *r.MaxFPS
+
+		// update only if changed
+		if peer.videoMaxFps != maxFps {
+			peer.videoMaxFps = maxFps
+
+			// reselect current stream to apply the new cap
+			if stream, ok := peer.videoTrack.Stream(); ok && r.Selector == nil {
+				r.Selector = &types.StreamSelector{
+					ID:   stream.ID(),
+					Type: types.StreamSelectorTypeExact,
+				}
+			}
+
+			peer.logger.Info().Float64("max_fps", maxFps).Msg("set video max fps")
+			modified = true
+		}
+	}
+
+	// video max resolution
+	if r.MaxResolution != nil {
+		var maxResolution *types.StreamResolution
+		if r.MaxResolution.Width > 0 || r.MaxResolution.Height > 0 {
+			maxResolution = &types.StreamResolution{
+				Width:  r.MaxResolution.Width,
+				Height: r.MaxResolution.Height,
+			}
+		}
+
+		// update only if changed
+		if !reflect.DeepEqual(peer.videoMaxResolution, maxResolution) {
+			peer.videoMaxResolution = maxResolution
+
+			// reselect current stream to apply the new cap
+			if stream, ok := peer.videoTrack.Stream(); ok && r.Selector == nil {
+				r.Selector = &types.StreamSelector{
+					ID:   stream.ID(),
+					Type: types.StreamSelectorTypeExact,
+				}
+			}
+
+			peer.logger.Info().Interface("max_resolution", maxResolution).Msg("set video max resolution")
+			modified = true
+		}
+	}
+
+	// video viewport
+	if r.Viewport != nil {
+		var viewport *types.PeerViewport
+		if r.Viewport.Width > 0 && r.Viewport.Height > 0 {
+			viewport = &types.PeerViewport{
+				Width:  r.Viewport.Width,
+				Height: r.Viewport.Height,
+			}
+		}
+
+		// update only if changed
+		if !reflect.DeepEqual(peer.videoViewport, viewport) {
+			peer.videoViewport = viewport
+
+			// select from the highest stream, it is capped by viewport afterwards
+			if r.Selector == nil {
+				r.Selector = peer.highestVideoSelector()
+			}
+
+			peer.logger.Info().Interface("viewport", viewport).Msg("set video viewport")
+			modified = true
+		}
+	}
+
+	// video resolution scaling
+	if r.ScaleResolutionDownBy != nil {
+		scale := *r.ScaleResolutionDownBy
+
+		// update only if changed
+		if peer.videoScale != scale {
+			peer.videoScale = scale
+
+			// reselect current stream to apply the new scale
+			if stream, ok := peer.videoTrack.Stream(); ok && r.Selector == nil {
+				r.Selector = &types.StreamSelector{
+					ID:   stream.ID(),
+					Type: types.StreamSelectorTypeExact,
+				}
+			}
+
+			peer.logger.Info().Float64("scale_resolution_down_by", scale).Msg("set video scale")
+			modified = true
+		}
+	}
+
+	// video forced encoder backend
+	if r.ForceEncoder != nil {
+		encoder := *r.ForceEncoder
+
+		// update only if changed
+		if peer.videoForceEncoder != encoder {
+			peer.videoForceEncoder = encoder
+
+			// reselect current stream to apply the new backend
+			if stream, ok := peer.videoTrack.Stream(); ok && r.Selector == nil {
+				r.Selector = &types.StreamSelector{
+					ID:   types.BaseStreamID(stream.ID()),
+					Type: types.StreamSelectorTypeExact,
+				}
+			}
+
+			peer.logger.Info().Str("force_encoder", string(encoder)).Msg("set video forced encoder")
+			modified = true
+		}
+	}
+
+	// video follows the host
+	if r.FollowHost != nil {
+		followHost := *r.FollowHost
+
+		// stream is selected by the host, not by estimator
+		if followHost && r.Auto == nil {
+			auto := false
+			r.Auto = &auto
+		}
+
+		// update only if changed
+		if peer.videoFollowHost != followHost {
+			peer.videoFollowHost = followHost
+
+			peer.logger.Info().Bool("follow_host", followHost).Msg("set video follow host")
+			modified = true
+		}
+	}
+
+	// video manual ceiling
+	if r.ManualCeiling != nil {
+		ceiling := ""
+		if *r.ManualCeiling {
+			if r.Selector != nil {
+				stream, ok := peer.getStream(*r.Selector)
+				if !ok {
+					return types.ErrWebRTCStreamNotFound
+				}
+				ceiling = stream.ID()
+			} else if stream, ok := peer.videoTrack.Stream(); ok {
+				ceiling = types.BaseStreamID(stream.ID())
+			}
+
+			// estimator may go lower under congestion, unless explicitly disabled
+			if r.Auto == nil {
+				auto := true
+				r.Auto = &auto
+			}
+		}
+
+		// update only if changed
+		if peer.videoCeiling != ceiling {
+			peer.videoCeiling = ceiling
+
+			peer.logger.Info().Str("ceiling", ceiling).Msg("set video manual ceiling")
+			modified = true
+		}
+	}
+
 	// video selector
 	if r.Selector != nil {
 		selector := *r.Selector
 
 		// get requested video stream from selector
-		stream, ok := peer.video.GetStream(selector)
+		stream, ok := peer.getStream(selector)
 		if !ok {
 			return types.ErrWebRTCStreamNotFound
 		}
 
+		// do not exceed manually picked stream
+		stream = peer.capVideoCeiling(stream)
+
+		// do not send larger stream than client is able to render
+		stream = peer.capVideoViewport(stream)
+
+		// respect framerate cap by selecting lower stream
+		stream = peer.capVideoFps(stream)
+
+		// scale resolution of the selected stream
+		stream = peer.scaleVideo(stream)
+
 		// set video stream to track
 		changed, err := peer.videoTrack.SetStream(stream)
 		if err != nil {
@@ -394,6 +1041,13 @@ func (peer *WebRTCPeerCtx) SetVideo(r types.PeerVideoRequest) error {
 		if peer.videoAuto != videoAuto {
 			peer.videoAuto = videoAuto
 
+			// estimator reader runs only while video auto is enabled
+			if videoAuto {
+				peer.startEstimatorReader()
+			} else {
+				peer.stopEstimatorReader()
+			}
+
 			peer.logger.Info().Bool("video_auto", videoAuto).Msg("set video auto")
 			modified = true
 		}
@@ -401,44 +1055,290 @@ func (peer *WebRTCPeerCtx) SetVideo(r types.PeerVideoRequest) error {
 
 	// send video signal if modified
 	if modified {
+		reason := r.Reason
+		if reason == "" {
+			reason = types.VideoChangeReasonManual
+		}
+
 		go func() {
 			// in goroutine because of mutex and we don't want to block
-			peer.session.Send(event.SIGNAL_VIDEO, peer.Video())
+			video := peer.Video().ForProtocol(peer.session.ProtocolVersion())
+			video.Reason = reason
+			peer.session.Send(event.SIGNAL_VIDEO, video)
 		}()
 	}
 
 	return nil
 }
 
-func (peer *WebRTCPeerCtx) Video() types.PeerVideo {
-	peer.mu.Lock()
-	defer peer.mu.Unlock()
+// getStream selects video stream, that never exceeds resolution cap of the peer
+// and uses forced encoder backend of the peer, if set
+func (peer *WebRTCPeerCtx) getStream(selector types.StreamSelector) (types.StreamSinkManager, bool) {
+	selector.MaxResolution = peer.videoMaxResolution
 
-	// get current video stream ID
-	ID := ""
-	stream, ok := peer.videoTrack.Stream()
-	if ok {
-		ID = stream.ID()
+	stream, ok := peer.video.GetStream(selector)
+	if !ok || peer.videoForceEncoder == "" || peer.encoderMatches(stream) {
+		return stream, ok
 	}
 
-	return types.PeerVideo{
-		Disabled: peer.videoDisabled,
-		ID:       ID,
-		Video:    ID, // TODO: Remove, used for backward compatibility
-		Auto:     peer.videoAuto,
+	// exact stream is replaced by the nearest lower one, or higher if there is none
+	directions := []types.StreamSelectorType{selector.Type}
+	if selector.Type == types.StreamSelectorTypeExact {
+		directions = []types.StreamSelectorType{types.StreamSelectorTypeLower, types.StreamSelectorTypeHigher}
+	}
+
+	for _, direction := range directions {
+		next := stream
+		for {
+			next, ok = peer.video.GetStream(types.StreamSelector{
+				ID:            next.ID(),
+				Type:          direction,
+				MaxResolution: peer.videoMaxResolution,
+			})
+			if !ok || next.ID() == stream.ID() {
+				break
+			}
+			if peer.encoderMatches(next) {
+				return next, true
+			}
+		}
 	}
+
+	return nil, false
 }
 
-//
-// audio
-//
+// encoderMatches checks, if stream is configured for the forced encoder backend
+func (peer *WebRTCPeerCtx) encoderMatches(stream types.StreamSinkManager) bool {
+	if stream.HwEncoder() {
+		return peer.videoForceEncoder == types.EncoderBackendHardware
+	}
+	return peer.videoForceEncoder == types.EncoderBackendSoftware
+}
+
+// must be called with peer mutex locked
+func (peer *WebRTCPeerCtx) capVideoCeiling(stream types.StreamSinkManager) types.StreamSinkManager {
+	if peer.videoCeiling == "" {
+		return stream
+	}
+
+	// video ids are ordered from the highest stream
+	for _, id := range peer.video.IDs() {
+		if id == stream.ID() {
+			// stream is above the ceiling
+			if ceiling, ok := peer.getStream(types.StreamSelector{
+				ID:   peer.videoCeiling,
+				Type: types.StreamSelectorTypeExact,
+			}); ok {
+				return ceiling
+			}
+			return stream
+		}
+		if id == peer.videoCeiling {
+			return stream
+		}
+	}
+
+	return stream
+}
+
+// must be called with peer mutex locked
+func (peer *WebRTCPeerCtx) capVideoFps(stream types.StreamSinkManager) types.StreamSinkManager {
+	if peer.videoMaxFps <= 0 {
+		return stream
+	}
+
+	for stream.Fps() > peer.videoMaxFps {
+		lower, ok := peer.getStream(types.StreamSelector{
+			ID:   stream.ID(),
+			Type: types.StreamSelectorTypeLower,
+		})
+		if !ok {
+			// already on the lowest stream
+			break
+		}
+		stream = lower
+	}
+
+	return stream
+}
+
+// highestVideoSelector selects the highest stream, nil if there are no streams
+func (peer *WebRTCPeerCtx) highestVideoSelector() *types.StreamSelector {
+	// video ids are ordered from the highest stream
+	ids := peer.video.IDs()
+	if len(ids) == 0 {
+		return nil
+	}
+
+	return &types.StreamSelector{
+		ID:   ids[0],
+		Type: types.StreamSelectorTypeExact,
+	}
+}
+
+// must be called with peer mutex locked
+func (peer *WebRTCPeerCtx) capVideoViewport(stream types.StreamSinkManager) types.StreamSinkManager {
+	if peer.videoViewport == nil {
+		return stream
+	}
+
+	for {
+		lower, ok := peer.getStream(types.StreamSelector{
+			ID:   stream.ID(),
+			Type: types.StreamSelectorTypeLower,
+		})
+		if !ok {
+			// already on the lowest stream
+			break
+		}
+
+		// lower stream would not cover the viewport anymore
+		width, height := lower.Size()
+		if width < peer.videoViewport.Width || height < peer.videoViewport.Height {
+			break
+		}
+		stream = lower
+	}
+
+	return stream
+}
+
+// must be called with peer mutex locked
+func (peer *WebRTCPeerCtx) scaleVideo(stream types.StreamSinkManager) types.StreamSinkManager {
+	if peer.videoScale <= 1 {
+		return stream
+	}
+
+	scaled, err := peer.capture.VideoScaled(stream.ID(), peer.videoScale)
+	if err == nil {
+		return scaled
+	}
+
+	// custom pipelines cannot be scaled, select the lowest stream
+	// that still covers the requested resolution instead
+	peer.logger.Warn().Err(err).Str("video_id", stream.ID()).Msg("unable to scale video, selecting nearest stream")
+
+	width, height := stream.Size()
+	minWidth := int(float64(width) / peer.videoScale)
+	minHeight := int(float64(height) / peer.videoScale)
+
+	for {
+		lower, ok := peer.getStream(types.StreamSelector{
+			ID:   stream.ID(),
+			Type: types.StreamSelectorTypeLower,
+		})
+		if !ok {
+			break
+		}
+
+		width, height := lower.Size()
+		if width < minWidth || height < minHeight {
+			break
+		}
+		stream = lower
+	}
+
+	return stream
+}
+
+func (peer *WebRTCPeerCtx) Video() types.PeerVideo {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	// get current video stream ID
+	ID, fps, encoder, flip := "", 0.0, types.EncoderBackend(""), false
+	stream, ok := peer.videoTrack.Stream()
+	if ok {
+		ID, fps, encoder, flip = stream.ID(), stream.Fps(), stream.Encoder(), stream.Flipped()
+	}
+
+	// scaled stream is reported by the ID of its source
+	scaled := ID != types.BaseStreamID(ID)
+	ID = types.BaseStreamID(ID)
+
+	return types.PeerVideo{
+		Disabled: peer.videoDisabled,
+		ID:       ID,
+		Video:    ID, // TODO: Remove, used for backward compatibility
+		Auto:     peer.videoAuto,
+		FPS:      fps,
+		MaxFPS:   peer.videoMaxFps,
+		NACK:     peer.nack,
+		Viewport: peer.videoViewport,
+		Ceiling:  peer.videoCeiling,
+		Encoder:  encoder,
+
+		ForceEncoder:  peer.videoForceEncoder,
+		MaxResolution: peer.videoMaxResolution,
+		FollowHost:    peer.videoFollowHost,
+		Flip:          flip,
+
+		ScaleResolutionDownBy: peer.videoScale,
+		Scaled:                scaled,
+	}
+}
+
+//
+// audio
+//
+
+func (peer *WebRTCPeerCtx) ValidateAudio(r types.PeerAudioRequest) error {
+	if r.SyncOffset != nil && (*r.SyncOffset > types.PeerAudioSyncOffsetMax || *r.SyncOffset < -types.PeerAudioSyncOffsetMax) {
+		return types.ErrWebRTCAudioSyncOffset
+	}
+
+	return nil
+}
 
 func (peer *WebRTCPeerCtx) SetAudio(r types.PeerAudioRequest) error {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
 
+	// audio is disabled on the server, there is nothing to set
+	if peer.audioTrack == nil {
+		return nil
+	}
+
+	// validate before anything is applied
+	if err := peer.ValidateAudio(r); err != nil {
+		return err
+	}
+
 	modified := false
 
+	// audio gain, streams with different gain are separate pipelines
+	// compared after rounding, as similar gains share the same pipeline
+	if r.Gain != nil && types.RoundAudioGain(*r.Gain) != peer.audioGain {
+		gain := types.RoundAudioGain(*r.Gain)
+		audio, err := peer.capture.AudioGain(gain, peer.audioEncoding)
+		if err != nil {
+			return err
+		}
+
+		if _, err := peer.audioTrack.SetStream(audio); err != nil {
+			return err
+		}
+
+		peer.audioGain = gain
+		peer.logger.Info().Float64("gain", peer.audioGain).Msg("set audio gain")
+		modified = true
+	}
+
+	// audio sync offset, compensates constant a/v desync of the client
+	if r.SyncOffset != nil && *r.SyncOffset != peer.audioSyncOffset {
+		offset := *r.SyncOffset
+
+		// rtp timestamps cannot be shifted, as sender reports would cancel it out,
+		// audio is delayed for positive offset and video for negative one instead
+		delay := time.Duration(offset) * time.Millisecond
+		peer.audioTrack.SetDelay(max(delay, 0))
+		peer.videoTrack.SetDelay(max(-delay, 0))
+		peer.audioSyncOffset = offset
+
+		peer.logger.Info().Int("sync_offset", offset).Msg("set audio sync offset")
+		modified = true
+	}
+
 	// audio disabled
 	if r.Disabled != nil {
 		disabled := *r.Disabled
@@ -469,14 +1369,320 @@ func (peer *WebRTCPeerCtx) Audio() types.PeerAudio {
 	defer peer.mu.Unlock()
 
 	return types.PeerAudio{
-		Disabled: peer.audioDisabled,
+		Disabled:   peer.audioDisabled,
+		Gain:       peer.audioGain,
+		Fmtp:       peer.audioFmtp(),
+		SyncOffset: peer.audioSyncOffset,
+	}
+}
+
+func (peer *WebRTCPeerCtx) SendSignalState() {
+	peer.session.Send(event.SIGNAL_VIDEO, peer.Video().ForProtocol(peer.session.ProtocolVersion()))
+	peer.session.Send(event.SIGNAL_AUDIO, peer.Audio())
+}
+
+func (peer *WebRTCPeerCtx) Diagnostics() types.PeerDiagnostics {
+	return types.PeerDiagnostics{
+		ConnectionState:    peer.connection.ConnectionState().String(),
+		ICEConnectionState: peer.connection.ICEConnectionState().String(),
+		SignalingState:     peer.connection.SignalingState().String(),
+
+		LocalDescription:  peer.connection.LocalDescription(),
+		RemoteDescription: peer.connection.RemoteDescription(),
+
+		CandidatePairs: candidatePairs(peer.connection.GetStats()),
 	}
 }
 
+// Stats is server side equivalent of browser getStats, it adds rtp stream stats of
+// outgoing tracks, because pion does not report them in connection stats.
+func (peer *WebRTCPeerCtx) Stats() types.PeerStats {
+	report := peer.connection.GetStats()
+
+	stats := types.PeerStats{
+		Timestamp:      time.Now(),
+		RTPStreams:     map[string]types.PeerRTPStreamStats{},
+		CandidatePairs: candidatePairs(report),
+	}
+
+	if data, ok := report["iceTransport"].(webrtc.TransportStats); ok {
+		stats.Transport = &data
+	}
+
+	if data, ok := report["sctpTransport"].(webrtc.TransportStats); ok {
+		stats.SCTPTransport = &data
+	}
+
+	// transceivers carry agreed parameters only after remote description was applied
+	stats.Codecs = map[string]types.PeerNegotiatedCodec{}
+	if peer.connection.RemoteDescription() != nil {
+		stats.Codecs = negotiatedCodecs(peer.connection.GetTransceivers())
+	}
+
+	for kind, track := range map[string]*Track{
+		"audio": peer.audioTrack,
+		"video": peer.videoTrack,
+	} {
+		if track == nil {
+			continue
+		}
+
+		s, ok := peer.rtpStats.Get(track.SSRC())
+		if !ok {
+			continue
+		}
+
+		stats.RTPStreams[kind] = types.PeerRTPStreamStats{
+			SSRC:          track.SSRC(),
+			PacketsSent:   s.OutboundRTPStreamStats.PacketsSent,
+			BytesSent:     s.OutboundRTPStreamStats.BytesSent,
+			NACKCount:     s.OutboundRTPStreamStats.NACKCount,
+			FIRCount:      s.OutboundRTPStreamStats.FIRCount,
+			PLICount:      s.OutboundRTPStreamStats.PLICount,
+			PacketsLost:   s.RemoteInboundRTPStreamStats.PacketsLost,
+			Jitter:        s.RemoteInboundRTPStreamStats.Jitter,
+			FractionLost:  s.RemoteInboundRTPStreamStats.FractionLost,
+			RoundTripTime: s.RemoteInboundRTPStreamStats.RoundTripTime.Seconds(),
+		}
+	}
+
+	if video, ok := stats.RTPStreams["video"]; ok && peer.freezes != nil {
+		video.Freezes, _ = peer.freezes.Stats()
+		stats.RTPStreams["video"] = video
+	}
+
+	return stats
+}
+
+// candidatePairs resolves candidates referenced by candidate pairs in stats report.
+func candidatePairs(stats webrtc.StatsReport) []types.PeerCandidatePair {
+	// candidate pairs reference candidates by their stats id
+	candidates := map[string]webrtc.ICECandidateStats{}
+	for _, s := range stats {
+		if candidate, ok := s.(webrtc.ICECandidateStats); ok {
+			candidates[candidate.ID] = candidate
+		}
+	}
+
+	pairs := []types.PeerCandidatePair{}
+	for _, s := range stats {
+		if pair, ok := s.(webrtc.ICECandidatePairStats); ok {
+			pairs = append(pairs, types.PeerCandidatePair{
+				ICECandidatePairStats: pair,
+				Local:                 candidates[pair.LocalCandidateID],
+				Remote:                candidates[pair.RemoteCandidateID],
+			})
+		}
+	}
+
+	return pairs
+}
+
+func (peer *WebRTCPeerCtx) SenderReports() map[string]types.SenderReport {
+	reports := map[string]types.SenderReport{}
+	for kind, track := range map[string]*Track{
+		"audio": peer.audioTrack,
+		"video": peer.videoTrack,
+	} {
+		if track == nil {
+			continue
+		}
+
+		if report, ok := peer.senderReports.Get(track.SSRC()); ok {
+			reports[kind] = report
+		}
+	}
+
+	return reports
+}
+
+// negotiated fmtp line of audio codec, empty if not negotiated yet
+func (peer *WebRTCPeerCtx) audioFmtp() string {
+	for _, transceiver := range peer.connection.GetTransceivers() {
+		sender := transceiver.Sender()
+		if transceiver.Kind() != webrtc.RTPCodecTypeAudio || sender == nil {
+			continue
+		}
+
+		codecs := sender.GetParameters().Codecs
+		if len(codecs) > 0 {
+			return codecs[0].SDPFmtpLine
+		}
+	}
+
+	return ""
+}
+
+//
+// pointer lock
+//
+
+func (peer *WebRTCPeerCtx) SetPointerLocked(locked bool) {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	peer.pointerLocked = locked
+}
+
+func (peer *WebRTCPeerCtx) PointerLocked() bool {
+	peer.mu.Lock()
+	defer peer.mu.Unlock()
+
+	return peer.pointerLocked
+}
+
 //
 // data channel
 //
 
+// DataKey returns key used for data channel encryption, or nil if disabled.
+func (peer *WebRTCPeerCtx) DataKey() []byte {
+	if peer.dataCipher == nil {
+		return nil
+	}
+
+	return peer.dataCipher.Key()
+}
+
+// current data channel along with its send queue
+func (peer *WebRTCPeerCtx) getDataChannel() (*webrtc.DataChannel, *sendQueue) {
+	peer.dataMu.RLock()
+	defer peer.dataMu.RUnlock()
+
+	return peer.dataChannel, peer.dataQueue
+}
+
+func (peer *WebRTCPeerCtx) setDataChannel(channel *webrtc.DataChannel, queue *sendQueue) {
+	peer.dataMu.Lock()
+	defer peer.dataMu.Unlock()
+
+	peer.dataChannel = channel
+	peer.dataQueue = queue
+}
+
+// send message over data channel, encrypted if enabled
+func (peer *WebRTCPeerCtx) sendData(data []byte) error {
+	channel, queue := peer.getDataChannel()
+	return peer.sendDataOn(channel, queue, data)
+}
+
+var errEmptyMessage = errors.New("data channel message is empty")
+
+// sealData encrypts message, if data channel encryption is enabled
+func (peer *WebRTCPeerCtx) sealData(data []byte) ([]byte, error) {
+	if peer.dataCipher == nil {
+		return data, nil
+	}
+
+	return peer.dataCipher.Seal(data)
+}
+
+// send message over given channel, through its send queue, if it has one
+func (peer *WebRTCPeerCtx) sendDataOn(channel *webrtc.DataChannel, queue *sendQueue, data []byte) error {
+	// opcode is the first byte of every message
+	if len(data) == 0 {
+		return errEmptyMessage
+	}
+
+	opcode := data[0]
+
+	data, err := peer.sealData(data)
+	if err != nil {
+		return err
+	}
+
+	if queue != nil {
+		return queue.Send(opcode, data)
+	}
+
+	return channel.Send(data)
+}
+
+func (peer *WebRTCPeerCtx) addRemoteChannel(dc *webrtc.DataChannel) {
+	peer.remoteChannelsMu.Lock()
+	defer peer.remoteChannelsMu.Unlock()
+
+	label := dc.Label()
+	peer.remoteChannels[label] = dc
+
+	dc.OnClose(func() {
+		peer.remoteChannelsMu.Lock()
+		defer peer.remoteChannelsMu.Unlock()
+
+		// channel could have been replaced by a new one with the same label
+		if peer.remoteChannels[label] == dc {
+			delete(peer.remoteChannels, label)
+		}
+	})
+}
+
+func (peer *WebRTCPeerCtx) SendData(label string, data []byte) error {
+	peer.remoteChannelsMu.Lock()
+	dc, ok := peer.remoteChannels[label]
+	peer.remoteChannelsMu.Unlock()
+
+	if !ok {
+		return types.ErrWebRTCDataChannelNotFound
+	}
+
+	return dc.Send(data)
+}
+
+// channel for messages that can be lost, falls back to reliable data channel
+func (peer *WebRTCPeerCtx) lossyChannel() (*webrtc.DataChannel, *sendQueue) {
+	if peer.inputChannel != nil && peer.inputChannel.ReadyState() == webrtc.DataChannelStateOpen {
+		return peer.inputChannel, nil
+	}
+
+	return peer.getDataChannel()
+}
+
+// receive message from data channel, decrypted if enabled
+func (peer *WebRTCPeerCtx) receiveData(data []byte) ([]byte, error) {
+	if peer.dataCipher != nil {
+		return peer.dataCipher.Open(data)
+	}
+
+	return data, nil
+}
+
+func (peer *WebRTCPeerCtx) ID() string {
+	return strconv.Itoa(int(peer.id))
+}
+
+// flipped returns whether the current video stream is mirrored horizontally
+func (peer *WebRTCPeerCtx) flipped() bool {
+	stream, ok := peer.videoTrack.Stream()
+	return ok && stream.Flipped()
+}
+
+// mirrorX converts x coordinate between region and the current video stream, if it is flipped
+func (peer *WebRTCPeerCtx) mirrorX(region *types.CaptureRegion, x int) int {
+	if !peer.flipped() {
+		return x
+	}
+
+	width := peer.desktop.GetScreenSize().Width
+	if region != nil {
+		width = region.Width
+	}
+
+	return width - 1 - x
+}
+
+// ToScreen converts coordinates of the current video stream to screen coordinates
+func (peer *WebRTCPeerCtx) ToScreen(x, y int) (int, int) {
+	region := peer.capture.Region()
+	return region.ToScreen(peer.mirrorX(region, x), y)
+}
+
+// toVideo converts screen coordinates to coordinates of the current video stream
+func (peer *WebRTCPeerCtx) toVideo(x, y int) (int, int) {
+	region := peer.capture.Region()
+	x, y = region.FromScreen(x, y)
+	return peer.mirrorX(region, x), y
+}
+
 func (peer *WebRTCPeerCtx) SendCursorPosition(x, y int) error {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
@@ -486,16 +1692,47 @@ func (peer *WebRTCPeerCtx) SendCursorPosition(x, y int) error {
 		return nil
 	}
 
+	x, y = peer.toVideo(x, y)
+
+	// skip position updates while data channel is congested, next one will follow soon
+	channel, queue := peer.lossyChannel()
+	if channel.BufferedAmount() > cursorMaxBufferedAmount {
+		peer.metrics.cursorFramesDropped.Inc()
+		return nil
+	}
+
 	header := payload.Header{
 		Event:  payload.OP_CURSOR_POSITION,
 		Length: 7,
 	}
 
-	data := payload.CursorPosition{
+	var data any = payload.CursorPosition{
 		X: uint16(x),
 		Y: uint16(y),
 	}
 
+	if peer.cursorMotion {
+		header = payload.Header{
+			Event:  payload.OP_CURSOR_POSITION_EXT,
+			Length: 15,
+		}
+
+		position := data.(payload.CursorPosition)
+		ext := payload.CursorPositionExt{
+			CursorPosition: position,
+			Timestamp:      uint32(time.Since(peer.cursorEpoch).Milliseconds()),
+		}
+
+		// first position has no movement
+		if last := peer.cursorLast; last != nil {
+			ext.DX = int16(x - int(last.X))
+			ext.DY = int16(y - int(last.Y))
+		}
+
+		peer.cursorLast = &position
+		data = ext
+	}
+
 	buffer := &bytes.Buffer{}
 
 	if err := binary.Write(buffer, binary.BigEndian, header); err != nil {
@@ -506,13 +1743,18 @@ func (peer *WebRTCPeerCtx) SendCursorPosition(x, y int) error {
 		return err
 	}
 
-	return peer.dataChannel.Send(buffer.Bytes())
+	return peer.sendDataOn(channel, queue, buffer.Bytes())
 }
 
-func (peer *WebRTCPeerCtx) SendCursorImage(cur *types.CursorImage, img []byte) error {
+func (peer *WebRTCPeerCtx) SendCursorImage(cur *types.CursorImage, img []byte, hash uint64) error {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
 
+	// shape did not change since last image, position updates are sent separately
+	if hash == peer.cursorImageHash {
+		return nil
+	}
+
 	header := payload.Header{
 		Event:  payload.OP_CURSOR_IMAGE,
 		Length: uint16(11 + len(img)),
@@ -539,5 +1781,74 @@ func (peer *WebRTCPeerCtx) SendCursorImage(cur *types.CursorImage, img []byte) e
 		return err
 	}
 
-	return peer.dataChannel.Send(buffer.Bytes())
+	// image is sent again, if sending failed
+	if err := peer.sendData(buffer.Bytes()); err != nil {
+		return err
+	}
+
+	peer.cursorImageHash = hash
+	return nil
+}
+
+// max size of still frame data in a single data channel message
+const stillFrameChunkSize = 60000
+
+func (peer *WebRTCPeerCtx) SendStillFrame(id uint32, img []byte) error {
+	for offset := 0; offset < len(img); offset += stillFrameChunkSize {
+		chunk := img[offset:min(offset+stillFrameChunkSize, len(img))]
+
+		header := payload.Header{
+			Event:  payload.OP_STILL_FRAME,
+			Length: uint16(15 + len(chunk)),
+		}
+
+		data := payload.StillFrame{
+			ID:     id,
+			Offset: uint32(offset),
+			Total:  uint32(len(img)),
+		}
+
+		buffer := &bytes.Buffer{}
+
+		if err := binary.Write(buffer, binary.BigEndian, header); err != nil {
+			return err
+		}
+
+		if err := binary.Write(buffer, binary.BigEndian, data); err != nil {
+			return err
+		}
+
+		if err := binary.Write(buffer, binary.BigEndian, chunk); err != nil {
+			return err
+		}
+
+		if err := peer.sendData(buffer.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (peer *WebRTCPeerCtx) ClearStillFrame(id uint32) error {
+	header := payload.Header{
+		Event:  payload.OP_STILL_CLEAR,
+		Length: 7,
+	}
+
+	data := payload.StillClear{
+		ID: id,
+	}
+
+	buffer := &bytes.Buffer{}
+
+	if err := binary.Write(buffer, binary.BigEndian, header); err != nil {
+		return err
+	}
+
+	if err := binary.Write(buffer, binary.BigEndian, data); err != nil {
+		return err
+	}
+
+	return peer.sendData(buffer.Bytes())
 }
@@ -0,0 +1,532 @@
+package webrtc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// newTestPeers returns server peer and remote client connection, both with a data channel
+func newTestPeers(t *testing.T) (*WebRTCPeerCtx, *webrtc.PeerConnection) {
+	t.Helper()
+
+	local, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = local.Close()
+		_ = remote.Close()
+	})
+
+	for _, connection := range []*webrtc.PeerConnection{local, remote} {
+		if _, err := connection.CreateDataChannel("data", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	peer := &WebRTCPeerCtx{
+		logger:     zerolog.Nop(),
+		connection: local,
+		iceTrickle: true,
+	}
+
+	return peer, remote
+}
+
+// remoteAnswer applies offer on remote connection and returns its answer
+func remoteAnswer(t *testing.T, remote *webrtc.PeerConnection, offer *webrtc.SessionDescription) webrtc.SessionDescription {
+	t.Helper()
+
+	if err := remote.SetRemoteDescription(*offer); err != nil {
+		t.Fatal(err)
+	}
+
+	answer, err := remote.CreateAnswer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := remote.SetLocalDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	return answer
+}
+
+// remoteOffer returns new offer created by remote connection
+func remoteOffer(t *testing.T, remote *webrtc.PeerConnection) webrtc.SessionDescription {
+	t.Helper()
+
+	offer, err := remote.CreateOffer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := remote.SetLocalDescription(offer); err != nil {
+		t.Fatal(err)
+	}
+
+	return offer
+}
+
+func TestWebRTCPeerCtx_SetRemoteDescription(t *testing.T) {
+	tests := []struct {
+		name      string
+		run       func(t *testing.T, peer *WebRTCPeerCtx, remote *webrtc.PeerConnection) error
+		wantErr   error
+		wantState webrtc.SignalingState
+	}{
+		{
+			name: "answer to local offer",
+			run: func(t *testing.T, peer *WebRTCPeerCtx, remote *webrtc.PeerConnection) error {
+				offer, err := peer.CreateOffer(false)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return peer.SetRemoteDescription(remoteAnswer(t, remote, offer))
+			},
+			wantState: webrtc.SignalingStateStable,
+		}, {
+			name: "duplicate answer is ignored",
+			run: func(t *testing.T, peer *WebRTCPeerCtx, remote *webrtc.PeerConnection) error {
+				offer, err := peer.CreateOffer(false)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				answer := remoteAnswer(t, remote, offer)
+				if err := peer.SetRemoteDescription(answer); err != nil {
+					t.Fatal(err)
+				}
+
+				return peer.SetRemoteDescription(answer)
+			},
+			wantState: webrtc.SignalingStateStable,
+		}, {
+			name: "answer without offer is ignored",
+			run: func(t *testing.T, peer *WebRTCPeerCtx, remote *webrtc.PeerConnection) error {
+				offer := remoteOffer(t, remote)
+
+				// answer created by another connection, never requested by peer
+				other, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer other.Close()
+
+				return peer.SetRemoteDescription(remoteAnswer(t, other, &offer))
+			},
+			wantState: webrtc.SignalingStateStable,
+		}, {
+			name: "remote offer while stable",
+			run: func(t *testing.T, peer *WebRTCPeerCtx, remote *webrtc.PeerConnection) error {
+				return peer.SetRemoteDescription(remoteOffer(t, remote))
+			},
+			wantState: webrtc.SignalingStateHaveRemoteOffer,
+		}, {
+			name: "offer collision is rejected",
+			run: func(t *testing.T, peer *WebRTCPeerCtx, remote *webrtc.PeerConnection) error {
+				if _, err := peer.CreateOffer(false); err != nil {
+					t.Fatal(err)
+				}
+
+				return peer.SetRemoteDescription(remoteOffer(t, remote))
+			},
+			wantErr:   types.ErrWebRTCOfferCollision,
+			wantState: webrtc.SignalingStateHaveLocalOffer,
+		}, {
+			name: "answer after offer collision is applied",
+			run: func(t *testing.T, peer *WebRTCPeerCtx, remote *webrtc.PeerConnection) error {
+				offer, err := peer.CreateOffer(false)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				// client offer collides and is rejected
+				collision := remoteOffer(t, remote)
+				if err := peer.SetRemoteDescription(collision); !errors.Is(err, types.ErrWebRTCOfferCollision) {
+					t.Fatalf("SetRemoteDescription() error = %v, want %v", err, types.ErrWebRTCOfferCollision)
+				}
+
+				// then client answers our offer, from a fresh connection as pion cannot roll back
+				other, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer other.Close()
+
+				return peer.SetRemoteDescription(remoteAnswer(t, other, offer))
+			},
+			wantState: webrtc.SignalingStateStable,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peer, remote := newTestPeers(t)
+
+			err := tt.run(t, peer, remote)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("SetRemoteDescription() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if state := peer.connection.SignalingState(); state != tt.wantState {
+				t.Errorf("SignalingState() = %v, want %v", state, tt.wantState)
+			}
+		})
+	}
+}
+
+// withoutCodec removes codec from all media sections of the description
+func withoutCodec(t *testing.T, desc webrtc.SessionDescription, codec string) webrtc.SessionDescription {
+	t.Helper()
+
+	parsed, err := desc.Unmarshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, media := range parsed.MediaDescriptions {
+		removed := map[string]bool{}
+		attributes := []sdp.Attribute{}
+		for _, attr := range media.Attributes {
+			pt, encoding, _ := strings.Cut(attr.Value, " ")
+			if attr.Key == "rtpmap" && strings.HasPrefix(encoding, codec+"/") {
+				removed[pt] = true
+				continue
+			}
+			attributes = append(attributes, attr)
+		}
+		media.Attributes = attributes
+
+		formats := []string{}
+		for _, format := range media.MediaName.Formats {
+			if !removed[format] {
+				formats = append(formats, format)
+			}
+		}
+		media.MediaName.Formats = formats
+	}
+
+	raw, err := parsed.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	desc.SDP = string(raw)
+	return desc
+}
+
+func TestWebRTCPeerCtx_CodecMismatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		answer  func(t *testing.T, offer *webrtc.SessionDescription) webrtc.SessionDescription
+		wantErr error
+	}{
+		{
+			name: "answer with offered codec",
+			answer: func(t *testing.T, offer *webrtc.SessionDescription) webrtc.SessionDescription {
+				remote, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { _ = remote.Close() })
+
+				return remoteAnswer(t, remote, offer)
+			},
+		}, {
+			name: "answer omits offered codec",
+			answer: func(t *testing.T, offer *webrtc.SessionDescription) webrtc.SessionDescription {
+				remote, err := webrtc.NewPeerConnection(webrtc.Configuration{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { _ = remote.Close() })
+
+				return withoutCodec(t, remoteAnswer(t, remote, offer), "VP8")
+			},
+			wantErr: types.ErrWebRTCCodecMismatch,
+		}, {
+			name: "remote does not support offered codec",
+			answer: func(t *testing.T, offer *webrtc.SessionDescription) webrtc.SessionDescription {
+				engine := &webrtc.MediaEngine{}
+				if err := engine.RegisterCodec(webrtc.RTPCodecParameters{
+					RTPCodecCapability: webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeH264, ClockRate: 90000},
+					PayloadType:        102,
+				}, webrtc.RTPCodecTypeVideo); err != nil {
+					t.Fatal(err)
+				}
+
+				api := webrtc.NewAPI(webrtc.WithMediaEngine(engine))
+				remote, err := api.NewPeerConnection(webrtc.Configuration{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { _ = remote.Close() })
+
+				return remoteAnswer(t, remote, offer)
+			},
+			wantErr: types.ErrWebRTCCodecMismatch,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peer, _ := newTestPeers(t)
+
+			track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", "stream")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := peer.connection.AddTrack(track); err != nil {
+				t.Fatal(err)
+			}
+
+			offer, err := peer.CreateOffer(false)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			err = peer.SetRemoteDescription(tt.answer(t, offer))
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("SetRemoteDescription() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			// answer is applied even without a common codec
+			if state := peer.connection.SignalingState(); state != webrtc.SignalingStateStable {
+				t.Errorf("SignalingState() = %v, want %v", state, webrtc.SignalingStateStable)
+			}
+		})
+	}
+}
+
+// testSession records events sent to the client
+type testSession struct {
+	types.Session
+	events chan testEvent
+}
+
+type testEvent struct {
+	event   string
+	payload any
+}
+
+func (s *testSession) Send(event string, payload any) {
+	s.events <- testEvent{event, payload}
+}
+
+func testCandidatePair(local, remote string) *webrtc.ICECandidatePair {
+	return webrtc.NewICECandidatePair(
+		&webrtc.ICECandidate{Protocol: webrtc.ICEProtocolUDP, Address: local, Port: 50000},
+		&webrtc.ICECandidate{Protocol: webrtc.ICEProtocolUDP, Address: remote, Port: 40000},
+	)
+}
+
+func iceUfrag(t *testing.T, raw string) string {
+	t.Helper()
+
+	parsed := &sdp.SessionDescription{}
+	if err := parsed.UnmarshalString(raw); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, media := range parsed.MediaDescriptions {
+		if ufrag, ok := media.Attribute("ice-ufrag"); ok {
+			return ufrag
+		}
+	}
+
+	ufrag, _ := parsed.Attribute("ice-ufrag")
+	return ufrag
+}
+
+func TestWebRTCPeerCtx_ICERestartOnNetworkChange(t *testing.T) {
+	peer, remote := newTestPeers(t)
+	session := &testSession{events: make(chan testEvent, 4)}
+	peer.session = session
+	peer.iceAutoRestart = true
+
+	track, err := webrtc.NewTrackLocalStaticSample(webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeVP8}, "video", "stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sender, err := peer.connection.AddTrack(track)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	offer, err := peer.CreateOffer(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := peer.SetRemoteDescription(remoteAnswer(t, remote, offer)); err != nil {
+		t.Fatal(err)
+	}
+
+	expectNoEvent := func() {
+		t.Helper()
+		select {
+		case e := <-session.events:
+			t.Fatalf("unexpected event %q", e.event)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	wifi := testCandidatePair("10.0.0.2", "192.168.1.10")
+	cellular := testCandidatePair("10.0.0.2", "100.64.20.30")
+
+	// initial pair and its reselection do not restart
+	peer.onSelectedCandidatePairChange(wifi)
+	peer.onSelectedCandidatePairChange(wifi)
+	expectNoEvent()
+
+	// client moved to another network
+	peer.onSelectedCandidatePairChange(cellular)
+
+	var restart message.SignalDescription
+	select {
+	case e := <-session.events:
+		if e.event != event.SIGNAL_RESTART {
+			t.Fatalf("event = %q, want %q", e.event, event.SIGNAL_RESTART)
+		}
+		restart = e.payload.(message.SignalDescription)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ice restart offer was not sent")
+	}
+
+	if iceUfrag(t, restart.SDP) == iceUfrag(t, offer.SDP) {
+		t.Error("restart offer has the same ice credentials")
+	}
+
+	// failure reported shortly after restart is rate limited
+	peer.onICEConnectionStateChange(webrtc.ICEConnectionStateFailed)
+	expectNoEvent()
+
+	answer := remoteAnswer(t, remote, &webrtc.SessionDescription{
+		Type: webrtc.SDPTypeOffer,
+		SDP:  restart.SDP,
+	})
+	if err := peer.SetRemoteDescription(answer); err != nil {
+		t.Fatal(err)
+	}
+
+	// media is preserved across restart
+	if senders := peer.connection.GetSenders(); len(senders) != 1 || senders[0] != sender || sender.Track() != track {
+		t.Error("video sender was not preserved")
+	}
+
+	if state := peer.connection.SignalingState(); state != webrtc.SignalingStateStable {
+		t.Errorf("SignalingState() = %v, want %v", state, webrtc.SignalingStateStable)
+	}
+}
+
+// testStream is a video stream with given size
+type testStream struct {
+	types.StreamSinkManager
+	id            string
+	width, height int
+}
+
+func (s *testStream) ID() string {
+	return s.id
+}
+
+func (s *testStream) Size() (int, int) {
+	return s.width, s.height
+}
+
+// testVideo selects from streams ordered from the highest one
+type testVideo struct {
+	types.StreamSelectorManager
+	streams []*testStream
+}
+
+func (v *testVideo) IDs() []string {
+	ids := make([]string, 0, len(v.streams))
+	for _, stream := range v.streams {
+		ids = append(ids, stream.id)
+	}
+	return ids
+}
+
+func (v *testVideo) GetStream(selector types.StreamSelector) (types.StreamSinkManager, bool) {
+	for i, stream := range v.streams {
+		if stream.id != selector.ID {
+			continue
+		}
+
+		switch selector.Type {
+		case types.StreamSelectorTypeExact:
+			return stream, true
+		case types.StreamSelectorTypeLower:
+			if i+1 < len(v.streams) {
+				return v.streams[i+1], true
+			}
+		}
+		return nil, false
+	}
+	return nil, false
+}
+
+func TestWebRTCPeerCtx_VideoViewport(t *testing.T) {
+	video := &testVideo{
+		streams: []*testStream{
+			{id: "hd", width: 1920, height: 1080},
+			{id: "sd", width: 1280, height: 720},
+			{id: "ld", width: 640, height: 360},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		viewport types.PeerViewport
+		want     string
+	}{
+		{"larger than highest", types.PeerViewport{Width: 2560, Height: 1440}, "hd"},
+		{"between streams", types.PeerViewport{Width: 1000, Height: 600}, "sd"},
+		{"exact size", types.PeerViewport{Width: 1280, Height: 720}, "sd"},
+		{"smaller than lowest", types.PeerViewport{Width: 320, Height: 180}, "ld"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peer := &WebRTCPeerCtx{
+				logger:        zerolog.Nop(),
+				video:         video,
+				videoViewport: &tt.viewport,
+			}
+
+			selector := peer.highestVideoSelector()
+			if selector == nil || selector.ID != "hd" {
+				t.Fatalf("highestVideoSelector() = %v, want hd", selector)
+			}
+
+			stream, ok := peer.getStream(*selector)
+			if !ok {
+				t.Fatalf("getStream(%v) not found", selector)
+			}
+
+			if got := peer.capVideoViewport(stream).ID(); got != tt.want {
+				t.Errorf("capVideoViewport() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
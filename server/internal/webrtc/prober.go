@@ -0,0 +1,76 @@
+package webrtc
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// size of a single padding packet generated by packetizer, padding and RTP header
+	probePacketSize = 255 + 12
+	// how often padding packets are sent while probing
+	probeInterval = 20 * time.Millisecond
+)
+
+// prober sends padding packets on a track to let bandwidth estimator
+// discover available capacity, before switching to a higher stream.
+type prober struct {
+	track   *Track
+	bitrate atomic.Int64
+	stop    chan struct{}
+}
+
+func newProber(track *Track) *prober {
+	return &prober{
+		track: track,
+	}
+}
+
+// Start starts probing with given bitrate, or updates bitrate if already running.
+func (p *prober) Start(bitrate int) {
+	p.bitrate.Store(int64(bitrate))
+
+	if p.stop == nil {
+		p.stop = make(chan struct{})
+		go p.run(p.stop)
+	}
+}
+
+func (p *prober) Stop() {
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+}
+
+func (p *prober) Running() bool {
+	return p.stop != nil
+}
+
+func (p *prober) run(stop <-chan struct{}) {
+	ticker := time.NewTicker(probeInterval)
+	defer ticker.Stop()
+
+	// bits that did not fit into whole packet are carried over to next tick
+	var carry int64
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		bits := p.bitrate.Load()*int64(probeInterval)/int64(time.Second) + carry
+		packets := bits / (probePacketSize * 8)
+		carry = bits % (probePacketSize * 8)
+
+		if packets == 0 {
+			continue
+		}
+
+		if err := p.track.GeneratePadding(uint32(packets)); err != nil {
+			p.track.logger.Debug().Err(err).Msg("failed to generate padding")
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+// qualityReporter periodically classifies connection quality from
+// remote reports of sent streams, client is notified when it changes.
+func (peer *WebRTCPeerCtx) qualityReporter(conf config.WebRTCQuality) {
+	ticker := time.NewTicker(conf.Interval)
+	defer ticker.Stop()
+
+	var last types.ConnectionQuality
+
+	for range ticker.C {
+		if peer.connection.ConnectionState() == webrtc.PeerConnectionStateClosed {
+			return
+		}
+
+		// video is preferred, audio is used when video is not being sent
+		s, ok := peer.rtpStats.Get(peer.videoTrack.SSRC())
+		if (!ok || s.RemoteInboundRTPStreamStats.RoundTripTimeMeasurements == 0) && peer.audioTrack != nil {
+			s, ok = peer.rtpStats.Get(peer.audioTrack.SSRC())
+		}
+		if !ok || s.RemoteInboundRTPStreamStats.RoundTripTimeMeasurements == 0 {
+			continue
+		}
+
+		report := types.PeerQuality{
+			RoundTripTime: s.RemoteInboundRTPStreamStats.RoundTripTime.Seconds(),
+			FractionLost:  s.RemoteInboundRTPStreamStats.FractionLost,
+			Degrading:     utils.TrendDirection(peer.estimateDirection.Load()) == utils.TrendDirectionDownward,
+		}
+		report.Quality = classifyQuality(conf, s.RemoteInboundRTPStreamStats.RoundTripTime, report.FractionLost, report.Degrading)
+
+		if report.Quality == last {
+			continue
+		}
+		last = report.Quality
+
+		peer.logger.Debug().Interface("quality", report).Msg("connection quality changed")
+		peer.session.Send(event.CONNECTION_QUALITY, report)
+	}
+}
+
+// classifyQuality returns the best level, whose thresholds are satisfied,
+// degrading bandwidth estimate lowers the quality by one level.
+func classifyQuality(conf config.WebRTCQuality, rtt time.Duration, loss float64, degrading bool) types.ConnectionQuality {
+	levels := []struct {
+		quality types.ConnectionQuality
+		rtt     time.Duration
+		loss    float64
+	}{
+		{types.ConnectionQualityExcellent, conf.ExcellentRTT, conf.ExcellentLoss},
+		{types.ConnectionQualityGood, conf.GoodRTT, conf.GoodLoss},
+		{types.ConnectionQualityFair, conf.FairRTT, conf.FairLoss},
+	}
+
+	i := len(levels)
+	for j, level := range levels {
+		if rtt <= level.rtt && loss <= level.loss {
+			i = j
+			break
+		}
+	}
+
+	if degrading && i < len(levels) {
+		i++
+	}
+
+	if i == len(levels) {
+		return types.ConnectionQualityPoor
+	}
+
+	return levels[i].quality
+}
@@ -0,0 +1,36 @@
+package webrtc
+
+import (
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+)
+
+// withoutRtcpRsize removes reduced-size RTCP (RFC 5506) attribute, that pion adds to every
+// media section, from the description. Clients then send only compound RTCP packets.
+//
+// Pion reads RTCP packets one by one and does not require them to be compound, so that
+// reduced-size packets are parsed the same way and reach the rtcp channel unchanged.
+func withoutRtcpRsize(description webrtc.SessionDescription) (webrtc.SessionDescription, error) {
+	parsed, err := description.Unmarshal()
+	if err != nil {
+		return description, err
+	}
+
+	for _, media := range parsed.MediaDescriptions {
+		attributes := media.Attributes[:0]
+		for _, attr := range media.Attributes {
+			if attr.Key != sdp.AttrKeyRTCPRsize {
+				attributes = append(attributes, attr)
+			}
+		}
+		media.Attributes = attributes
+	}
+
+	raw, err := parsed.Marshal()
+	if err != nil {
+		return description, err
+	}
+
+	description.SDP = string(raw)
+	return description, nil
+}
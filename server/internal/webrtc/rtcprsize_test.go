@@ -0,0 +1,137 @@
+package webrtc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+type rtcpRsizeSession struct {
+	types.Session
+}
+
+func (s *rtcpRsizeSession) ID() string {
+	return "rtcp-rsize-test"
+}
+
+func TestWithoutRtcpRsize(t *testing.T) {
+	rsize := strings.ReplaceAll(testSDP, "a=sendonly\r\n", "a=rtcp-mux\r\na=rtcp-rsize\r\na=sendonly\r\n")
+
+	tests := []struct {
+		name string
+		sdp  string
+	}{
+		{
+			name: "every media section",
+			sdp:  rsize,
+		}, {
+			name: "first media section",
+			sdp:  strings.Replace(testSDP, "a=sendonly\r\n", "a=rtcp-mux\r\na=rtcp-rsize\r\na=sendonly\r\n", 1),
+		}, {
+			name: "no attribute",
+			sdp:  testSDP,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			description, err := withoutRtcpRsize(webrtc.SessionDescription{
+				Type: webrtc.SDPTypeOffer,
+				SDP:  tt.sdp,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if description.Type != webrtc.SDPTypeOffer {
+				t.Errorf("type = %v, want %v", description.Type, webrtc.SDPTypeOffer)
+			}
+			if strings.Contains(description.SDP, "a=rtcp-rsize") {
+				t.Errorf("rtcp-rsize was not removed:\n%s", description.SDP)
+			}
+			if want := strings.Count(tt.sdp, "a=rtcp-mux"); strings.Count(description.SDP, "a=rtcp-mux") != want {
+				t.Errorf("other attributes were not kept:\n%s", description.SDP)
+			}
+			if strings.Count(description.SDP, "m=") != 2 {
+				t.Errorf("media sections were not kept:\n%s", description.SDP)
+			}
+		})
+	}
+}
+
+func TestRtcpReceiver_ReducedSize(t *testing.T) {
+	met := newMetricsManager().getBySession(&rtcpRsizeSession{})
+
+	// reduced-size packets are not preceded by a report
+	tests := []struct {
+		name   string
+		packet rtcp.Packet
+		metric func() prometheus.Metric
+		want   float64
+	}{
+		{
+			name:   "picture loss indication",
+			packet: &rtcp.PictureLossIndication{SenderSSRC: 1, MediaSSRC: 2},
+		}, {
+			name: "transport layer nack",
+			packet: &rtcp.TransportLayerNack{SenderSSRC: 1, MediaSSRC: 2, Nacks: []rtcp.NackPair{
+				{PacketID: 10, LostPackets: 0b11},
+			}},
+			metric: func() prometheus.Metric { return met.transportLayerNacks },
+			want:   3,
+		}, {
+			name: "receiver report",
+			packet: &rtcp.ReceiverReport{SSRC: 1, Reports: []rtcp.ReceptionReport{
+				{SSRC: 2, LastSequenceNumber: 100, TotalLost: 5},
+			}},
+			metric: func() prometheus.Metric { return met.receiverReportTotalLost },
+			want:   5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := tt.packet.Marshal()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			packets, err := rtcp.Unmarshal(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(packets) != 1 {
+				t.Fatalf("packets = %d, want 1", len(packets))
+			}
+
+			rtcpCh := make(chan []rtcp.Packet)
+			done := make(chan struct{})
+			go func() {
+				met.rtcpReceiver(rtcpCh, newFreezeDetector(90000, func() (uint64, bool) {
+					return 0, false
+				}))
+				close(done)
+			}()
+
+			rtcpCh <- packets
+			close(rtcpCh)
+			<-done
+
+			if tt.metric != nil {
+				var m dto.Metric
+				if err := tt.metric().Write(&m); err != nil {
+					t.Fatal(err)
+				}
+
+				got := m.GetGauge().GetValue() + m.GetCounter().GetValue()
+				if got != tt.want {
+					t.Errorf("metric = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package webrtc
+
+import (
+	"sync"
+
+	"github.com/pion/interceptor/pkg/stats"
+)
+
+// rtpStatsGetter holds stats getter of a peer connection, that is
+// only available after the interceptor was bound to the connection.
+type rtpStatsGetter struct {
+	mu     sync.Mutex
+	getter stats.Getter
+}
+
+func (g *rtpStatsGetter) newInterceptor() (*stats.InterceptorFactory, error) {
+	factory, err := stats.NewInterceptor()
+	if err != nil {
+		return nil, err
+	}
+
+	factory.OnNewPeerConnection(func(_ string, getter stats.Getter) {
+		g.mu.Lock()
+		defer g.mu.Unlock()
+
+		g.getter = getter
+	})
+
+	return factory, nil
+}
+
+func (g *rtpStatsGetter) Get(ssrc uint32) (*stats.Stats, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.getter == nil {
+		return nil, false
+	}
+
+	s := g.getter.Get(ssrc)
+	return s, s != nil
+}
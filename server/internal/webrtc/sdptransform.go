@@ -0,0 +1,209 @@
+package webrtc
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pion/sdp/v3"
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// newSDPTransform creates built-in transform from its specification, e.g.
+// strip:extmap-allow-mixed removes the attribute from all sections, and
+// prefer:H264 moves payload types of the codec to the front of formats.
+func newSDPTransform(spec string) (types.SDPTransform, error) {
+	name, arg, ok := strings.Cut(spec, ":")
+	if !ok || arg == "" {
+		return nil, fmt.Errorf("invalid sdp transform %q", spec)
+	}
+
+	switch name {
+	case "strip":
+		return sdpStripAttribute(arg), nil
+	case "prefer":
+		return sdpPreferCodec(arg), nil
+	default:
+		return nil, fmt.Errorf("unknown sdp transform %q", name)
+	}
+}
+
+func sdpStripAttribute(key string) types.SDPTransformFunc {
+	strip := func(attributes []sdp.Attribute) []sdp.Attribute {
+		return slices.DeleteFunc(attributes, func(attr sdp.Attribute) bool {
+			return attr.Key == key
+		})
+	}
+
+	return func(raw string) (string, error) {
+		parsed := &sdp.SessionDescription{}
+		if err := parsed.UnmarshalString(raw); err != nil {
+			return "", err
+		}
+
+		parsed.Attributes = strip(parsed.Attributes)
+		for _, media := range parsed.MediaDescriptions {
+			media.Attributes = strip(media.Attributes)
+		}
+
+		out, err := parsed.Marshal()
+		return string(out), err
+	}
+}
+
+func sdpPreferCodec(codec string) types.SDPTransformFunc {
+	return func(raw string) (string, error) {
+		parsed := &sdp.SessionDescription{}
+		if err := parsed.UnmarshalString(raw); err != nil {
+			return "", err
+		}
+
+		for _, media := range parsed.MediaDescriptions {
+			// payload types of the codec, from a=rtpmap:<pt> <codec>/<clock rate>
+			preferred := map[string]bool{}
+			for _, attr := range media.Attributes {
+				if attr.Key != "rtpmap" {
+					continue
+				}
+				pt, encoding, ok := strings.Cut(attr.Value, " ")
+				name, _, _ := strings.Cut(encoding, "/")
+				if ok && strings.EqualFold(name, codec) {
+					preferred[pt] = true
+				}
+			}
+
+			// stable sort keeps order of the remaining formats
+			slices.SortStableFunc(media.MediaName.Formats, func(a, b string) int {
+				switch {
+				case preferred[a] && !preferred[b]:
+					return -1
+				case !preferred[a] && preferred[b]:
+					return 1
+				}
+				return 0
+			})
+		}
+
+		out, err := parsed.Marshal()
+		return string(out), err
+	}
+}
+
+// attributes that transforms must not touch, they secure the connection or are
+// needed to establish it, candidates are added only after transforms were applied
+var sdpProtectedAttributes = []string{
+	"fingerprint",
+	"ice-ufrag",
+	"ice-pwd",
+	sdp.AttrKeyICEOptions,
+	sdp.AttrKeyICELite,
+	sdp.AttrKeyConnectionSetup,
+	sdp.AttrKeyGroup,
+	sdp.AttrKeyMID,
+	sdp.AttrKeyCandidate,
+	sdp.AttrKeyEndOfCandidates,
+}
+
+// protectedAttributes returns protected attributes in their order
+func protectedAttributes(attributes []sdp.Attribute) []sdp.Attribute {
+	return slices.DeleteFunc(slices.Clone(attributes), func(attr sdp.Attribute) bool {
+		return !slices.Contains(sdpProtectedAttributes, attr.Key)
+	})
+}
+
+// validateTransformedSDP checks that transformed description can be parsed, that
+// media sections were not added, removed or reordered, and that no protected
+// attribute was added, removed or modified.
+func validateTransformedSDP(original, transformed string) error {
+	before := &sdp.SessionDescription{}
+	if err := before.UnmarshalString(original); err != nil {
+		return err
+	}
+
+	after := &sdp.SessionDescription{}
+	if err := after.UnmarshalString(transformed); err != nil {
+		return fmt.Errorf("%w: %w", types.ErrWebRTCSDPMalformed, err)
+	}
+
+	if !slices.Equal(protectedAttributes(before.Attributes), protectedAttributes(after.Attributes)) {
+		return fmt.Errorf("%w: protected session attribute changed", types.ErrWebRTCSDPMalformed)
+	}
+
+	if len(before.MediaDescriptions) != len(after.MediaDescriptions) {
+		return fmt.Errorf("%w: media sections count changed", types.ErrWebRTCSDPMalformed)
+	}
+
+	for i, media := range after.MediaDescriptions {
+		mid, _ := media.Attribute(sdp.AttrKeyMID)
+		origMid, _ := before.MediaDescriptions[i].Attribute(sdp.AttrKeyMID)
+		if mid != origMid || media.MediaName.Media != before.MediaDescriptions[i].MediaName.Media {
+			return fmt.Errorf("%w: media section %d changed", types.ErrWebRTCSDPMalformed, i)
+		}
+
+		if !slices.Equal(protectedAttributes(before.MediaDescriptions[i].Attributes), protectedAttributes(media.Attributes)) {
+			return fmt.Errorf("%w: protected attribute of media section %d changed", types.ErrWebRTCSDPMalformed, i)
+		}
+	}
+
+	return nil
+}
+
+// withLocalCandidates adds candidates gathered after the description was set
+// to the transformed description, media sections are matched by their order.
+func withLocalCandidates(transformed, local webrtc.SessionDescription) (webrtc.SessionDescription, error) {
+	isCandidate := func(attr sdp.Attribute) bool {
+		return attr.Key == sdp.AttrKeyCandidate || attr.Key == sdp.AttrKeyEndOfCandidates
+	}
+
+	parsed, err := transformed.Unmarshal()
+	if err != nil {
+		return transformed, err
+	}
+
+	gathered, err := local.Unmarshal()
+	if err != nil {
+		return transformed, err
+	}
+
+	if len(parsed.MediaDescriptions) != len(gathered.MediaDescriptions) {
+		return transformed, fmt.Errorf("%w: media sections count changed", types.ErrWebRTCSDPMalformed)
+	}
+
+	for i, media := range parsed.MediaDescriptions {
+		media.Attributes = slices.DeleteFunc(media.Attributes, isCandidate)
+		for _, attr := range gathered.MediaDescriptions[i].Attributes {
+			if isCandidate(attr) {
+				media.Attributes = append(media.Attributes, attr)
+			}
+		}
+	}
+
+	raw, err := parsed.Marshal()
+	if err != nil {
+		return transformed, err
+	}
+
+	local.SDP = string(raw)
+	return local, nil
+}
+
+// transformSDP applies transforms in order, rejected outputs are skipped.
+func (peer *WebRTCPeerCtx) transformSDP(description webrtc.SessionDescription) webrtc.SessionDescription {
+	for i, transform := range peer.sdpTransforms {
+		out, err := transform.TransformSDP(description.SDP)
+		if err == nil {
+			err = validateTransformedSDP(description.SDP, out)
+		}
+
+		if err != nil {
+			peer.logger.Warn().Err(err).Int("transform", i).Msg("sdp transform rejected")
+			continue
+		}
+
+		description.SDP = out
+	}
+
+	return description
+}
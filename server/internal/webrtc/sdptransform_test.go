@@ -0,0 +1,149 @@
+package webrtc
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+const testSDP = "v=0\r\n" +
+	"o=- 1 2 IN IP4 0.0.0.0\r\n" +
+	"s=-\r\n" +
+	"t=0 0\r\n" +
+	"a=fingerprint:sha-256 AA:BB:CC\r\n" +
+	"a=extmap-allow-mixed\r\n" +
+	"a=group:BUNDLE 0 1\r\n" +
+	"m=video 9 UDP/TLS/RTP/SAVPF 96 102\r\n" +
+	"c=IN IP4 0.0.0.0\r\n" +
+	"a=setup:actpass\r\n" +
+	"a=mid:0\r\n" +
+	"a=ice-ufrag:ufrag\r\n" +
+	"a=ice-pwd:password\r\n" +
+	"a=extmap-allow-mixed\r\n" +
+	"a=rtpmap:96 VP8/90000\r\n" +
+	"a=rtpmap:102 H264/90000\r\n" +
+	"a=sendonly\r\n" +
+	"m=audio 9 UDP/TLS/RTP/SAVPF 111\r\n" +
+	"c=IN IP4 0.0.0.0\r\n" +
+	"a=setup:actpass\r\n" +
+	"a=mid:1\r\n" +
+	"a=ice-ufrag:ufrag\r\n" +
+	"a=ice-pwd:password\r\n" +
+	"a=rtpmap:111 opus/48000/2\r\n" +
+	"a=sendonly\r\n"
+
+func TestValidateTransformedSDP(t *testing.T) {
+	replace := func(old, new string) types.SDPTransform {
+		return types.SDPTransformFunc(func(sdp string) (string, error) {
+			return strings.Replace(sdp, old, new, 1), nil
+		})
+	}
+
+	builtin := func(spec string) types.SDPTransform {
+		transform, err := newSDPTransform(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return transform
+	}
+
+	tests := []struct {
+		name      string
+		transform types.SDPTransform
+		contains  string
+		wantErr   bool
+	}{
+		{
+			name:      "strip attribute",
+			transform: builtin("strip:extmap-allow-mixed"),
+		}, {
+			name:      "prefer codec",
+			transform: builtin("prefer:H264"),
+			contains:  "m=video 9 UDP/TLS/RTP/SAVPF 102 96",
+		}, {
+			name:      "strip fingerprint",
+			transform: builtin("strip:fingerprint"),
+			wantErr:   true,
+		}, {
+			name:      "strip ice ufrag",
+			transform: builtin("strip:ice-ufrag"),
+			wantErr:   true,
+		}, {
+			name:      "modify ice password",
+			transform: replace("a=ice-pwd:password", "a=ice-pwd:other"),
+			wantErr:   true,
+		}, {
+			name:      "modify setup",
+			transform: replace("a=setup:actpass", "a=setup:active"),
+			wantErr:   true,
+		}, {
+			name:      "add candidate",
+			transform: replace("a=sendonly\r\n", "a=sendonly\r\na=candidate:1 1 udp 1 10.0.0.1 5000 typ host\r\n"),
+			wantErr:   true,
+		}, {
+			name:      "remove media section",
+			transform: replace(testSDP[strings.Index(testSDP, "m=audio"):], ""),
+			wantErr:   true,
+		}, {
+			name:      "malformed output",
+			transform: replace("v=0", "x"),
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := tt.transform.TransformSDP(testSDP)
+			if err == nil {
+				err = validateTransformedSDP(testSDP, out)
+			}
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, types.ErrWebRTCSDPMalformed) {
+				t.Errorf("err = %v, want %v", err, types.ErrWebRTCSDPMalformed)
+			}
+			if tt.contains != "" && !strings.Contains(out, tt.contains) {
+				t.Errorf("output does not contain %q:\n%s", tt.contains, out)
+			}
+		})
+	}
+}
+
+func TestWithLocalCandidates(t *testing.T) {
+	transform, err := newSDPTransform("strip:extmap-allow-mixed")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stripped, err := transform.TransformSDP(testSDP)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// candidates are gathered only after the description was set
+	candidate := "a=candidate:1 1 udp 2130706431 10.0.0.1 5000 typ host\r\n"
+	gathered := strings.Replace(testSDP, "a=sendonly\r\nm=audio", "a=sendonly\r\n"+candidate+"a=end-of-candidates\r\nm=audio", 1)
+
+	out, err := withLocalCandidates(
+		webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: stripped},
+		webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: gathered},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out.Type != webrtc.SDPTypeOffer {
+		t.Errorf("type = %v, want %v", out.Type, webrtc.SDPTypeOffer)
+	}
+	if strings.Contains(out.SDP, "extmap-allow-mixed") {
+		t.Errorf("transform was not kept:\n%s", out.SDP)
+	}
+	if strings.Count(out.SDP, "a=candidate:") != 1 || !strings.Contains(out.SDP, candidate+"a=end-of-candidates\r\nm=audio") {
+		t.Errorf("candidates were not added to the first media section:\n%s", out.SDP)
+	}
+}
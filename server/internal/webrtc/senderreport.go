@@ -0,0 +1,86 @@
+package webrtc
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/interceptor"
+	"github.com/pion/rtcp"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// seconds between NTP epoch (1900) and unix epoch (1970)
+const ntpEpochOffset = 2208988800
+
+// senderReportInterceptor records the latest outgoing RTCP sender report
+// per SSRC, so that RTP to NTP timestamp mapping can be inspected.
+type senderReportInterceptor struct {
+	interceptor.NoOp
+
+	logger  zerolog.Logger
+	mu      sync.Mutex
+	reports map[uint32]types.SenderReport
+}
+
+func newSenderReportInterceptor(logger zerolog.Logger) *senderReportInterceptor {
+	return &senderReportInterceptor{
+		logger:  logger,
+		reports: map[uint32]types.SenderReport{},
+	}
+}
+
+// NewInterceptor implements interceptor.Factory, a new instance is created for every peer connection.
+func (i *senderReportInterceptor) NewInterceptor(_ string) (interceptor.Interceptor, error) {
+	return i, nil
+}
+
+func (i *senderReportInterceptor) BindRTCPWriter(writer interceptor.RTCPWriter) interceptor.RTCPWriter {
+	return interceptor.RTCPWriterFunc(func(pkts []rtcp.Packet, attributes interceptor.Attributes) (int, error) {
+		for _, pkt := range pkts {
+			if sr, ok := pkt.(*rtcp.SenderReport); ok {
+				i.save(sr)
+			}
+		}
+
+		return writer.Write(pkts, attributes)
+	})
+}
+
+func (i *senderReportInterceptor) save(sr *rtcp.SenderReport) {
+	report := types.SenderReport{
+		SSRC:        sr.SSRC,
+		NTPTime:     ntpToTime(sr.NTPTime),
+		RTPTime:     sr.RTPTime,
+		PacketCount: sr.PacketCount,
+		OctetCount:  sr.OctetCount,
+		SentAt:      time.Now(),
+	}
+
+	i.mu.Lock()
+	i.reports[sr.SSRC] = report
+	i.mu.Unlock()
+
+	i.logger.Debug().
+		Uint32("ssrc", report.SSRC).
+		Time("ntp_time", report.NTPTime).
+		Uint32("rtp_time", report.RTPTime).
+		Uint32("packet_count", report.PacketCount).
+		Uint32("octet_count", report.OctetCount).
+		Msg("rtcp sender report sent")
+}
+
+func (i *senderReportInterceptor) Get(ssrc uint32) (types.SenderReport, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	report, ok := i.reports[ssrc]
+	return report, ok
+}
+
+func ntpToTime(ntp uint64) time.Time {
+	sec := int64(ntp>>32) - ntpEpochOffset
+	nsec := int64((ntp & 0xFFFFFFFF) * 1e9 >> 32)
+	return time.Unix(sec, nsec)
+}
@@ -0,0 +1,190 @@
+package webrtc
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/webrtc/payload"
+)
+
+// queued messages are held back while at least this much data is buffered,
+// so that urgent messages do not wait behind them in the ordered channel
+const sendQueueBufferedLow = 64 * 1024
+
+// maximum size of queued messages, so that a stalled channel cannot grow without bounds
+const sendQueueMaxBytes = 8 * 1024 * 1024
+
+var errSendQueueFull = errors.New("data channel send queue is full")
+
+// messages with lower priority are sent only when no message with higher priority is queued
+const (
+	// input related messages, sent immediately
+	priorityUrgent = iota
+	// cursor images and still frames
+	priorityBulk
+	// clipboard content, yields to everything else
+	priorityClipboard
+
+	priorityCount
+)
+
+// opcodePriority returns priority of a message, messages that must keep their
+// relative order (e.g. still frame and its clear) share the same priority
+func opcodePriority(opcode uint8) int {
+	switch opcode {
+	case payload.OP_CURSOR_IMAGE, payload.OP_STILL_FRAME, payload.OP_STILL_CLEAR:
+		return priorityBulk
+	case payload.OP_CLIPBOARD:
+		return priorityClipboard
+	default:
+		return priorityUrgent
+	}
+}
+
+// dataChannelSender is the part of data channel used by the queue
+type dataChannelSender interface {
+	Send(data []byte) error
+	BufferedAmount() uint64
+}
+
+type queuedMessage struct {
+	opcode uint8
+	data   []byte
+}
+
+// sendQueue sends urgent messages immediately and queues other messages by their
+// priority until the channel buffer drains, messages of the same priority keep their order.
+type sendQueue struct {
+	logger  zerolog.Logger
+	channel dataChannelSender
+
+	queues [priorityCount][]queuedMessage
+	size   int
+	mu     sync.Mutex
+}
+
+func newSendQueue(logger zerolog.Logger, channel *webrtc.DataChannel) *sendQueue {
+	queue := &sendQueue{
+		logger:  logger.With().Str("submodule", "send-queue").Logger(),
+		channel: channel,
+	}
+
+	channel.SetBufferedAmountLowThreshold(sendQueueBufferedLow)
+	channel.OnBufferedAmountLow(queue.flush)
+
+	return queue
+}
+
+// opcodeSuperseded returns true for messages, that are replaced by a newer one
+// with the same opcode, e.g. only the latest cursor image or clipboard is relevant
+func opcodeSuperseded(opcode uint8) bool {
+	return opcode == payload.OP_CURSOR_IMAGE || opcode == payload.OP_CLIPBOARD
+}
+
+// Send sends or queues message, opcode is read from unencrypted data
+func (queue *sendQueue) Send(opcode uint8, data []byte) error {
+	return queue.SendAll(opcode, [][]byte{data})
+}
+
+// SendAll sends or queues messages with the same opcode, e.g. chunks of a single
+// transfer, either all of them are queued or none, when the queue is full
+func (queue *sendQueue) SendAll(opcode uint8, messages [][]byte) error {
+	priority := opcodePriority(opcode)
+	if priority == priorityUrgent {
+		for _, data := range messages {
+			if err := queue.channel.Send(data); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	queue.mu.Lock()
+
+	// superseded messages are dropped, client discards their incomplete transfer
+	if opcodeSuperseded(opcode) {
+		queue.drop(priority, opcode)
+	}
+
+	size := 0
+	for _, data := range messages {
+		size += len(data)
+	}
+
+	if queue.size+size > sendQueueMaxBytes {
+		queue.mu.Unlock()
+		return errSendQueueFull
+	}
+
+	for _, data := range messages {
+		queue.queues[priority] = append(queue.queues[priority], queuedMessage{
+			opcode: opcode,
+			data:   data,
+		})
+	}
+	queue.size += size
+	queue.mu.Unlock()
+
+	queue.flush()
+	return nil
+}
+
+// drop removes queued messages with given opcode, must be called with mutex locked
+func (queue *sendQueue) drop(priority int, opcode uint8) {
+	messages := queue.queues[priority][:0]
+	for _, msg := range queue.queues[priority] {
+		if msg.opcode == opcode {
+			queue.size -= len(msg.data)
+			continue
+		}
+		messages = append(messages, msg)
+	}
+	queue.queues[priority] = messages
+}
+
+// pop returns the first queued message with the highest priority, must be called with mutex locked
+func (queue *sendQueue) pop() ([]byte, bool) {
+	for priority := range queue.queues {
+		messages := queue.queues[priority]
+		if len(messages) == 0 {
+			continue
+		}
+
+		data := messages[0].data
+		messages[0] = queuedMessage{}
+		queue.queues[priority] = messages[1:]
+		queue.size -= len(data)
+		return data, true
+	}
+
+	return nil, false
+}
+
+// flush sends queued messages while the channel buffer is not full
+func (queue *sendQueue) flush() {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	for queue.channel.BufferedAmount() < sendQueueBufferedLow {
+		data, ok := queue.pop()
+		if !ok {
+			return
+		}
+
+		if err := queue.channel.Send(data); err != nil {
+			queue.logger.Err(err).Msg("failed to send queued message")
+		}
+	}
+}
+
+// Clear drops queued messages, e.g. when the channel was closed
+func (queue *sendQueue) Clear() {
+	queue.mu.Lock()
+	defer queue.mu.Unlock()
+
+	queue.queues = [priorityCount][]queuedMessage{}
+	queue.size = 0
+}
@@ -0,0 +1,199 @@
+package webrtc
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/webrtc/payload"
+)
+
+type fakeDataChannel struct {
+	buffered uint64
+	sent     [][]byte
+}
+
+func (c *fakeDataChannel) Send(data []byte) error {
+	c.sent = append(c.sent, data)
+	return nil
+}
+
+func (c *fakeDataChannel) BufferedAmount() uint64 {
+	return c.buffered
+}
+
+func TestSendQueue_Send(t *testing.T) {
+	type message struct {
+		opcode uint8
+		data   []byte
+	}
+
+	msg := func(opcode uint8, data ...byte) message {
+		return message{opcode, append([]byte{opcode}, data...)}
+	}
+
+	tests := []struct {
+		name     string
+		messages []message
+		// sent while the channel buffer is full
+		wantImmediate []message
+		// sent after the channel buffer drained
+		wantFlushed []message
+	}{
+		{
+			name: "urgent messages are not queued",
+			messages: []message{
+				msg(payload.OP_CURSOR_POSITION, 1),
+				msg(payload.OP_PONG, 2),
+			},
+			wantImmediate: []message{
+				msg(payload.OP_CURSOR_POSITION, 1),
+				msg(payload.OP_PONG, 2),
+			},
+		}, {
+			name: "urgent messages overtake queued ones",
+			messages: []message{
+				msg(payload.OP_CLIPBOARD, 1),
+				msg(payload.OP_STILL_FRAME, 2),
+				msg(payload.OP_CURSOR_POSITION, 3),
+			},
+			wantImmediate: []message{
+				msg(payload.OP_CURSOR_POSITION, 3),
+			},
+			wantFlushed: []message{
+				msg(payload.OP_STILL_FRAME, 2),
+				msg(payload.OP_CLIPBOARD, 1),
+			},
+		}, {
+			name: "same priority keeps order",
+			messages: []message{
+				msg(payload.OP_STILL_FRAME, 1),
+				msg(payload.OP_CURSOR_IMAGE, 2),
+				msg(payload.OP_STILL_CLEAR, 3),
+			},
+			wantFlushed: []message{
+				msg(payload.OP_STILL_FRAME, 1),
+				msg(payload.OP_CURSOR_IMAGE, 2),
+				msg(payload.OP_STILL_CLEAR, 3),
+			},
+		}, {
+			name: "superseded cursor image is dropped",
+			messages: []message{
+				msg(payload.OP_CURSOR_IMAGE, 1),
+				msg(payload.OP_STILL_FRAME, 2),
+				msg(payload.OP_CURSOR_IMAGE, 3),
+			},
+			wantFlushed: []message{
+				msg(payload.OP_STILL_FRAME, 2),
+				msg(payload.OP_CURSOR_IMAGE, 3),
+			},
+		}, {
+			name: "superseded clipboard is dropped",
+			messages: []message{
+				msg(payload.OP_CLIPBOARD, 1),
+				msg(payload.OP_CLIPBOARD, 2),
+			},
+			wantFlushed: []message{
+				msg(payload.OP_CLIPBOARD, 2),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel := &fakeDataChannel{buffered: sendQueueBufferedLow}
+			queue := &sendQueue{
+				logger:  zerolog.Nop(),
+				channel: channel,
+			}
+
+			for _, m := range tt.messages {
+				if err := queue.Send(m.opcode, m.data); err != nil {
+					t.Fatalf("Send() error = %v", err)
+				}
+			}
+
+			check := func(stage string, want []message) {
+				t.Helper()
+
+				if len(channel.sent) != len(want) {
+					t.Fatalf("%s: sent %d messages, want %d", stage, len(channel.sent), len(want))
+				}
+				for i, m := range want {
+					if !bytes.Equal(channel.sent[i], m.data) {
+						t.Errorf("%s: message %d = %v, want %v", stage, i, channel.sent[i], m.data)
+					}
+				}
+			}
+
+			check("immediate", tt.wantImmediate)
+
+			channel.buffered = 0
+			channel.sent = nil
+			queue.flush()
+
+			check("flushed", tt.wantFlushed)
+		})
+	}
+}
+
+func TestSendQueue_MaxBytes(t *testing.T) {
+	half := make([]byte, sendQueueMaxBytes/2)
+
+	tests := []struct {
+		name     string
+		queued   [][]byte
+		messages [][]byte
+		wantErr  error
+		wantSize int
+	}{
+		{
+			name:     "fits",
+			messages: [][]byte{half, half},
+			wantSize: sendQueueMaxBytes,
+		}, {
+			name:     "transfer over limit is rejected as a whole",
+			queued:   [][]byte{half},
+			messages: [][]byte{half, {0}},
+			wantErr:  errSendQueueFull,
+			wantSize: sendQueueMaxBytes / 2,
+		}, {
+			name:     "urgent messages are not counted",
+			queued:   [][]byte{half, half},
+			messages: nil,
+			wantSize: sendQueueMaxBytes,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			channel := &fakeDataChannel{buffered: sendQueueBufferedLow}
+			queue := &sendQueue{
+				logger:  zerolog.Nop(),
+				channel: channel,
+			}
+
+			for _, data := range tt.queued {
+				if err := queue.Send(payload.OP_STILL_FRAME, data); err != nil {
+					t.Fatalf("Send() error = %v", err)
+				}
+			}
+
+			if tt.messages != nil {
+				err := queue.SendAll(payload.OP_STILL_FRAME, tt.messages)
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("SendAll() error = %v, want %v", err, tt.wantErr)
+				}
+			}
+
+			// urgent messages are sent even when queue is full
+			if err := queue.Send(payload.OP_PONG, []byte{payload.OP_PONG}); err != nil {
+				t.Errorf("Send() of urgent message error = %v", err)
+			}
+
+			if queue.size != tt.wantSize {
+				t.Errorf("size = %d, want %d", queue.size, tt.wantSize)
+			}
+		})
+	}
+}
@@ -0,0 +1,211 @@
+package webrtc
+
+import (
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// standbyPeer is a peer with local offer and gathered candidates, that is
+// not attached to its session until a reconnecting client adopts it.
+type standbyPeer struct {
+	peer    *WebRTCPeerCtx
+	offer   *webrtc.SessionDescription
+	options types.PeerOptions
+	timer   *time.Timer
+}
+
+// standbyPool holds at most one standby peer per session and at most max in total.
+//
+// Lifecycle: standby peer is prepared in background after the session peer connects,
+// it is adopted by the next peer request of the session, and the adopted peer then
+// prepares another one once connected. Unused standby peers expire after ttl and
+// are prepared again, if the session is still connected.
+type standbyPool struct {
+	mu      sync.Mutex
+	max     int
+	ttl     time.Duration
+	peers   map[string]*standbyPeer
+	pending map[string]struct{}
+
+	// creates standby peer for the session
+	create func(session types.Session, options types.PeerOptions) (*webrtc.SessionDescription, types.WebRTCPeer, error)
+}
+
+func newStandbyPool(max int, ttl time.Duration) *standbyPool {
+	return &standbyPool{
+		max:     max,
+		ttl:     ttl,
+		peers:   map[string]*standbyPeer{},
+		pending: map[string]struct{}{},
+	}
+}
+
+// prepareStandby creates standby peer for the session in background, if it is eligible
+func (manager *WebRTCManagerCtx) prepareStandby(session types.Session, options types.PeerOptions) {
+	pool := manager.standby
+	if pool == nil || !session.Profile().WarmStandby {
+		return
+	}
+
+	id := session.ID()
+
+	pool.mu.Lock()
+	_, exists := pool.peers[id]
+	_, pending := pool.pending[id]
+	full := len(pool.peers)+len(pool.pending) >= pool.max
+	if exists || pending || full {
+		pool.mu.Unlock()
+		if full && !exists && !pending {
+			manager.logger.Debug().Str("session_id", id).Msg("standby peers limit reached")
+		}
+		return
+	}
+	pool.pending[id] = struct{}{}
+	pool.mu.Unlock()
+
+	go func() {
+		offer, peer, err := pool.create(session, options)
+
+		pool.mu.Lock()
+		defer pool.mu.Unlock()
+
+		delete(pool.pending, id)
+
+		if err != nil {
+			manager.logger.Err(err).Str("session_id", id).Msg("failed to create standby peer")
+			return
+		}
+
+		// session could have disconnected while the peer was being created
+		if !session.State().IsConnected {
+			peer.Destroy()
+			return
+		}
+
+		entry := &standbyPeer{
+			peer:    peer.(*WebRTCPeerCtx),
+			offer:   offer,
+			options: options,
+		}
+		entry.timer = time.AfterFunc(pool.ttl, func() {
+			manager.expireStandby(session, entry)
+		})
+		pool.peers[id] = entry
+
+		entry.peer.logger.Info().Msg("standby peer ready")
+	}()
+}
+
+// createStandbyPeer creates peer, that is not attached to its session until it is adopted
+func (manager *WebRTCManagerCtx) createStandbyPeer(session types.Session, options types.PeerOptions) (*webrtc.SessionDescription, types.WebRTCPeer, error) {
+	// candidates are gathered into the offer, they cannot be trickled before adoption
+	return manager.createPeer(session, options, peerKindStandby, func(peer *WebRTCPeerCtx) (*webrtc.SessionDescription, error) {
+		return peer.CreateOffer(false)
+	})
+}
+
+// standbyPeerConnected prepares standby peer for the next reconnect of the session,
+// adopted standby peers prepare it as well, only http peers cannot adopt one
+func (manager *WebRTCManagerCtx) standbyPeerConnected(session types.Session, peer *WebRTCPeerCtx, options types.PeerOptions) {
+	if peer.kind != peerKindHTTP {
+		manager.prepareStandby(session, options)
+	}
+}
+
+// expireStandby destroys unused standby peer and prepares a fresh one
+func (manager *WebRTCManagerCtx) expireStandby(session types.Session, entry *standbyPeer) {
+	pool := manager.standby
+
+	pool.mu.Lock()
+	current, ok := pool.peers[session.ID()]
+	if ok && current == entry {
+		delete(pool.peers, session.ID())
+	}
+	pool.mu.Unlock()
+
+	// already adopted or discarded
+	if !ok || current != entry {
+		return
+	}
+
+	entry.peer.logger.Info().Msg("standby peer expired")
+	entry.peer.Destroy()
+
+	if session.State().IsConnected {
+		manager.prepareStandby(session, entry.options)
+	}
+}
+
+// adoptStandby attaches standby peer to its session, if it was created with the same options
+func (manager *WebRTCManagerCtx) adoptStandby(session types.Session, options types.PeerOptions) (*webrtc.SessionDescription, *WebRTCPeerCtx, bool) {
+	pool := manager.standby
+	if pool == nil {
+		return nil, nil, false
+	}
+
+	pool.mu.Lock()
+	entry, ok := pool.peers[session.ID()]
+	delete(pool.peers, session.ID())
+	pool.mu.Unlock()
+
+	if !ok {
+		return nil, nil, false
+	}
+
+	entry.timer.Stop()
+
+	// peer options are applied when the peer connection is created
+	if !reflect.DeepEqual(entry.options, options) {
+		entry.peer.logger.Info().Msg("discarding standby peer, requested options differ")
+		entry.peer.Destroy()
+		return nil, nil, false
+	}
+
+	entry.peer.logger.Info().Msg("adopting standby peer")
+	manager.attachPeer(session, entry.peer)
+	manager.startCollectors(entry.peer)
+	manager.rekeyAfter(session, entry.peer, options)
+
+	return entry.offer, entry.peer, true
+}
+
+// discardSessionStandby destroys standby peer of the session, e.g. when it disconnects
+func (manager *WebRTCManagerCtx) discardSessionStandby(session types.Session) {
+	pool := manager.standby
+
+	pool.mu.Lock()
+	entry, ok := pool.peers[session.ID()]
+	delete(pool.peers, session.ID())
+	pool.mu.Unlock()
+
+	if !ok {
+		return
+	}
+
+	entry.timer.Stop()
+	entry.peer.logger.Info().Msg("discarding standby peer, session is gone")
+	entry.peer.Destroy()
+}
+
+// discardStandby destroys all standby peers, e.g. on shutdown
+func (manager *WebRTCManagerCtx) discardStandby() {
+	pool := manager.standby
+	if pool == nil {
+		return
+	}
+
+	pool.mu.Lock()
+	peers := pool.peers
+	pool.peers = map[string]*standbyPeer{}
+	pool.mu.Unlock()
+
+	for _, entry := range peers {
+		entry.timer.Stop()
+		entry.peer.Destroy()
+	}
+}
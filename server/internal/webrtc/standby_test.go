@@ -0,0 +1,99 @@
+package webrtc
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+type standbySession struct {
+	types.Session
+	peer atomic.Value
+}
+
+func (s *standbySession) ID() string {
+	return "standby-test"
+}
+
+func (s *standbySession) Profile() types.MemberProfile {
+	return types.MemberProfile{WarmStandby: true}
+}
+
+func (s *standbySession) State() types.SessionState {
+	return types.SessionState{IsConnected: true}
+}
+
+func (s *standbySession) SetWebRTCPeer(peer types.WebRTCPeer) {
+	s.peer.Store(peer)
+}
+
+func TestWebRTCManagerCtx_StandbyAdoptedTwice(t *testing.T) {
+	session := &standbySession{}
+	metrics := newMetricsManager()
+
+	manager := &WebRTCManagerCtx{
+		logger:  zerolog.Nop(),
+		config:  &config.WebRTC{},
+		metrics: metrics,
+		standby: newStandbyPool(1, time.Minute),
+	}
+
+	var created atomic.Int32
+	manager.standby.create = func(session types.Session, options types.PeerOptions) (*webrtc.SessionDescription, types.WebRTCPeer, error) {
+		created.Add(1)
+
+		peer, _ := newTestPeers(t)
+		peer.kind = peerKindStandby
+		peer.metrics = metrics.getBySession(session)
+		peer.rtcpChannel = make(chan []rtcp.Packet)
+
+		return &webrtc.SessionDescription{}, peer, nil
+	}
+
+	// waits until standby peer is ready and adopts it
+	adopt := func() *WebRTCPeerCtx {
+		t.Helper()
+
+		deadline := time.Now().Add(time.Second)
+		for {
+			manager.standby.mu.Lock()
+			_, ready := manager.standby.peers[session.ID()]
+			manager.standby.mu.Unlock()
+
+			if ready {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatal("standby peer was not prepared")
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		_, peer, ok := manager.adoptStandby(session, types.PeerOptions{})
+		if !ok {
+			t.Fatal("standby peer was not adopted")
+		}
+		return peer
+	}
+
+	// first peer of the session is signaled over websocket
+	first, _ := newTestPeers(t)
+	first.kind = peerKindWebSocket
+	manager.standbyPeerConnected(session, first, types.PeerOptions{})
+
+	// adopted peer prepares standby for the next reconnect as well
+	adopted := adopt()
+	manager.standbyPeerConnected(session, adopted, types.PeerOptions{})
+	adopt()
+
+	if count := created.Load(); count != 2 {
+		t.Errorf("created standby peers = %d, want 2", count)
+	}
+}
@@ -0,0 +1,168 @@
+package webrtc
+
+import (
+	"image"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/utils"
+)
+
+type stillFrameListener interface {
+	SendStillFrame(id uint32, img []byte) error
+	ClearStillFrame(id uint32) error
+}
+
+// stillFrame sends lossless frame of the screen to its listeners, after the
+// screen was static for a while, and clears it again as soon as it changes.
+// Screen is compared by capture manager, only while there are listeners.
+type stillFrame struct {
+	logger  zerolog.Logger
+	capture types.CaptureManager
+	delay   time.Duration
+
+	listeners map[stillFrameListener]struct{}
+	// current frame is sent to listeners joining later
+	current   []byte
+	currentID uint32
+	mu        sync.Mutex
+}
+
+func newStillFrame(logger zerolog.Logger, capture types.CaptureManager, delay time.Duration) *stillFrame {
+	return &stillFrame{
+		logger:    logger.With().Str("submodule", "still-frame").Logger(),
+		capture:   capture,
+		delay:     delay,
+		listeners: map[stillFrameListener]struct{}{},
+	}
+}
+
+func (s *stillFrame) Shutdown() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.capture.RemoveScreenListener(s)
+	clear(s.listeners)
+	s.current = nil
+}
+
+// ScreenCompared is called by capture manager after every comparison of the screen
+func (s *stillFrame) ScreenCompared(img *image.RGBA, static time.Duration) {
+	if static == 0 {
+		s.clear()
+		return
+	}
+
+	if static >= s.delay {
+		s.send(img)
+	}
+}
+
+// getListeners returns snapshot of listeners, must be called with mutex locked
+func (s *stillFrame) getListeners() []stillFrameListener {
+	listeners := make([]stillFrameListener, 0, len(s.listeners))
+	for l := range s.listeners {
+		listeners = append(listeners, l)
+	}
+	return listeners
+}
+
+// send encodes and broadcasts the frame, only once until the screen changes,
+// frames are sent without holding the mutex, as they are sent in chunks
+func (s *stillFrame) send(img *image.RGBA) {
+	s.mu.Lock()
+	sent := s.current != nil
+	s.mu.Unlock()
+
+	if sent {
+		return
+	}
+
+	// crop to the captured region, so that it matches the video
+	if r := s.capture.Region(); r != nil {
+		img = img.SubImage(image.Rect(r.X, r.Y, r.X+r.Width, r.Y+r.Height)).(*image.RGBA)
+	}
+
+	data, err := utils.CreatePNGImage(img)
+	if err != nil {
+		s.logger.Err(err).Msg("failed to encode still frame")
+		return
+	}
+
+	s.mu.Lock()
+	s.current = data
+	s.currentID++
+	id := s.currentID
+	listeners := s.getListeners()
+	s.mu.Unlock()
+
+	for _, l := range listeners {
+		if err := l.SendStillFrame(id, data); err != nil {
+			s.logger.Err(err).Msg("failed to send still frame")
+		}
+	}
+}
+
+// clear tells listeners to revert to video, if a frame was sent
+func (s *stillFrame) clear() {
+	s.mu.Lock()
+	if s.current == nil {
+		s.mu.Unlock()
+		return
+	}
+
+	s.current = nil
+	id := s.currentID
+	listeners := s.getListeners()
+	s.mu.Unlock()
+
+	for _, l := range listeners {
+		if err := l.ClearStillFrame(id); err != nil {
+			s.logger.Err(err).Msg("failed to clear still frame")
+		}
+	}
+}
+
+func (s *stillFrame) AddListener(listener stillFrameListener) {
+	s.mu.Lock()
+	s.listeners[listener] = struct{}{}
+	// screen is compared only while somebody is interested
+	if len(s.listeners) == 1 {
+		s.capture.AddScreenListener(s)
+	}
+	current, id := s.current, s.currentID
+	s.mu.Unlock()
+
+	if current == nil {
+		return
+	}
+
+	if err := listener.SendStillFrame(id, current); err != nil {
+		s.logger.Err(err).Msg("failed to send still frame")
+	}
+
+	// frame could have been cleared while it was being sent
+	s.mu.Lock()
+	cleared := s.current == nil || s.currentID != id
+	s.mu.Unlock()
+
+	if cleared {
+		if err := listener.ClearStillFrame(id); err != nil {
+			s.logger.Err(err).Msg("failed to clear still frame")
+		}
+	}
+}
+
+func (s *stillFrame) RemoveListener(listener stillFrameListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.listeners, listener)
+	if len(s.listeners) == 0 {
+		s.capture.RemoveScreenListener(s)
+		s.current = nil
+	}
+}
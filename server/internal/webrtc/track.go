@@ -4,26 +4,63 @@ import (
 	"errors"
 	"io"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/pion/rtcp"
 	"github.com/pion/webrtc/v3"
 	"github.com/pion/webrtc/v3/pkg/media"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog"
 
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/codec"
 )
 
+// how many samples can be queued for a track before they start being dropped
+const trackSampleBufferSize = 64
+
+// how many samples are held back by the maximal delay, at up to 100 samples
+// per second (10ms audio frames or 100fps video), on top of the sample buffer
+const trackDelayBufferSize = types.PeerAudioSyncOffsetMax * 100 / 1000
+
+// how long a full sample buffer holds back the stream, before the sample is dropped
+const trackBackpressureTimeout = 20 * time.Millisecond
+
 type Track struct {
 	logger zerolog.Logger
 	track  *webrtc.TrackLocalStaticSample
+	ssrc   uint32
 
 	rtcpCh chan []rtcp.Packet
 	sample chan types.Sample
 
+	// after a sample was dropped, delta units are skipped until next keyframe
+	waitKeyframe        bool
+	droppedBackpressure prometheus.Counter
+	droppedKeyframeWait prometheus.Counter
+
 	paused   bool
 	stream   types.StreamSinkManager
 	streamMu sync.Mutex
+	// called with stream mutex locked, whenever stream is switched
+	followFn func(stream types.StreamSinkManager)
+
+	// samples since last keyframe are replayed, when no sample arrived for keepAlive
+	keepAlive  time.Duration
+	gop        []types.Sample
+	lastSample time.Time
+
+	// guards writing of samples, along with keyframe and keepalive state
+	mu sync.Mutex
+	// sample is held back by full buffer, waiting without the mutex
+	pending bool
+
+	// samples are held back for this long after they were captured
+	delay atomic.Int64
+
+	shutdown chan struct{}
+	wg       sync.WaitGroup
 }
 
 type trackOption func(*Track)
@@ -34,6 +71,21 @@ func WithRtcpChan(rtcp chan []rtcp.Packet) trackOption {
 	}
 }
 
+func WithDropCounters(backpressure, keyframeWait prometheus.Counter) trackOption {
+	return func(t *Track) {
+		t.droppedBackpressure = backpressure
+		t.droppedKeyframeWait = keyframeWait
+	}
+}
+
+// WithKeepAlive replays samples since last keyframe, when the source produced
+// no sample for given interval, so that decoders do not stall on static content.
+func WithKeepAlive(interval time.Duration) trackOption {
+	return func(t *Track) {
+		t.keepAlive = interval
+	}
+}
+
 func NewTrack(logger zerolog.Logger, codec codec.RTPCodec, connection *webrtc.PeerConnection, opts ...trackOption) (*Track, error) {
 	id := codec.Type.String()
 	track, err := webrtc.NewTrackLocalStaticSample(codec.Capability, id, "stream")
@@ -45,7 +97,9 @@ func NewTrack(logger zerolog.Logger, codec codec.RTPCodec, connection *webrtc.Pe
 		logger: logger.With().Str("id", id).Logger(),
 		track:  track,
 		rtcpCh: nil,
-		sample: make(chan types.Sample),
+		sample: make(chan types.Sample, trackSampleBufferSize+trackDelayBufferSize),
+
+		shutdown: make(chan struct{}),
 	}
 
 	for _, opt := range opts {
@@ -57,14 +111,34 @@ func NewTrack(logger zerolog.Logger, codec codec.RTPCodec, connection *webrtc.Pe
 		return nil, err
 	}
 
+	if encodings := sender.GetParameters().Encodings; len(encodings) > 0 {
+		t.ssrc = uint32(encodings[0].SSRC)
+	}
+
 	go t.rtcpReader(sender)
 	go t.sampleReader()
 
+	// replaying is only meaningful for video, audio is sent continuously
+	if t.keepAlive > 0 && codec.IsVideo() {
+		t.wg.Add(1)
+		go t.keepAliveLoop()
+	}
+
 	return t, nil
 }
 
+// SSRC of the outgoing RTP stream
+func (t *Track) SSRC() uint32 {
+	return t.ssrc
+}
+
 func (t *Track) Shutdown() {
 	t.RemoveStream()
+
+	// keepalive must not write to closed sample channel
+	close(t.shutdown)
+	t.wg.Wait()
+
 	close(t.sample)
 }
 
@@ -97,6 +171,12 @@ func (t *Track) sampleReader() {
 			return
 		}
 
+		// sender reports map rtp time to the time samples are actually sent,
+		// so the receiver aligns playout of delayed samples accordingly
+		if delay := time.Duration(t.delay.Load()); delay > 0 {
+			time.Sleep(time.Until(sample.Timestamp.Add(delay)))
+		}
+
 		err := t.track.WriteSample(media.Sample{
 			Data:      sample.Data,
 			Duration:  sample.Duration,
@@ -109,8 +189,152 @@ func (t *Track) sampleReader() {
 	}
 }
 
+// SetDelay holds back following samples, so that they are sent the given
+// time after they were captured, delayed samples are queued in the sample buffer
+// that has room only for the maximal sync offset, so the delay is capped to it.
+func (t *Track) SetDelay(delay time.Duration) {
+	delay = min(delay, types.PeerAudioSyncOffsetMax*time.Millisecond)
+	t.delay.Store(int64(delay))
+}
+
+// WriteSample queues sample without blocking, so that a slow peer does not
+// hold back other listeners of the same stream.
 func (t *Track) WriteSample(sample types.Sample) {
-	t.sample <- sample
+	// replayed samples must not interleave with new ones
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// another sample is already held back, the buffer is full
+	if t.pending {
+		t.dropSample()
+		return
+	}
+
+	// delta units cannot be decoded without previously dropped frames
+	if t.waitKeyframe {
+		if sample.DeltaUnit {
+			if t.droppedKeyframeWait != nil {
+				t.droppedKeyframeWait.Inc()
+			}
+			return
+		}
+		t.waitKeyframe = false
+	}
+
+	select {
+	case t.sample <- sample:
+		t.saveSample(sample)
+		return
+	default:
+	}
+
+	// full buffer holds back the stream for a short while, so that
+	// short stalls of the peer do not immediately cost a keyframe,
+	// mutex is released meanwhile, so that the track is not blocked
+	t.pending = true
+	t.mu.Unlock()
+
+	timer := time.NewTimer(trackBackpressureTimeout)
+	defer timer.Stop()
+
+	sent := false
+	select {
+	case t.sample <- sample:
+		sent = true
+	case <-timer.C:
+	}
+
+	t.mu.Lock()
+	t.pending = false
+
+	if sent {
+		t.saveSample(sample)
+	} else {
+		t.dropSample()
+	}
+}
+
+// dropSample must be called with mutex locked
+func (t *Track) dropSample() {
+	t.waitKeyframe = true
+	if t.droppedBackpressure != nil {
+		t.droppedBackpressure.Inc()
+	}
+}
+
+// --- keepalive ---
+
+// saveSample remembers samples since last keyframe, they can be replayed
+// only as a whole, so that the decoder ends up with the same picture.
+func (t *Track) saveSample(sample types.Sample) {
+	if t.keepAlive == 0 {
+		return
+	}
+
+	t.lastSample = time.Now()
+
+	if !sample.DeltaUnit {
+		t.gop = append(t.gop[:0], sample)
+		return
+	}
+
+	// too long group of pictures would not fit into the sample buffer
+	if t.gop != nil && len(t.gop) < trackSampleBufferSize/2 {
+		t.gop = append(t.gop, sample)
+	} else {
+		t.gop = nil
+	}
+}
+
+func (t *Track) keepAliveLoop() {
+	defer t.wg.Done()
+
+	ticker := time.NewTicker(t.keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.shutdown:
+			return
+		case <-ticker.C:
+			t.replay()
+		}
+	}
+}
+
+func (t *Track) replay() {
+	t.streamMu.Lock()
+	active := t.stream != nil && !t.paused
+	t.streamMu.Unlock()
+
+	if !active {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// samples must be replayed as a whole, or not at all
+	if t.pending || time.Since(t.lastSample) < t.keepAlive || len(t.gop) == 0 || cap(t.sample)-len(t.sample) < len(t.gop) {
+		return
+	}
+
+	t.logger.Debug().Int("samples", len(t.gop)).Msg("source is static, replaying samples since last keyframe")
+	t.lastSample = time.Now()
+
+	for i, sample := range t.gop {
+		// intermediate pictures are not meant to be seen again
+		if i < len(t.gop)-1 {
+			sample.Duration = time.Millisecond
+		}
+
+		t.sample <- sample
+	}
+}
+
+// GeneratePadding sends padding-only packets, used for bandwidth probing.
+func (t *Track) GeneratePadding(packets uint32) error {
+	return t.track.GeneratePadding(packets)
 }
 
 // --- stream ---
@@ -126,7 +350,7 @@ func (t *Track) SetStream(stream types.StreamSinkManager) (bool, error) {
 
 	// if paused, we switch the stream but don't add the listener
 	if t.paused {
-		t.stream = stream
+		t.setStream(stream)
 		return true, nil
 	}
 
@@ -140,10 +364,32 @@ func (t *Track) SetStream(stream types.StreamSinkManager) (bool, error) {
 		return false, err
 	}
 
-	t.stream = stream
+	t.setStream(stream)
 	return true, nil
 }
 
+// setStream must be called with stream mutex locked
+func (t *Track) setStream(stream types.StreamSinkManager) {
+	t.stream = stream
+
+	if t.followFn != nil {
+		t.followFn(stream)
+	}
+}
+
+// FollowStream calls fn with current stream and then every time the stream is switched,
+// so that other listeners can receive the same stream as the track, nil fn stops it.
+func (t *Track) FollowStream(fn func(stream types.StreamSinkManager)) {
+	t.streamMu.Lock()
+	defer t.streamMu.Unlock()
+
+	t.followFn = fn
+
+	if fn != nil && t.stream != nil {
+		fn(t.stream)
+	}
+}
+
 func (t *Track) RemoveStream() {
 	t.streamMu.Lock()
 	defer t.streamMu.Unlock()
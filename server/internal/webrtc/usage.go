@@ -0,0 +1,108 @@
+package webrtc
+
+import (
+	"time"
+
+	"github.com/pion/webrtc/v3"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// how often is traffic of a peer added to usage of its session
+const usageInterval = 5 * time.Second
+
+// quotaExceeded starts a new period if the previous one is over,
+// and reports whether the session has exceeded its quota
+func quotaExceeded(session types.Session, quota config.WebRTCQuota) bool {
+	if quota.Bytes == 0 {
+		return false
+	}
+
+	usage := session.Usage()
+	if quota.Period == config.WebRTCQuotaPeriodMonthly && !usage.Since.IsZero() {
+		since, now := usage.Since.UTC(), time.Now().UTC()
+		if since.Year() != now.Year() || since.Month() != now.Month() {
+			session.ResetUsage()
+			return false
+		}
+	}
+
+	return usage.QuotaExceeded
+}
+
+// usageReporter accumulates bytes transferred by the peer to its session and enforces the quota,
+// ice transport counts all traffic of the connection, including data channels
+func (peer *WebRTCPeerCtx) usageReporter() {
+	ticker := time.NewTicker(usageInterval)
+	defer ticker.Stop()
+
+	var last webrtc.TransportStats
+
+	for range ticker.C {
+		if peer.connection.ConnectionState() == webrtc.PeerConnectionStateClosed {
+			return
+		}
+
+		stats, ok := peer.connection.GetStats()["iceTransport"].(webrtc.TransportStats)
+		if !ok {
+			continue
+		}
+
+		if peer.accountUsage(stats, &last) && !peer.enforceQuota() {
+			return
+		}
+	}
+}
+
+// accountUsage adds traffic since the last stats to usage of the session,
+// and reports whether the session is over its quota
+func (peer *WebRTCPeerCtx) accountUsage(stats webrtc.TransportStats, last *webrtc.TransportStats) bool {
+	// counters start over, when the transport is replaced
+	if stats.BytesSent < last.BytesSent || stats.BytesReceived < last.BytesReceived {
+		*last = webrtc.TransportStats{}
+	}
+
+	// period is rolled over before new traffic is added
+	exceeded := quotaExceeded(peer.session, peer.quota)
+
+	usage := peer.session.AddUsage(stats.BytesSent-last.BytesSent, stats.BytesReceived-last.BytesReceived)
+	*last = stats
+
+	if peer.quota.Bytes == 0 {
+		return false
+	}
+
+	if !exceeded && usage.BytesSent+usage.BytesReceived >= peer.quota.Bytes {
+		peer.logger.Warn().
+			Uint64("bytes_sent", usage.BytesSent).
+			Uint64("bytes_received", usage.BytesReceived).
+			Str("action", string(peer.quota.Action)).
+			Msg("bandwidth quota exceeded")
+
+		peer.session.SetQuotaExceeded(true)
+		exceeded = true
+	}
+
+	return exceeded
+}
+
+// enforceQuota applies the over-quota action, returns false if the peer is gone
+func (peer *WebRTCPeerCtx) enforceQuota() bool {
+	switch peer.quota.Action {
+	case config.WebRTCQuotaActionAudioOnly:
+		disabled := true
+		err := peer.SetVideo(types.PeerVideoRequest{
+			Disabled: &disabled,
+			Reason:   types.VideoChangeReasonQuotaExceeded,
+		})
+		if err != nil {
+			peer.logger.Err(err).Msg("failed to disable video over quota")
+		}
+		return true
+	default:
+		peer.session.DestroyWebSocketPeer(types.ErrWebRTCQuotaExceeded.Error())
+		peer.Destroy()
+		return false
+	}
+}
@@ -0,0 +1,207 @@
+package webrtc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/webrtc/v3"
+	"github.com/rs/zerolog"
+
+	"github.com/m1k1o/neko/server/internal/config"
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// usageSession keeps usage in memory, as the session manager does
+type usageSession struct {
+	types.Session
+	usage  types.SessionUsage
+	resets int
+}
+
+func (s *usageSession) AddUsage(sent, received uint64) types.SessionUsage {
+	if s.usage.Since.IsZero() {
+		s.usage.Since = time.Now()
+	}
+
+	s.usage.BytesSent += sent
+	s.usage.BytesReceived += received
+	return s.usage
+}
+
+func (s *usageSession) ResetUsage() {
+	s.usage = types.SessionUsage{Since: time.Now()}
+	s.resets++
+}
+
+func (s *usageSession) SetQuotaExceeded(exceeded bool) {
+	s.usage.QuotaExceeded = exceeded
+}
+
+func (s *usageSession) Usage() types.SessionUsage {
+	return s.usage
+}
+
+func TestQuotaExceeded(t *testing.T) {
+	now := time.Now().UTC()
+	lastMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+	lastYear := now.AddDate(-1, 0, 0)
+
+	monthly := config.WebRTCQuota{Bytes: 100, Period: config.WebRTCQuotaPeriodMonthly}
+	session := config.WebRTCQuota{Bytes: 100}
+
+	tests := []struct {
+		name      string
+		quota     config.WebRTCQuota
+		usage     types.SessionUsage
+		want      bool
+		wantReset bool
+	}{
+		{
+			name:  "no quota",
+			quota: config.WebRTCQuota{},
+			usage: types.SessionUsage{BytesSent: 1000, Since: now, QuotaExceeded: true},
+			want:  false,
+		}, {
+			name:  "under quota",
+			quota: monthly,
+			usage: types.SessionUsage{BytesSent: 50, Since: now},
+			want:  false,
+		}, {
+			name:  "exceeded",
+			quota: monthly,
+			usage: types.SessionUsage{BytesSent: 150, Since: now, QuotaExceeded: true},
+			want:  true,
+		}, {
+			name:  "no usage yet",
+			quota: monthly,
+			usage: types.SessionUsage{},
+			want:  false,
+		}, {
+			name:      "previous month",
+			quota:     monthly,
+			usage:     types.SessionUsage{BytesSent: 150, Since: lastMonth, QuotaExceeded: true},
+			want:      false,
+			wantReset: true,
+		}, {
+			name:      "same month of previous year",
+			quota:     monthly,
+			usage:     types.SessionUsage{BytesSent: 150, Since: lastYear, QuotaExceeded: true},
+			want:      false,
+			wantReset: true,
+		}, {
+			name:  "session period",
+			quota: session,
+			usage: types.SessionUsage{BytesSent: 150, Since: lastMonth, QuotaExceeded: true},
+			want:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &usageSession{usage: tt.usage}
+
+			if got := quotaExceeded(s, tt.quota); got != tt.want {
+				t.Errorf("quotaExceeded() = %v, want %v", got, tt.want)
+			}
+			if (s.resets > 0) != tt.wantReset {
+				t.Errorf("resets = %d, wantReset %v", s.resets, tt.wantReset)
+			}
+			if tt.wantReset && (s.usage.BytesSent != 0 || s.usage.QuotaExceeded) {
+				t.Errorf("usage was not reset: %+v", s.usage)
+			}
+		})
+	}
+}
+
+func TestWebRTCPeerCtx_AccountUsage(t *testing.T) {
+	now := time.Now().UTC()
+	lastMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+
+	type step struct {
+		sent, received uint64
+		wantTotal      uint64
+		wantExceeded   bool
+	}
+
+	tests := []struct {
+		name  string
+		quota config.WebRTCQuota
+		usage types.SessionUsage
+		steps []step
+	}{
+		{
+			name: "no quota",
+			steps: []step{
+				{sent: 100, received: 10, wantTotal: 110},
+				{sent: 300, received: 20, wantTotal: 320},
+			},
+		}, {
+			name:  "under quota",
+			quota: config.WebRTCQuota{Bytes: 1000},
+			steps: []step{
+				{sent: 100, wantTotal: 100},
+				{sent: 200, received: 50, wantTotal: 250},
+			},
+		}, {
+			name:  "quota reached",
+			quota: config.WebRTCQuota{Bytes: 1000},
+			steps: []step{
+				{sent: 600, wantTotal: 600},
+				{sent: 900, received: 100, wantTotal: 1000, wantExceeded: true},
+				{sent: 950, received: 100, wantTotal: 1050, wantExceeded: true},
+			},
+		}, {
+			name:  "traffic of other peers",
+			quota: config.WebRTCQuota{Bytes: 1000},
+			usage: types.SessionUsage{BytesSent: 800, Since: now},
+			steps: []step{
+				{sent: 100, wantTotal: 900},
+				{sent: 200, wantTotal: 1000, wantExceeded: true},
+			},
+		}, {
+			name:  "transport replaced",
+			quota: config.WebRTCQuota{Bytes: 1000},
+			steps: []step{
+				{sent: 500, received: 100, wantTotal: 600},
+				// counters start over, traffic of the new transport is added
+				{sent: 50, received: 10, wantTotal: 660},
+				{sent: 100, received: 20, wantTotal: 720},
+			},
+		}, {
+			name:  "previous month",
+			quota: config.WebRTCQuota{Bytes: 1000, Period: config.WebRTCQuotaPeriodMonthly},
+			usage: types.SessionUsage{BytesSent: 1500, Since: lastMonth, QuotaExceeded: true},
+			steps: []step{
+				{sent: 100, wantTotal: 100},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &usageSession{usage: tt.usage}
+			peer := &WebRTCPeerCtx{
+				logger:  zerolog.Nop(),
+				session: session,
+				quota:   tt.quota,
+			}
+
+			var last webrtc.TransportStats
+			for i, s := range tt.steps {
+				exceeded := peer.accountUsage(webrtc.TransportStats{
+					BytesSent:     s.sent,
+					BytesReceived: s.received,
+				}, &last)
+
+				usage := session.Usage()
+				if total := usage.BytesSent + usage.BytesReceived; total != s.wantTotal {
+					t.Errorf("step %d: total = %d, want %d", i, total, s.wantTotal)
+				}
+				if exceeded != s.wantExceeded {
+					t.Errorf("step %d: exceeded = %v, want %v", i, exceeded, s.wantExceeded)
+				}
+				if usage.QuotaExceeded != s.wantExceeded {
+					t.Errorf("step %d: session quota exceeded = %v, want %v", i, usage.QuotaExceeded, s.wantExceeded)
+				}
+			}
+		})
+	}
+}
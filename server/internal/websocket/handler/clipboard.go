@@ -1,23 +1,69 @@
 package handler
 
 import (
-	"errors"
+	"fmt"
 
 	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
 	"github.com/m1k1o/neko/server/pkg/types/message"
 )
 
 func (h *MessageHandlerCtx) clipboardSet(session types.Session, payload *message.ClipboardData) error {
-	if !session.Profile().CanAccessClipboard {
-		return errors.New("cannot access clipboard")
+	if !session.Profile().CanWriteClipboard() {
+		return ErrCannotAccessClipboard
 	}
 
 	if !session.IsHost() {
-		return errors.New("is not the host")
+		return ErrIsNotTheHost
 	}
 
-	return h.desktop.ClipboardSetText(types.ClipboardText{
+	data := types.ClipboardText{
 		Text: payload.Text,
 		// TODO: Send HTML?
+	}
+
+	if err := h.desktop.ClipboardLimit(&data); err != nil {
+		return err
+	}
+
+	err := h.desktop.ClipboardSetText(data)
+	if err != nil {
+		return err
+	}
+
+	if data.Truncated {
+		session.Send(
+			event.CLIPBOARD_TRUNCATED,
+			message.ClipboardTruncated{
+				Size:     len(data.Text),
+				Original: len(payload.Text),
+			})
+	}
+
+	h.sessions.Audit(types.AuditEntry{
+		Actor:   session.ID(),
+		Action:  types.AuditClipboardSet,
+		Details: fmt.Sprintf("%d bytes", len(payload.Text)),
+	})
+	return nil
+}
+
+func (h *MessageHandlerCtx) clipboardClear(session types.Session) error {
+	if !session.Profile().CanWriteClipboard() {
+		return ErrCannotAccessClipboard
+	}
+
+	if !session.IsHost() {
+		return ErrIsNotTheHost
+	}
+
+	if err := h.desktop.ClipboardClear(); err != nil {
+		return err
+	}
+
+	h.sessions.Audit(types.AuditEntry{
+		Actor:  session.ID(),
+		Action: types.AuditClipboardClear,
 	})
+	return nil
 }
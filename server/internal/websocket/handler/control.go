@@ -4,69 +4,77 @@ import (
 	"errors"
 
 	"github.com/m1k1o/neko/server/pkg/types"
-	"github.com/m1k1o/neko/server/pkg/types/event"
 	"github.com/m1k1o/neko/server/pkg/types/message"
 	"github.com/m1k1o/neko/server/pkg/xorg"
 )
 
 var (
-	ErrIsNotAllowedToHost = errors.New("is not allowed to host")
-	ErrIsNotTheHost       = errors.New("is not the host")
-	ErrIsAlreadyTheHost   = errors.New("is already the host")
-	ErrIsAlreadyHosted    = errors.New("is already hosted")
+	ErrIsNotAllowedToHost = types.ErrSessionNotAllowedToHost
+	ErrIsNotTheHost       = types.ErrSessionNotTheHost
+	ErrIsAlreadyTheHost   = types.ErrSessionAlreadyTheHost
+	ErrIsAlreadyHosted    = types.ErrSessionAlreadyHosted
+	ErrTargetCannotHost   = errors.New("target is not allowed to host")
 )
 
 func (h *MessageHandlerCtx) controlRelease(session types.Session) error {
-	if !session.Profile().CanHost || session.PrivateModeEnabled() {
-		return ErrIsNotAllowedToHost
+	if err := h.sessions.ControlRelease(session); err != nil {
+		return err
 	}
 
-	if !session.IsHost() {
-		return ErrIsNotTheHost
+	h.desktop.ResetKeys()
+	return nil
+}
+
+func (h *MessageHandlerCtx) controlRequest(session types.Session) error {
+	host, err := h.sessions.ControlRequest(session)
+	if err != nil {
+		return err
 	}
 
-	h.desktop.ResetKeys()
-	session.ClearHost()
+	// control was taken from the previous host
+	if host != nil {
+		h.desktop.ResetKeys()
+	}
 
 	return nil
 }
 
-func (h *MessageHandlerCtx) controlRequest(session types.Session) error {
-	if !session.Profile().CanHost || session.PrivateModeEnabled() {
-		return ErrIsNotAllowedToHost
+// controlGive hands control directly to the target session, bypassing the request flow
+func (h *MessageHandlerCtx) controlGive(session types.Session, payload *message.SessionID) error {
+	if !session.IsHost() && !session.Profile().IsAdmin {
+		return ErrIsNotTheHost
 	}
 
-	if session.IsHost() {
-		return ErrIsAlreadyTheHost
+	target, ok := h.sessions.Get(payload.ID)
+	if !ok {
+		return types.ErrSessionNotFound
 	}
 
-	if h.sessions.Settings().LockedControls && !session.Profile().IsAdmin {
-		return ErrIsNotAllowedToHost
+	err := h.sessions.ControlGive(session, target)
+	if errors.Is(err, types.ErrSessionNotAllowedToHost) {
+		return ErrTargetCannotHost
+	} else if err != nil {
+		return err
 	}
 
-	// if implicit hosting is enabled, set session as host without asking
-	if h.sessions.Settings().ImplicitHosting {
-		session.SetAsHost()
-		return nil
+	h.desktop.ResetKeys()
+	return nil
+}
+
+// controlRevoke forcibly takes control from the current host
+func (h *MessageHandlerCtx) controlRevoke(session types.Session) error {
+	if !session.Profile().IsAdmin {
+		return ErrIsNotTheAdmin
 	}
 
-	// if there is no host, set session as host
-	host, hasHost := h.sessions.GetHost()
-	if !hasHost {
-		session.SetAsHost()
+	if _, hasHost := h.sessions.GetHost(); !hasHost {
 		return nil
 	}
 
-	// TODO: Some throttling mechanism to prevent spamming.
-
-	// let host know that someone wants to take control
-	host.Send(
-		event.CONTROL_REQUEST,
-		message.SessionID{
-			ID: session.ID(),
-		})
+	h.desktop.ResetKeys()
+	session.ClearHost()
 
-	return ErrIsAlreadyHosted
+	return nil
 }
 
 func (h *MessageHandlerCtx) controlMove(session types.Session, payload *message.ControlPos) error {
@@ -74,12 +82,23 @@ func (h *MessageHandlerCtx) controlMove(session types.Session, payload *message.
 		return err
 	}
 
+	x, y := h.toScreen(session, payload.X, payload.Y)
+
 	// handle active cursor movement
-	h.desktop.Move(payload.X, payload.Y)
-	h.webrtc.SetCursorPosition(payload.X, payload.Y)
+	h.desktop.Move(x, y)
+	h.webrtc.SetCursorPosition(x, y)
 	return nil
 }
 
+// toScreen converts coordinates relative to the video, that can be mirrored and cropped, to screen coordinates
+func (h *MessageHandlerCtx) toScreen(session types.Session, x, y int) (int, int) {
+	if peer := session.GetWebRTCPeer(); peer != nil {
+		return peer.ToScreen(x, y)
+	}
+
+	return h.capture.Region().ToScreen(x, y)
+}
+
 func (h *MessageHandlerCtx) controlScroll(session types.Session, payload *message.ControlScroll) error {
 	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
 		return err
@@ -140,6 +159,10 @@ func (h *MessageHandlerCtx) controlKeyPress(session types.Session, payload *mess
 		return err
 	}
 
+	if h.keyBlocked(session, payload.Keysym, h.desktop.KeysDown()) {
+		return nil
+	}
+
 	return h.desktop.KeyPress(payload.Keysym)
 }
 
@@ -152,6 +175,10 @@ func (h *MessageHandlerCtx) controlKeyDown(session types.Session, payload *messa
 		return err
 	}
 
+	if h.keyBlocked(session, payload.Keysym, h.desktop.KeysDown()) {
+		return nil
+	}
+
 	return h.desktop.KeyDown(payload.Keysym)
 }
 
@@ -167,25 +194,44 @@ func (h *MessageHandlerCtx) controlKeyUp(session types.Session, payload *message
 	return h.desktop.KeyUp(payload.Keysym)
 }
 
+// keyBlocked checks key filter of the session, blocked keys are dropped
+func (h *MessageHandlerCtx) keyBlocked(session types.Session, keysym uint32, pressed []uint32) bool {
+	if !session.Profile().KeyBlocked(keysym, pressed) {
+		return false
+	}
+
+	h.logger.Warn().
+		Str("session_id", session.ID()).
+		Uint32("keysym", keysym).
+		Msg("blocked key")
+	return true
+}
+
 func (h *MessageHandlerCtx) controlTouchBegin(session types.Session, payload *message.ControlTouch) error {
 	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
 		return err
 	}
-	return h.desktop.TouchBegin(payload.TouchId, payload.X, payload.Y, payload.Pressure)
+
+	x, y := h.toScreen(session, payload.X, payload.Y)
+	return h.desktop.TouchBegin(payload.TouchId, x, y, payload.Pressure)
 }
 
 func (h *MessageHandlerCtx) controlTouchUpdate(session types.Session, payload *message.ControlTouch) error {
 	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
 		return err
 	}
-	return h.desktop.TouchUpdate(payload.TouchId, payload.X, payload.Y, payload.Pressure)
+
+	x, y := h.toScreen(session, payload.X, payload.Y)
+	return h.desktop.TouchUpdate(payload.TouchId, x, y, payload.Pressure)
 }
 
 func (h *MessageHandlerCtx) controlTouchEnd(session types.Session, payload *message.ControlTouch) error {
 	if err := h.controlRequest(session); err != nil && !errors.Is(err, ErrIsAlreadyTheHost) {
 		return err
 	}
-	return h.desktop.TouchEnd(payload.TouchId, payload.X, payload.Y, payload.Pressure)
+
+	x, y := h.toScreen(session, payload.X, payload.Y)
+	return h.desktop.TouchEnd(payload.TouchId, x, y, payload.Pressure)
 }
 
 func (h *MessageHandlerCtx) controlCut(session types.Session) error {
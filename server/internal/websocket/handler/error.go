@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+var (
+	ErrIsNotTheAdmin         = errors.New("is not the admin")
+	ErrIsNotAllowedToWatch   = errors.New("not allowed to watch")
+	ErrCannotAccessClipboard = errors.New("cannot access clipboard")
+	ErrPeerNotFound          = errors.New("webRTC peer does not exist")
+	ErrReceiverNotFound      = errors.New("receiver session ID not found")
+	ErrInvalidResumeToken    = errors.New("invalid resume token")
+	ErrNoPresentationTargets = errors.New("presentation has no targets")
+)
+
+// error codes sent to the client in system/error event
+const (
+	ErrorCodeBadRequest = "bad_request"
+	ErrorCodeForbidden  = "forbidden"
+	ErrorCodeNotFound   = "not_found"
+	ErrorCodeConflict   = "conflict"
+	ErrorCodeInternal   = "internal"
+)
+
+// errors that are safe to be shown to the client, with their error code,
+// any other error is reported as internal without its details
+var clientErrors = map[error]string{
+	ErrIsNotAllowedToHost:    ErrorCodeForbidden,
+	ErrIsNotTheHost:          ErrorCodeForbidden,
+	ErrIsNotTheAdmin:         ErrorCodeForbidden,
+	ErrIsNotAllowedToWatch:   ErrorCodeForbidden,
+	ErrCannotAccessClipboard: ErrorCodeForbidden,
+	ErrIsAlreadyTheHost:      ErrorCodeConflict,
+	ErrIsAlreadyHosted:       ErrorCodeConflict,
+	ErrTargetCannotHost:      ErrorCodeBadRequest,
+	ErrPeerNotFound:          ErrorCodeNotFound,
+	ErrReceiverNotFound:      ErrorCodeNotFound,
+	ErrInvalidResumeToken:    ErrorCodeForbidden,
+	ErrNoPresentationTargets: ErrorCodeBadRequest,
+
+	types.ErrSessionNotFound:             ErrorCodeNotFound,
+	types.ErrCaptureDisplayNotFound:      ErrorCodeNotFound,
+	types.ErrCaptureRegionInvalid:        ErrorCodeBadRequest,
+	types.ErrCaptureRegionUnsupported:    ErrorCodeBadRequest,
+	types.ErrCaptureAudioGainOutOfRange:  ErrorCodeBadRequest,
+	types.ErrCaptureAudioGainUnsupported: ErrorCodeBadRequest,
+	types.ErrCaptureVideoScaleOutOfRange: ErrorCodeBadRequest,
+	types.ErrWebRTCStreamNotFound:        ErrorCodeNotFound,
+	types.ErrWebRTCOfferCollision:        ErrorCodeConflict,
+	types.ErrWebRTCICECredentials:        ErrorCodeBadRequest,
+	types.ErrWebRTCAudioSyncOffset:       ErrorCodeBadRequest,
+	types.ErrWebRTCCodecMismatch:         ErrorCodeBadRequest,
+	types.ErrWebRTCNegotiationFailed:     ErrorCodeInternal,
+	types.ErrWebRTCQuotaExceeded:         ErrorCodeForbidden,
+	types.ErrWebRTCForwardInvalid:        ErrorCodeBadRequest,
+	types.ErrWebRTCEncoderUnknown:        ErrorCodeBadRequest,
+	types.ErrSessionMetadataTooLarge:     ErrorCodeBadRequest,
+	types.ErrClipboardTooLarge:           ErrorCodeBadRequest,
+	types.ErrKeyboardMapUnavailable:      ErrorCodeBadRequest,
+	types.ErrScreenSizeUnsupported:       ErrorCodeBadRequest,
+	types.ErrDisplayUnavailable:          ErrorCodeBadRequest,
+}
+
+func errorMessage(eventName string, err error) message.SystemError {
+	for clientErr, code := range clientErrors {
+		if errors.Is(err, clientErr) {
+			return message.SystemError{
+				Event:   eventName,
+				Code:    code,
+				Message: clientErr.Error(),
+			}
+		}
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return message.SystemError{
+			Event:   eventName,
+			Code:    ErrorCodeBadRequest,
+			Message: "invalid payload",
+		}
+	}
+
+	return message.SystemError{
+		Event:   eventName,
+		Code:    ErrorCodeInternal,
+		Message: "internal error",
+	}
+}
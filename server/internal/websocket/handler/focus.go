@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"encoding/json"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+// input events, that are dropped while client window is not focused
+var focusMutedEvents = map[string]struct{}{
+	event.CONTROL_MOVE:        {},
+	event.CONTROL_SCROLL:      {},
+	event.CONTROL_BUTTONPRESS: {},
+	event.CONTROL_BUTTONDOWN:  {},
+	event.CONTROL_BUTTONUP:    {},
+	event.CONTROL_KEYPRESS:    {},
+	event.CONTROL_KEYDOWN:     {},
+	event.CONTROL_KEYUP:       {},
+	event.CONTROL_TOUCHBEGIN:  {},
+	event.CONTROL_TOUCHUPDATE: {},
+	event.CONTROL_TOUCHEND:    {},
+	event.CONTROL_CUT:         {},
+	event.CONTROL_COPY:        {},
+	event.CONTROL_PASTE:       {},
+	event.CONTROL_SELECT_ALL:  {},
+	event.KEYBOARD_MODIFIERS:  {},
+}
+
+// focusMiddleware drops input events of sessions, whose window is not focused
+func focusMiddleware(event string, next types.WebSocketMessageHandler) types.WebSocketMessageHandler {
+	if _, ok := focusMutedEvents[event]; !ok {
+		return next
+	}
+
+	return func(session types.Session, payload json.RawMessage) error {
+		if !session.Focused() {
+			return nil
+		}
+
+		return next(session, payload)
+	}
+}
+
+func (h *MessageHandlerCtx) focusChanged(session types.Session, payload *message.FocusChanged) error {
+	wasFocused := session.Focused()
+	session.SetFocused(payload.Focused)
+
+	// keys and buttons held when the window lost focus would never be released
+	if wasFocused && !payload.Focused && session.IsHost() {
+		h.desktop.ResetModifiers()
+	}
+
+	return nil
+}
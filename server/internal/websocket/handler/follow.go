@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+// hostVideoID returns ID of the video stream that the host is watching.
+func (h *MessageHandlerCtx) hostVideoID() (string, bool) {
+	host, ok := h.sessions.GetHost()
+	if !ok {
+		return "", false
+	}
+
+	peer := host.GetWebRTCPeer()
+	if peer == nil {
+		return "", false
+	}
+
+	id := peer.Video().ID
+	return id, id != ""
+}
+
+// syncFollowers selects video stream of the host for all sessions following it.
+// It is called when the host selects stream itself or when the host changes.
+// Streams switched by estimator of the host are not propagated, as they reflect
+// bandwidth of the host, not of the followers.
+func (h *MessageHandlerCtx) syncFollowers() {
+	id, ok := h.hostVideoID()
+	if !ok {
+		return
+	}
+
+	h.sessions.Range(func(session types.Session) bool {
+		if session.IsHost() {
+			return true
+		}
+
+		peer := session.GetWebRTCPeer()
+		if peer == nil || !peer.Video().FollowHost {
+			return true
+		}
+
+		err := peer.SetVideo(types.PeerVideoRequest{
+			Selector: &types.StreamSelector{
+				ID:   id,
+				Type: types.StreamSelectorTypeExact,
+			},
+		})
+		if err != nil {
+			h.logger.Warn().Err(err).
+				Str("session_id", session.ID()).
+				Str("video_id", id).
+				Msg("unable to follow host video")
+		}
+
+		return true
+	})
+}
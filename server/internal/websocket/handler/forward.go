@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/event"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+func (h *MessageHandlerCtx) forwardStart(session types.Session, payload *message.ForwardStart) error {
+	if !session.Profile().IsAdmin {
+		return ErrIsNotTheAdmin
+	}
+
+	target, ok := h.sessions.Get(payload.SessionID)
+	if !ok {
+		return types.ErrSessionNotFound
+	}
+
+	if target.GetWebRTCPeer() == nil {
+		return ErrPeerNotFound
+	}
+
+	if err := h.webrtc.StartForward(target, payload.RTPForward); err != nil {
+		return err
+	}
+
+	h.sessions.Audit(types.AuditEntry{
+		Actor:   session.ID(),
+		Action:  types.AuditForwardStart,
+		Target:  target.ID(),
+		Details: payload.Address,
+	})
+
+	h.forwardStatus()
+	return nil
+}
+
+func (h *MessageHandlerCtx) forwardStop(session types.Session) error {
+	if !session.Profile().IsAdmin {
+		return ErrIsNotTheAdmin
+	}
+
+	h.webrtc.StopForward()
+
+	h.sessions.Audit(types.AuditEntry{
+		Actor:  session.ID(),
+		Action: types.AuditForwardStop,
+	})
+
+	h.forwardStatus()
+	return nil
+}
+
+func (h *MessageHandlerCtx) forwardStatus() {
+	h.sessions.AdminBroadcast(event.FORWARD_STATUS, message.ForwardStatus{
+		RTPForwardStatus: h.webrtc.ForwardStatus(),
+	})
+}
@@ -1,25 +1,54 @@
 package handler
 
 import (
-	"errors"
-
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/message"
 )
 
 func (h *MessageHandlerCtx) keyboardMap(session types.Session, payload *message.KeyboardMap) error {
 	if !session.IsHost() {
-		return errors.New("is not the host")
+		return ErrIsNotTheHost
+	}
+
+	if err := h.desktop.SetKeyboardMap(payload.KeyboardMap); err != nil {
+		return err
+	}
+
+	session.SetKeyboardMap(payload.KeyboardMap)
+	return nil
+}
+
+// SessionHostChanged applies keyboard map that was set by the new host
+// and lets followers mirror video stream of the new host.
+func (h *MessageHandlerCtx) SessionHostChanged(host types.Session) error {
+	if host == nil {
+		return nil
 	}
 
-	return h.desktop.SetKeyboardMap(payload.KeyboardMap)
+	h.syncFollowers()
+
+	kbd := host.KeyboardMap()
+	if kbd == nil {
+		return nil
+	}
+
+	return h.desktop.SetKeyboardMap(*kbd)
 }
 
 func (h *MessageHandlerCtx) keyboardModifiers(session types.Session, payload *message.KeyboardModifiers) error {
 	if !session.IsHost() {
-		return errors.New("is not the host")
+		return ErrIsNotTheHost
 	}
 
 	h.desktop.SetKeyboardModifiers(payload.KeyboardModifiers)
 	return nil
 }
+
+func (h *MessageHandlerCtx) keyboardReset(session types.Session) error {
+	if !session.IsHost() && !session.Profile().IsAdmin {
+		return ErrIsNotTheHost
+	}
+
+	h.desktop.ResetModifiers()
+	return nil
+}
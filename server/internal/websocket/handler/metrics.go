@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+)
+
+var handlingDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:      "message_handling_seconds",
+	Namespace: "neko",
+	Subsystem: "websocket",
+	Help:      "Time spent handling websocket messages, by event.",
+	Buckets:   []float64{0.0001, 0.0005, 0.001, 0.005, 0.01, 0.05, 0.1, 0.5, 1},
+}, []string{"event", "result"})
+
+// metricsMiddleware measures handling latency of each event
+func metricsMiddleware(event string, next types.WebSocketMessageHandler) types.WebSocketMessageHandler {
+	return func(session types.Session, payload json.RawMessage) error {
+		start := time.Now()
+		err := next(session, payload)
+
+		result := "success"
+		if err != nil {
+			result = "error"
+		}
+
+		handlingDuration.WithLabelValues(event, result).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"strings"
+	"time"
+
+	"github.com/m1k1o/neko/server/pkg/types"
+	"github.com/m1k1o/neko/server/pkg/types/message"
+)
+
+func (h *MessageHandlerCtx) presentationStart(session types.Session, payload *message.PresentationStart) error {
+	if !session.Profile().IsAdmin {
+		return ErrIsNotTheAdmin
+	}
+
+	if len(payload.Targets) == 0 {
+		return ErrNoPresentationTargets
+	}
+
+	for _, id := range payload.Targets {
+		if _, ok := h.sessions.Get(id); !ok {
+			return types.ErrSessionNotFound
+		}
+	}
+
+	h.sessions.SetPresentation(&types.Presentation{
+		Presenter: session.ID(),
+		Targets:   payload.Targets,
+		CreatedAt: time.Now(),
+	})
+
+	h.sessions.Audit(types.AuditEntry{
+		Actor:   session.ID(),
+		Action:  types.AuditPresentationStart,
+		Details: strings.Join(payload.Targets, ","),
+	})
+
+	return nil
+}
+
+func (h *MessageHandlerCtx) presentationStop(session types.Session) error {
+	if !session.Profile().IsAdmin {
+		return ErrIsNotTheAdmin
+	}
+
+	h.sessions.SetPresentation(nil)
+
+	h.sessions.Audit(types.AuditEntry{
+		Actor:  session.ID(),
+		Action: types.AuditPresentationStop,
+	})
+
+	return nil
+}
@@ -1,7 +1,7 @@
 package handler
 
 import (
-	"errors"
+	"slices"
 
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/event"
@@ -10,7 +10,7 @@ import (
 
 func (h *MessageHandlerCtx) screenSet(session types.Session, payload *message.ScreenSize) error {
 	if !session.Profile().IsAdmin {
-		return errors.New("is not the admin")
+		return ErrIsNotTheAdmin
 	}
 
 	size, err := h.desktop.SetScreenSize(payload.ScreenSize)
@@ -18,9 +18,91 @@ func (h *MessageHandlerCtx) screenSet(session types.Session, payload *message.Sc
 		return err
 	}
 
+	h.sessions.Audit(types.AuditEntry{
+		Actor:   session.ID(),
+		Action:  types.AuditScreenSet,
+		Details: size.String(),
+	})
+
 	h.sessions.Broadcast(event.SCREEN_UPDATED, message.ScreenSizeUpdate{
 		ID:         session.ID(),
+		Display:    h.capture.Display(),
+		Region:     h.capture.Region(),
 		ScreenSize: size,
 	})
+
+	h.syncFollowers()
+	return nil
+}
+
+func (h *MessageHandlerCtx) screenDisplaySet(session types.Session, payload *message.ScreenDisplay) error {
+	if !session.Profile().IsAdmin {
+		return ErrIsNotTheAdmin
+	}
+
+	if !slices.Contains(h.capture.Displays(), payload.Display) {
+		return types.ErrCaptureDisplayNotFound
+	}
+
+	// input is routed to the captured display
+	prevDisplay := h.capture.Display()
+	if err := h.desktop.SetDisplay(payload.Display); err != nil {
+		return err
+	}
+
+	if err := h.capture.SetDisplay(payload.Display); err != nil {
+		if err := h.desktop.SetDisplay(prevDisplay); err != nil {
+			h.logger.Err(err).Str("display", prevDisplay).Msg("unable to restore input display")
+		}
+		return err
+	}
+
+	h.sessions.Broadcast(event.SCREEN_UPDATED, message.ScreenSizeUpdate{
+		ID:         session.ID(),
+		Display:    h.capture.Display(),
+		Region:     h.capture.Region(),
+		ScreenSize: h.desktop.GetScreenSize(),
+	})
+
+	h.syncFollowers()
+	return nil
+}
+
+func (h *MessageHandlerCtx) screenRegionSet(session types.Session, payload *message.ScreenRegion) error {
+	if !session.Profile().IsAdmin {
+		return ErrIsNotTheAdmin
+	}
+
+	if err := h.capture.SetRegion(payload.Region); err != nil {
+		return err
+	}
+
+	h.sessions.Broadcast(event.SCREEN_UPDATED, message.ScreenSizeUpdate{
+		ID:         session.ID(),
+		Display:    h.capture.Display(),
+		Region:     h.capture.Region(),
+		ScreenSize: h.desktop.GetScreenSize(),
+	})
+
+	h.syncFollowers()
+	return nil
+}
+
+func (h *MessageHandlerCtx) screenConfigurations(session types.Session) error {
+	if !session.Profile().IsAdmin {
+		return ErrIsNotTheAdmin
+	}
+
+	configurations := h.desktop.ScreenConfigurations()
+	if configurations == nil {
+		configurations = []types.ScreenSize{}
+	}
+
+	session.Send(
+		event.SCREEN_CONFIGURATIONS,
+		message.ScreenConfigurations{
+			Configurations: configurations,
+		})
+
 	return nil
 }
@@ -1,8 +1,6 @@
 package handler
 
 import (
-	"errors"
-
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/event"
 	"github.com/m1k1o/neko/server/pkg/types/message"
@@ -11,7 +9,7 @@ import (
 func (h *MessageHandlerCtx) sendUnicast(session types.Session, payload *message.SendUnicast) error {
 	receiver, ok := h.sessions.Get(payload.Receiver)
 	if !ok {
-		return errors.New("receiver session ID not found")
+		return ErrReceiverNotFound
 	}
 
 	receiver.Send(
@@ -10,9 +10,10 @@ func (h *MessageHandlerCtx) SessionCreated(session types.Session) error {
 	h.sessions.Broadcast(
 		event.SESSION_CREATED,
 		message.SessionData{
-			ID:      session.ID(),
-			Profile: session.Profile(),
-			State:   session.State(),
+			ID:       session.ID(),
+			Profile:  session.Profile(),
+			State:    session.State(),
+			Metadata: session.Metadata(),
 		})
 
 	return nil
@@ -53,10 +54,11 @@ func (h *MessageHandlerCtx) SessionConnected(session types.Session) error {
 }
 
 func (h *MessageHandlerCtx) SessionDisconnected(session types.Session) error {
-	// clear host if exists
+	// clear host if exists, next host starts with clean keyboard state
 	if session.IsHost() {
-		h.desktop.ResetKeys()
-		session.ClearHost()
+		h.desktop.ResetModifiers()
+		h.desktop.GamepadDisconnectAll()
+		h.sessions.ControlReset(session)
 	}
 
 	if session.Profile().IsAdmin {
@@ -99,8 +101,20 @@ func (h *MessageHandlerCtx) SessionStateChanged(session types.Session) error {
 		event.SESSION_STATE,
 		message.SessionState{
 			ID:           session.ID(),
+			Metadata:     session.Metadata(),
 			SessionState: session.State(),
 		})
 
 	return nil
 }
+
+func (h *MessageHandlerCtx) SessionMetadataChanged(session types.Session, new, old types.SessionMetadata) error {
+	h.sessions.Broadcast(
+		event.SESSION_METADATA,
+		message.SessionMetadata{
+			ID:       session.ID(),
+			Metadata: new,
+		})
+
+	return nil
+}
@@ -6,27 +6,44 @@ import (
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/event"
 	"github.com/m1k1o/neko/server/pkg/types/message"
+	"github.com/m1k1o/neko/server/pkg/utils"
 
 	"github.com/pion/webrtc/v3"
 )
 
 func (h *MessageHandlerCtx) signalRequest(session types.Session, payload *message.SignalRequest) error {
 	if !session.Profile().CanWatch {
-		return errors.New("not allowed to watch")
+		return ErrIsNotAllowedToWatch
 	}
 
-	offer, peer, err := h.webrtc.CreatePeer(session)
+	offer, peer, err := h.webrtc.CreatePeer(session, payload.Options)
 	if err != nil {
 		return err
 	}
 
-	// set webrtc as paused if session has private mode enabled
-	if session.PrivateModeEnabled() {
+	// set webrtc as paused if session has private mode enabled or is not presented to
+	if session.StreamPaused() {
 		peer.SetPaused(true)
 	}
 
 	video := payload.Video
 
+	// start with preferred video, if it still exists, estimator takes over from there
+	if video.Selector == nil && payload.PreferredVideo != "" {
+		preferred := types.BaseStreamID(payload.PreferredVideo)
+		if ok, _ := utils.ArrayIn(preferred, h.capture.Video().IDs()); ok {
+			video.Selector = &types.StreamSelector{
+				ID:   preferred,
+				Type: types.StreamSelectorTypeExact,
+			}
+		} else {
+			h.logger.Warn().
+				Str("session_id", session.ID()).
+				Str("video_id", preferred).
+				Msg("preferred video not found, using default")
+		}
+	}
+
 	// use default first video, if not provided
 	if video.Selector == nil {
 		videos := h.capture.Video().IDs()
@@ -37,7 +54,7 @@ func (h *MessageHandlerCtx) signalRequest(session types.Session, payload *messag
 	}
 
 	// TODO: Remove, used for compatibility with old clients.
-	if video.Auto == nil {
+	if video.Auto == nil && session.ProtocolVersion() < types.WebSocketProtocolV2 {
 		video.Auto = &payload.Auto
 	}
 
@@ -61,23 +78,89 @@ func (h *MessageHandlerCtx) signalRequest(session types.Session, payload *messag
 		return err
 	}
 
+	// resume token is issued only if resuming is enabled
+	resumeToken, err := h.sessions.ResumeTokenCreate(session)
+	if err != nil && !errors.Is(err, types.ErrSessionResumeDisabled) {
+		return err
+	}
+
 	session.Send(
 		event.SIGNAL_PROVIDE,
 		message.SignalProvide{
 			SDP:        offer.SDP,
-			ICEServers: h.webrtc.ICEServers(),
+			ICEServers: payload.Options.ICECredentials.Apply(h.webrtc.ICEServersFor(session, session.RemoteAddr())),
 
-			Video: peer.Video(),
+			Video: peer.Video().ForProtocol(session.ProtocolVersion()),
 			Audio: peer.Audio(),
+
+			DataKey:     peer.DataKey(),
+			ResumeToken: resumeToken,
 		})
 
 	return nil
 }
 
+// SignalRekey replaces current peer with a new one, because DTLS cannot be renegotiated
+// and only a new handshake derives fresh SRTP keys. Video and audio settings are kept.
+func (h *MessageHandlerCtx) SignalRekey(session types.Session, peer types.WebRTCPeer, options types.PeerOptions) error {
+	// peer was already replaced or destroyed
+	if session.GetWebRTCPeer() != peer {
+		return nil
+	}
+
+	video := peer.Video()
+	audio := peer.Audio()
+
+	request := &message.SignalRequest{
+		Video: types.PeerVideoRequest{
+			Disabled: &video.Disabled,
+			Auto:     &video.Auto,
+			MaxFPS:   &video.MaxFPS,
+			Viewport: video.Viewport,
+
+			MaxResolution: video.MaxResolution,
+			FollowHost:    &video.FollowHost,
+			ForceEncoder:  &video.ForceEncoder,
+		},
+		Audio: types.PeerAudioRequest{
+			Disabled:   &audio.Disabled,
+			Gain:       &audio.Gain,
+			SyncOffset: &audio.SyncOffset,
+		},
+		Auto:    video.Auto,
+		Options: options,
+	}
+
+	// zero scale means it was never set
+	if video.ScaleResolutionDownBy >= 1 {
+		request.Video.ScaleResolutionDownBy = &video.ScaleResolutionDownBy
+	}
+
+	if video.ID != "" {
+		request.Video.Selector = &types.StreamSelector{
+			ID:   video.ID,
+			Type: types.StreamSelectorTypeExact,
+		}
+	}
+
+	return h.signalRequest(session, request)
+}
+
+// signalSync re-sends current video and audio state, e.g. after client UI reload
+func (h *MessageHandlerCtx) signalSync(session types.Session) error {
+	peer := session.GetWebRTCPeer()
+	if peer == nil {
+		return ErrPeerNotFound
+	}
+
+	peer.SendSignalState()
+	return nil
+}
+
 func (h *MessageHandlerCtx) signalRestart(session types.Session) error {
 	peer := session.GetWebRTCPeer()
 	if peer == nil {
-		return errors.New("webRTC peer does not exist")
+		return ErrPeerNotFound
 	}
 
 	offer, err := peer.CreateOffer(true)
@@ -95,10 +178,19 @@ func (h *MessageHandlerCtx) signalRestart(session types.Session) error {
 	return nil
 }
 
+// signalResume reattaches reconnected client to its existing peer using ICE restart
+func (h *MessageHandlerCtx) signalResume(session types.Session, payload *message.SignalResume) error {
+	if !h.sessions.ResumeTokenVerify(session, payload.Token) {
+		return ErrInvalidResumeToken
+	}
+
+	return h.signalRestart(session)
+}
+
 func (h *MessageHandlerCtx) signalOffer(session types.Session, payload *message.SignalDescription) error {
 	peer := session.GetWebRTCPeer()
 	if peer == nil {
-		return errors.New("webRTC peer does not exist")
+		return ErrPeerNotFound
 	}
 
 	err := peer.SetRemoteDescription(webrtc.SessionDescription{
@@ -126,7 +218,7 @@ func (h *MessageHandlerCtx) signalOffer(session types.Session, payload *message.
 func (h *MessageHandlerCtx) signalAnswer(session types.Session, payload *message.SignalDescription) error {
 	peer := session.GetWebRTCPeer()
 	if peer == nil {
-		return errors.New("webRTC peer does not exist")
+		return ErrPeerNotFound
 	}
 
 	return peer.SetRemoteDescription(webrtc.SessionDescription{
@@ -138,7 +230,7 @@ func (h *MessageHandlerCtx) signalAnswer(session types.Session, payload *message
 func (h *MessageHandlerCtx) signalCandidate(session types.Session, payload *message.SignalCandidate) error {
 	peer := session.GetWebRTCPeer()
 	if peer == nil {
-		return errors.New("webRTC peer does not exist")
+		return ErrPeerNotFound
 	}
 
 	return peer.SetCandidate(payload.ICECandidateInit)
@@ -147,16 +239,35 @@ func (h *MessageHandlerCtx) signalCandidate(session types.Session, payload *mess
 func (h *MessageHandlerCtx) signalVideo(session types.Session, payload *message.SignalVideo) error {
 	peer := session.GetWebRTCPeer()
 	if peer == nil {
-		return errors.New("webRTC peer does not exist")
+		return ErrPeerNotFound
 	}
 
-	return peer.SetVideo(payload.PeerVideoRequest)
+	// follower starts with the stream that the host is watching
+	if payload.FollowHost != nil && *payload.FollowHost && payload.Selector == nil && !session.IsHost() {
+		if id, ok := h.hostVideoID(); ok {
+			payload.Selector = &types.StreamSelector{
+				ID:   id,
+				Type: types.StreamSelectorTypeExact,
+			}
+		}
+	}
+
+	if err := peer.SetVideo(payload.PeerVideoRequest); err != nil {
+		return err
+	}
+
+	// followers mirror streams selected by the host
+	if session.IsHost() && payload.Selector != nil {
+		h.syncFollowers()
+	}
+
+	return nil
 }
 
 func (h *MessageHandlerCtx) signalAudio(session types.Session, payload *message.SignalAudio) error {
 	peer := session.GetWebRTCPeer()
 	if peer == nil {
-		return errors.New("webRTC peer does not exist")
+		return ErrPeerNotFound
 	}
 
 	return peer.SetAudio(payload.PeerAudioRequest)
@@ -26,9 +26,10 @@ func (h *MessageHandlerCtx) systemInit(session types.Session) error {
 	for _, session := range h.sessions.List() {
 		sessionId := session.ID()
 		sessions[sessionId] = message.SessionData{
-			ID:      sessionId,
-			Profile: session.Profile(),
-			State:   session.State(),
+			ID:       sessionId,
+			Profile:  session.Profile(),
+			State:    session.State(),
+			Metadata: session.Metadata(),
 		}
 	}
 
@@ -38,6 +39,9 @@ func (h *MessageHandlerCtx) systemInit(session types.Session) error {
 			SessionId:         session.ID(),
 			ControlHost:       controlHost,
 			ScreenSize:        h.desktop.GetScreenSize(),
+			ScreenDPI:         h.desktop.GetScreenDPI(),
+			Display:           h.capture.Display(),
+			ScreenRegion:      h.capture.Region(),
 			Sessions:          sessions,
 			Settings:          h.sessions.Settings(),
 			TouchEvents:       h.desktop.HasTouchSupport(),
@@ -45,6 +49,9 @@ func (h *MessageHandlerCtx) systemInit(session types.Session) error {
 			WebRTC: message.SystemWebRTC{
 				Videos: h.capture.Video().IDs(),
 			},
+			Draining:     h.sessions.Draining(),
+			Banner:       h.sessions.Banner(),
+			Presentation: h.sessions.Presentation(),
 		})
 
 	return nil
@@ -67,10 +74,14 @@ func (h *MessageHandlerCtx) systemAdmin(session types.Session) error {
 		event.SYSTEM_ADMIN,
 		message.SystemAdmin{
 			ScreenSizesList: list, // TODO: remove
+			DisplaysList:    h.capture.Displays(),
 			BroadcastStatus: message.BroadcastStatus{
 				IsActive: broadcast.Started(),
 				URL:      broadcast.Url(),
 			},
+			ForwardStatus: message.ForwardStatus{
+				RTPForwardStatus: h.webrtc.ForwardStatus(),
+			},
 		})
 
 	return nil
@@ -3,6 +3,7 @@ package websocket
 import (
 	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"sync"
 	"time"
@@ -11,6 +12,7 @@ import (
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"github.com/m1k1o/neko/server/internal/config"
 	"github.com/m1k1o/neko/server/internal/websocket/handler"
 	"github.com/m1k1o/neko/server/pkg/types"
 	"github.com/m1k1o/neko/server/pkg/types/event"
@@ -27,6 +29,8 @@ const inactiveCursorsPeriod = 750 * time.Millisecond
 // maximum payload length for logging
 const maxPayloadLogLength = 10_000
 
+var errMessageTooLarge = errors.New("message too large")
+
 // events that are not logged in debug mode
 var nologEvents = []string{
 	// don't log twice
@@ -43,11 +47,13 @@ func New(
 	desktop types.DesktopManager,
 	capture types.CaptureManager,
 	webrtc types.WebRTCManager,
+	config *config.Session,
 ) *WebSocketManagerCtx {
 	logger := log.With().Str("module", "websocket").Logger()
 
 	return &WebSocketManagerCtx{
 		logger:   logger,
+		config:   config,
 		shutdown: make(chan struct{}),
 		sessions: sessions,
 		desktop:  desktop,
@@ -58,6 +64,7 @@ func New(
 
 type WebSocketManagerCtx struct {
 	logger   zerolog.Logger
+	config   *config.Session
 	wg       sync.WaitGroup
 	shutdown chan struct{}
 	sessions types.SessionManager
@@ -106,6 +113,15 @@ func (manager *WebSocketManagerCtx) Start() {
 			Msg("session profile changed")
 	})
 
+	manager.sessions.OnMetadataChanged(func(session types.Session, new, old types.SessionMetadata) {
+		err := manager.handler.SessionMetadataChanged(session, new, old)
+		manager.logger.Err(err).
+			Str("session_id", session.ID()).
+			Interface("new", new).
+			Interface("old", old).
+			Msg("session metadata changed")
+	})
+
 	manager.sessions.OnStateChanged(func(session types.Session) {
 		err := manager.handler.SessionStateChanged(session)
 		manager.logger.Err(err).
@@ -125,6 +141,10 @@ func (manager *WebSocketManagerCtx) Start() {
 
 		manager.sessions.Broadcast(event.CONTROL_HOST, payload)
 
+		if err := manager.handler.SessionHostChanged(host); err != nil {
+			manager.logger.Err(err).Msg("could not apply keyboard map of new host")
+		}
+
 		manager.logger.Info().
 			Str("session_id", session.ID()).
 			Bool("has_host", payload.HasHost).
@@ -155,24 +175,64 @@ func (manager *WebSocketManagerCtx) Start() {
 			Msg("settings changed")
 	})
 
+	manager.sessions.OnDrainingChanged(func(draining bool) {
+		manager.sessions.Broadcast(event.SYSTEM_DRAINING, message.SystemDraining{
+			Draining: draining,
+		})
+	})
+
+	manager.sessions.OnBannerChanged(func(banner *types.Banner) {
+		manager.sessions.Broadcast(event.SYSTEM_BANNER, message.SystemBanner{
+			Banner: banner,
+		})
+	})
+
+	manager.sessions.OnPresentationChanged(func(presentation *types.Presentation) {
+		manager.sessions.Broadcast(event.PRESENTATION_STATUS, message.PresentationStatus{
+			Presentation: presentation,
+		})
+	})
+
+	manager.desktop.OnScreenDPIChange(func(dpi types.ScreenDPI) {
+		manager.sessions.Broadcast(event.SCREEN_DPI_UPDATED, message.ScreenDPI{
+			ScreenDPI: dpi,
+		})
+	})
+
 	manager.desktop.OnClipboardUpdated(func() {
 		host, hasHost := manager.sessions.GetHost()
-		if !hasHost || !host.Profile().CanAccessClipboard {
+		if !hasHost || !host.Profile().CanReadClipboard() {
 			return
 		}
 
 		manager.logger.Info().Msg("sync clipboard")
 
 		data, err := manager.desktop.ClipboardGetText()
+		if errors.Is(err, types.ErrClipboardTooLarge) {
+			manager.logger.Warn().Msg("clipboard content exceeds size limit, not synced")
+			return
+		}
 		if err != nil {
 			manager.logger.Err(err).Msg("could not get clipboard content")
 			return
 		}
 
+		// client can receive clipboard over the data channel, where it yields to input
+		if webrtcPeer := host.GetWebRTCPeer(); webrtcPeer != nil {
+			ok, err := webrtcPeer.SendClipboard(*data)
+			if err != nil {
+				manager.logger.Warn().Err(err).Msg("could not send clipboard over data channel, sending over websocket")
+			}
+			if ok {
+				return
+			}
+		}
+
 		host.Send(
 			event.CLIPBOARD_UPDATED,
 			message.ClipboardData{
-				Text: data.Text,
+				Text:      data.Text,
+				Truncated: data.Truncated,
 				// TODO: Send HTML?
 			})
 	})
@@ -200,10 +260,16 @@ func (manager *WebSocketManagerCtx) AddHandler(handler types.WebSocketHandler) {
 	manager.handlers = append(manager.handlers, handler)
 }
 
+// AddMiddleware wraps handlers of built-in events, custom handlers are not affected.
+func (manager *WebSocketManagerCtx) AddMiddleware(middleware types.WebSocketMiddleware) {
+	manager.handler.Use(middleware)
+}
+
 func (manager *WebSocketManagerCtx) Upgrade(checkOrigin types.CheckOrigin) types.RouterHandler {
 	return func(w http.ResponseWriter, r *http.Request) error {
 		upgrader := websocket.Upgrader{
-			CheckOrigin: checkOrigin,
+			CheckOrigin:  checkOrigin,
+			Subprotocols: types.WebSocketSubprotocols,
 			// Do not return any error while handshake
 			Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {},
 		}
@@ -223,7 +289,7 @@ func (manager *WebSocketManagerCtx) connect(connection *websocket.Conn, r *http.
 	session, err := manager.sessions.Authenticate(r)
 	if err != nil {
 		manager.logger.Warn().Err(err).Msg("authentication failed")
-		newPeer(manager.logger, connection).Destroy(err.Error())
+		newPeer(manager.logger, connection, r.RemoteAddr).Destroy(err.Error())
 		return
 	}
 
@@ -231,7 +297,7 @@ func (manager *WebSocketManagerCtx) connect(connection *websocket.Conn, r *http.
 	logger := manager.logger.With().Str("session_id", session.ID()).Logger()
 
 	// create new peer
-	peer := newPeer(logger, connection)
+	peer := newPeer(logger, connection, r.RemoteAddr)
 
 	if !session.Profile().CanConnect {
 		logger.Warn().Msg("connection disabled")
@@ -239,6 +305,13 @@ func (manager *WebSocketManagerCtx) connect(connection *websocket.Conn, r *http.
 		return
 	}
 
+	// sessions that were already connected can reconnect and finish
+	if state := session.State(); manager.sessions.Draining() && state.ConnectedSince == nil && state.NotConnectedSince == nil {
+		logger.Warn().Msg("server is draining")
+		peer.Destroy(types.ErrSessionDraining.Error())
+		return
+	}
+
 	if session.State().IsConnected {
 		logger.Warn().Msg("already connected")
 
@@ -250,11 +323,15 @@ func (manager *WebSocketManagerCtx) connect(connection *websocket.Conn, r *http.
 		logger.Info().Msg("replacing peer connection")
 	}
 
+	version := types.WebSocketProtocolVersion(connection.Subprotocol())
+
 	logger.Info().
 		Str("address", connection.RemoteAddr().String()).
 		Str("agent", r.UserAgent()).
+		Int("protocol_version", version).
 		Msg("connection started")
 
+	session.SetProtocolVersion(version)
 	session.ConnectWebSocketPeer(peer)
 
 	// this is a blocking function that lives
@@ -272,6 +349,14 @@ func (manager *WebSocketManagerCtx) connect(connection *websocket.Conn, r *http.
 		return
 	}
 
+	// offender is not expected to reconnect
+	if errors.Is(err, errMessageTooLarge) {
+		logger.Warn().Int("max_size", manager.config.MaxMessageSize).Msg("oversize message, disconnecting")
+		peer.Destroy(err.Error())
+		session.DisconnectWebSocketPeer(peer, false)
+		return
+	}
+
 	delayedDisconnect := false
 
 	e, ok := err.(*websocket.CloseError)
@@ -305,6 +390,7 @@ func (manager *WebSocketManagerCtx) handle(connection *websocket.Conn, peer type
 
 	bytes := make(chan []byte)
 	cancel := make(chan error)
+	rejected := make(chan struct{})
 
 	ticker := time.NewTicker(pingPeriod)
 	defer ticker.Stop()
@@ -314,7 +400,14 @@ func (manager *WebSocketManagerCtx) handle(connection *websocket.Conn, peer type
 		defer manager.wg.Done()
 
 		for {
-			_, raw, err := connection.ReadMessage()
+			raw, err := manager.readMessage(connection)
+			if errors.Is(err, errMessageTooLarge) {
+				oversizeMessages.Inc()
+				if !manager.config.OversizeDisconnect {
+					rejected <- struct{}{}
+					continue
+				}
+			}
 			if err != nil {
 				cancel <- err
 				break
@@ -359,6 +452,12 @@ func (manager *WebSocketManagerCtx) handle(connection *websocket.Conn, peer type
 			if !handled {
 				logger.Warn().Str("event", data.Event).Msg("unhandled message")
 			}
+		case <-rejected:
+			logger.Warn().Int("max_size", manager.config.MaxMessageSize).Msg("oversize message rejected")
+			session.Send(event.SYSTEM_ERROR, message.SystemError{
+				Code:    handler.ErrorCodeBadRequest,
+				Message: errMessageTooLarge.Error(),
+			})
 		case err := <-cancel:
 			return err
 		case <-manager.shutdown:
@@ -372,6 +471,31 @@ func (manager *WebSocketManagerCtx) handle(connection *websocket.Conn, peer type
 	}
 }
 
+// readMessage reads next message from the connection, message over maximum
+// size is not buffered, its remainder is discarded by the next read
+func (manager *WebSocketManagerCtx) readMessage(connection *websocket.Conn) ([]byte, error) {
+	_, reader, err := connection.NextReader()
+	if err != nil {
+		return nil, err
+	}
+
+	limit := manager.config.MaxMessageSize
+	if limit <= 0 {
+		return io.ReadAll(reader)
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(reader, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(raw) > limit {
+		return nil, errMessageTooLarge
+	}
+
+	return raw, nil
+}
+
 func (manager *WebSocketManagerCtx) startInactiveCursors() {
 	if manager.shutdownInactiveCursors != nil {
 		manager.logger.Warn().Msg("inactive cursors handler already running")
@@ -0,0 +1,13 @@
+package websocket
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var oversizeMessages = promauto.NewCounter(prometheus.CounterOpts{
+	Name:      "oversize_messages_total",
+	Namespace: "neko",
+	Subsystem: "websocket",
+	Help:      "Count of received websocket messages rejected for exceeding maximum size.",
+})
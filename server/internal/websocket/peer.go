@@ -18,12 +18,14 @@ type WebSocketPeerCtx struct {
 	mu         sync.Mutex
 	logger     zerolog.Logger
 	connection *websocket.Conn
+	remoteAddr string
 }
 
-func newPeer(logger zerolog.Logger, connection *websocket.Conn) *WebSocketPeerCtx {
+func newPeer(logger zerolog.Logger, connection *websocket.Conn, remoteAddr string) *WebSocketPeerCtx {
 	return &WebSocketPeerCtx{
 		logger:     logger.With().Str("submodule", "peer").Logger(),
 		connection: connection,
+		remoteAddr: remoteAddr,
 	}
 }
 
@@ -64,6 +66,10 @@ func (peer *WebSocketPeerCtx) Send(event string, payload any) {
 	}
 }
 
+func (peer *WebSocketPeerCtx) RemoteAddr() string {
+	return peer.remoteAddr
+}
+
 func (peer *WebSocketPeerCtx) Ping() error {
 	peer.mu.Lock()
 	defer peer.mu.Unlock()
@@ -80,6 +80,24 @@ func CanAccessClipboardOnly(w http.ResponseWriter, r *http.Request) (context.Con
 	return nil, nil
 }
 
+func CanReadClipboardOnly(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	session, ok := GetSession(r)
+	if !ok || !session.Profile().CanReadClipboard() {
+		return nil, utils.HttpForbidden("session cannot read clipboard")
+	}
+
+	return nil, nil
+}
+
+func CanWriteClipboardOnly(w http.ResponseWriter, r *http.Request) (context.Context, error) {
+	session, ok := GetSession(r)
+	if !ok || !session.Profile().CanWriteClipboard() {
+		return nil, utils.HttpForbidden("session cannot write clipboard")
+	}
+
+	return nil, nil
+}
+
 func PluginsGenericOnly[V comparable](key string, exp V) func(w http.ResponseWriter, r *http.Request) (context.Context, error) {
 	return func(w http.ResponseWriter, r *http.Request) (context.Context, error) {
 		session, ok := GetSession(r)
@@ -9,6 +9,29 @@ static void gstreamer_pipeline_log(GstPipelineCtx *ctx, char* level, const char*
   goPipelineLog(ctx->pipelineId, level, buffer);
 }
 
+static void gstreamer_pipeline_level(GstPipelineCtx *ctx, const GstStructure *s) {
+  gdouble rms = 0;
+  gboolean found = FALSE;
+
+  G_GNUC_BEGIN_IGNORE_DEPRECATIONS
+  const GValue *value = gst_structure_get_value(s, "rms");
+  GValueArray *channels = (GValueArray *) g_value_get_boxed(value);
+
+  // loudest channel is reported
+  for (guint i = 0; channels != NULL && i < channels->n_values; i++) {
+    gdouble channel = g_value_get_double(g_value_array_get_nth(channels, i));
+    if (!found || channel > rms) {
+      rms = channel;
+    }
+    found = TRUE;
+  }
+  G_GNUC_END_IGNORE_DEPRECATIONS
+
+  if (found) {
+    goHandlePipelineLevel(ctx->pipelineId, rms);
+  }
+}
+
 static gboolean gstreamer_bus_call(GstBus *bus, GstMessage *msg, gpointer user_data) {
   GstPipelineCtx *ctx = (GstPipelineCtx *)user_data;
 
@@ -59,6 +82,15 @@ static gboolean gstreamer_bus_call(GstBus *bus, GstMessage *msg, gpointer user_d
       break;
     }
 
+    case GST_MESSAGE_ELEMENT: {
+      const GstStructure *s = gst_message_get_structure(msg);
+
+      if (s != NULL && gst_structure_has_name(s, "level")) {
+        gstreamer_pipeline_level(ctx, s);
+      }
+      break;
+    }
+
     default:
       gstreamer_pipeline_log(ctx, "trace", "unknown message");
       break;
@@ -48,6 +48,8 @@ type Pipeline interface {
 	SetCapsResolution(binName string, width, height int) bool
 	// emit video keyframe
 	EmitVideoKeyframe() bool
+	// latest rms level in dB posted by level element, along with the time it was posted
+	Level() (float64, time.Time)
 }
 
 type pipeline struct {
@@ -56,6 +58,10 @@ type pipeline struct {
 	src    string
 	ctx    *C.GstPipelineCtx
 	sample chan types.Sample
+
+	level   float64
+	levelAt time.Time
+	levelMu sync.Mutex
 }
 
 func CreatePipeline(pipelineStr string) (Pipeline, error) {
@@ -184,6 +190,13 @@ func (p *pipeline) EmitVideoKeyframe() bool {
 	return ok == C.TRUE
 }
 
+func (p *pipeline) Level() (float64, time.Time) {
+	p.levelMu.Lock()
+	defer p.levelMu.Unlock()
+
+	return p.level, p.levelAt
+}
+
 // gst-inspect-1.0
 func CheckPlugins(plugins []string) error {
 	var plugin *C.GstPlugin
@@ -224,6 +237,22 @@ func goHandlePipelineBuffer(pipelineID C.int, buf C.gpointer, bufLen C.int, dura
 	}
 }
 
+//export goHandlePipelineLevel
+func goHandlePipelineLevel(pipelineID C.int, rms C.gdouble) {
+	pipelinesLock.Lock()
+	pipeline, ok := pipelines[int(pipelineID)]
+	pipelinesLock.Unlock()
+
+	if !ok {
+		return
+	}
+
+	pipeline.levelMu.Lock()
+	pipeline.level = float64(rms)
+	pipeline.levelAt = time.Now()
+	pipeline.levelMu.Unlock()
+}
+
 //export goPipelineLog
 func goPipelineLog(pipelineID C.int, levelUnsafe *C.char, msgUnsafe *C.char) {
 	levelStr := C.GoString(levelUnsafe)
@@ -24,6 +24,7 @@ typedef struct GstPipelineCtx {
 } GstPipelineCtx;
 
 extern void goHandlePipelineBuffer(int pipelineId, void *buffer, int bufferLen, guint64 duration, gboolean deltaUnit);
+extern void goHandlePipelineLevel(int pipelineId, gdouble rms);
 extern void goPipelineLog(int pipelineId, char *level, char *msg);
 
 GstPipelineCtx *gstreamer_pipeline_create(char *pipelineStr, int pipelineId, GError **error);
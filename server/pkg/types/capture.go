@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"image"
 	"math"
 	"strings"
 	"time"
@@ -15,8 +16,52 @@ import (
 
 var (
 	ErrCapturePipelineAlreadyExists = errors.New("capture pipeline already exists")
+	ErrCaptureDisplayNotFound       = errors.New("capture display not found")
+	ErrCaptureAudioGainOutOfRange   = errors.New("capture audio gain out of range")
+	ErrCaptureAudioGainUnsupported  = errors.New("capture audio gain is not supported with custom pipeline")
+	ErrCaptureAudioEncUnsupported   = errors.New("capture audio encoding is not supported with custom pipeline")
+	ErrCaptureHwEncoderExhausted    = errors.New("capture hardware encoder sessions exhausted")
+	ErrCaptureRegionInvalid         = errors.New("capture region is outside of the screen")
+	ErrCaptureRegionUnsupported     = errors.New("capture region is not supported by custom video pipelines")
+	ErrCaptureVideoScaleOutOfRange  = errors.New("capture video scale out of range")
+	ErrCaptureVideoScaleUnsupported = errors.New("capture video scale is not supported with custom pipeline")
+	ErrCaptureSinkReleased          = errors.New("capture sink was released")
 )
 
+// maximum factor the video resolution can be scaled down by
+const VideoScaleMax = 8.0
+
+// ScaledStreamID returns ID of video stream scaled down by given factor.
+func ScaledStreamID(id string, factor float64) string {
+	return fmt.Sprintf("%s@%.2f", id, factor)
+}
+
+// BaseStreamID returns ID of the configured video stream, the scaled one is derived from.
+func BaseStreamID(id string) string {
+	base, _, _ := strings.Cut(id, "@")
+	return base
+}
+
+// allowed range of audio gain in dB
+const (
+	AudioGainMin = -60.0
+	AudioGainMax = 20.0
+)
+
+// RoundAudioGain rounds gain to tenths of dB, so that similar gains share the same pipeline.
+func RoundAudioGain(gain float64) float64 {
+	return math.Round(gain*10) / 10
+}
+
+// AudioEncoding are opus encoder settings of audio stream, other codecs ignore them.
+type AudioEncoding struct {
+	InbandFEC bool
+	DTX       bool
+}
+
+// DefaultAudioEncoding matches encoder of the default audio pipeline.
+var DefaultAudioEncoding = AudioEncoding{InbandFEC: true}
+
 type Sample struct {
 	// timing information
 	Timestamp time.Time
@@ -101,6 +146,20 @@ type StreamSelector struct {
 	ID string `json:"id"`
 	// select stream by its bitrate
 	Bitrate uint64 `json:"bitrate"`
+	// selected stream must not exceed this resolution, lower stream is selected instead
+	MaxResolution *StreamResolution `json:"max_resolution,omitempty"`
+}
+
+type StreamResolution struct {
+	// zero means no limit
+	Width int `json:"width"`
+	// zero means no limit
+	Height int `json:"height"`
+}
+
+// Fits reports, whether given size is within the resolution, unknown size always fits
+func (r StreamResolution) Fits(width, height int) bool {
+	return (r.Width <= 0 || width <= r.Width) && (r.Height <= 0 || height <= r.Height)
 }
 
 type StreamSelectorManager interface {
@@ -110,10 +169,27 @@ type StreamSelectorManager interface {
 	GetStream(selector StreamSelector) (StreamSinkManager, bool)
 }
 
+type EncoderBackend string
+
+const (
+	EncoderBackendSoftware EncoderBackend = "software"
+	EncoderBackendHardware EncoderBackend = "hardware"
+)
+
 type StreamSinkManager interface {
 	ID() string
 	Codec() codec.RTPCodec
+	// backend of the running pipeline, empty if not running
+	Encoder() EncoderBackend
 	Bitrate() uint64
+	Fps() float64
+	Size() (width int, height int)
+	// whether the video is mirrored horizontally
+	Flipped() bool
+	// whether the pipeline is configured to use hardware encoder
+	HwEncoder() bool
+	// rms audio level in dB, false if it is not measured
+	Level() (float64, bool)
 
 	AddListener(listener SampleListener) error
 	RemoveListener(listener SampleListener) error
@@ -143,10 +219,32 @@ type CaptureManager interface {
 	Broadcast() BroadcastManager
 	Screencast() ScreencastManager
 	Audio() StreamSinkManager
+	AudioGain(gain float64, encoding AudioEncoding) (StreamSinkManager, error)
 	Video() StreamSelectorManager
+	VideoScaled(videoID string, factor float64) (StreamSinkManager, error)
 
 	Webcam() StreamSrcManager
 	Microphone() StreamSrcManager
+
+	Displays() []string
+	Display() string
+	SetDisplay(display string) error
+
+	Region() *CaptureRegion
+	SetRegion(region *CaptureRegion) error
+
+	Snapshot(videoID string, quality int) ([]byte, error)
+
+	AddScreenListener(listener ScreenListener)
+	RemoveScreenListener(listener ScreenListener)
+}
+
+// ScreenListener is notified by the screen change detector of capture manager,
+// after every comparison of the screen with the previous one.
+type ScreenListener interface {
+	// img is the current screenshot, static is how long the screen did not
+	// change, it is zero when it changed since the previous comparison
+	ScreenCompared(img *image.RGBA, static time.Duration)
 }
 
 type VideoConfig struct {
@@ -160,6 +258,76 @@ type VideoConfig struct {
 	GstSuffix   string            `mapstructure:"gst_suffix"`   // pipeline suffix, starts with !
 	GstPipeline string            `mapstructure:"gst_pipeline"` // whole pipeline as a string
 	ShowPointer bool              `mapstructure:"show_pointer"` // show pointer in the video
+	HwEncoder   bool              `mapstructure:"hw_encoder"`   // pipeline uses a hardware encoder session
+	Flip        bool              `mapstructure:"flip"`         // mirror the video horizontally
+	Fallback    *VideoConfig      `mapstructure:"fallback"`     // software pipeline, used when hardware sessions are exhausted
+}
+
+// GetFps returns configured framerate, or screen rate if not set
+func (config *VideoConfig) GetFps(screen ScreenSize) (float64, error) {
+	if config.Fps == "" {
+		return float64(screen.Rate), nil
+	}
+
+	values := map[string]any{
+		"width":  screen.Width,
+		"height": screen.Height,
+		"fps":    screen.Rate,
+	}
+
+	language := []gval.Language{
+		gval.Function("round", func(args ...any) (any, error) {
+			return (int)(math.Round(args[0].(float64))), nil
+		}),
+	}
+
+	eval, err := gval.Full(language...).NewEvaluable(config.Fps)
+	if err != nil {
+		return 0, err
+	}
+
+	return eval.EvalFloat64(context.Background(), values)
+}
+
+// GetSize returns configured output resolution, or zero if not scaled
+func (config *VideoConfig) GetSize(screen ScreenSize) (int, int, error) {
+	if config.Width == "" || config.Height == "" {
+		return 0, 0, nil
+	}
+
+	values := map[string]any{
+		"width":  screen.Width,
+		"height": screen.Height,
+		"fps":    screen.Rate,
+	}
+
+	language := []gval.Language{
+		gval.Function("round", func(args ...any) (any, error) {
+			return (int)(math.Round(args[0].(float64))), nil
+		}),
+	}
+
+	eval, err := gval.Full(language...).NewEvaluable(config.Width)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	w, err := eval.EvalInt(context.Background(), values)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	eval, err = gval.Full(language...).NewEvaluable(config.Height)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	h, err := eval.EvalInt(context.Background(), values)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return w, h, nil
 }
 
 func (config *VideoConfig) GetPipeline(screen ScreenSize) (string, error) {
@@ -178,12 +346,7 @@ func (config *VideoConfig) GetPipeline(screen ScreenSize) (string, error) {
 	// get fps pipeline
 	fpsPipeline := "! video/x-raw ! videoconvert ! queue"
 	if config.Fps != "" {
-		eval, err := gval.Full(language...).NewEvaluable(config.Fps)
-		if err != nil {
-			return "", err
-		}
-
-		val, err := eval.EvalFloat64(context.Background(), values)
+		val, err := config.GetFps(screen)
 		if err != nil {
 			return "", err
 		}
@@ -194,22 +357,7 @@ func (config *VideoConfig) GetPipeline(screen ScreenSize) (string, error) {
 	// get scale pipeline
 	scalePipeline := ""
 	if config.Width != "" && config.Height != "" {
-		eval, err := gval.Full(language...).NewEvaluable(config.Width)
-		if err != nil {
-			return "", err
-		}
-
-		w, err := eval.EvalInt(context.Background(), values)
-		if err != nil {
-			return "", err
-		}
-
-		eval, err = gval.Full(language...).NewEvaluable(config.Height)
-		if err != nil {
-			return "", err
-		}
-
-		h, err := eval.EvalInt(context.Background(), values)
+		w, h, err := config.GetSize(screen)
 		if err != nil {
 			return "", err
 		}
@@ -218,6 +366,11 @@ func (config *VideoConfig) GetPipeline(screen ScreenSize) (string, error) {
 		scalePipeline = fmt.Sprintf("! videoscale method=0 ! capsfilter caps=video/x-raw,width=%d,height=%d name=resolution ! queue", w, h)
 	}
 
+	// get flip pipeline
+	if config.Flip {
+		scalePipeline += " ! videoflip method=horizontal-flip"
+	}
+
 	// get encoder pipeline
 	encPipeline := fmt.Sprintf("! %s name=encoder", config.GstEncoder)
 	for key, expr := range config.GstParams {
@@ -0,0 +1,65 @@
+package codec
+
+import (
+	"fmt"
+	"slices"
+)
+
+// https://gstreamer.freedesktop.org/documentation/opus/opusenc.html
+var opusSampleRates = []int{8000, 12000, 16000, 24000, 48000}
+
+// AudioFormat returns the codec adjusted for the given capture sample rate and
+// channel count, zero values keep the defaults. Combinations that the codec
+// cannot carry over RTP are rejected.
+func (codec RTPCodec) AudioFormat(sampleRate, channels int) (RTPCodec, error) {
+	if !codec.IsAudio() {
+		return codec, fmt.Errorf("%s is not an audio codec", codec.Name)
+	}
+
+	if sampleRate < 0 || channels < 0 {
+		return codec, fmt.Errorf("sample rate and channels must not be negative")
+	}
+
+	switch codec.Name {
+	case Opus().Name:
+		if sampleRate != 0 && !slices.Contains(opusSampleRates, sampleRate) {
+			return codec, fmt.Errorf("opus supports sample rates %v, got %d", opusSampleRates, sampleRate)
+		}
+		if channels > 2 {
+			return codec, fmt.Errorf("opus supports 1 or 2 channels, got %d", channels)
+		}
+
+		// rtp clock rate and channels of opus are always 48000/2 (RFC 7587),
+		// actual format is only signaled using format parameters
+		fmtp := "useinbandfec=1"
+		switch channels {
+		case 0:
+			fmtp += ";stereo=1"
+		case 1:
+			fmtp += ";stereo=0;sprop-stereo=0"
+		case 2:
+			fmtp += ";stereo=1;sprop-stereo=1"
+		}
+		if sampleRate != 0 && sampleRate != 48000 {
+			fmtp += fmt.Sprintf(";sprop-maxcapturerate=%d", sampleRate)
+		}
+		codec.Capability.SDPFmtpLine = fmtp
+	case G722().Name:
+		// g722 uses 8000 rtp clock rate for historical reasons, but samples at 16000 (RFC 3551)
+		if sampleRate != 0 && sampleRate != 16000 {
+			return codec, fmt.Errorf("g722 supports only 16000 sample rate, got %d", sampleRate)
+		}
+		if channels > 1 {
+			return codec, fmt.Errorf("g722 supports only 1 channel, got %d", channels)
+		}
+	case PCMU().Name, PCMA().Name:
+		if sampleRate != 0 && sampleRate != 8000 {
+			return codec, fmt.Errorf("%s supports only 8000 sample rate, got %d", codec.Name, sampleRate)
+		}
+		if channels > 1 {
+			return codec, fmt.Errorf("%s supports only 1 channel, got %d", codec.Name, channels)
+		}
+	}
+
+	return codec, nil
+}
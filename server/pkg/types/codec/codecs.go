@@ -65,6 +65,21 @@ func (codec *RTPCodec) Register(engine *webrtc.MediaEngine) error {
 	}, codec.Type)
 }
 
+// WithoutNACK returns copy of the codec that does not offer nack feedback,
+// so that the receiver does not request retransmissions that never come.
+func (codec *RTPCodec) WithoutNACK() RTPCodec {
+	feedback := []webrtc.RTCPFeedback{}
+	for _, fb := range codec.Capability.RTCPFeedback {
+		if fb.Type != webrtc.TypeRTCPFBNACK {
+			feedback = append(feedback, fb)
+		}
+	}
+
+	stripped := *codec
+	stripped.Capability.RTCPFeedback = feedback
+	return stripped
+}
+
 func (codec *RTPCodec) IsVideo() bool {
 	return codec.Type == webrtc.RTPCodecTypeVideo
 }
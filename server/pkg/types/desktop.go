@@ -1,8 +1,17 @@
 package types
 
 import (
+	"errors"
 	"fmt"
 	"image"
+	"math"
+)
+
+var (
+	ErrClipboardTooLarge      = errors.New("clipboard content too large")
+	ErrKeyboardMapUnavailable = errors.New("keyboard layout or variant is not available")
+	ErrScreenSizeUnsupported  = errors.New("screen size is not supported")
+	ErrDisplayUnavailable     = errors.New("display cannot be opened")
 )
 
 type CursorImage struct {
@@ -24,6 +33,63 @@ func (s ScreenSize) String() string {
 	return fmt.Sprintf("%dx%d@%d", s.Width, s.Height, s.Rate)
 }
 
+// DPI of the X server at scale 1
+const ScreenDPIDefault = 96
+
+type ScreenDPI struct {
+	DPI   int     `json:"dpi"`
+	Scale float64 `json:"scale"`
+}
+
+func NewScreenDPI(dpi int) ScreenDPI {
+	if dpi <= 0 {
+		dpi = ScreenDPIDefault
+	}
+
+	return ScreenDPI{
+		DPI:   dpi,
+		Scale: math.Round(float64(dpi)/ScreenDPIDefault*100) / 100,
+	}
+}
+
+// CaptureRegion is a sub-rectangle of the screen, that is streamed instead of the whole screen.
+type CaptureRegion struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+func (r CaptureRegion) String() string {
+	return fmt.Sprintf("%dx%d+%d+%d", r.Width, r.Height, r.X, r.Y)
+}
+
+// Fits returns whether the region is not empty and lies within the screen.
+func (r CaptureRegion) Fits(screen ScreenSize) bool {
+	return r.X >= 0 && r.Y >= 0 && r.Width > 0 && r.Height > 0 &&
+		r.X+r.Width <= screen.Width && r.Y+r.Height <= screen.Height
+}
+
+// ToScreen converts coordinates relative to the region to screen coordinates, nil region is the whole screen.
+func (r *CaptureRegion) ToScreen(x, y int) (int, int) {
+	if r == nil {
+		return x, y
+	}
+
+	return r.X + x, r.Y + y
+}
+
+// FromScreen converts screen coordinates to coordinates relative to the region, clamped to its bounds.
+func (r *CaptureRegion) FromScreen(x, y int) (int, int) {
+	if r == nil {
+		return x, y
+	}
+
+	x = min(max(x-r.X, 0), r.Width-1)
+	y = min(max(y-r.Y, 0), r.Height-1)
+	return x, y
+}
+
 type KeyboardModifiers struct {
 	Shift    *bool `json:"shift"`
 	CapsLock *bool `json:"capslock"`
@@ -43,6 +109,8 @@ type KeyboardMap struct {
 type ClipboardText struct {
 	Text string
 	HTML string
+	// content was shortened to fit the size limit
+	Truncated bool
 }
 
 type DesktopManager interface {
@@ -50,9 +118,11 @@ type DesktopManager interface {
 	Shutdown() error
 	OnBeforeScreenSizeChange(listener func())
 	OnAfterScreenSizeChange(listener func())
+	OnScreenDPIChange(listener func(dpi ScreenDPI))
 
 	// xorg
 	Move(x, y int)
+	MoveRelative(dx, dy int)
 	GetCursorPosition() (int, int)
 	Scroll(deltaX, deltaY int, controlKey bool)
 	ButtonDown(code uint32) error
@@ -61,10 +131,19 @@ type DesktopManager interface {
 	KeyUp(code uint32) error
 	ButtonPress(code uint32) error
 	KeyPress(codes ...uint32) error
+	KeysDown() []uint32
 	ResetKeys()
+	ResetModifiers()
 	ScreenConfigurations() []ScreenSize
+	// input is sent to the display, X events are still received from the initial one
+	SetDisplay(display string) error
+	ScreenSizeSupported(ScreenSize) bool
 	SetScreenSize(ScreenSize) (ScreenSize, error)
 	GetScreenSize() ScreenSize
+	GetScreenDPI() ScreenDPI
+	HasDamage() bool
+	// returns ErrKeyboardMapUnavailable, if layout or variant is not available
+	KeyboardMapSupported(KeyboardMap) error
 	SetKeyboardMap(KeyboardMap) error
 	GetKeyboardMap() (*KeyboardMap, error)
 	SetKeyboardModifiers(mod KeyboardModifiers)
@@ -85,9 +164,18 @@ type DesktopManager interface {
 	TouchUpdate(touchId uint32, x, y int, pressure uint8) error
 	TouchEnd(touchId uint32, x, y int, pressure uint8) error
 
+	// gamepad
+	HasGamepadSupport() bool
+	GamepadConnect(index uint8) error
+	GamepadUpdate(index uint8, axes []int16, buttons []uint8) error
+	GamepadDisconnect(index uint8) error
+	GamepadDisconnectAll()
+
 	// clipboard
+	ClipboardLimit(data *ClipboardText) error
 	ClipboardGetText() (*ClipboardText, error)
 	ClipboardSetText(data ClipboardText) error
+	ClipboardClear() error
 	ClipboardGetBinary(mime string) ([]byte, error)
 	ClipboardSetBinary(mime string, data []byte) error
 	ClipboardGetTargets() ([]string, error)
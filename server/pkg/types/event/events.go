@@ -7,15 +7,20 @@ const (
 	SYSTEM_LOGS       = "system/logs"
 	SYSTEM_DISCONNECT = "system/disconnect"
 	SYSTEM_HEARTBEAT  = "system/heartbeat"
+	SYSTEM_ERROR      = "system/error"
+	SYSTEM_DRAINING   = "system/draining"
+	SYSTEM_BANNER     = "system/banner"
 )
 
 const (
 	CLIENT_HEARTBEAT = "client/heartbeat"
+	FOCUS_CHANGED    = "client/focus_changed"
 )
 
 const (
 	SIGNAL_REQUEST   = "signal/request"
 	SIGNAL_RESTART   = "signal/restart"
+	SIGNAL_RESUME    = "signal/resume"
 	SIGNAL_OFFER     = "signal/offer"
 	SIGNAL_ANSWER    = "signal/answer"
 	SIGNAL_PROVIDE   = "signal/provide"
@@ -23,20 +28,34 @@ const (
 	SIGNAL_VIDEO     = "signal/video"
 	SIGNAL_AUDIO     = "signal/audio"
 	SIGNAL_CLOSE     = "signal/close"
+	SIGNAL_SYNC      = "signal/sync"
+	// data channel was recreated, media is not affected
+	SIGNAL_DATA_CHANNEL = "signal/data_channel"
 )
 
 const (
-	SESSION_CREATED = "session/created"
-	SESSION_DELETED = "session/deleted"
-	SESSION_PROFILE = "session/profile"
-	SESSION_STATE   = "session/state"
-	SESSION_CURSORS = "session/cursors"
+	CONNECTION_QUALITY = "connection/quality"
+)
+
+const (
+	AUDIO_LEVEL = "audio/level"
+)
+
+const (
+	SESSION_CREATED  = "session/created"
+	SESSION_DELETED  = "session/deleted"
+	SESSION_PROFILE  = "session/profile"
+	SESSION_METADATA = "session/metadata"
+	SESSION_STATE    = "session/state"
+	SESSION_CURSORS  = "session/cursors"
 )
 
 const (
 	CONTROL_HOST    = "control/host"
 	CONTROL_RELEASE = "control/release"
 	CONTROL_REQUEST = "control/request"
+	CONTROL_GIVE    = "control/give"
+	CONTROL_REVOKE  = "control/revoke"
 	// mouse
 	CONTROL_MOVE        = "control/move"
 	CONTROL_SCROLL      = "control/scroll"
@@ -59,24 +78,43 @@ const (
 )
 
 const (
-	SCREEN_UPDATED = "screen/updated"
-	SCREEN_SET     = "screen/set"
+	SCREEN_UPDATED        = "screen/updated"
+	SCREEN_SET            = "screen/set"
+	SCREEN_DISPLAY_SET    = "screen/display_set"
+	SCREEN_REGION_SET     = "screen/region_set"
+	SCREEN_CONFIGURATIONS = "screen/configurations"
+	SCREEN_DPI_UPDATED    = "screen/dpi_updated"
 )
 
 const (
-	CLIPBOARD_UPDATED = "clipboard/updated"
-	CLIPBOARD_SET     = "clipboard/set"
+	CLIPBOARD_UPDATED   = "clipboard/updated"
+	CLIPBOARD_SET       = "clipboard/set"
+	CLIPBOARD_CLEAR     = "clipboard/clear"
+	CLIPBOARD_TRUNCATED = "clipboard/truncated"
 )
 
 const (
 	KEYBOARD_MODIFIERS = "keyboard/modifiers"
 	KEYBOARD_MAP       = "keyboard/map"
+	KEYBOARD_RESET     = "keyboard/reset"
 )
 
 const (
 	BROADCAST_STATUS = "broadcast/status"
 )
 
+const (
+	PRESENTATION_START  = "presentation/start"
+	PRESENTATION_STOP   = "presentation/stop"
+	PRESENTATION_STATUS = "presentation/status"
+)
+
+const (
+	FORWARD_START  = "forward/start"
+	FORWARD_STOP   = "forward/stop"
+	FORWARD_STATUS = "forward/status"
+)
+
 const (
 	SEND_UNICAST   = "send/unicast"
 	SEND_BROADCAST = "send/broadcast"
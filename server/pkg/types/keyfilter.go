@@ -0,0 +1,138 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// keysyms matched by key names, modifiers match both left and right keys
+var keyNames = map[string][]uint32{
+	"shift":   {0xffe1, 0xffe2},
+	"ctrl":    {0xffe3, 0xffe4},
+	"control": {0xffe3, 0xffe4},
+	"alt":     {0xffe9, 0xffea},
+	"meta":    {0xffe7, 0xffe8},
+	"super":   {0xffeb, 0xffec},
+	"win":     {0xffeb, 0xffec},
+	"hyper":   {0xffed, 0xffee},
+
+	"shift_l": {0xffe1}, "shift_r": {0xffe2},
+	"control_l": {0xffe3}, "control_r": {0xffe4},
+	"alt_l": {0xffe9}, "alt_r": {0xffea},
+	"meta_l": {0xffe7}, "meta_r": {0xffe8},
+	"super_l": {0xffeb}, "super_r": {0xffec},
+
+	"escape":    {0xff1b},
+	"esc":       {0xff1b},
+	"tab":       {0xff09},
+	"enter":     {0xff0d},
+	"return":    {0xff0d},
+	"backspace": {0xff08},
+	"delete":    {0xffff},
+	"insert":    {0xff63},
+	"home":      {0xff50},
+	"end":       {0xff57},
+	"pageup":    {0xff55},
+	"pagedown":  {0xff56},
+	"left":      {0xff51},
+	"up":        {0xff52},
+	"right":     {0xff53},
+	"down":      {0xff54},
+	"space":     {0x0020},
+	"print":     {0xff61},
+	"menu":      {0xff67},
+	"sysreq":    {0xff15},
+}
+
+// keyCombo is matched when all its keys are pressed, each key can be one of more keysyms
+type keyCombo [][]uint32
+
+// parseKeyCombo parses key combination such as "super", "ctrl+alt+f1" or "0xffeb".
+func parseKeyCombo(combo string) (keyCombo, error) {
+	var keys keyCombo
+	for _, name := range strings.Split(combo, "+") {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		if keysyms, ok := keyNames[name]; ok {
+			keys = append(keys, keysyms)
+			continue
+		}
+
+		// function keys
+		if n, err := strconv.Atoi(strings.TrimPrefix(name, "f")); err == nil && name[0] == 'f' && n >= 1 && n <= 35 {
+			keys = append(keys, []uint32{0xffbe + uint32(n-1)})
+			continue
+		}
+
+		// raw keysym
+		if hex, ok := strings.CutPrefix(name, "0x"); ok {
+			keysym, err := strconv.ParseUint(hex, 16, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid keysym %q in %q", name, combo)
+			}
+
+			keys = append(keys, []uint32{uint32(keysym)})
+			continue
+		}
+
+		// printable characters, in both cases
+		if len(name) == 1 && name[0] > 0x20 && name[0] < 0x7f {
+			keys = append(keys, []uint32{uint32(name[0]), uint32(strings.ToUpper(name)[0])})
+			continue
+		}
+
+		return nil, fmt.Errorf("unknown key %q in %q", name, combo)
+	}
+
+	return keys, nil
+}
+
+// matches returns whether pressing key completes the combination, while pressed keys are held
+func (combo keyCombo) matches(key uint32, pressed []uint32) bool {
+	found := false
+	for _, keysyms := range combo {
+		if !found && slices.Contains(keysyms, key) {
+			found = true
+			continue
+		}
+
+		held := false
+		for _, keysym := range keysyms {
+			if slices.Contains(pressed, keysym) {
+				held = true
+				break
+			}
+		}
+
+		if !held {
+			return false
+		}
+	}
+
+	return found
+}
+
+// ValidateBlockedKeys returns error if any of the blocked key combinations cannot be parsed.
+func (profile MemberProfile) ValidateBlockedKeys() error {
+	for _, combo := range profile.BlockedKeys {
+		if _, err := parseKeyCombo(combo); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// KeyBlocked returns whether the member must not press key, while pressed keys are held.
+func (profile MemberProfile) KeyBlocked(key uint32, pressed []uint32) bool {
+	for _, combo := range profile.BlockedKeys {
+		keys, err := parseKeyCombo(combo)
+		if err == nil && keys.matches(key, pressed) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -22,10 +22,61 @@ type MemberProfile struct {
 	SendsInactiveCursor   bool `json:"sends_inactive_cursor"    mapstructure:"sends_inactive_cursor"`
 	CanSeeInactiveCursors bool `json:"can_see_inactive_cursors" mapstructure:"can_see_inactive_cursors"`
 
+	// restricts clipboard direction, if clipboard can be accessed
+	ClipboardPolicy ClipboardPolicy `json:"clipboard_policy,omitempty" mapstructure:"clipboard_policy"`
+
+	// keys and key combinations that are not sent to the desktop, e.g. "super" or "ctrl+alt+f1"
+	BlockedKeys []string `json:"blocked_keys,omitempty" mapstructure:"blocked_keys"`
+
+	// sessions with higher priority get higher streams first, when bandwidth budget is limited
+	Priority int `json:"priority" mapstructure:"priority"`
+
+	// keep pre-negotiated standby peer for near-instant reconnect
+	WarmStandby bool `json:"warm_standby" mapstructure:"warm_standby"`
+
 	// plugin scope
 	Plugins PluginSettings `json:"plugins"`
 }
 
+type ClipboardPolicy string
+
+const (
+	// clipboard cannot be read nor written
+	ClipboardPolicyNone ClipboardPolicy = "none"
+	// remote clipboard can only be read by the client
+	ClipboardPolicyRead ClipboardPolicy = "read"
+	// client can only write to the remote clipboard
+	ClipboardPolicyWrite ClipboardPolicy = "write"
+	// both directions are allowed, same as empty policy
+	ClipboardPolicyBoth ClipboardPolicy = "both"
+)
+
+// CanReadClipboard returns whether remote clipboard content can be sent to the member.
+func (profile MemberProfile) CanReadClipboard() bool {
+	if !profile.CanAccessClipboard {
+		return false
+	}
+
+	switch profile.ClipboardPolicy {
+	case "", ClipboardPolicyRead, ClipboardPolicyBoth:
+		return true
+	}
+	return false
+}
+
+// CanWriteClipboard returns whether the member can set content of remote clipboard.
+func (profile MemberProfile) CanWriteClipboard() bool {
+	if !profile.CanAccessClipboard {
+		return false
+	}
+
+	switch profile.ClipboardPolicy {
+	case "", ClipboardPolicyWrite, ClipboardPolicyBoth:
+		return true
+	}
+	return false
+}
+
 type MemberProvider interface {
 	Connect() error
 	Disconnect() error
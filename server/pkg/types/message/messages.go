@@ -18,16 +18,24 @@ type SystemInit struct {
 	SessionId         string                 `json:"session_id"`
 	ControlHost       ControlHost            `json:"control_host"`
 	ScreenSize        types.ScreenSize       `json:"screen_size"`
+	ScreenDPI         types.ScreenDPI        `json:"screen_dpi"`
+	Display           string                 `json:"display"`
+	ScreenRegion      *types.CaptureRegion   `json:"screen_region,omitempty"`
 	Sessions          map[string]SessionData `json:"sessions"`
 	Settings          types.Settings         `json:"settings"`
 	TouchEvents       bool                   `json:"touch_events"`
 	ScreencastEnabled bool                   `json:"screencast_enabled"`
 	WebRTC            SystemWebRTC           `json:"webrtc"`
+	Draining          bool                   `json:"draining,omitempty"`
+	Banner            *types.Banner          `json:"banner,omitempty"`
+	Presentation      *types.Presentation    `json:"presentation,omitempty"`
 }
 
 type SystemAdmin struct {
 	ScreenSizesList []types.ScreenSize `json:"screen_sizes_list"`
+	DisplaysList    []string           `json:"displays_list"`
 	BroadcastStatus BroadcastStatus    `json:"broadcast_status"`
+	ForwardStatus   ForwardStatus      `json:"forward_status"`
 }
 
 type SystemLogs = []SystemLog
@@ -42,6 +50,21 @@ type SystemDisconnect struct {
 	Message string `json:"message"`
 }
 
+type SystemDraining struct {
+	Draining bool `json:"draining"`
+}
+
+// SystemBanner carries current banner, nil when it was cleared
+type SystemBanner struct {
+	Banner *types.Banner `json:"banner"`
+}
+
+type SystemError struct {
+	Event   string `json:"event"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 type SystemSettingsUpdate struct {
 	ID string `json:"id"`
 	types.Settings
@@ -55,6 +78,11 @@ type SignalRequest struct {
 	Video types.PeerVideoRequest `json:"video"`
 	Audio types.PeerAudioRequest `json:"audio"`
 
+	Options types.PeerOptions `json:"options"`
+
+	// stream used last time by returning client, used only if no selector is provided
+	PreferredVideo string `json:"preferred_video,omitempty"`
+
 	Auto bool `json:"auto"` // TODO: Remove this
 }
 
@@ -64,6 +92,22 @@ type SignalProvide struct {
 
 	Video types.PeerVideo `json:"video"`
 	Audio types.PeerAudio `json:"audio"`
+
+	// base64 encoded key for data channel encryption, if enabled
+	DataKey []byte `json:"data_key,omitempty"`
+	// token for resuming this peer after reconnect, if enabled
+	ResumeToken string `json:"resume_token,omitempty"`
+}
+
+type AudioLevel struct {
+	// rms level in dB, 0 is the loudest
+	Level float64 `json:"level"`
+	// level is above the threshold of sound activity
+	Active bool `json:"active"`
+}
+
+type SignalResume struct {
+	Token string `json:"token"`
 }
 
 type SignalCandidate struct {
@@ -74,6 +118,11 @@ type SignalDescription struct {
 	SDP string `json:"sdp"`
 }
 
+// SignalDataChannel announces data channel, that replaces the closed one
+type SignalDataChannel struct {
+	Label string `json:"label"`
+}
+
 type SignalVideo struct {
 	types.PeerVideoRequest
 }
@@ -96,14 +145,21 @@ type MemberProfile struct {
 }
 
 type SessionState struct {
-	ID string `json:"id"`
+	ID       string                `json:"id"`
+	Metadata types.SessionMetadata `json:"metadata,omitempty"`
 	types.SessionState
 }
 
+type SessionMetadata struct {
+	ID       string                `json:"id"`
+	Metadata types.SessionMetadata `json:"metadata"`
+}
+
 type SessionData struct {
-	ID      string              `json:"id"`
-	Profile types.MemberProfile `json:"profile"`
-	State   types.SessionState  `json:"state"`
+	ID       string                `json:"id"`
+	Profile  types.MemberProfile   `json:"profile"`
+	State    types.SessionState    `json:"state"`
+	Metadata types.SessionMetadata `json:"metadata,omitempty"`
 }
 
 type SessionCursors struct {
@@ -161,16 +217,49 @@ type ScreenSize struct {
 }
 
 type ScreenSizeUpdate struct {
-	ID string `json:"id"`
+	ID      string               `json:"id"`
+	Display string               `json:"display"`
+	Region  *types.CaptureRegion `json:"region,omitempty"`
 	types.ScreenSize
 }
 
+type ScreenDisplay struct {
+	Display string `json:"display"`
+}
+
+type ScreenRegion struct {
+	// nil region resets capture to the whole screen
+	Region *types.CaptureRegion `json:"region"`
+}
+
+type ScreenDPI struct {
+	types.ScreenDPI
+}
+
+type ScreenConfigurations struct {
+	Configurations []types.ScreenSize `json:"configurations"`
+}
+
 /////////////////////////////
 // Clipboard
 /////////////////////////////
 
 type ClipboardData struct {
-	Text string `json:"text"`
+	Text      string `json:"text"`
+	Truncated bool   `json:"truncated,omitempty"`
+}
+
+type ClipboardTruncated struct {
+	Size     int `json:"size"`
+	Original int `json:"original"`
+}
+
+/////////////////////////////
+// Client
+/////////////////////////////
+
+type FocusChanged struct {
+	Focused bool `json:"focused"`
 }
 
 /////////////////////////////
@@ -194,6 +283,34 @@ type BroadcastStatus struct {
 	URL      string `json:"url,omitempty"`
 }
 
+/////////////////////////////
+// Presentation
+/////////////////////////////
+
+type PresentationStart struct {
+	// sessions that receive the stream, besides the presenter and admins
+	Targets []string `json:"targets"`
+}
+
+// PresentationStatus carries current presentation, nil when it was stopped
+type PresentationStatus struct {
+	Presentation *types.Presentation `json:"presentation"`
+}
+
+/////////////////////////////
+// Forward
+/////////////////////////////
+
+type ForwardStart struct {
+	// session whose video is forwarded
+	SessionID string `json:"session_id"`
+	types.RTPForward
+}
+
+type ForwardStatus struct {
+	types.RTPForwardStatus
+}
+
 /////////////////////////////
 // Send (opaque comunication channel)
 /////////////////////////////
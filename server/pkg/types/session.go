@@ -2,7 +2,9 @@ package types
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 	"time"
 )
 
@@ -12,17 +14,52 @@ var (
 	ErrSessionAlreadyConnected = errors.New("session is already connected")
 	ErrSessionLoginDisabled    = errors.New("session login disabled")
 	ErrSessionLoginsLocked     = errors.New("session logins locked")
+	ErrSessionMetadataTooLarge = errors.New("session metadata too large")
+	ErrSessionResumeDisabled   = errors.New("session resuming is disabled")
+	ErrSessionDraining         = errors.New("server is draining")
+	ErrControlPolicyUnknown    = errors.New("unknown control policy")
+	ErrSessionNotAllowedToHost = errors.New("is not allowed to host")
+	ErrSessionNotTheHost       = errors.New("is not the host")
+	ErrSessionAlreadyTheHost   = errors.New("is already the host")
+	ErrSessionAlreadyHosted    = errors.New("is already hosted")
 )
 
+// limits for session metadata to prevent abuse
+const (
+	SessionMetadataMaxKeys        = 32
+	SessionMetadataMaxKeyLength   = 64
+	SessionMetadataMaxValueLength = 512
+)
+
+type SessionMetadata map[string]string
+
+func (m SessionMetadata) Validate() error {
+	if len(m) > SessionMetadataMaxKeys {
+		return fmt.Errorf("%w: more than %d keys", ErrSessionMetadataTooLarge, SessionMetadataMaxKeys)
+	}
+
+	for key, value := range m {
+		if len(key) > SessionMetadataMaxKeyLength {
+			return fmt.Errorf("%w: key longer than %d bytes", ErrSessionMetadataTooLarge, SessionMetadataMaxKeyLength)
+		}
+		if len(value) > SessionMetadataMaxValueLength {
+			return fmt.Errorf("%w: value of '%s' longer than %d bytes", ErrSessionMetadataTooLarge, key, SessionMetadataMaxValueLength)
+		}
+	}
+
+	return nil
+}
+
 type Cursor struct {
 	X int `json:"x"`
 	Y int `json:"y"`
 }
 
 type SessionProfile struct {
-	Id      string
-	Token   string
-	Profile MemberProfile
+	Id       string
+	Token    string
+	Profile  MemberProfile
+	Metadata SessionMetadata `json:",omitempty"`
 }
 
 type SessionState struct {
@@ -39,6 +76,36 @@ type SessionState struct {
 	NotWatchingSince *time.Time `json:"not_watching_since,omitempty"`
 }
 
+// SessionUsage is network traffic of all webrtc peers of the session, since the start of the period
+type SessionUsage struct {
+	BytesSent     uint64    `json:"bytes_sent"`
+	BytesReceived uint64    `json:"bytes_received"`
+	Since         time.Time `json:"since"`
+	QuotaExceeded bool      `json:"quota_exceeded"`
+}
+
+// ControlPolicy decides what happens when a session requests control,
+// while another session is the host.
+type ControlPolicy string
+
+const (
+	// host keeps control, it is only notified about the request
+	ControlPolicyLocked ControlPolicy = "locked"
+	// requesting session takes control from the host
+	ControlPolicySteal ControlPolicy = "steal"
+	// requesting session is queued and gets control when the host releases it
+	ControlPolicyQueue ControlPolicy = "queue"
+)
+
+// Valid reports whether the policy is known, empty policy is valid.
+func (p ControlPolicy) Valid() bool {
+	switch p {
+	case "", ControlPolicyLocked, ControlPolicySteal, ControlPolicyQueue:
+		return true
+	}
+	return false
+}
+
 type Settings struct {
 	PrivateMode       bool `json:"private_mode"`
 	LockedLogins      bool `json:"locked_logins"`
@@ -48,11 +115,83 @@ type Settings struct {
 	InactiveCursors   bool `json:"inactive_cursors"`
 	MercifulReconnect bool `json:"merciful_reconnect"`
 	HeartbeatInterval int  `json:"heartbeat_interval"`
+	// empty policy is derived from implicit hosting
+	ControlPolicy ControlPolicy `json:"control_policy,omitempty"`
 
 	// plugin scope
 	Plugins PluginSettings `json:"plugins"`
 }
 
+// GetControlPolicy returns configured control policy, when it is not set,
+// implicit hosting steals control and otherwise the host keeps it.
+func (s Settings) GetControlPolicy() ControlPolicy {
+	if s.ControlPolicy != "" {
+		return s.ControlPolicy
+	}
+	if s.ImplicitHosting {
+		return ControlPolicySteal
+	}
+	return ControlPolicyLocked
+}
+
+type BannerLevel string
+
+const (
+	BannerLevelInfo    BannerLevel = "info"
+	BannerLevelWarning BannerLevel = "warning"
+	BannerLevelError   BannerLevel = "error"
+)
+
+// Banner is a notification, that clients show to all users until it is cleared.
+type Banner struct {
+	Message   string      `json:"message"`
+	Level     BannerLevel `json:"level"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// Presentation limits room video and audio to targeted sessions, others do not receive it.
+type Presentation struct {
+	// session that started the presentation
+	Presenter string    `json:"presenter"`
+	Targets   []string  `json:"targets"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Includes reports, whether the session receives the presentation
+func (p *Presentation) Includes(sessionID string) bool {
+	return sessionID == p.Presenter || slices.Contains(p.Targets, sessionID)
+}
+
+// audited actions
+const (
+	AuditControlGrab       = "control/grab"
+	AuditControlRelease    = "control/release"
+	AuditControlGive       = "control/give"
+	AuditControlRevoke     = "control/revoke"
+	AuditClipboardSet      = "clipboard/set"
+	AuditClipboardClear    = "clipboard/clear"
+	AuditScreenSet         = "screen/set"
+	AuditSettingsUpdate    = "settings/update"
+	AuditSessionDisconnect = "session/disconnect"
+	AuditSessionDelete     = "session/delete"
+	AuditBannerSet         = "banner/set"
+	AuditBannerClear       = "banner/clear"
+	AuditForwardStart      = "forward/start"
+	AuditForwardStop       = "forward/stop"
+	AuditPresentationStart = "presentation/start"
+	AuditPresentationStop  = "presentation/stop"
+)
+
+type AuditEntry struct {
+	Timestamp time.Time `json:"timestamp"`
+	// session that performed the action
+	Actor  string `json:"actor"`
+	Action string `json:"action"`
+	// session that was affected by the action, if any
+	Target  string `json:"target,omitempty"`
+	Details string `json:"details,omitempty"`
+}
+
 type Stats struct {
 	HasHost         bool       `json:"has_host"`
 	HostId          string     `json:"host_id,omitempty"`
@@ -61,37 +200,77 @@ type Stats struct {
 	LastUserLeftAt  *time.Time `json:"last_user_left_at,omitempty"`
 	TotalAdmins     int        `json:"total_admins"`
 	LastAdminLeftAt *time.Time `json:"last_admin_left_at,omitempty"`
+	Draining        bool       `json:"draining"`
 }
 
 type Session interface {
 	ID() string
 	Profile() MemberProfile
 	State() SessionState
+	Metadata() SessionMetadata
 	IsHost() bool
 	LegacyIsHost() bool
 	SetAsHost()
 	SetAsHostBy(session Session)
 	ClearHost()
 	PrivateModeEnabled() bool
+	// room video and audio are not sent to the session, in private mode or outside of presentation
+	StreamPaused() bool
+	// how long is disconnected webrtc peer kept for resuming, 0 if disabled
+	ResumeGrace() time.Duration
 
 	// cursor
 	SetCursor(cursor Cursor)
 
+	// network traffic accounting, kept in memory only
+	AddUsage(sent, received uint64) SessionUsage
+	ResetUsage()
+	SetQuotaExceeded(exceeded bool)
+	Usage() SessionUsage
+
+	// input of the client is not forwarded while its window is not focused
+	SetFocused(focused bool)
+	Focused() bool
+
 	// websocket
 	ConnectWebSocketPeer(websocketPeer WebSocketPeer)
 	DisconnectWebSocketPeer(websocketPeer WebSocketPeer, delayed bool)
 	DestroyWebSocketPeer(reason string)
 	Send(event string, payload any)
+	// address of connected websocket client, empty if not connected
+	RemoteAddr() string
+	// negotiated websocket protocol version of the client
+	SetProtocolVersion(version int)
+	ProtocolVersion() int
 
 	// webrtc
 	SetWebRTCPeer(webrtcPeer WebRTCPeer)
 	SetWebRTCConnected(webrtcPeer WebRTCPeer, connected bool)
 	GetWebRTCPeer() WebRTCPeer
+
+	// keyboard map set by the client, applied to desktop while hosting
+	SetKeyboardMap(kbd KeyboardMap)
+	KeyboardMap() *KeyboardMap
+
+	// configuration that can be exported and applied to another session
+	ExportConfig() SessionConfig
+	ApplyConfig(config SessionConfig) error
+}
+
+// SessionConfig is the current setup of a session, unset fields are not applied.
+type SessionConfig struct {
+	Video         *PeerVideoRequest `json:"video,omitempty"`
+	Audio         *PeerAudioRequest `json:"audio,omitempty"`
+	PointerLocked *bool             `json:"pointer_locked,omitempty"`
+	KeyboardMap   *KeyboardMap      `json:"keyboard_map,omitempty"`
+	// screen size is shared by all sessions
+	ScreenSize *ScreenSize `json:"screen_size,omitempty"`
 }
 
 type SessionManager interface {
 	Create(id string, profile MemberProfile) (Session, string, error)
 	Update(id string, profile MemberProfile) error
+	SetMetadata(id string, metadata SessionMetadata) error
 	Delete(id string) error
 	Disconnect(id string) error
 	Get(id string) (Session, bool)
@@ -101,9 +280,29 @@ type SessionManager interface {
 
 	GetHost() (Session, bool)
 
+	// takes control according to the control policy, returns previous host if control was
+	// taken from it or ErrSessionAlreadyHosted if the request was sent to the host instead
+	ControlRequest(session Session) (Session, error)
+	// releases control of the host and hands it to the next queued session, if any
+	ControlRelease(session Session) error
+	// clears the current host and hands control to the next queued session, if any
+	ControlReset(session Session)
+	// gives control to the target session, returns ErrSessionNotAllowedToHost
+	// if target cannot host, e.g. because controls are locked
+	ControlGive(session Session, target Session) error
+
+	// resume token is issued with webrtc peer and is valid as long as the peer
+	// exists, allowing reconnecting client to reattach to it with ICE restart
+	ResumeTokenCreate(session Session) (string, error)
+	ResumeTokenVerify(session Session, token string) bool
+
 	SetCursor(cursor Cursor, session Session)
 	PopCursors() map[Session][]Cursor
 
+	// audit entry is stored for both actor and target session
+	Audit(entry AuditEntry)
+	AuditLog(id string) []AuditEntry
+
 	Broadcast(event string, payload any, exclude ...string)
 	AdminBroadcast(event string, payload any, exclude ...string)
 	InactiveCursorsBroadcast(event string, payload any, exclude ...string)
@@ -113,14 +312,30 @@ type SessionManager interface {
 	OnConnected(listener func(session Session))
 	OnDisconnected(listener func(session Session))
 	OnProfileChanged(listener func(session Session, new, old MemberProfile))
+	OnMetadataChanged(listener func(session Session, new, old SessionMetadata))
 	OnStateChanged(listener func(session Session))
 	OnHostChanged(listener func(session, host Session))
 	OnSettingsChanged(listener func(session Session, new, old Settings))
+	OnDrainingChanged(listener func(draining bool))
+	OnBannerChanged(listener func(banner *Banner))
+	OnPresentationChanged(listener func(presentation *Presentation))
 
 	UpdateSettingsFunc(session Session, f func(settings *Settings) bool)
 	Settings() Settings
 	CookieEnabled() bool
 
+	// while draining, no new sessions are accepted and existing ones can finish
+	SetDraining(draining bool)
+	Draining() bool
+
+	// current banner is sent to sessions joining later, nil clears it
+	SetBanner(banner *Banner)
+	Banner() *Banner
+
+	// while presenting, only targeted sessions and admins receive the stream, nil stops it
+	SetPresentation(presentation *Presentation)
+	Presentation() *Presentation
+
 	Stats() Stats
 
 	CookieSetToken(w http.ResponseWriter, token string)
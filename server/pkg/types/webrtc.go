@@ -2,6 +2,9 @@ package types
 
 import (
 	"errors"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/pion/webrtc/v3"
 )
@@ -10,51 +13,415 @@ var (
 	ErrWebRTCDataChannelNotFound = errors.New("webrtc data channel not found")
 	ErrWebRTCConnectionNotFound  = errors.New("webrtc connection not found")
 	ErrWebRTCStreamNotFound      = errors.New("webrtc stream not found")
+	ErrWebRTCOfferCollision      = errors.New("webrtc offer collision, server offer is pending")
+	ErrWebRTCICECredentials      = errors.New("webrtc ice credentials are invalid")
+	ErrWebRTCAudioSyncOffset     = errors.New("webrtc audio sync offset out of range")
+	ErrWebRTCSDPMalformed        = errors.New("webrtc transformed sdp is malformed")
+	ErrWebRTCCodecMismatch       = errors.New("webrtc answer has no common codec")
+	ErrWebRTCNegotiationFailed   = errors.New("webrtc negotiation failed, local description could not be created")
+	ErrWebRTCQuotaExceeded       = errors.New("webrtc bandwidth quota exceeded")
+	ErrWebRTCForwardInvalid      = errors.New("webrtc rtp forward is invalid")
+	ErrWebRTCEncoderUnknown      = errors.New("webrtc forced encoder backend is unknown")
 )
 
 type ICEServer struct {
 	URLs       []string `mapstructure:"urls"       json:"urls"`
 	Username   string   `mapstructure:"username"   json:"username,omitempty"`
 	Credential string   `mapstructure:"credential" json:"credential,omitempty"`
+	// used by ice server selector to prefer servers close to the client
+	Region string `mapstructure:"region" json:"region,omitempty"`
+}
+
+// IsTURN returns whether the server is a TURN server, that requires credentials.
+func (s ICEServer) IsTURN() bool {
+	for _, url := range s.URLs {
+		if strings.HasPrefix(url, "turn:") || strings.HasPrefix(url, "turns:") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// maximum length of ICE username and credential
+const ICECredentialsMaxLength = 256
+
+// ICECredentials are static long-term credentials for TURN servers, provided per peer.
+type ICECredentials struct {
+	Username   string `json:"username"`
+	Credential string `json:"credential"`
+}
+
+func (c ICECredentials) Validate() error {
+	for _, value := range []string{c.Username, c.Credential} {
+		if value == "" || len(value) > ICECredentialsMaxLength {
+			return ErrWebRTCICECredentials
+		}
+
+		if strings.IndexFunc(value, unicode.IsControl) >= 0 {
+			return ErrWebRTCICECredentials
+		}
+	}
+
+	return nil
+}
+
+// Apply returns copy of servers, where TURN servers use these credentials instead of configured ones.
+func (c *ICECredentials) Apply(servers []ICEServer) []ICEServer {
+	if c == nil {
+		return servers
+	}
+
+	result := make([]ICEServer, 0, len(servers))
+	for _, server := range servers {
+		if server.IsTURN() {
+			server.Username = c.Username
+			server.Credential = c.Credential
+		}
+		result = append(result, server)
+	}
+
+	return result
+}
+
+// DataChannelHandler receives messages from client-initiated data channel with registered label.
+type DataChannelHandler func(session Session, data []byte) error
+
+// PeerRekeyHandler replaces the peer with a new one, so that fresh SRTP keys are negotiated.
+type PeerRekeyHandler func(session Session, peer WebRTCPeer, options PeerOptions) error
+
+// ICEServerSelector chooses and orders ICE servers advertised to a client.
+type ICEServerSelector interface {
+	SelectICEServers(session Session, remoteAddr string, servers []ICEServer) []ICEServer
+}
+
+// SDPTransform modifies local session description after it was created and before
+// it is set, the description set on the server stays unmodified, the transformed one
+// is sent to the client along with gathered candidates. Output must be a valid SDP
+// with the same media sections in the same order, and with unmodified fingerprint,
+// ICE credentials and other protected attributes, otherwise it is rejected and the
+// description is sent without this transform.
+type SDPTransform interface {
+	TransformSDP(sdp string) (string, error)
+}
+
+type SDPTransformFunc func(sdp string) (string, error)
+
+func (f SDPTransformFunc) TransformSDP(sdp string) (string, error) {
+	return f(sdp)
 }
 
 type PeerVideo struct {
 	Disabled bool   `json:"disabled"`
 	ID       string `json:"id"`
-	Video    string `json:"video"` // TODO: Remove this, used for compatibility with old clients.
+	Video    string `json:"video,omitempty"` // TODO: Remove this, used for compatibility with old clients.
 	Auto     bool   `json:"auto"`
+	// effective framerate of the current stream
+	FPS    float64 `json:"fps"`
+	MaxFPS float64 `json:"max_fps"`
+	// whether lost packets are retransmitted on NACK
+	NACK bool `json:"nack"`
+	// rendered size of the video on the client
+	Viewport *PeerViewport `json:"viewport,omitempty"`
+	// manually picked stream, that estimator does not exceed
+	Ceiling string `json:"ceiling,omitempty"`
+	// encoder backend of the current stream
+	Encoder EncoderBackend `json:"encoder,omitempty"`
+	// only streams configured for this encoder backend are selected
+	ForceEncoder EncoderBackend `json:"force_encoder,omitempty"`
+	// resolution cap, that no selected stream exceeds
+	MaxResolution *StreamResolution `json:"max_resolution,omitempty"`
+	// video stream mirrors the one that the host selected, estimator changes of the host are not mirrored
+	FollowHost bool `json:"follow_host"`
+	// current stream is mirrored horizontally
+	Flip bool `json:"flip,omitempty"`
+	// requested resolution scaling, Scaled is false when only the nearest stream was selected
+	ScaleResolutionDownBy float64 `json:"scale_resolution_down_by,omitempty"`
+	Scaled                bool    `json:"scaled,omitempty"`
+	// why the video was changed, set only when the signal is a result of a change
+	Reason VideoChangeReason `json:"reason,omitempty"`
+}
+
+type VideoChangeReason string
+
+const (
+	VideoChangeReasonManual             VideoChangeReason = "manual"
+	VideoChangeReasonEstimatorUpgrade   VideoChangeReason = "estimator_upgrade"
+	VideoChangeReasonEstimatorDowngrade VideoChangeReason = "estimator_downgrade"
+	VideoChangeReasonQuotaExceeded      VideoChangeReason = "quota_exceeded"
+)
+
+// ForProtocol returns video, as it is sent to clients using given websocket protocol version.
+func (v PeerVideo) ForProtocol(version int) PeerVideo {
+	if version >= WebSocketProtocolV2 {
+		v.Video = ""
+	}
+	return v
+}
+
+type PeerViewport struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
 }
 
 type PeerVideoRequest struct {
 	Disabled *bool           `json:"disabled,omitempty"`
 	Selector *StreamSelector `json:"selector,omitempty"`
 	Auto     *bool           `json:"auto,omitempty"`
+	// framerate cap, 0 means no cap
+	MaxFPS *float64 `json:"max_fps,omitempty"`
+	// smallest stream covering the viewport is selected at most, zero size means no limit
+	Viewport *PeerViewport `json:"viewport,omitempty"`
+	// selected stream becomes the highest one estimator may switch to,
+	// estimator is enabled and may only go lower under congestion
+	ManualCeiling *bool `json:"manual_ceiling,omitempty"`
+	// streams above this resolution are never selected, zero size means no cap
+	MaxResolution *StreamResolution `json:"max_resolution,omitempty"`
+	// mirror video stream selected by the host, estimator is disabled while following,
+	// streams switched by estimator of the host are not mirrored
+	FollowHost *bool `json:"follow_host,omitempty"`
+	// selected stream is scaled down by this factor, 1 means no scaling
+	ScaleResolutionDownBy *float64 `json:"scale_resolution_down_by,omitempty"`
+	// only streams configured for this encoder backend are selected, empty means any
+	ForceEncoder *EncoderBackend `json:"force_encoder,omitempty"`
+	// set by the server, requests from clients are always manual
+	Reason VideoChangeReason `json:"-"`
+}
+
+type ConnectionQuality string
+
+const (
+	ConnectionQualityExcellent ConnectionQuality = "excellent"
+	ConnectionQualityGood      ConnectionQuality = "good"
+	ConnectionQualityFair      ConnectionQuality = "fair"
+	ConnectionQualityPoor      ConnectionQuality = "poor"
+)
+
+type PeerQuality struct {
+	Quality ConnectionQuality `json:"quality"`
+	// round trip time in seconds
+	RoundTripTime float64 `json:"round_trip_time"`
+	FractionLost  float64 `json:"fraction_lost"`
+	// bandwidth estimate has a downward trend
+	Degrading bool `json:"degrading"`
 }
 
 type PeerAudio struct {
 	Disabled bool `json:"disabled"`
+	// gain in dB applied to the audio stream
+	Gain float64 `json:"gain"`
+	// negotiated audio codec fmtp line
+	Fmtp string `json:"fmtp,omitempty"`
+	// audio delay relative to video in milliseconds
+	SyncOffset int `json:"sync_offset"`
 }
 
+// maximum absolute audio sync offset in milliseconds
+const PeerAudioSyncOffsetMax = 1000
+
 type PeerAudioRequest struct {
 	Disabled *bool `json:"disabled,omitempty"`
+	// gain in dB, 0 means unchanged audio
+	Gain *float64 `json:"gain,omitempty"`
+	// audio delay relative to video in milliseconds, negative plays audio earlier
+	SyncOffset *int `json:"sync_offset,omitempty"`
+}
+
+// RTPForward configures forwarding of encoded video to an external endpoint, e.g. SFU.
+type RTPForward struct {
+	// host:port of the UDP endpoint
+	Address string `json:"address"`
+	// random, if not set
+	SSRC uint32 `json:"ssrc,omitempty"`
+	// payload type of the codec, if not set
+	PayloadType uint8 `json:"payload_type,omitempty"`
+	// base64 encoded master key and salt for AES_CM_128_HMAC_SHA1_80, plain RTP if not set
+	SRTPKey string `json:"srtp_key,omitempty"`
+}
+
+type RTPForwardStatus struct {
+	IsActive    bool   `json:"is_active"`
+	SessionID   string `json:"session_id,omitempty"`
+	StreamID    string `json:"stream_id,omitempty"`
+	Address     string `json:"address,omitempty"`
+	SSRC        uint32 `json:"ssrc,omitempty"`
+	PayloadType uint8  `json:"payload_type,omitempty"`
+	SRTP        bool   `json:"srtp,omitempty"`
+}
+
+// PeerOptions are applied when creating a peer, before negotiation.
+type PeerOptions struct {
+	// opus in-band forward error correction, enabled by default
+	AudioFEC *bool `json:"audio_fec,omitempty"`
+	// opus discontinuous transmission, disabled by default
+	AudioDTX *bool `json:"audio_dtx,omitempty"`
+	// NACK based retransmission, enabled by default
+	NACK *bool `json:"nack,omitempty"`
+	// separate unordered data channel without retransmissions for pointer movement,
+	// scroll and cursor position, other messages stay on the reliable channel
+	UnreliableInput bool `json:"unreliable_input,omitempty"`
+	// cursor positions are sent in extended form, with timestamp and movement
+	CursorMotion bool `json:"cursor_motion,omitempty"`
+	// lossless frame is sent over the data channel when the screen is static
+	LosslessStills bool `json:"lossless_stills,omitempty"`
+	// clipboard updates are sent to the host over the data channel instead of
+	// websocket, queued behind input related messages, requires data priority
+	ClipboardChannel bool `json:"clipboard_channel,omitempty"`
+	// static credentials for configured TURN servers, e.g. a TURN account per user
+	ICECredentials *ICECredentials `json:"ice_credentials,omitempty"`
+}
+
+// SenderReport is the latest RTCP sender report sent for a track.
+type SenderReport struct {
+	SSRC        uint32    `json:"ssrc"`
+	NTPTime     time.Time `json:"ntp_time"`
+	RTPTime     uint32    `json:"rtp_time"`
+	PacketCount uint32    `json:"packet_count"`
+	OctetCount  uint32    `json:"octet_count"`
+	SentAt      time.Time `json:"sent_at"`
+}
+
+const (
+	EstimatorActionUpgrade   = "upgrade"
+	EstimatorActionDowngrade = "downgrade"
+	EstimatorActionHold      = "hold"
+)
+
+const (
+	EstimatorReasonBudget           = "budget_exceeded"
+	EstimatorReasonDownwardTrend    = "downward_trend"
+	EstimatorReasonStalled          = "stalled"
+	EstimatorReasonDowngradeBackoff = "downgrade_backoff"
+	EstimatorReasonUnstable         = "unstable_duration"
+	EstimatorReasonDiffThreshold    = "diff_threshold"
+	EstimatorReasonCeiling          = "manual_ceiling"
+	EstimatorReasonUpgradeBackoff   = "upgrade_backoff"
+	EstimatorReasonStable           = "stable_duration"
+	EstimatorReasonStableEstimate   = "stable_estimate"
+	EstimatorReasonLowestStream     = "lowest_stream"
+	EstimatorReasonHighestStream    = "highest_stream"
+)
+
+// EstimatorDecision is a single decision of the bandwidth estimator, kept for tuning.
+type EstimatorDecision struct {
+	Timestamp     time.Time `json:"timestamp"`
+	Action        string    `json:"action"`
+	Reason        string    `json:"reason"`
+	StreamID      string    `json:"stream_id"`
+	TargetBitrate int       `json:"target_bitrate"`
+	StreamBitrate uint64    `json:"stream_bitrate"`
+	Diff          float64   `json:"diff"`
+	Direction     string    `json:"direction"`
+}
+
+// PeerDiagnostics describe negotiated state of a peer connection, for troubleshooting.
+type PeerDiagnostics struct {
+	ConnectionState    string `json:"connection_state"`
+	ICEConnectionState string `json:"ice_connection_state"`
+	SignalingState     string `json:"signaling_state"`
+
+	LocalDescription  *webrtc.SessionDescription `json:"local_description"`
+	RemoteDescription *webrtc.SessionDescription `json:"remote_description"`
+
+	CandidatePairs []PeerCandidatePair `json:"candidate_pairs"`
+}
+
+type PeerCandidatePair struct {
+	webrtc.ICECandidatePairStats
+	Local  webrtc.ICECandidateStats `json:"local"`
+	Remote webrtc.ICECandidateStats `json:"remote"`
+}
+
+// PeerStats is server side equivalent of browser getStats.
+type PeerStats struct {
+	Timestamp time.Time `json:"timestamp"`
+
+	// outgoing rtp streams by track kind
+	RTPStreams     map[string]PeerRTPStreamStats `json:"rtp_streams"`
+	CandidatePairs []PeerCandidatePair           `json:"candidate_pairs"`
+
+	Transport     *webrtc.TransportStats `json:"transport,omitempty"`
+	SCTPTransport *webrtc.TransportStats `json:"sctp_transport,omitempty"`
+
+	// agreed codecs of outgoing tracks by track kind, empty before negotiation
+	Codecs map[string]PeerNegotiatedCodec `json:"codecs"`
+}
+
+// PeerNegotiatedCodec is codec agreed for sending a track, with header extensions of its section.
+type PeerNegotiatedCodec struct {
+	Mid              string                `json:"mid"`
+	MimeType         string                `json:"mime_type"`
+	ClockRate        uint32                `json:"clock_rate"`
+	Channels         uint16                `json:"channels,omitempty"`
+	SDPFmtpLine      string                `json:"sdp_fmtp_line,omitempty"`
+	PayloadType      uint8                 `json:"payload_type"`
+	HeaderExtensions []PeerHeaderExtension `json:"header_extensions"`
+}
+
+type PeerHeaderExtension struct {
+	ID  int    `json:"id"`
+	URI string `json:"uri"`
+}
+
+type PeerRTPStreamStats struct {
+	SSRC uint32 `json:"ssrc"`
+
+	// outbound, as seen by the server
+	PacketsSent uint64 `json:"packets_sent"`
+	BytesSent   uint64 `json:"bytes_sent"`
+	NACKCount   uint32 `json:"nack_count"`
+	FIRCount    uint32 `json:"fir_count"`
+	PLICount    uint32 `json:"pli_count"`
+
+	// remote inbound, as reported by the client in receiver reports
+	PacketsLost   int64   `json:"packets_lost"`
+	Jitter        float64 `json:"jitter"`
+	FractionLost  float64 `json:"fraction_lost"`
+	RoundTripTime float64 `json:"round_trip_time"` // in seconds
+	// client received no packets for a while although they were sent, video only
+	Freezes uint32 `json:"freezes,omitempty"`
 }
 
 type WebRTCPeer interface {
+	// unique within the server, identifies WHIP/WHEP resource
+	ID() string
 	CreateOffer(ICERestart bool) (*webrtc.SessionDescription, error)
 	CreateAnswer() (*webrtc.SessionDescription, error)
+	Renegotiate() error
 	SetRemoteDescription(webrtc.SessionDescription) error
 	SetCandidate(webrtc.ICECandidateInit) error
 
 	SetPaused(isPaused bool) error
 	Paused() bool
 
+	// validates request without applying it, returns the same error as SetVideo would
+	ValidateVideo(PeerVideoRequest) error
 	SetVideo(PeerVideoRequest) error
 	Video() PeerVideo
+	// validates request without applying it, returns the same error as SetAudio would
+	ValidateAudio(PeerAudioRequest) error
 	SetAudio(PeerAudioRequest) error
 	Audio() PeerAudio
+	// sends current video and audio state to the client again
+	SendSignalState()
+	SetPointerLocked(locked bool)
+	PointerLocked() bool
 
+	DataKey() []byte
+	// latest sender reports by track kind, for A/V sync debugging
+	SenderReports() map[string]SenderReport
+	Diagnostics() PeerDiagnostics
+	EstimatorHistory() []EstimatorDecision
+	Stats() PeerStats
+	// converts coordinates of the current video stream to screen coordinates
+	ToScreen(x, y int) (int, int)
 	SendCursorPosition(x, y int) error
-	SendCursorImage(cur *CursorImage, img []byte) error
+	// hash identifies the image together with its hotspot, unchanged image is not sent again
+	SendCursorImage(cur *CursorImage, img []byte, hash uint64) error
+	// returns false, if clipboard was not sent over the data channel and must be sent over websocket
+	SendClipboard(data ClipboardText) (bool, error)
+	// send raw message over client-initiated data channel
+	SendData(label string, data []byte) error
 
 	Destroy()
 }
@@ -64,7 +431,23 @@ type WebRTCManager interface {
 	Shutdown() error
 
 	ICEServers() []ICEServer
+	// ICE servers for a specific client, ordered by selector if set
+	ICEServersFor(session Session, remoteAddr string) []ICEServer
+	SetICEServerSelector(selector ICEServerSelector)
+	// transforms are applied in order of addition, after built-in ones
+	AddSDPTransform(transform SDPTransform)
+	// route messages of client-initiated data channels with given label
+	AddDataChannelHandler(label string, handler DataChannelHandler)
+	// called periodically for every peer, when rekeying is enabled
+	SetRekeyHandler(handler PeerRekeyHandler)
+	Fingerprints() ([]webrtc.DTLSFingerprint, error)
 
-	CreatePeer(session Session) (*webrtc.SessionDescription, WebRTCPeer, error)
+	CreatePeer(session Session, options PeerOptions) (*webrtc.SessionDescription, WebRTCPeer, error)
+	CreatePeerWithOffer(session Session, offer webrtc.SessionDescription, options PeerOptions) (*webrtc.SessionDescription, WebRTCPeer, error)
 	SetCursorPosition(x, y int)
+
+	// forward video received by the session to an external endpoint
+	StartForward(session Session, config RTPForward) error
+	StopForward()
+	ForwardStatus() RTPForwardStatus
 }
@@ -5,6 +5,35 @@ import (
 	"net/http"
 )
 
+// Versions of the websocket message protocol, negotiated as websocket subprotocol
+// during upgrade, so that old and new clients can be served at the same time.
+//
+//	version | subprotocol        | differences
+//	--------+--------------------+------------------------------------------------------
+//	1       | none or "neko.v1"  | signal/request accepts top level "auto" field,
+//	        |                    | video events contain deprecated "video" field
+//	2       | "neko.v2"          | "auto" is accepted only in the video request,
+//	        |                    | deprecated "video" field is omitted from video events
+const (
+	WebSocketProtocolV1 = 1
+	WebSocketProtocolV2 = 2
+
+	WebSocketProtocolLatest = WebSocketProtocolV2
+)
+
+// supported subprotocols, in order of preference
+var WebSocketSubprotocols = []string{"neko.v2", "neko.v1"}
+
+// WebSocketProtocolVersion returns version of negotiated subprotocol, clients without subprotocol use version 1.
+func WebSocketProtocolVersion(subprotocol string) int {
+	switch subprotocol {
+	case "neko.v2":
+		return WebSocketProtocolV2
+	default:
+		return WebSocketProtocolV1
+	}
+}
+
 type WebSocketMessage struct {
 	Event   string          `json:"event"`
 	Payload json.RawMessage `json:"payload,omitempty"`
@@ -12,17 +41,26 @@ type WebSocketMessage struct {
 
 type WebSocketHandler func(Session, WebSocketMessage) bool
 
+// WebSocketMessageHandler handles payload of a single built-in event.
+type WebSocketMessageHandler func(session Session, payload json.RawMessage) error
+
+// WebSocketMiddleware wraps handler of the given event, e.g. to measure it or to check permissions.
+type WebSocketMiddleware func(event string, next WebSocketMessageHandler) WebSocketMessageHandler
+
 type CheckOrigin func(r *http.Request) bool
 
 type WebSocketPeer interface {
 	Send(event string, payload any)
 	Ping() error
 	Destroy(reason string)
+	// address of the client, as seen by http server
+	RemoteAddr() string
 }
 
 type WebSocketManager interface {
 	Start()
 	Shutdown() error
 	AddHandler(handler WebSocketHandler)
+	AddMiddleware(middleware WebSocketMiddleware)
 	Upgrade(checkOrigin CheckOrigin) RouterHandler
 }
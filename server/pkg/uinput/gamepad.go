@@ -0,0 +1,186 @@
+/* virtual gamepad using linux uinput kernel module */
+package uinput
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"syscall"
+)
+
+type Gamepad struct {
+	mu   sync.Mutex
+	file *os.File
+
+	// last reported state, only changes are written to the device
+	axes    map[uint16]int32
+	buttons map[uint16]int32
+}
+
+func NewGamepad(device string, name string) (*Gamepad, error) {
+	file, err := os.OpenFile(device, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	gamepad := &Gamepad{
+		file:    file,
+		axes:    make(map[uint16]int32),
+		buttons: make(map[uint16]int32),
+	}
+
+	if err := gamepad.create(name); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return gamepad, nil
+}
+
+func (g *Gamepad) ioctl(request, value uintptr) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, g.file.Fd(), request, value)
+	if errno != 0 {
+		return fmt.Errorf("ioctl %#x failed: %w", request, errno)
+	}
+	return nil
+}
+
+func (g *Gamepad) create(name string) error {
+	if err := g.ioctl(UI_SET_EVBIT, EV_KEY); err != nil {
+		return err
+	}
+
+	if err := g.ioctl(UI_SET_EVBIT, EV_ABS); err != nil {
+		return err
+	}
+
+	for _, button := range GamepadButtons {
+		if err := g.ioctl(UI_SET_KEYBIT, uintptr(button)); err != nil {
+			return err
+		}
+	}
+
+	dev := userDev{
+		ID: inputID{
+			Bustype: BUS_VIRTUAL,
+			Vendor:  0x1209, // pid.codes open source vendor id
+			Product: 0x0001,
+			Version: 1,
+		},
+	}
+	copy(dev.Name[:len(dev.Name)-1], name)
+
+	for _, axis := range GamepadAxes {
+		if err := g.ioctl(UI_SET_ABSBIT, uintptr(axis)); err != nil {
+			return err
+		}
+
+		dev.Absmin[axis] = math.MinInt16
+		dev.Absmax[axis] = math.MaxInt16
+		dev.Absflat[axis] = 128
+	}
+
+	for _, axis := range GamepadTriggers {
+		if err := g.ioctl(UI_SET_ABSBIT, uintptr(axis)); err != nil {
+			return err
+		}
+
+		dev.Absmin[axis] = 0
+		dev.Absmax[axis] = math.MaxUint8
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := binary.Write(buffer, binary.NativeEndian, dev); err != nil {
+		return err
+	}
+
+	if _, err := g.file.Write(buffer.Bytes()); err != nil {
+		return err
+	}
+
+	return g.ioctl(UI_DEV_CREATE, 0)
+}
+
+func (g *Gamepad) write(events []inputEvent) error {
+	buffer := &bytes.Buffer{}
+	for _, event := range events {
+		if err := binary.Write(buffer, binary.NativeEndian, event); err != nil {
+			return err
+		}
+	}
+
+	_, err := g.file.Write(buffer.Bytes())
+	return err
+}
+
+// Update sets gamepad state, axes are in range of int16 and buttons
+// are in range of uint8 (to support analog triggers), both ordered
+// by the browser Gamepad API standard mapping.
+func (g *Gamepad) Update(axes []int16, buttons []uint8) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	events := []inputEvent{}
+
+	for i, value := range axes {
+		if i >= len(GamepadAxes) {
+			break
+		}
+
+		code := GamepadAxes[i]
+		if g.axes[code] == int32(value) {
+			continue
+		}
+
+		g.axes[code] = int32(value)
+		events = append(events, inputEvent{Type: EV_ABS, Code: code, Value: int32(value)})
+	}
+
+	for i, value := range buttons {
+		if i >= len(GamepadButtons) {
+			break
+		}
+
+		// analog triggers are reported as axes as well
+		if code, ok := GamepadTriggers[i]; ok && g.axes[code] != int32(value) {
+			g.axes[code] = int32(value)
+			events = append(events, inputEvent{Type: EV_ABS, Code: code, Value: int32(value)})
+		}
+
+		// button is pressed, if its value is at least half of the range
+		pressed := int32(0)
+		if value >= math.MaxUint8/2 {
+			pressed = 1
+		}
+
+		code := GamepadButtons[i]
+		if g.buttons[code] == pressed {
+			continue
+		}
+
+		g.buttons[code] = pressed
+		events = append(events, inputEvent{Type: EV_KEY, Code: code, Value: pressed})
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	events = append(events, inputEvent{Type: EV_SYN, Code: SYN_REPORT})
+	return g.write(events)
+}
+
+func (g *Gamepad) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	err := g.ioctl(UI_DEV_DESTROY, 0)
+	if errClose := g.file.Close(); err == nil {
+		err = errClose
+	}
+
+	return err
+}
@@ -0,0 +1,118 @@
+package uinput
+
+// linux/uinput.h ioctl requests
+const (
+	UI_DEV_CREATE  = 0x5501
+	UI_DEV_DESTROY = 0x5502
+	UI_SET_EVBIT   = 0x40045564
+	UI_SET_KEYBIT  = 0x40045565
+	UI_SET_ABSBIT  = 0x40045567
+)
+
+// linux/input-event-codes.h event types
+const (
+	EV_SYN = 0x00
+	EV_KEY = 0x01
+	EV_ABS = 0x03
+
+	SYN_REPORT = 0x00
+)
+
+// linux/input-event-codes.h absolute axes
+const (
+	ABS_X     = 0x00
+	ABS_Y     = 0x01
+	ABS_Z     = 0x02
+	ABS_RX    = 0x03
+	ABS_RY    = 0x04
+	ABS_RZ    = 0x05
+	ABS_HAT0X = 0x10
+	ABS_HAT0Y = 0x11
+	ABS_CNT   = 0x40
+)
+
+// linux/input-event-codes.h gamepad buttons
+const (
+	BTN_SOUTH      = 0x130
+	BTN_EAST       = 0x131
+	BTN_NORTH      = 0x133
+	BTN_WEST       = 0x134
+	BTN_TL         = 0x136
+	BTN_TR         = 0x137
+	BTN_TL2        = 0x138
+	BTN_TR2        = 0x139
+	BTN_SELECT     = 0x13a
+	BTN_START      = 0x13b
+	BTN_MODE       = 0x13c
+	BTN_THUMBL     = 0x13d
+	BTN_THUMBR     = 0x13e
+	BTN_DPAD_UP    = 0x220
+	BTN_DPAD_DOWN  = 0x221
+	BTN_DPAD_LEFT  = 0x222
+	BTN_DPAD_RIGHT = 0x223
+)
+
+const BUS_VIRTUAL = 0x06
+
+// gamepad buttons ordered by the browser Gamepad API standard mapping
+// https://w3c.github.io/gamepad/#remapping
+var GamepadButtons = []uint16{
+	BTN_SOUTH,      // 0: bottom button in right cluster
+	BTN_EAST,       // 1: right button in right cluster
+	BTN_WEST,       // 2: left button in right cluster
+	BTN_NORTH,      // 3: top button in right cluster
+	BTN_TL,         // 4: top left front button
+	BTN_TR,         // 5: top right front button
+	BTN_TL2,        // 6: bottom left front button
+	BTN_TR2,        // 7: bottom right front button
+	BTN_SELECT,     // 8: left button in center cluster
+	BTN_START,      // 9: right button in center cluster
+	BTN_THUMBL,     // 10: left stick pressed
+	BTN_THUMBR,     // 11: right stick pressed
+	BTN_DPAD_UP,    // 12: top button in left cluster
+	BTN_DPAD_DOWN,  // 13: bottom button in left cluster
+	BTN_DPAD_LEFT,  // 14: left button in left cluster
+	BTN_DPAD_RIGHT, // 15: right button in left cluster
+	BTN_MODE,       // 16: center button in center cluster
+}
+
+// gamepad axes ordered by the browser Gamepad API standard mapping
+var GamepadAxes = []uint16{
+	ABS_X,  // 0: left stick horizontal
+	ABS_Y,  // 1: left stick vertical
+	ABS_RX, // 2: right stick horizontal
+	ABS_RY, // 3: right stick vertical
+}
+
+// analog triggers are reported as buttons in the browser, but as axes in linux
+var GamepadTriggers = map[int]uint16{
+	6: ABS_Z,  // bottom left front button
+	7: ABS_RZ, // bottom right front button
+}
+
+type inputID struct {
+	Bustype uint16
+	Vendor  uint16
+	Product uint16
+	Version uint16
+}
+
+// struct uinput_user_dev
+type userDev struct {
+	Name         [80]byte
+	ID           inputID
+	FFEffectsMax uint32
+	Absmax       [ABS_CNT]int32
+	Absmin       [ABS_CNT]int32
+	Absfuzz      [ABS_CNT]int32
+	Absflat      [ABS_CNT]int32
+}
+
+// struct input_event
+type inputEvent struct {
+	Sec   int64
+	Usec  int64
+	Type  uint16
+	Code  uint16
+	Value int32
+}
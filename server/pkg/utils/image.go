@@ -37,3 +37,40 @@ func CreatePNGImageURI(img *image.RGBA) (string, error) {
 	uri := "data:image/png;base64," + base64.StdEncoding.EncodeToString(data)
 	return uri, nil
 }
+
+// ScaleImage downscales image to given size using box filter, averaging all
+// source pixels covered by destination pixel.
+func ScaleImage(img *image.RGBA, width, height int) *image.RGBA {
+	src := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+
+	for y := 0; y < height; y++ {
+		y0 := src.Min.Y + y*src.Dy()/height
+		y1 := max(src.Min.Y+(y+1)*src.Dy()/height, y0+1)
+
+		for x := 0; x < width; x++ {
+			x0 := src.Min.X + x*src.Dx()/width
+			x1 := max(src.Min.X+(x+1)*src.Dx()/width, x0+1)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					i := img.PixOffset(sx, sy)
+					r += uint32(img.Pix[i+0])
+					g += uint32(img.Pix[i+1])
+					b += uint32(img.Pix[i+2])
+					a += uint32(img.Pix[i+3])
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i+0] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/rs/zerolog"
+)
+
+// moduleLevelWriter filters log events by level configured for their module or submodule,
+// events of modules without override are filtered by the default level.
+type moduleLevelWriter struct {
+	out      io.Writer
+	fallback zerolog.Level
+	levels   map[string]zerolog.Level
+	// events at or above this level pass regardless of their module
+	passLevel zerolog.Level
+}
+
+func NewModuleLevelWriter(out io.Writer, fallback zerolog.Level, levels map[string]zerolog.Level) zerolog.LevelWriter {
+	passLevel := fallback
+	for _, level := range levels {
+		if level > passLevel {
+			passLevel = level
+		}
+	}
+
+	return &moduleLevelWriter{
+		out:       out,
+		fallback:  fallback,
+		levels:    levels,
+		passLevel: passLevel,
+	}
+}
+
+func (w *moduleLevelWriter) Write(p []byte) (int, error) {
+	return w.out.Write(p)
+}
+
+func (w *moduleLevelWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level >= w.passLevel || w.allowed(level, p) {
+		return w.out.Write(p)
+	}
+
+	// pretend the event was written, it was filtered out intentionally
+	return len(p), nil
+}
+
+func (w *moduleLevelWriter) allowed(level zerolog.Level, p []byte) bool {
+	// submodule is more specific, therefore it takes precedence
+	for _, key := range []string{"submodule", "module"} {
+		value, ok := jsonStringField(p, key)
+		if !ok {
+			continue
+		}
+
+		if threshold, ok := w.levels[value]; ok {
+			return level >= threshold
+		}
+	}
+
+	return level >= w.fallback
+}
+
+// jsonStringField extracts value of top level string field from JSON encoded event.
+func jsonStringField(p []byte, key string) (string, bool) {
+	prefix := []byte(`"` + key + `":"`)
+
+	i := bytes.Index(p, prefix)
+	if i < 0 {
+		return "", false
+	}
+
+	rest := p[i+len(prefix):]
+	j := bytes.IndexByte(rest, '"')
+	if j < 0 {
+		return "", false
+	}
+
+	return string(rest[:j]), true
+}
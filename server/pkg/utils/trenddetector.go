@@ -35,6 +35,9 @@ type TrendDetectorParams struct {
 	RequiredSamples        int
 	DownwardTrendThreshold float64
 	CollapseValues         bool
+	// SmoothingFactor is the weight of a new sample in the exponentially
+	// weighted moving average, defaults to 0.2 when not in (0, 1].
+	SmoothingFactor float64
 }
 
 type TrendDetector struct {
@@ -45,11 +48,16 @@ type TrendDetector struct {
 	values       []int64
 	lowestValue  int64
 	highestValue int64
+	smoothed     float64
 
 	direction TrendDirection
 }
 
 func NewTrendDetector(params TrendDetectorParams) *TrendDetector {
+	if params.SmoothingFactor <= 0 || params.SmoothingFactor > 1 {
+		params.SmoothingFactor = 0.2
+	}
+
 	return &TrendDetector{
 		params:    params,
 		startTime: time.Now(),
@@ -74,6 +82,12 @@ func (t *TrendDetector) AddValue(value int64) {
 		t.highestValue = value
 	}
 
+	if t.numSamples == 1 {
+		t.smoothed = float64(value)
+	} else {
+		t.smoothed += t.params.SmoothingFactor * (float64(value) - t.smoothed)
+	}
+
 	// ignore duplicate values
 	if t.params.CollapseValues && len(t.values) != 0 && t.values[len(t.values)-1] == value {
 		return
@@ -103,6 +117,16 @@ func (t *TrendDetector) GetDirection() TrendDirection {
 	return t.direction
 }
 
+// GetSmoothedValue returns exponentially weighted moving average of all added values.
+func (t *TrendDetector) GetSmoothedValue() float64 {
+	return t.smoothed
+}
+
+// GetSlope returns least squares slope of values in the window, in value units per sample.
+func (t *TrendDetector) GetSlope() float64 {
+	return linearSlope(t.values)
+}
+
 func (t *TrendDetector) ToString() string {
 	now := time.Now()
 	elapsed := now.Sub(t.startTime).Seconds()
@@ -151,3 +175,21 @@ func kendallsTau(values []int64) float64 {
 
 	return (float64(concordantPairs) - float64(discordantPairs)) / (float64(concordantPairs) + float64(discordantPairs))
 }
+
+func linearSlope(values []int64) float64 {
+	n := float64(len(values))
+	if n < 2 {
+		return 0.0
+	}
+
+	var sumX, sumY, sumXY, sumXX float64
+	for i, v := range values {
+		x, y := float64(i), float64(v)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	return (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+}
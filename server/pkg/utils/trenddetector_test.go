@@ -0,0 +1,57 @@
+package utils
+
+import "testing"
+
+func TestTrendDetectorSlope(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []int64
+		sign   int
+	}{
+		{"increasing", []int64{100, 200, 300, 400, 500, 600, 700, 800}, 1},
+		{"decreasing", []int64{800, 700, 600, 500, 400, 300, 200, 100}, -1},
+		{"flat", []int64{500, 500, 500, 500, 500, 500, 500, 500}, 0},
+		{"noisy increasing", []int64{100, 300, 200, 400, 350, 600, 500, 700}, 1},
+		{"noisy decreasing", []int64{700, 500, 600, 350, 400, 200, 300, 100}, -1},
+		{"single value", []int64{500}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			td := NewTrendDetector(TrendDetectorParams{
+				RequiredSamples: 8,
+			})
+			for _, v := range tt.values {
+				td.AddValue(v)
+			}
+
+			slope := td.GetSlope()
+			switch {
+			case tt.sign > 0 && slope <= 0,
+				tt.sign < 0 && slope >= 0,
+				tt.sign == 0 && slope != 0:
+				t.Errorf("slope = %f, want sign %d", slope, tt.sign)
+			}
+
+			smoothed := td.GetSmoothedValue()
+			if smoothed < float64(td.GetLowest()) || smoothed > float64(td.GetHighest()) {
+				t.Errorf("smoothed = %f, want between %d and %d", smoothed, td.GetLowest(), td.GetHighest())
+			}
+		})
+	}
+}
+
+func TestTrendDetectorSlopeWindow(t *testing.T) {
+	td := NewTrendDetector(TrendDetectorParams{
+		RequiredSamples: 4,
+	})
+
+	// rising values fall out of the window, only the falling tail is considered
+	for _, v := range []int64{100, 200, 300, 400, 900, 800, 700, 600} {
+		td.AddValue(v)
+	}
+
+	if slope := td.GetSlope(); slope != -100 {
+		t.Errorf("slope = %f, want -100", slope)
+	}
+}
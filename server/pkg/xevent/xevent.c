@@ -27,6 +27,13 @@ void XEventLoop(char *name) {
     return;
   }
 
+  // screen configuration changes are used to detect dpi changes
+  int xrandr_event_base, xrandr_error_base;
+  int xrandr = XRRQueryExtension(display, &xrandr_event_base, &xrandr_error_base);
+  if (xrandr) {
+    XRRSelectInput(display, root, RRScreenChangeNotifyMask);
+  }
+
   // save last size id for fullscreen bug fix
   SizeID last_size_id;
 
@@ -34,7 +41,7 @@ void XEventLoop(char *name) {
   Atom XA_CLIPBOARD = XInternAtom(display, "CLIPBOARD", 0);
   XFixesSelectSelectionInput(display, root, XA_CLIPBOARD, XFixesSetSelectionOwnerNotifyMask);
   XFixesSelectCursorInput(display, root, XFixesDisplayCursorNotifyMask);
-  XSelectInput(display, root, SubstructureNotifyMask);
+  XSelectInput(display, root, SubstructureNotifyMask | PropertyChangeMask);
 
   XSync(display, 0);
 
@@ -55,11 +62,29 @@ void XEventLoop(char *name) {
     if (event.type == xfixes_event_base + XFixesSelectionNotify) {
       XFixesSelectionNotifyEvent notifyEvent = *((XFixesSelectionNotifyEvent *) &event);
       if (notifyEvent.subtype == XFixesSetSelectionOwnerNotify && notifyEvent.selection == XA_CLIPBOARD) {
-        goXEventClipboardUpdated();
+        // cleared clipboard has no owner and no content to sync
+        if (notifyEvent.owner != None) {
+          goXEventClipboardUpdated();
+        }
         continue;
       }
     }
 
+    // RRScreenChangeNotify
+    if (xrandr && event.type == xrandr_event_base + RRScreenChangeNotify) {
+      XRRUpdateConfiguration(&event);
+      goXEventScreenChanged();
+      continue;
+    }
+
+    // PropertyNotify, Xft.dpi is part of the resource manager property
+    if (event.type == PropertyNotify) {
+      if (event.xproperty.window == root && event.xproperty.atom == XA_RESOURCE_MANAGER) {
+        goXEventScreenChanged();
+      }
+      continue;
+    }
+
     // ConfigureNotify
     if (event.type == ConfigureNotify) {
       Window window = event.xconfigure.window;
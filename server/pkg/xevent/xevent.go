@@ -1,7 +1,7 @@
 package xevent
 
 /*
-#cgo LDFLAGS: -lX11 -lXfixes
+#cgo LDFLAGS: -lX11 -lXfixes -lXrandr
 
 #include "xevent.h"
 */
@@ -44,6 +44,11 @@ func goXEventClipboardUpdated() {
 	Emmiter.Emit("clipboard-updated")
 }
 
+//export goXEventScreenChanged
+func goXEventScreenChanged() {
+	Emmiter.Emit("screen-changed")
+}
+
 //export goXEventConfigureNotify
 func goXEventConfigureNotify(display *C.Display, window C.Window, name *C.char, role *C.char) {
 	if C.GoString(role) != "GtkFileChooserDialog" || !FileChooserDialog {
@@ -15,12 +15,33 @@ void XDisplayClose(void) {
   XCloseDisplay(DISPLAY);
 }
 
+int XDisplaySwitch(char *name) {
+  Display *display = XOpenDisplay(name);
+  if (display == NULL) {
+    return 1;
+  }
+
+  // previous display is closed only when the new one was opened
+  if (DISPLAY != NULL) {
+    XCloseDisplay(DISPLAY);
+  }
+
+  DISPLAY = display;
+  return 0;
+}
+
 void XMove(int x, int y) {
   Display *display = getXDisplay();
   XWarpPointer(display, None, DefaultRootWindow(display), 0, 0, 0, 0, x, y);
   XSync(display, 0);
 }
 
+void XMoveRelative(int dx, int dy) {
+  Display *display = getXDisplay();
+  XTestFakeRelativeMotionEvent(display, dx, dy, CurrentTime);
+  XSync(display, 0);
+}
+
 void XCursorPosition(int *x, int *y) {
   Display *display = getXDisplay();
   Window root = DefaultRootWindow(display);
@@ -282,6 +303,56 @@ void XGetScreenConfiguration(int *width, int *height, short *rate) {
   XRRFreeScreenConfigInfo(conf);
 }
 
+int XGetScreenDPI() {
+  Display *display = getXDisplay();
+  Window root = DefaultRootWindow(display);
+  int dpi = 0;
+
+  // Xft.dpi is set by desktop environments when scaling changes,
+  // property is read directly because the resource database is cached
+  Atom type;
+  int format;
+  unsigned long nitems, bytes_after;
+  unsigned char *data = NULL;
+
+  if (XGetWindowProperty(display, root, XA_RESOURCE_MANAGER, 0, 16384, False, XA_STRING,
+      &type, &format, &nitems, &bytes_after, &data) == Success && data != NULL) {
+    char *value = strstr((char *) data, "Xft.dpi:");
+    if (value != NULL) {
+      dpi = (int) (strtod(value + strlen("Xft.dpi:"), NULL) + 0.5);
+    }
+    XFree(data);
+  }
+
+  if (dpi > 0) {
+    return dpi;
+  }
+
+  // otherwise compute it from physical size of the current configuration
+  XRRScreenConfiguration *conf = XRRGetScreenInfo(display, root);
+
+  Rotation current_rotation;
+  SizeID current_size_id = XRRConfigCurrentConfiguration(conf, &current_rotation);
+
+  XRRScreenSize *xrrs;
+  int num_sizes;
+  xrrs = XRRConfigSizes(conf, &num_sizes);
+
+  if (current_size_id < num_sizes && xrrs[current_size_id].mwidth > 0) {
+    dpi = (int) (xrrs[current_size_id].width * 25.4 / xrrs[current_size_id].mwidth + 0.5);
+  }
+
+  XRRFreeScreenConfigInfo(conf);
+  return dpi;
+}
+
+int XHasDamage() {
+  Display *display = getXDisplay();
+  int major_opcode, first_event, first_error;
+
+  return XQueryExtension(display, "DAMAGE", &major_opcode, &first_event, &first_error);
+}
+
 void XGetScreenConfigurations() {
   Display *display = getXDisplay();
   Window root = DefaultRootWindow(display);
@@ -405,3 +476,14 @@ char *XGetScreenshot(int *w, int *h) {
   XDestroyImage(ximage);
   return pixels;
 }
+
+void XClearSelections(void) {
+  Display *display = getXDisplay();
+  Atom XA_CLIPBOARD = XInternAtom(display, "CLIPBOARD", 0);
+
+  // selections without owner are empty
+  XSetSelectionOwner(display, XA_PRIMARY, None, CurrentTime);
+  XSetSelectionOwner(display, XA_SECONDARY, None, CurrentTime);
+  XSetSelectionOwner(display, XA_CLIPBOARD, None, CurrentTime);
+  XSync(display, 0);
+}
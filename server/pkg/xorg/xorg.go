@@ -50,6 +50,8 @@ func GetScreenConfigurations() {
 	mu.Lock()
 	defer mu.Unlock()
 
+	// configurations of previously opened display are replaced
+	clear(ScreenConfigurations)
 	C.XGetScreenConfigurations()
 }
 
@@ -71,6 +73,22 @@ func DisplayClose() {
 	C.XDisplayClose()
 }
 
+// DisplaySwitch opens the display and closes the previous one, that
+// stays open when the display cannot be opened
+func DisplaySwitch(display string) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	displayUnsafe := C.CString(display)
+	defer C.free(unsafe.Pointer(displayUnsafe))
+
+	if int(C.XDisplaySwitch(displayUnsafe)) == 1 {
+		return types.ErrDisplayUnavailable
+	}
+
+	return nil
+}
+
 func Move(x, y int) {
 	mu.Lock()
 	defer mu.Unlock()
@@ -78,6 +96,13 @@ func Move(x, y int) {
 	C.XMove(C.int(x), C.int(y))
 }
 
+func MoveRelative(dx, dy int) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	C.XMoveRelative(C.int(dx), C.int(dy))
+}
+
 func GetCursorPosition() (int, int) {
 	mu.Lock()
 	defer mu.Unlock()
@@ -157,6 +182,19 @@ func KeyUp(code uint32) error {
 	return nil
 }
 
+// KeysDown returns keys, that are currently held down
+func KeysDown() []uint32 {
+	mu.Lock()
+	defer mu.Unlock()
+
+	keys := make([]uint32, 0, len(debounce_key))
+	for code := range debounce_key {
+		keys = append(keys, code)
+	}
+
+	return keys
+}
+
 func ResetKeys() {
 	mu.Lock()
 	defer mu.Unlock()
@@ -251,6 +289,21 @@ func GetScreenSize() types.ScreenSize {
 	}
 }
 
+func GetScreenDPI() int {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return int(C.XGetScreenDPI())
+}
+
+// HasDamage reports whether X server supports DAMAGE extension, that reports changed regions
+func HasDamage() bool {
+	mu.Lock()
+	defer mu.Unlock()
+
+	return C.XHasDamage() != 0
+}
+
 func SetKeyboardModifier(mod KbdMod, active bool) {
 	mu.Lock()
 	defer mu.Unlock()
@@ -336,6 +389,14 @@ func GetScreenshotImage() *image.RGBA {
 	return img
 }
 
+// ClearSelections empties primary, secondary and clipboard selections
+func ClearSelections() {
+	mu.Lock()
+	defer mu.Unlock()
+
+	C.XClearSelections()
+}
+
 //export goCreateScreenSize
 func goCreateScreenSize(index C.int, width C.int, height C.int, mwidth C.int, mheight C.int) {
 	ScreenConfigurations[int(index)] = ScreenConfiguration{
@@ -3,6 +3,7 @@
 #include <X11/Xlib.h>
 #include <X11/XKBlib.h>
 #include <X11/Xutil.h>
+#include <X11/Xatom.h>
 #include <X11/extensions/Xrandr.h>
 #include <X11/extensions/XTest.h>
 #include <X11/extensions/Xfixes.h>
@@ -19,8 +20,10 @@ extern void goSetScreenRates(int index, int rate_index, short rate);
 Display *getXDisplay(void);
 int XDisplayOpen(char *input);
 void XDisplayClose(void);
+int XDisplaySwitch(char *input);
 
 void XMove(int x, int y);
+void XMoveRelative(int dx, int dy);
 void XCursorPosition(int *x, int *y);
 void XScroll(int deltaX, int deltaY);
 void XButton(unsigned int button, int down);
@@ -39,6 +42,8 @@ void XKey(KeySym keysym, int down);
 Status XSetScreenConfiguration(int width, int height, short rate);
 void XGetScreenConfiguration(int *width, int *height, short *rate);
 void XGetScreenConfigurations();
+int XGetScreenDPI();
+int XHasDamage();
 void XCreateScreenMode(int width, int height, short rate);
 XRRModeInfo *XCreateScreenModeInfo(int hdisplay, int vdisplay, short vrefresh);
 
@@ -47,3 +52,5 @@ unsigned char XGetKeyboardModifiers();
 XFixesCursorImage *XGetCursorImage(void);
 
 char *XGetScreenshot(int *w, int *h);
+
+void XClearSelections(void);